@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go-crud-app/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// runCreateAdmin creates an admin account. The password is read from
+// ADMIN_PASSWORD or, with -password-stdin, from the first line of stdin, so it
+// never appears in the process list or shell history.
+func runCreateAdmin(args []string) int {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", os.Getenv("ADMIN_EMAIL"), "admin email address (default $ADMIN_EMAIL)")
+	username := fs.String("username", getEnv("ADMIN_USERNAME", "admin"), "admin username (default $ADMIN_USERNAME or admin)")
+	superAdmin := fs.Bool("superadmin", getEnvBool("ADMIN_SUPERADMIN", false), "make the account an admin of every organization (default $ADMIN_SUPERADMIN)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of $ADMIN_PASSWORD")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if *passwordStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(os.Stderr, "failed to read password from stdin")
+			return exitUsage
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if *email == "" || password == "" {
+		fmt.Fprintln(os.Stderr, "create-admin requires -email (or ADMIN_EMAIL) and a password (ADMIN_PASSWORD or -password-stdin)")
+		return exitUsage
+	}
+
+	if err := applyInputPolicies(); err != nil {
+		log.Printf("Invalid configuration: %v", err)
+		return exitUsage
+	}
+
+	if err := database.Connect(databaseConfigFromEnv()); err != nil {
+		log.Printf("Failed to connect to database: %v", err)
+		return exitError
+	}
+	defer database.Close()
+
+	admin, err := database.CreateAdmin(database.AdminSeed{
+		Username:   *username,
+		Email:      *email,
+		Password:   password,
+		SuperAdmin: *superAdmin,
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		log.Print("A user with that email or username already exists")
+		return exitError
+	}
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+
+	log.Printf("Created admin user %s (id %d)", admin.Email, admin.ID)
+	return exitOK
+}
@@ -1,107 +1,190 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"go-crud-app/internal/database"
 	"go-crud-app/internal/handlers"
-	"go-crud-app/internal/middleware"
 	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
+// Exit codes shared by every subcommand
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// Build information, injected at build time with
+// -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+// buildInfo returns the injected build information
+func buildInfo() handlers.BuildInfo {
+	return handlers.BuildInfo{Version: version, Commit: commit, BuildDate: buildDate}
+}
+
+// command is a subcommand of the server binary
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands lists the subcommands; the first is the default when none is given
+var commands = []command{
+	{name: "serve", summary: "Run the HTTP and gRPC servers (default)", run: runServe},
+	{name: "migrate", summary: "Apply (up) or roll back (down) database migrations", run: runMigrate},
+	{name: "create-admin", summary: "Create an admin account", run: runCreateAdmin},
+	{name: "purge", summary: "Permanently delete users soft-deleted longer ago than the retention period", run: runPurge},
+	{name: "healthcheck", summary: "Check that a running server is healthy", run: runHealthcheck},
+}
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	// Database configuration
-	dbConfig := database.Config{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", "postgres"),
-		DBName:   getEnv("DB_NAME", "gocrud"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+	os.Exit(run(os.Args[1:]))
+}
+
+// run dispatches to the subcommand named by args[0], defaulting to serve, and
+// returns the process exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		return commands[0].run(nil)
 	}
 
-	// Connect to database
-	if err := database.Connect(dbConfig); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer database.Close()
-
-	// Run migrations
-	if err := database.Migrate(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
-	}
-
-	// JWT configuration
-	jwtConfig := utils.JWTConfig{
-		SecretKey:       getEnv("JWT_SECRET", "your-secret-key-change-this-in-production"),
-		ExpirationHours: 24, // 24 hours
-	}
-
-	// Initialize Gin router
-	router := gin.Default()
-
-	// CORS configuration
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{getEnv("CORS_ORIGIN", "*")},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	// Rate limiters
-	authLimiter := middleware.NewRateLimiter(5, 1*time.Minute)      // 5 requests per minute for auth
-	registerLimiter := middleware.NewRateLimiter(3, 1*time.Minute)  // 3 requests per minute for registration
-	generalLimiter := middleware.NewRateLimiter(100, 1*time.Minute) // 100 requests per minute for general endpoints
-
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "healthy",
-			"time":   time.Now().Format(time.RFC3339),
-		})
-	})
-
-	// API routes
-	api := router.Group("/api")
-	{
-		// Authentication routes (with rate limiting)
-		auth := api.Group("/auth")
-		{
-			auth.POST("/register", middleware.RateLimitMiddleware(registerLimiter), handlers.Register(jwtConfig))
-			auth.POST("/login", middleware.RateLimitMiddleware(authLimiter), handlers.Login(jwtConfig))
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
 		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printUsage(os.Stderr)
+	return exitUsage
+}
+
+// printUsage lists the available subcommands
+func printUsage(w *os.File) {
+	fmt.Fprintln(w, "usage: server [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// applyInputPolicies configures input normalization, password strength and
+// password hashing from the environment, so every subcommand that writes
+// users validates and stores them the same way
+func applyInputPolicies() error {
+	// Input normalization policy applied across all write paths
+	validation.Policy = validation.NormalizationPolicy{
+		TrimSpace:          getEnvBool("NORMALIZE_TRIM_SPACE", validation.DefaultPolicy.TrimSpace),
+		LowercaseEmail:     getEnvBool("NORMALIZE_LOWERCASE_EMAIL", validation.DefaultPolicy.LowercaseEmail),
+		CollapseWhitespace: getEnvBool("NORMALIZE_COLLAPSE_WHITESPACE", validation.DefaultPolicy.CollapseWhitespace),
+	}
+
+	// Email domains that may or may not self-register
+	allow, err := validation.ParseDomainList(os.Getenv("EMAIL_DOMAIN_ALLOWLIST"))
+	if err != nil {
+		return fmt.Errorf("EMAIL_DOMAIN_ALLOWLIST: %w", err)
+	}
+	deny, err := validation.ParseDomainList(os.Getenv("EMAIL_DOMAIN_DENYLIST"))
+	if err != nil {
+		return fmt.Errorf("EMAIL_DOMAIN_DENYLIST: %w", err)
+	}
+	validation.EmailDomains = validation.DomainPolicy{
+		Allow:           allow,
+		Deny:            deny,
+		BlockDisposable: getEnvBool("BLOCK_DISPOSABLE_EMAILS", false),
+	}
 
-		// Protected user routes (require authentication)
-		users := api.Group("/users")
-		users.Use(middleware.AuthMiddleware(jwtConfig.SecretKey))
-		users.Use(middleware.RateLimitMiddleware(generalLimiter))
-		{
-			users.GET("", handlers.GetAllUsers)       // List all users except current user
-			users.GET("/me", handlers.GetCurrentUser) // Get current user profile
-			users.GET("/:id", handlers.GetUserByID)   // Get user by ID
-			users.PUT("/:id", handlers.UpdateUser)    // Update user (own profile only)
-			users.DELETE("/:id", handlers.DeleteUser) // Delete user (own profile only)
+	// Usernames users may not register or change to
+	reserved, err := validation.ParseUsernameList(os.Getenv("RESERVED_USERNAMES"))
+	if err != nil {
+		return fmt.Errorf("RESERVED_USERNAMES: %w", err)
+	}
+	validation.Usernames = validation.NewUsernamePolicy(getEnvBool("RESERVE_DEFAULT_USERNAMES", true), reserved...)
+
+	// Password strength requirements for registration and password reset
+	utils.PasswordRules = utils.PasswordPolicy{
+		MinLength:        getEnvInt("PASSWORD_MIN_LENGTH", utils.DefaultPasswordPolicy.MinLength),
+		RequireUppercase: getEnvBool("PASSWORD_REQUIRE_UPPERCASE", utils.DefaultPasswordPolicy.RequireUppercase),
+		RequireLowercase: getEnvBool("PASSWORD_REQUIRE_LOWERCASE", utils.DefaultPasswordPolicy.RequireLowercase),
+		RequireDigit:     getEnvBool("PASSWORD_REQUIRE_DIGIT", utils.DefaultPasswordPolicy.RequireDigit),
+	}
+
+	// Days a password may be used before it must be changed (0 disables expiry)
+	maxAgeDays := getEnvInt("PASSWORD_MAX_AGE_DAYS", 0)
+	if maxAgeDays < 0 {
+		return fmt.Errorf("PASSWORD_MAX_AGE_DAYS must not be negative")
+	}
+	utils.PasswordMaxAge = time.Duration(maxAgeDays) * 24 * time.Hour
+
+	// Algorithm for new and changed password hashes; existing hashes of either
+	// algorithm keep verifying and are upgraded on login
+	hasher, err := utils.NewPasswordHasher(getEnv("PASSWORD_HASH_ALGORITHM", utils.PasswordHashBcrypt))
+	if err != nil {
+		return err
+	}
+	utils.Hasher = hasher
+
+	// Optional pepper for new hashes, plus retired peppers still accepted
+	// until their hashes are upgraded on login
+	utils.CurrentPepper = nil
+	if secret := os.Getenv("PEPPER"); secret != "" {
+		pepper := utils.Pepper{ID: getEnv("PEPPER_ID", "1"), Secret: secret}
+		if err := pepper.Validate(); err != nil {
+			return err
 		}
+		utils.CurrentPepper = &pepper
 	}
+	previous, err := utils.ParsePeppers(os.Getenv("PEPPER_PREVIOUS"))
+	if err != nil {
+		return err
+	}
+	for _, pepper := range previous {
+		if utils.CurrentPepper != nil && pepper.ID == utils.CurrentPepper.ID {
+			return fmt.Errorf("PEPPER_PREVIOUS reuses the current PEPPER_ID %q", pepper.ID)
+		}
+	}
+	utils.PreviousPeppers = previous
+	return nil
+}
 
-	// Start server
-	port := getEnv("PORT", "8080")
-	log.Printf("Server starting on port %s...", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+// databaseConfigFromEnv builds the database configuration from environment variables
+func databaseConfigFromEnv() database.Config {
+	port := getEnv("DB_PORT", "5432")
+	return database.Config{
+		Host:     getEnv("DB_HOST", "localhost"),
+		Port:     port,
+		User:     getEnv("DB_USER", "postgres"),
+		Password: getEnv("DB_PASSWORD", "postgres"),
+		DBName:   getEnv("DB_NAME", "gocrud"),
+		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+		Replicas: database.ParseReplicas(os.Getenv("DB_REPLICAS"), port),
+		SlowQueryThreshold: time.Duration(getEnvInt("DB_SLOW_QUERY_MS",
+			int(database.DefaultSlowQueryThreshold/time.Millisecond))) * time.Millisecond,
 	}
 }
 
@@ -113,3 +196,30 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvInt gets an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat gets a floating point environment variable or returns a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvBool gets a boolean environment variable or returns a default value
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
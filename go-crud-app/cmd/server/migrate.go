@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"go-crud-app/internal/database"
+)
+
+// runMigrate handles "migrate up" (apply pending migrations) and "migrate
+// down" (roll back the last one)
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), "usage: server migrate up|down") }
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 || (fs.Arg(0) != "up" && fs.Arg(0) != "down") {
+		fs.Usage()
+		return exitUsage
+	}
+
+	if err := database.Connect(databaseConfigFromEnv()); err != nil {
+		log.Printf("Failed to connect to database: %v", err)
+		return exitError
+	}
+	defer database.Close()
+
+	run := database.Migrate
+	if fs.Arg(0) == "down" {
+		run = database.Rollback
+	}
+	if err := run(); err != nil {
+		log.Print(err)
+		return exitError
+	}
+	return exitOK
+}
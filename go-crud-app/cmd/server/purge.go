@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/retention"
+)
+
+// runPurge permanently deletes users soft-deleted more than -days days ago,
+// as the background purger does when DELETED_USER_RETENTION_DAYS is set. An
+// interrupt stops it between batches.
+func runPurge(args []string) int {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	days := fs.Int("days", getEnvInt("DELETED_USER_RETENTION_DAYS", 0), "purge users soft-deleted more than this many days ago (default $DELETED_USER_RETENTION_DAYS)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *days <= 0 {
+		fmt.Fprintln(os.Stderr, "purge requires a positive -days (or DELETED_USER_RETENTION_DAYS)")
+		return exitUsage
+	}
+
+	if err := database.Connect(databaseConfigFromEnv()); err != nil {
+		log.Printf("Failed to connect to database: %v", err)
+		return exitError
+	}
+	defer database.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	purger := &retention.Purger{After: time.Duration(*days) * 24 * time.Hour}
+	purged, err := purger.Purge(ctx, time.Now())
+	log.Printf("Purged %d users soft-deleted more than %d days ago", purged, *days)
+	if err != nil {
+		log.Printf("Purge stopped early: %v", err)
+		return exitError
+	}
+	return exitOK
+}
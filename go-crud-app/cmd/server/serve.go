@@ -0,0 +1,696 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go-crud-app/internal/config"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/graphqlapi"
+	"go-crud-app/internal/grpcapi"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/loginthrottle"
+	"go-crud-app/internal/mailer"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/retention"
+	"go-crud-app/internal/storage"
+	"go-crud-app/internal/supervisor"
+	"go-crud-app/internal/tlsserver"
+	"go-crud-app/internal/tracing"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+	"go-crud-app/internal/webhook"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// runServe starts the HTTP and gRPC servers and blocks until interrupted. It
+// is the default subcommand.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), "usage: server serve") }
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	log.Printf("Starting version %s (commit %s, built %s)", version, commit, buildDate)
+
+	// Refuse to start with insecure critical settings outside development
+	appEnv, err := config.AppEnv(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	warnings, err := config.Validate(appEnv, os.Getenv)
+	for _, warning := range warnings {
+		log.Printf("Config warning: %s", warning)
+	}
+	if err != nil {
+		log.Fatalf("Refusing to start in %s: %v", appEnv, err)
+	}
+
+	// Export traces over OTLP when a collector is configured
+	traceConfig := tracing.Config{
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		ServiceName: getEnv("OTEL_SERVICE_NAME", tracing.DefaultServiceName),
+		SampleRatio: getEnvFloat("OTEL_TRACES_SAMPLE_RATIO", 1),
+	}
+	if traceConfig.SampleRatio < 0 || traceConfig.SampleRatio > 1 {
+		log.Fatalf("OTEL_TRACES_SAMPLE_RATIO must be between 0 and 1")
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), traceConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	if traceConfig.Endpoint == "" {
+		log.Println("OTEL_EXPORTER_OTLP_ENDPOINT not set; traces are not exported")
+	}
+
+	// Connect to database
+	if err := database.Connect(databaseConfigFromEnv()); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	// Apply pending versioned migrations, or sync straight from the models in
+	// development when AUTO_MIGRATE is set
+	migrate := database.Migrate
+	if getEnvBool("AUTO_MIGRATE", false) {
+		migrate = database.AutoMigrate
+	}
+	if err := migrate(); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// JWT configuration
+	jwtConfig := utils.JWTConfig{
+		SecretKey:              getEnv("JWT_SECRET", config.DefaultJWTSecret),
+		ExpirationHours:        getEnvInt("JWT_EXPIRATION_HOURS", 24),
+		RefreshExpirationHours: getEnvInt("JWT_REFRESH_EXPIRATION_HOURS", 168),
+		MinimalClaims:          getEnvBool("JWT_MINIMAL_CLAIMS", false),
+	}
+	if err := jwtConfig.Validate(); err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+
+	// Password reset configuration
+	passwordResetConfig := handlers.PasswordResetConfig{
+		Mailer:             mailer.LogMailer{},
+		TokenTTL:           time.Duration(getEnvInt("PASSWORD_RESET_TOKEN_TTL_MINUTES", 60)) * time.Minute,
+		MaxRequestsPerHour: getEnvInt("PASSWORD_RESET_MAX_PER_HOUR", 3),
+		ResetURL:           getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+	}
+
+	// Data retention bounds for user preferences
+	retentionPolicy := retention.Policy{
+		MinDays:     getEnvInt("RETENTION_MIN_DAYS", retention.DefaultPolicy().MinDays),
+		MaxDays:     getEnvInt("RETENTION_MAX_DAYS", retention.DefaultPolicy().MaxDays),
+		DefaultDays: getEnvInt("RETENTION_DEFAULT_DAYS", retention.DefaultPolicy().DefaultDays),
+	}
+
+	// Avatar storage (local disk or S3-compatible) and image processing
+	var avatarStore storage.Store
+	switch getEnv("AVATAR_STORAGE", "local") {
+	case "s3":
+		store, err := storage.NewS3Store(storage.S3Config{
+			Endpoint:        getEnv("S3_ENDPOINT", ""),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          getEnv("S3_BUCKET", ""),
+			AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure avatar storage: %v", err)
+		}
+		avatarStore = store
+	default:
+		store, err := storage.NewLocalStore(getEnv("AVATAR_LOCAL_DIR", "./uploads"))
+		if err != nil {
+			log.Fatalf("Failed to configure avatar storage: %v", err)
+		}
+		avatarStore = store
+	}
+
+	avatarImage := utils.DefaultImageConfig()
+	avatarImage.MaxDimension = getEnvInt("AVATAR_MAX_DIMENSION", avatarImage.MaxDimension)
+	avatarImage.ResizeTo = getEnvInt("AVATAR_SIZE", avatarImage.ResizeTo)
+	avatarImage.Format = getEnv("AVATAR_FORMAT", avatarImage.Format)
+	avatarImage.JPEGQuality = getEnvInt("AVATAR_JPEG_QUALITY", avatarImage.JPEGQuality)
+
+	avatarConfig := handlers.AvatarConfig{
+		Store:    avatarStore,
+		MaxBytes: int64(getEnvInt("AVATAR_MAX_BYTES", int(handlers.DefaultAvatarMaxBytes))),
+		Image:    avatarImage,
+	}
+
+	// Page sizes for the user list; cursors are signed with the JWT secret
+	userListConfig := handlers.UserListConfig{
+		DefaultPageSize: getEnvInt("USER_PAGE_SIZE", handlers.DefaultUserPageSize),
+		MaxPageSize:     getEnvInt("USER_MAX_PAGE_SIZE", handlers.DefaultMaxUserPageSize),
+		CursorSecret:    jwtConfig.SecretKey,
+	}
+	if userListConfig.DefaultPageSize < 1 || userListConfig.DefaultPageSize > userListConfig.MaxPageSize {
+		log.Fatalf("Invalid configuration: USER_PAGE_SIZE must be between 1 and USER_MAX_PAGE_SIZE")
+	}
+
+	// Include the request ID in error response bodies unless disabled
+	middleware.IncludeRequestIDInErrors = getEnvBool("ERROR_INCLUDE_REQUEST_ID", true)
+
+	// Retry-After header format for 429 and 503 responses
+	middleware.RetryAfterMode = middleware.RetryAfterFormat(getEnv("RETRY_AFTER_FORMAT", string(middleware.RetryAfterSeconds)))
+
+	// Share of a rate limit at which clients are warned, in percent (0 disables)
+	middleware.RateLimitWarningPercent = getEnvInt("RATE_LIMIT_WARNING_PERCENT", 0)
+	if middleware.RateLimitWarningPercent < 0 || middleware.RateLimitWarningPercent > 100 {
+		log.Fatalf("RATE_LIMIT_WARNING_PERCENT must be between 0 and 100")
+	}
+
+	// Input normalization and password policies
+	if err := applyInputPolicies(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Per-account delays after repeated failed logins (off, fixed or exponential)
+	defaultThrottle := loginthrottle.DefaultPolicy()
+	loginthrottle.Current = loginthrottle.Policy{
+		Strategy:     getEnv("LOGIN_THROTTLE_STRATEGY", defaultThrottle.Strategy),
+		MaxAttempts:  getEnvInt("LOGIN_THROTTLE_MAX_ATTEMPTS", defaultThrottle.MaxAttempts),
+		LockDuration: time.Duration(getEnvInt("LOGIN_THROTTLE_LOCK_SECONDS", int(defaultThrottle.LockDuration.Seconds()))) * time.Second,
+		BaseDelay:    time.Duration(getEnvInt("LOGIN_THROTTLE_BASE_DELAY_SECONDS", int(defaultThrottle.BaseDelay.Seconds()))) * time.Second,
+		MaxDelay:     time.Duration(getEnvInt("LOGIN_THROTTLE_MAX_DELAY_SECONDS", int(defaultThrottle.MaxDelay.Seconds()))) * time.Second,
+	}
+	if err := loginthrottle.Current.Validate(); err != nil {
+		log.Fatalf("Invalid login throttle configuration: %v", err)
+	}
+
+	// What non-admins see of other users' profiles (restricted or full)
+	handlers.ProfileVisibility = getEnv("PROFILE_VISIBILITY", handlers.ProfileVisibilityRestricted)
+
+	// Whether self-registration is open
+	handlers.RegistrationOpen = getEnvBool("REGISTRATION_OPEN", true)
+
+	// Which organization self-registered users join (none, invite or open)
+	handlers.OrgRegistration = getEnv("ORG_REGISTRATION", handlers.OrgRegistrationNone)
+	if !handlers.ValidOrgRegistration(handlers.OrgRegistration) {
+		log.Fatalf("Invalid ORG_REGISTRATION %q: must be none, invite or open", handlers.OrgRegistration)
+	}
+
+	// Whether self-registration requires an admin-issued invite, and how long invites last
+	handlers.Invites = handlers.InviteConfig{
+		Required: getEnvBool("REGISTRATION_INVITE_ONLY", false),
+		TTL:      time.Duration(getEnvInt("INVITE_TTL_HOURS", int(handlers.DefaultInviteTTL/time.Hour))) * time.Hour,
+	}
+
+	// How many recent passwords can't be chosen again (0 disables the check)
+	handlers.PasswordHistorySize = getEnvInt("PASSWORD_HISTORY_SIZE", 0)
+	if handlers.PasswordHistorySize < 0 || handlers.PasswordHistorySize > handlers.MaxPasswordHistorySize {
+		log.Fatalf("PASSWORD_HISTORY_SIZE must be between 0 and %d", handlers.MaxPasswordHistorySize)
+	}
+
+	// Whether the availability check says which of username and email is taken
+	handlers.AvailabilityDetailed = getEnvBool("AVAILABILITY_DETAILED", false)
+
+	// Whether registering with a deleted account's email restores that account
+	handlers.ReactivateDeletedAccounts = getEnvBool("REACTIVATE_DELETED_ACCOUNTS", false)
+
+	// What registration reveals when the email or username is taken (verbose,
+	// generic or strict)
+	handlers.RegistrationPrivacy = handlers.RegistrationPrivacyConfig{
+		DuplicateResponse: getEnv("REGISTRATION_DUPLICATE_RESPONSE", handlers.DuplicateResponseVerbose),
+		Mailer:            mailer.LogMailer{},
+		TokenTTL:          time.Duration(getEnvInt("REGISTRATION_TOKEN_TTL_HOURS", int(handlers.DefaultRegistrationTokenTTL/time.Hour))) * time.Hour,
+		ConfirmURL:        getEnv("REGISTRATION_CONFIRM_URL", "http://localhost:8080/api/auth/confirm-registration"),
+	}
+	if err := handlers.RegistrationPrivacy.Validate(); err != nil {
+		log.Fatalf("Invalid registration privacy configuration: %v", err)
+	}
+
+	// Whether a new email must be confirmed from that address before it applies
+	handlers.EmailChange = handlers.EmailChangeConfig{
+		Confirm:    getEnvBool("EMAIL_CHANGE_CONFIRMATION", true),
+		Mailer:     mailer.LogMailer{},
+		TokenTTL:   time.Duration(getEnvInt("EMAIL_CHANGE_TOKEN_TTL_MINUTES", int(handlers.DefaultEmailChangeTokenTTL/time.Minute))) * time.Minute,
+		ConfirmURL: getEnv("EMAIL_CHANGE_CONFIRM_URL", "http://localhost:8080/api/auth/confirm-email"),
+	}
+
+	// Optionally create the initial admin account on a fresh database
+	if getEnvBool("SEED_ADMIN", false) {
+		if err := database.SeedAdmin(database.AdminSeed{
+			Username:   getEnv("ADMIN_USERNAME", "admin"),
+			Email:      os.Getenv("ADMIN_EMAIL"),
+			Password:   os.Getenv("ADMIN_PASSWORD"),
+			SuperAdmin: getEnvBool("ADMIN_SUPERADMIN", false),
+		}); err != nil {
+			log.Fatalf("Failed to seed admin user: %v", err)
+		}
+	}
+
+	// Initialize Gin router
+	router := gin.New()
+	router.Use(gin.Logger())
+
+	// Turn panics into logged 500s; registered first so a panic in any later
+	// middleware still gets the error envelope
+	router.Use(middleware.RecoveryMiddleware(getEnvBool("PANIC_EXPOSE_STACK", false)))
+
+	// Optionally accept and set the access token in a cookie for browser clients
+	middleware.AuthCookie, err = middleware.AuthCookieConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid auth cookie configuration: %v", err)
+	}
+	if middleware.AuthCookie.Enabled() {
+		log.Printf("Cookie auth enabled; access tokens are also accepted from the %s cookie", middleware.AuthCookie.Name)
+	}
+
+	// Require a CSRF token on cookie-authenticated writes (header-token clients are unaffected)
+	middleware.CSRFProtection = getEnvBool("CSRF_PROTECTION", false)
+	csrf := middleware.CSRFMiddleware(jwtConfig.SecretKey)
+
+	// Only trust forwarding headers from known proxies; the client IP is the rate-limit key
+	trustedProxies := middleware.TrustedProxiesFromEnv()
+	if err := middleware.ConfigureTrustedProxies(router, trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	if len(trustedProxies) == 0 {
+		log.Println("No trusted proxies configured; client IPs are taken from the connection address")
+	}
+
+	// Start a span per request, continuing any incoming trace; runs before the
+	// request ID middleware so the ID can default to the trace ID
+	router.Use(middleware.TracingMiddleware(traceConfig.ServiceName, "/health", "/health/details", "/metrics"))
+
+	// Assign a request ID to every request
+	router.Use(middleware.RequestIDMiddleware())
+
+	// Request count and latency metrics
+	router.Use(middleware.MetricsMiddleware())
+
+	// Shed requests beyond MAX_CONCURRENT_REQUESTS in flight with a 503 before
+	// they do any work (health checks and metrics stay available)
+	router.Use(middleware.ConcurrencyLimitMiddleware(
+		getEnvInt("MAX_CONCURRENT_REQUESTS", middleware.DefaultMaxConcurrentRequests),
+		time.Duration(getEnvInt("CONCURRENCY_RETRY_AFTER_SECONDS", int(middleware.DefaultConcurrencyRetryAfter/time.Second)))*time.Second,
+		"/health", "/health/details", "/metrics",
+	))
+
+	// Compress large responses for clients that accept gzip or brotli; runs
+	// before field naming so it compresses the rewritten JSON
+	compression := middleware.CompressionConfig{
+		Level:    getEnvInt("COMPRESSION_LEVEL", middleware.DefaultCompressionLevel),
+		MinBytes: getEnvInt("COMPRESSION_MIN_BYTES", middleware.DefaultCompressionMinBytes),
+	}
+	if err := compression.Validate(); err != nil {
+		log.Fatalf("Invalid compression configuration: %v", err)
+	}
+	router.Use(middleware.CompressionMiddleware(compression))
+
+	// JSON field naming (snake_case or camelCase), by default or per request
+	// via an Accept profile
+	fieldNaming := getEnv("JSON_FIELD_NAMING", middleware.FieldNamingSnake)
+	if !middleware.ValidFieldNaming(fieldNaming) {
+		log.Fatalf("Invalid JSON_FIELD_NAMING %q: must be %s or %s", fieldNaming, middleware.FieldNamingSnake, middleware.FieldNamingCamel)
+	}
+	router.Use(middleware.FieldNamingMiddleware(fieldNaming, "/api/graphql"))
+
+	// CORS configuration
+	router.Use(cors.New(middleware.CORSConfigFromEnv()))
+
+	// Security headers
+	securityHeaders := middleware.DefaultSecurityHeadersConfig()
+	if !getEnvBool("SECURITY_HEADERS_CSP", true) {
+		securityHeaders.ContentSecurityPolicy = ""
+	}
+	if !getEnvBool("SECURITY_HEADERS_HSTS", true) {
+		securityHeaders.HSTSMaxAge = 0
+	}
+	router.Use(middleware.SecurityHeadersMiddleware(securityHeaders))
+
+	// Maintenance mode (health checks stay available)
+	router.Use(middleware.MaintenanceMiddleware(
+		getEnvBool("MAINTENANCE_MODE", false),
+		time.Duration(getEnvInt("MAINTENANCE_RETRY_AFTER_SECONDS", 300))*time.Second,
+		"/health", "/health/details", "/metrics", "/version",
+	))
+
+	// Per-request deadline, propagated to database queries via the request context
+	router.Use(middleware.TimeoutMiddleware(
+		time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", int(middleware.DefaultRequestTimeout/time.Second))) * time.Second,
+	))
+
+	// Request body size and JSON depth limits (routes may apply their own limit to override)
+	router.Use(middleware.BodyLimitMiddleware(
+		int64(getEnvInt("MAX_BODY_BYTES", int(middleware.DefaultMaxBodyBytes))),
+		getEnvInt("MAX_JSON_DEPTH", middleware.DefaultMaxJSONDepth),
+	))
+
+	// Rate limiters: auth endpoints use a strict sliding window, general API
+	// traffic a token bucket that allows short bursts
+	authLimiter := middleware.NewRateLimiter(5, 1*time.Minute)          // 5 requests per minute for auth
+	registerLimiter := middleware.NewRateLimiter(3, 1*time.Minute)      // 3 requests per minute for registration
+	resetLimiter := middleware.NewRateLimiter(3, 1*time.Minute)         // 3 requests per minute for password reset
+	exportLimiter := middleware.NewRateLimiter(5, 1*time.Hour)          // 5 requests per hour for data exports
+	availabilityLimiter := middleware.NewRateLimiter(10, 1*time.Minute) // 10 requests per minute for availability checks
+	strengthLimiter := middleware.NewRateLimiter(30, 1*time.Minute)     // 30 requests per minute for password strength checks
+	generalRate := getEnvInt("GENERAL_RATE_LIMIT_PER_MINUTE", 100)
+	generalBurst := getEnvInt("GENERAL_RATE_LIMIT_BURST", 20)
+	if generalRate <= 0 || generalBurst <= 0 {
+		log.Fatalf("GENERAL_RATE_LIMIT_PER_MINUTE and GENERAL_RATE_LIMIT_BURST must be positive")
+	}
+	generalLimiter := middleware.NewTokenBucketLimiter(generalBurst, time.Minute/time.Duration(generalRate))
+
+	// Bound how often and how much state the limiters keep per client
+	cleanupInterval := time.Duration(getEnvInt("RATE_LIMIT_CLEANUP_INTERVAL_SECONDS", int(middleware.DefaultCleanupInterval/time.Second))) * time.Second
+	maxKeys := getEnvInt("RATE_LIMIT_MAX_KEYS", middleware.DefaultRateLimitMaxKeys)
+	if cleanupInterval <= 0 || maxKeys <= 0 {
+		log.Fatalf("RATE_LIMIT_CLEANUP_INTERVAL_SECONDS and RATE_LIMIT_MAX_KEYS must be positive")
+	}
+	for _, limiter := range []*middleware.RateLimiter{authLimiter, registerLimiter, resetLimiter, exportLimiter, availabilityLimiter, strengthLimiter} {
+		limiter.CleanupInterval = cleanupInterval
+		limiter.MaxKeys = maxKeys
+	}
+	generalLimiter.CleanupInterval = cleanupInterval
+	generalLimiter.MaxKeys = maxKeys
+
+	// Supervise background workers so a panic restarts them instead of silently stopping
+	workers := supervisor.New(5 * time.Second)
+	workers.Go("ratelimit-auth-cleanup", authLimiter.Cleanup)
+	workers.Go("ratelimit-register-cleanup", registerLimiter.Cleanup)
+	workers.Go("ratelimit-general-cleanup", generalLimiter.Cleanup)
+	workers.Go("ratelimit-reset-cleanup", resetLimiter.Cleanup)
+	workers.Go("ratelimit-export-cleanup", exportLimiter.Cleanup)
+	workers.Go("ratelimit-availability-cleanup", availabilityLimiter.Cleanup)
+	workers.Go("ratelimit-strength-cleanup", strengthLimiter.Cleanup)
+
+	// Stored responses for retried registrations carrying an Idempotency-Key
+	idempotencyStore := middleware.NewIdempotencyStore(
+		time.Duration(getEnvInt("IDEMPOTENCY_TTL_MINUTES", int(middleware.DefaultIdempotencyTTL/time.Minute))) * time.Minute,
+	)
+	workers.Go("idempotency-cleanup", idempotencyStore.Cleanup)
+	if interval := getEnvInt("RETENTION_SWEEP_INTERVAL_MINUTES", 60); interval > 0 {
+		sweeper := &retention.Sweeper{Policy: retentionPolicy, Interval: time.Duration(interval) * time.Minute}
+		workers.Go("retention-sweeper", sweeper.Run)
+	}
+	if days := getEnvInt("DELETED_USER_RETENTION_DAYS", 0); days > 0 {
+		interval := getEnvInt("DELETED_USER_PURGE_INTERVAL_MINUTES", 60)
+		if interval <= 0 {
+			log.Fatalf("DELETED_USER_PURGE_INTERVAL_MINUTES must be positive")
+		}
+		purger := &retention.Purger{
+			After:    time.Duration(days) * 24 * time.Hour,
+			Interval: time.Duration(interval) * time.Minute,
+		}
+		workers.Go("deleted-user-purger", purger.Run)
+	}
+
+	// Outbound webhooks for user lifecycle events, delivered in the background
+	if endpoints := webhook.EndpointsFromEnv(); len(endpoints) > 0 {
+		for _, endpoint := range endpoints {
+			if endpoint.Secret == "" {
+				log.Printf("Config warning: webhook endpoint %s has no signing secret", endpoint.URL)
+			}
+		}
+		notifier := webhook.NewNotifier(webhook.Config{
+			Endpoints:      endpoints,
+			MaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", webhook.DefaultMaxAttempts),
+			InitialBackoff: time.Duration(getEnvInt("WEBHOOK_INITIAL_BACKOFF_SECONDS", int(webhook.DefaultInitialBackoff/time.Second))) * time.Second,
+			MaxBackoff:     time.Duration(getEnvInt("WEBHOOK_MAX_BACKOFF_SECONDS", int(webhook.DefaultMaxBackoff/time.Second))) * time.Second,
+			QueueSize:      getEnvInt("WEBHOOK_QUEUE_SIZE", webhook.DefaultQueueSize),
+			Timeout:        time.Duration(getEnvInt("WEBHOOK_TIMEOUT_SECONDS", int(webhook.DefaultTimeout/time.Second))) * time.Second,
+		})
+		workers.Go("webhook-delivery", notifier.Run)
+		handlers.Webhooks = notifier
+	}
+
+	// Health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status": "healthy",
+			"time":   time.Now().Format(time.RFC3339),
+		})
+	})
+	router.GET("/health/details", handlers.HealthDetails(workers))
+
+	// Build and schema version, for support and incident response
+	router.GET("/version", handlers.Version(buildInfo()))
+
+	// Metrics endpoint (METRICS_FORMAT: prometheus, openmetrics or auto to negotiate via Accept)
+	router.GET("/metrics", handlers.Metrics(getEnv("METRICS_FORMAT", handlers.MetricsFormatAuto)))
+
+	// Public client configuration (password policy, registration, token settings)
+	publicConfig := handlers.PublicConfig(handlers.PublicConfigOptions{
+		JWT:           jwtConfig,
+		PasswordReset: passwordResetConfig,
+		MaxAge:        time.Duration(getEnvInt("PUBLIC_CONFIG_MAX_AGE_SECONDS", int(handlers.DefaultPublicConfigMaxAge.Seconds()))) * time.Second,
+	})
+
+	// Users with a temporary password may only change it until they do
+	passwordChangeGate := middleware.PasswordChangeGate("/api/users/me/password")
+
+	// API routes
+	api := router.Group("/api")
+	{
+		api.GET("/config", publicConfig)
+		api.GET("/.well-known/config", publicConfig)
+
+		// Authentication routes (with rate limiting)
+		auth := api.Group("/auth")
+		{
+			// Replays are served before the rate limiter so retries don't use up the registration budget
+			auth.POST("/register", middleware.IdempotencyMiddleware(idempotencyStore),
+				middleware.RateLimitMiddleware(registerLimiter), handlers.Register(jwtConfig))
+			// The availability check would give away what strict registration hides
+			if !handlers.RegistrationPrivacy.Strict() {
+				auth.GET("/availability", middleware.RateLimitMiddleware(availabilityLimiter), handlers.CheckAvailability)
+			}
+			auth.POST("/password-strength", middleware.RateLimitMiddleware(strengthLimiter), handlers.CheckPasswordStrength)
+			auth.POST("/login", middleware.RateLimitMiddleware(authLimiter), handlers.Login(jwtConfig))
+			auth.POST("/refresh", middleware.RateLimitMiddleware(authLimiter), handlers.Refresh(jwtConfig))
+			auth.POST("/forgot-password", middleware.RateLimitMiddleware(resetLimiter), handlers.ForgotPassword(passwordResetConfig))
+			auth.POST("/reset-password", middleware.RateLimitMiddleware(resetLimiter), handlers.ResetPassword)
+			auth.GET("/confirm-email", middleware.RateLimitMiddleware(resetLimiter), handlers.ConfirmEmail)
+			auth.GET("/confirm-registration", middleware.RateLimitMiddleware(resetLimiter), handlers.ConfirmRegistration(jwtConfig))
+			auth.GET("/csrf-token", middleware.AuthMiddleware(jwtConfig.SecretKey),
+				middleware.RateLimitMiddleware(generalLimiter), handlers.GetCSRFToken(jwtConfig.SecretKey))
+
+			// Token introspection for internal services; off unless service keys are configured
+			if serviceKeys := middleware.ServiceKeysFromEnv(); len(serviceKeys) > 0 {
+				auth.POST("/introspect", middleware.RequireServiceKey(serviceKeys), handlers.IntrospectToken(jwtConfig.SecretKey))
+			}
+		}
+
+		// Avatars are public so they can be used directly in <img> tags
+		api.GET("/users/:id/avatar", middleware.RateLimitMiddleware(generalLimiter), handlers.GetAvatar(avatarStore))
+
+		// Protected user routes (require authentication). Reads need the
+		// users:read scope and changes users:write.
+		readScope := middleware.RequireScope(models.ScopeUsersRead)
+		writeScope := middleware.RequireScope(models.ScopeUsersWrite)
+		users := api.Group("/users")
+		users.Use(middleware.AuthMiddleware(jwtConfig.SecretKey))
+		users.Use(csrf)
+		users.Use(middleware.RateLimitMiddleware(generalLimiter))
+		users.Use(passwordChangeGate)
+		{
+			users.GET("", readScope, handlers.GetAllUsers(userListConfig))                    // List all users except current user
+			users.GET("/me", readScope, handlers.GetCurrentUser)                              // Get current user profile
+			users.GET("/me/providers", readScope, handlers.GetLinkedProviders)                // List linked sign-in methods
+			users.GET("/me/org", readScope, handlers.GetCurrentOrg)                           // Get the current user's organization
+			users.DELETE("/me/providers/:provider", writeScope, handlers.UnlinkProvider)      // Unlink a sign-in method (not the last one)
+			users.GET("/me/retention", readScope, handlers.GetRetention(retentionPolicy))     // Get data retention preference
+			users.PUT("/me/retention", writeScope, handlers.UpdateRetention(retentionPolicy)) // Set data retention preference
+			users.POST("/me/logout-all", writeScope, handlers.LogoutAll)                      // Revoke all of the current user's tokens
+			users.GET("/me/sessions", readScope, handlers.ListSessions)                       // List the current user's active sessions
+			users.DELETE("/me/sessions/:id", writeScope, handlers.RevokeSession)              // Revoke one session and its tokens
+			users.GET("/:id", readScope, handlers.GetUserByID)                                // Get user by ID
+			users.PUT("/:id", writeScope, handlers.UpdateUser)                                // Update user (own profile only)
+			users.PATCH("/:id", writeScope, handlers.UpdateUser)                              // Partially update user (same semantics as PUT)
+			users.DELETE("/:id", writeScope, handlers.DeleteUser)                             // Delete user (own profile only)
+
+			// Exports gather every record about the user, so they get a tighter limit
+			users.GET("/me/export", readScope, middleware.RateLimitMiddleware(exportLimiter), handlers.ExportCurrentUser(retentionPolicy))
+
+			// Password changes verify the current password, so share the login rate limit
+			users.PUT("/me/password", writeScope, middleware.RateLimitMiddleware(authLimiter), handlers.ChangePassword(jwtConfig))
+
+			// Avatar upload gets its own body limit (file size plus multipart overhead)
+			users.POST("/me/avatar", writeScope, middleware.BodyLimitMiddleware(avatarConfig.MaxBytes+64<<10, 0),
+				handlers.UploadAvatar(avatarConfig))
+
+			// Admin-only routes
+			users.POST("", writeScope, middleware.RequireAdmin(), handlers.CreateUser)
+			users.POST("/bulk-delete", writeScope, middleware.RequireAdmin(),
+				handlers.BulkDeleteUsers(getEnvInt("BULK_DELETE_MAX_BATCH", handlers.DefaultBulkDeleteMaxBatch)))
+			users.PUT("/:id/status", writeScope, middleware.RequireAdmin(), handlers.UpdateUserStatus)
+			users.POST("/:id/reset-password", writeScope, middleware.RequireAdmin(), handlers.AdminResetPassword)
+			users.GET("/inactive", readScope, middleware.RequireAdmin(), handlers.ListInactiveUsers)
+		}
+
+		// GraphQL user queries and mutations, with the same authentication and
+		// ownership rules as the REST user routes; mutations also need users:write
+		api.POST("/graphql",
+			middleware.AuthMiddleware(jwtConfig.SecretKey),
+			csrf,
+			middleware.RateLimitMiddleware(generalLimiter),
+			middleware.PasswordChangeGate(),
+			readScope,
+			graphqlapi.Handler(graphqlapi.NewSchema()))
+
+		// Admin-only registration invites
+		api.POST("/invites",
+			middleware.AuthMiddleware(jwtConfig.SecretKey),
+			csrf,
+			middleware.RateLimitMiddleware(generalLimiter),
+			middleware.PasswordChangeGate(),
+			writeScope,
+			middleware.RequireAdmin(),
+			handlers.CreateInvite(jwtConfig.SecretKey))
+
+		// Admin-only API keys for service-to-service calls
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(middleware.AuthMiddleware(jwtConfig.SecretKey))
+		apiKeys.Use(csrf)
+		apiKeys.Use(middleware.RateLimitMiddleware(generalLimiter))
+		apiKeys.Use(middleware.PasswordChangeGate())
+		apiKeys.Use(middleware.RequireAdmin())
+		{
+			apiKeys.GET("", readScope, handlers.ListAPIKeys)
+			apiKeys.POST("", writeScope, handlers.CreateAPIKey)
+			apiKeys.DELETE("/:id", writeScope, handlers.RevokeAPIKey)
+		}
+
+		// Admin-only audit trail
+		api.GET("/audit-logs",
+			middleware.AuthMiddleware(jwtConfig.SecretKey),
+			middleware.RateLimitMiddleware(generalLimiter),
+			middleware.PasswordChangeGate(),
+			readScope,
+			middleware.RequireAdmin(),
+			handlers.ListAuditLogs)
+	}
+
+	// Exact path matching; unknown routes and unsupported methods get
+	// structured 404 and 405 errors
+	handlers.ConfigureRouting(router)
+
+	// Usernames can't shadow route segments such as /api/users/inactive
+	var routePaths []string
+	for _, route := range router.Routes() {
+		routePaths = append(routePaths, route.Path)
+	}
+	validation.Usernames = validation.Usernames.ReserveRouteSegments(routePaths...)
+
+	// Start server
+	port := getEnv("PORT", "8080")
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+
+	// Serve HTTPS (and HTTP/2) directly when a certificate is configured, for
+	// deployments without a TLS-terminating proxy
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if (certFile == "") != (keyFile == "") {
+		log.Fatalf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if certFile != "" {
+		reloader, err := tlsserver.NewCertReloader(certFile, keyFile)
+		if err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
+		}
+		tlsSettings, err := tlsserver.ParseSettings(os.Getenv("TLS_MIN_VERSION"), os.Getenv("TLS_CIPHER_SUITES"), os.Getenv("TLS_CURVE_PREFERENCES"))
+		if err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
+		}
+		srv.TLSConfig = tlsserver.Config(reloader, tlsSettings)
+	}
+
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			log.Printf("Server starting on port %s (HTTPS)...", port)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Server starting on port %s...", port)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Optionally redirect plain HTTP to HTTPS
+	var redirectSrv *http.Server
+	if redirectPort := os.Getenv("TLS_REDIRECT_PORT"); redirectPort != "" {
+		if srv.TLSConfig == nil {
+			log.Fatalf("TLS_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		redirectSrv = &http.Server{
+			Addr:              ":" + redirectPort,
+			Handler:           tlsserver.RedirectHandler(port),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			log.Printf("HTTPS redirect starting on port %s...", redirectPort)
+			if err := redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Failed to start HTTPS redirect: %v", err)
+			}
+		}()
+	}
+
+	// Start the gRPC API on its own port, sharing configuration with the REST API
+	grpcPort := getEnv("GRPC_PORT", "9090")
+	grpcListener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		log.Fatalf("Failed to listen on gRPC port %s: %v", grpcPort, err)
+	}
+	grpcServer := grpcapi.NewGRPCServer(jwtConfig, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+
+	go func() {
+		log.Printf("gRPC server starting on port %s...", grpcPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}()
+
+	// Wait for an interrupt signal, then shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shut down: %v", err)
+	}
+	if redirectSrv != nil {
+		if err := redirectSrv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("HTTPS redirect forced to shut down: %v", err)
+		}
+	}
+	grpcServer.GracefulStop()
+
+	// Stop background workers, then flush buffered spans
+	workers.Stop()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+	log.Println("Server stopped")
+	return exitOK
+}
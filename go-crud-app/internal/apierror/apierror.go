@@ -0,0 +1,55 @@
+package apierror
+
+// Error codes are stable, machine-readable identifiers clients can branch on
+// instead of matching human-readable messages
+const (
+	CodeBadRequest             = "bad_request"
+	CodeValidationFailed       = "validation_failed"
+	CodeEmailDomainNotAllowed  = "email_domain_not_allowed"
+	CodeUsernameReserved       = "username_reserved"
+	CodeInvalidCredentials     = "invalid_credentials"
+	CodeUnauthorized           = "unauthorized"
+	CodeTokenExpired           = "token_expired"
+	CodeTokenInvalid           = "token_invalid"
+	CodeForbidden              = "forbidden"
+	CodeInsufficientScope      = "insufficient_scope"
+	CodeCSRFTokenInvalid       = "csrf_token_invalid"
+	CodePasswordChangeRequired = "password_change_required"
+	CodeAccountSuspended       = "account_suspended"
+	CodeConfirmationRequired   = "confirmation_required"
+	CodeNotFound               = "not_found"
+	CodeRouteNotFound          = "route_not_found"
+	CodeMethodNotAllowed       = "method_not_allowed"
+	CodeConflict               = "conflict"
+	CodeVersionConflict        = "version_conflict"
+	CodePreconditionFailed     = "precondition_failed"
+	CodeIdempotencyConflict    = "idempotency_conflict"
+	CodeIdempotencyMismatch    = "idempotency_key_mismatch"
+	CodePayloadTooLarge        = "payload_too_large"
+	CodeUnsupportedMediaType   = "unsupported_media_type"
+	CodeRateLimited            = "rate_limited"
+	CodeLoginThrottled         = "login_throttled"
+	CodeInternal               = "internal_error"
+	CodeServiceUnavailable     = "service_unavailable"
+	CodeTimeout                = "timeout"
+)
+
+// FieldError describes a validation failure for a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Body is the content of an error response
+type Body struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Stack     string       `json:"stack,omitempty"` // Only set for recovered panics when stack exposure is enabled
+}
+
+// Response is the envelope every error response uses: {"error": {...}}
+type Response struct {
+	Error Body `json:"error"`
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvDevelopment relaxes startup checks for local development
+	EnvDevelopment = "development"
+	// EnvProduction enforces startup checks; it is the default when APP_ENV is unset
+	EnvProduction = "production"
+
+	// DefaultJWTSecret is the fallback JWT secret used in development only
+	DefaultJWTSecret = "your-secret-key-change-this-in-production"
+	// MinJWTSecretLength is the shortest JWT secret accepted without a warning
+	MinJWTSecretLength = 32
+	// MinPepperLength is the shortest password pepper accepted without a warning
+	MinPepperLength = 32
+)
+
+// placeholderJWTSecrets are published example secrets that must never sign real tokens
+var placeholderJWTSecrets = map[string]bool{
+	DefaultJWTSecret: true,
+	"your-secret-key-change-this-in-production-use-a-long-random-string": true,
+	"your-very-long-random-secret-key-here":                              true,
+}
+
+// weakDBPasswords are default database passwords that are fine locally only
+var weakDBPasswords = map[string]bool{
+	"":         true,
+	"postgres": true,
+	"password": true,
+}
+
+// AppEnv returns the normalized APP_ENV value, defaulting to production so a
+// missing setting fails safe
+func AppEnv(getenv func(string) string) (string, error) {
+	env := strings.ToLower(strings.TrimSpace(getenv("APP_ENV")))
+	switch env {
+	case "":
+		return EnvProduction, nil
+	case EnvDevelopment, EnvProduction:
+		return env, nil
+	default:
+		return "", fmt.Errorf("APP_ENV must be %q or %q, got %q", EnvDevelopment, EnvProduction, env)
+	}
+}
+
+// Validate checks the startup configuration. Critical problems outside
+// development are returned as an error so the server refuses to start; weak
+// but survivable defaults are returned as warnings.
+func Validate(env string, getenv func(string) string) (warnings []string, err error) {
+	var critical []string
+
+	secret := getenv("JWT_SECRET")
+	switch {
+	case secret == "":
+		critical = append(critical, "JWT_SECRET is not set")
+	case placeholderJWTSecrets[secret]:
+		critical = append(critical, "JWT_SECRET is set to a published placeholder value")
+	case len(secret) < MinJWTSecretLength:
+		warnings = append(warnings, fmt.Sprintf("JWT_SECRET is shorter than %d characters", MinJWTSecretLength))
+	}
+
+	if pepper := getenv("PEPPER"); pepper != "" && len(pepper) < MinPepperLength {
+		warnings = append(warnings, fmt.Sprintf("PEPPER is shorter than %d characters", MinPepperLength))
+	}
+	if weakDBPasswords[getenv("DB_PASSWORD")] {
+		warnings = append(warnings, "DB_PASSWORD is empty or a well-known default")
+	}
+	if env == EnvProduction && getenv("DB_SSLMODE") == "disable" {
+		warnings = append(warnings, "DB_SSLMODE=disable sends database traffic unencrypted")
+	}
+	if getenv("CORS_ORIGIN") == "*" {
+		warnings = append(warnings, "CORS_ORIGIN=* allows requests from any origin")
+	}
+	if getenv("AUTH_COOKIE_NAME") != "" {
+		sameSite := strings.ToLower(getenv("AUTH_COOKIE_SAMESITE"))
+		csrf, _ := strconv.ParseBool(getenv("CSRF_PROTECTION"))
+		if sameSite != "" && sameSite != "strict" && !csrf {
+			warnings = append(warnings, fmt.Sprintf("AUTH_COOKIE_SAMESITE=%s sends the auth cookie on cross-site requests without CSRF_PROTECTION", sameSite))
+		}
+		if env == EnvProduction && getenv("AUTH_COOKIE_SECURE") == "false" {
+			warnings = append(warnings, "AUTH_COOKIE_SECURE=false sends the auth cookie over plain HTTP")
+		}
+	}
+
+	if expose, _ := strconv.ParseBool(getenv("PANIC_EXPOSE_STACK")); expose {
+		critical = append(critical, "PANIC_EXPOSE_STACK=true sends stack traces to clients")
+	}
+
+	if env == EnvDevelopment {
+		// Development runs with the fallback secret, so downgrade to warnings
+		return append(critical, warnings...), nil
+	}
+	if len(critical) > 0 {
+		return warnings, errors.New(strings.Join(critical, "; "))
+	}
+	return warnings, nil
+}
@@ -1,14 +1,21 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
+	"os"
+	"time"
 
+	"go-crud-app/internal/migrations"
 	"go-crud-app/internal/models"
 
+	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	otelgorm "gorm.io/plugin/opentelemetry/tracing"
 )
 
 // Config holds database configuration
@@ -19,49 +26,151 @@ type Config struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// Replicas are read replica addresses (host:port) sharing the primary's
+	// credentials; reads go to the primary when there are none
+	Replicas []string
+	// SlowQueryThreshold is the duration above which queries are logged; 0
+	// disables the slow query log
+	SlowQueryThreshold time.Duration
 }
 
-// DB is the global database instance
-var DB *gorm.DB
-
-// Connect establishes a connection to the database
-func Connect(config Config) error {
-	dsn := fmt.Sprintf(
+// dsn returns the connection string for host and port with config's credentials
+func (config Config) dsn(host, port string) string {
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.Host,
-		config.Port,
+		host,
+		port,
 		config.User,
 		config.Password,
 		config.DBName,
 		config.SSLMode,
 	)
+}
 
+// DB is the global database instance
+var DB *gorm.DB
+
+// Connect establishes a connection to the database
+func Connect(config Config) error {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	DB, err = gorm.Open(postgres.Open(config.dsn(config.Host, config.Port)), &gorm.Config{
+		// Slow queries are logged by UseQueryMetrics instead, and no query is
+		// logged with its arguments
+		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			LogLevel:             logger.Info,
+			ParameterizedQueries: true,
+			Colorful:             true,
+		}),
+		// Translate driver errors (e.g. unique violations) into gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := UseTracing(DB); err != nil {
+		return fmt.Errorf("failed to enable query tracing: %w", err)
+	}
+	if err := UseQueryMetrics(DB, config.SlowQueryThreshold); err != nil {
+		return fmt.Errorf("failed to enable query metrics: %w", err)
+	}
+
+	replicas := make([]gorm.Dialector, 0, len(config.Replicas))
+	for _, address := range config.Replicas {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("invalid replica address %q: %w", address, err)
+		}
+		replicas = append(replicas, postgres.Open(config.dsn(host, port)))
+	}
+	if err := UseReplicas(DB, replicas...); err != nil {
+		return fmt.Errorf("failed to configure read replicas: %w", err)
+	}
+	if len(replicas) > 0 {
+		log.Printf("Routing reads to %d replica(s)", len(replicas))
+	}
+
 	log.Println("Database connection established successfully")
 	return nil
 }
 
-// Migrate runs database migrations
+// UseTracing records a span for every query, as a child of the span in the
+// query's context. Query arguments are left out so spans never carry personal
+// data or password hashes.
+func UseTracing(db *gorm.DB) error {
+	return db.Use(otelgorm.NewPlugin(otelgorm.WithoutQueryVariables(), otelgorm.WithoutMetrics()))
+}
+
+// Migrate applies all pending versioned migrations, recording them in the
+// migrations table. This is the production path for schema changes.
 func Migrate() error {
 	log.Println("Running database migrations...")
 
+	if err := gormigrate.New(DB, migrations.Options(), migrations.All()).Migrate(); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	log.Println("Database migrations completed successfully")
+	return nil
+}
+
+// Rollback reverts the most recently applied versioned migration
+func Rollback() error {
+	log.Println("Rolling back the last database migration...")
+
+	if err := gormigrate.New(DB, migrations.Options(), migrations.All()).RollbackLast(); err != nil {
+		return fmt.Errorf("failed to roll back migration: %w", err)
+	}
+
+	log.Println("Database migration rolled back successfully")
+	return nil
+}
+
+// SchemaVersion returns the ID of the most recently applied versioned
+// migration, or "" when none has been recorded (e.g. with AutoMigrate)
+func SchemaVersion(ctx context.Context) (string, error) {
+	db := DB.WithContext(ctx)
+	if !db.Migrator().HasTable(migrations.TableName) {
+		return "", nil
+	}
+
+	var ids []string
+	if err := db.Table(migrations.TableName).Order("id DESC").Limit(1).Pluck("id", &ids).Error; err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", nil
+	}
+	return ids[0], nil
+}
+
+// AutoMigrate syncs the schema straight from the models without recording any
+// history. It is a development convenience: it cannot drop or rename columns
+// or backfill data, so production uses Migrate.
+func AutoMigrate() error {
+	log.Println("Auto-migrating database schema from models...")
+
 	err := DB.AutoMigrate(
 		&models.User{},
+		&models.PasswordResetToken{},
+		&models.AuthIdentity{},
+		&models.AuditLog{},
+		&models.Session{},
+		&models.EmailChangeToken{},
+		&models.Organization{},
+		&models.Invite{},
+		&models.PasswordHistory{},
+		&models.APIKey{},
+		&models.RegistrationToken{},
+		&models.LoginFailure{},
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+		return fmt.Errorf("failed to auto-migrate: %w", err)
 	}
 
-	log.Println("Database migrations completed successfully")
+	log.Println("Database auto-migration completed successfully")
 	return nil
 }
 
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"go-crud-app/internal/metrics"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSlowQueryThreshold is the duration above which queries are logged as slow
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// queryDurationBuckets are histogram buckets (in seconds) suited to query
+// latencies, which are mostly well below request latencies
+var queryDurationBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+var queryDuration = metrics.DefaultRegistry.NewHistogramVec(
+	"db_query_duration_seconds", "Database query latency in seconds.", "seconds",
+	queryDurationBuckets, "operation",
+)
+
+// queryStartKey is the statement setting holding the query's start time
+const queryStartKey = "app:query_start"
+
+// UseQueryMetrics records the duration of every query in the
+// db_query_duration_seconds histogram, labelled by operation (create, query,
+// update, delete, row or raw), and logs queries slower than slowThreshold.
+// The log carries the SQL with its placeholders but never the query
+// arguments, so it can't leak personal data or password hashes. A threshold
+// of 0 disables the slow query log.
+func UseQueryMetrics(db *gorm.DB, slowThreshold time.Duration) error {
+	callbacks := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+	for _, processor := range processors {
+		if err := processor.before("app:query_metrics_start", startQueryTimer); err != nil {
+			return err
+		}
+		if err := processor.after("app:query_metrics_end", observeQuery(processor.operation, slowThreshold)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startQueryTimer records when the statement started
+func startQueryTimer(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// observeQuery returns a callback recording the statement's duration as operation
+func observeQuery(operation string, slowThreshold time.Duration) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(start)
+		queryDuration.Observe(elapsed.Seconds(), operation)
+
+		if slowThreshold > 0 && elapsed > slowThreshold {
+			log.Printf("Slow query warning: operation=%s duration=%s threshold=%s rows=%d sql=%s",
+				operation, elapsed, slowThreshold, db.RowsAffected, db.Statement.SQL.String())
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// ParseReplicas parses DB_REPLICAS, a comma-separated list of replica hosts
+// given as host or host:port. Entries without a port use defaultPort.
+func ParseReplicas(value, defaultPort string) []string {
+	var replicas []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(entry); err != nil {
+			entry = net.JoinHostPort(entry, defaultPort)
+		}
+		replicas = append(replicas, entry)
+	}
+	return replicas
+}
+
+// UseReplicas routes reads (queries and row scans outside transactions) to
+// the replicas, picked at random per query, while writes and transactions
+// stay on db's own connection. It does nothing without replicas.
+func UseReplicas(db *gorm.DB, replicas ...gorm.Dialector) error {
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	})); err != nil {
+		return err
+	}
+
+	// Remember writes made with a TrackWrites context, so WithContext can
+	// send the reads that follow to the primary
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("app:track_write", markWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("app:track_write", markWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("app:track_write", markWrite); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("app:track_write", markWrite)
+}
+
+// writeTracker records whether a write has been made in a context
+type writeTracker struct {
+	wrote atomic.Bool
+}
+
+type writeTrackerKey struct{}
+
+// TrackWrites returns a context that records writes made with it. It
+// returns ctx itself when ctx already tracks writes.
+func TrackWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(writeTrackerKey{}).(*writeTracker); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, writeTrackerKey{}, &writeTracker{})
+}
+
+// WithContext returns DB bound to ctx. Once a write has been made with a
+// TrackWrites context, its later reads go to the primary, so a request
+// reads back what it just wrote instead of a lagging replica.
+func WithContext(ctx context.Context) *gorm.DB {
+	db := DB.WithContext(ctx)
+	if tracker, ok := ctx.Value(writeTrackerKey{}).(*writeTracker); ok && tracker.wrote.Load() {
+		db = db.Clauses(dbresolver.Write)
+	}
+	return db
+}
+
+// markWrite flags the statement's context as having written
+func markWrite(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return
+	}
+	if tracker, ok := db.Statement.Context.Value(writeTrackerKey{}).(*writeTracker); ok {
+		tracker.wrote.Store(true)
+	}
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"go-crud-app/internal/metrics"
+
+	"gorm.io/gorm"
+)
+
+// ErrWriteConflict is returned by RetryTransaction when a transaction still
+// conflicts with concurrent writes after every retry
+var ErrWriteConflict = errors.New("transaction conflicted with concurrent writes")
+
+// TransactionRetries is how many times RetryTransaction retries a
+// transaction that failed with a transient conflict
+var TransactionRetries = 3
+
+// TransactionRetryBackoff is the delay before the first retry; it doubles
+// for each later one, and every delay is jittered
+var TransactionRetryBackoff = 20 * time.Millisecond
+
+// Postgres SQLSTATEs of conflicts that succeed when the transaction is rerun
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+var transactionRetries = metrics.DefaultRegistry.NewCounterVec(
+	"db_transaction_retries", "Transactions rerun after a serialization failure or deadlock.", "",
+)
+
+// RetryTransaction runs fn in a transaction on db like db.Transaction, but
+// reruns the whole transaction when Postgres aborts it with a serialization
+// failure or deadlock, up to TransactionRetries times with jittered
+// exponential backoff. When the retries run out it returns an error wrapping
+// ErrWriteConflict and the last failure. fn may run more than once, so it
+// must not leave state behind from an attempt that was rolled back.
+func RetryTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	ctx := db.Statement.Context
+	backoff := TransactionRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := db.Transaction(fn)
+		if err == nil || !IsTransientConflict(err) {
+			return err
+		}
+		if attempt >= TransactionRetries {
+			return fmt.Errorf("%w after %d attempts: %w", ErrWriteConflict, attempt+1, err)
+		}
+
+		transactionRetries.Inc()
+		log.Printf("Retrying transaction after a write conflict (attempt %d): %v", attempt+1, err)
+
+		// Jitter keeps requests that collided from retrying in lockstep
+		delay := backoff/2 + rand.N(backoff/2+1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// IsTransientConflict reports whether err is a Postgres serialization
+// failure or deadlock, which rerunning the transaction can resolve. It
+// matches any error with a SQLSTATE, as pgconn.PgError has.
+func IsTransientConflict(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	switch sqlErr.SQLState() {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	}
+	return false
+}
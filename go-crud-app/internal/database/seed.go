@@ -0,0 +1,94 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// AdminSeed holds the credentials of the initial admin account
+type AdminSeed struct {
+	Username string
+	Email    string
+	Password string
+	// SuperAdmin makes the account an admin of every organization
+	SuperAdmin bool
+}
+
+// SeedAdmin creates the initial admin account on a fresh database. It does
+// nothing if any user (including soft-deleted ones) already exists, so it is
+// safe to run on every boot. The password is hashed through the normal
+// password policy and is never logged.
+func SeedAdmin(seed AdminSeed) error {
+	if seed.Email == "" || seed.Password == "" {
+		return errors.New("admin seeding requires ADMIN_EMAIL and ADMIN_PASSWORD")
+	}
+
+	var count int64
+	if err := DB.Unscoped().Model(&models.User{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check for existing users: %w", err)
+	}
+	if count > 0 {
+		log.Println("Users already exist, skipping admin seeding")
+		return nil
+	}
+
+	admin, err := CreateAdmin(seed)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another instance seeded concurrently
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Seeded admin user %s", admin.Email)
+	return nil
+}
+
+// CreateAdmin creates an admin account, applying the same normalization,
+// username, email and password rules as registration. It returns an error
+// wrapping gorm.ErrDuplicatedKey when the username or email is taken.
+func CreateAdmin(seed AdminSeed) (*models.User, error) {
+	username := validation.NormalizeUsername(seed.Username)
+	if !validation.ValidUsername(username) {
+		return nil, errors.New(validation.UsernameMessage)
+	}
+	email := validation.NormalizeEmail(seed.Email)
+	if !validation.ValidEmail(email) {
+		return nil, errors.New(validation.EmailMessage)
+	}
+
+	// Hashing enforces the password policy
+	passwordHash, err := utils.HashPassword(seed.Password)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin password: %w", err)
+	}
+
+	now := time.Now()
+	admin := models.User{
+		Username:          username,
+		Email:             email,
+		PasswordHash:      passwordHash,
+		PasswordChangedAt: &now,
+		Role:              models.RoleAdmin,
+	}
+	if seed.SuperAdmin {
+		admin.Role = models.RoleSuperAdmin
+	}
+
+	// Keep the password hash out of the SQL log
+	quiet := DB.Session(&gorm.Session{Logger: DB.Logger.LogMode(logger.Silent)})
+	if err := quiet.Create(&admin).Error; err != nil {
+		return nil, fmt.Errorf("failed to create admin user: %w", err)
+	}
+	return &admin, nil
+}
@@ -0,0 +1,361 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/validation"
+	"go-crud-app/internal/webhook"
+
+	"github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPageSize is the number of users returned per page by default
+	DefaultPageSize = 20
+	// MaxPageSize caps the pageSize argument
+	MaxPageSize = 100
+)
+
+// Resolver resolves the root Query and Mutation fields on top of the same
+// models, database and validation packages as the REST handlers
+type Resolver struct{}
+
+// UpdateUserInput holds the fields to change; nil fields are left unchanged
+type UpdateUserInput struct {
+	Username *string
+	Email    *string
+}
+
+// Me returns the authenticated user
+func (r *Resolver) Me(ctx context.Context) (*userResolver, error) {
+	caller, err := callerFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := findUser(ctx, caller.orgScope, caller.userID)
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{user: user}, nil
+}
+
+// User returns a user, reduced to public fields for other users unless
+// ProfileVisibility or the admin role allows more
+func (r *Resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	caller, err := callerFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := parseUserID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := findUser(ctx, caller.orgScope, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == caller.userID {
+		return &userResolver{user: user}, nil
+	}
+
+	fullProfiles, err := canViewFullProfiles(ctx, caller)
+	if err != nil {
+		return nil, internalError(ctx, err, "Failed to fetch user")
+	}
+	return &userResolver{user: user, public: !fullProfiles}, nil
+}
+
+// Users returns a page of users other than the caller, oldest first
+func (r *Resolver) Users(ctx context.Context, args struct{ Page, PageSize *int32 }) (*userPageResolver, error) {
+	caller, err := callerFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page, pageSize := int32(1), int32(DefaultPageSize)
+	if args.Page != nil {
+		page = *args.Page
+	}
+	if args.PageSize != nil {
+		pageSize = *args.PageSize
+	}
+	var fieldErrors []apierror.FieldError
+	if page < 1 {
+		fieldErrors = append(fieldErrors, apierror.FieldError{Field: "page", Message: "Must be at least 1"})
+	}
+	if pageSize < 1 || pageSize > MaxPageSize {
+		fieldErrors = append(fieldErrors, apierror.FieldError{
+			Field:   "pageSize",
+			Message: fmt.Sprintf("Must be between 1 and %d", MaxPageSize),
+		})
+	}
+	if len(fieldErrors) > 0 {
+		return nil, validationError(fieldErrors)
+	}
+
+	fullProfiles, err := canViewFullProfiles(ctx, caller)
+	if err != nil {
+		return nil, internalError(ctx, err, "Failed to fetch users")
+	}
+
+	db := database.DB.WithContext(ctx).Model(&models.User{}).Scopes(caller.orgScope).Where("id != ?", caller.userID)
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, internalError(ctx, err, "Failed to fetch users")
+	}
+	var users []models.User
+	if err := db.Order("id").Limit(int(pageSize)).Offset(int(page-1) * int(pageSize)).Find(&users).Error; err != nil {
+		return nil, internalError(ctx, err, "Failed to fetch users")
+	}
+
+	resolvers := make([]*userResolver, len(users))
+	for i := range users {
+		resolvers[i] = &userResolver{user: &users[i], public: !fullProfiles}
+	}
+	return &userPageResolver{users: resolvers, page: page, pageSize: pageSize, total: int32(total)}, nil
+}
+
+// UpdateUser partially updates the caller's own profile
+func (r *Resolver) UpdateUser(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input UpdateUserInput
+}) (*userResolver, error) {
+	caller, err := callerFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := caller.requireWrite(); err != nil {
+		return nil, err
+	}
+
+	id, err := parseUserID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := findUser(ctx, caller.orgScope, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID != caller.userID {
+		return nil, &apiError{code: apierror.CodeForbidden, message: "You can only update your own profile"}
+	}
+
+	updates := make(map[string]interface{})
+	var fieldErrors []apierror.FieldError
+	if args.Input.Username != nil {
+		username := validation.NormalizeUsername(*args.Input.Username)
+		if !validation.ValidUsername(username) {
+			fieldErrors = append(fieldErrors, apierror.FieldError{Field: "username", Message: validation.UsernameMessage})
+		}
+		updates["username"] = username
+	}
+	if args.Input.Email != nil {
+		email := validation.NormalizeEmail(*args.Input.Email)
+		if !validation.ValidEmail(email) {
+			fieldErrors = append(fieldErrors, apierror.FieldError{Field: "email", Message: validation.EmailMessage})
+		}
+		updates["email"] = email
+	}
+	if len(fieldErrors) > 0 {
+		return nil, validationError(fieldErrors)
+	}
+	// As over REST, users may keep a reserved username they already hold
+	if username, ok := updates["username"].(string); ok && username != user.Username && !validation.Usernames.PermitsUsername(username) {
+		return nil, &apiError{
+			code:    apierror.CodeUsernameReserved,
+			message: validation.ReservedUsernameMessage,
+			fields:  []apierror.FieldError{{Field: "username", Message: validation.ReservedUsernameMessage}},
+		}
+	}
+	if len(updates) == 0 {
+		return nil, &apiError{code: apierror.CodeValidationFailed, message: "No fields to update"}
+	}
+
+	if err := handlers.SaveProfile(database.DB.WithContext(ctx), user, updates); err != nil {
+		if errors.Is(err, handlers.ErrVersionConflict) {
+			return nil, &apiError{code: apierror.CodeVersionConflict, message: "The user was modified by another request; fetch it again and retry"}
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, &apiError{code: apierror.CodeConflict, message: "Username or email is already taken"}
+		}
+		return nil, internalError(ctx, err, "Failed to update user")
+	}
+	handlers.Webhooks.Notify(ctx, webhook.EventUserUpdated, user.ID)
+
+	return &userResolver{user: user}, nil
+}
+
+// DeleteUser soft deletes the caller's own account
+func (r *Resolver) DeleteUser(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	caller, err := callerFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := caller.requireWrite(); err != nil {
+		return false, err
+	}
+
+	id, err := parseUserID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	user, err := findUser(ctx, caller.orgScope, id)
+	if err != nil {
+		return false, err
+	}
+	if user.ID != caller.userID {
+		return false, &apiError{code: apierror.CodeForbidden, message: "You can only delete your own profile"}
+	}
+
+	// Soft delete user, recording the deletion in the same transaction
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AuditLog{
+			ActorID:   &caller.userID,
+			Action:    models.AuditUserDeleted,
+			Target:    fmt.Sprintf("user:%d", user.ID),
+			IP:        caller.ip,
+			UserAgent: caller.userAgent,
+		}).Error
+	})
+	if err != nil {
+		return false, internalError(ctx, err, "Failed to delete user")
+	}
+	handlers.Webhooks.Notify(ctx, webhook.EventUserDeleted, user.ID)
+
+	return true, nil
+}
+
+// parseUserID parses a user ID argument
+func parseUserID(id graphql.ID) (uint, error) {
+	parsed, err := strconv.ParseUint(string(id), 10, 0)
+	if err != nil || parsed == 0 {
+		return 0, &apiError{code: apierror.CodeBadRequest, message: "Invalid user ID"}
+	}
+	return uint(parsed), nil
+}
+
+// findUser loads a user within orgScope by ID, returning a not_found error
+// when it doesn't exist there
+func findUser(ctx context.Context, orgScope func(*gorm.DB) *gorm.DB, id uint) (*models.User, error) {
+	var user models.User
+	if err := database.DB.WithContext(ctx).Scopes(orgScope).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &apiError{code: apierror.CodeNotFound, message: "User not found"}
+		}
+		return nil, internalError(ctx, err, "Failed to fetch user")
+	}
+	return &user, nil
+}
+
+// canViewFullProfiles reports whether the caller may see other users' private
+// fields, which takes an admin with the admin scope
+func canViewFullProfiles(ctx context.Context, caller caller) (bool, error) {
+	if handlers.ProfileVisibility == handlers.ProfileVisibilityFull {
+		return true, nil
+	}
+
+	var user models.User
+	if err := database.DB.WithContext(ctx).Select("role").First(&user, caller.userID).Error; err != nil {
+		return false, err
+	}
+	return models.IsAdminRole(models.ScopedRole(user.Role, caller.canAdmin)), nil
+}
+
+// validationError returns a validation_failed error listing the field errors
+func validationError(fieldErrors []apierror.FieldError) error {
+	return &apiError{code: apierror.CodeValidationFailed, message: "Validation failed", fields: fieldErrors}
+}
+
+// userResolver resolves User fields. Public users expose only their public
+// profile; private fields resolve to null.
+type userResolver struct {
+	user   *models.User
+	public bool
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(r.user.ID), 10))
+}
+
+func (r *userResolver) Username() string {
+	return r.user.Username
+}
+
+func (r *userResolver) Email() *string {
+	if r.public {
+		return nil
+	}
+	return &r.user.Email
+}
+
+func (r *userResolver) PendingEmail() *string {
+	if r.public || r.user.PendingEmail == "" {
+		return nil
+	}
+	return &r.user.PendingEmail
+}
+
+func (r *userResolver) Role() *string {
+	if r.public {
+		return nil
+	}
+	return &r.user.Role
+}
+
+func (r *userResolver) AvatarURL() *string {
+	if r.user.AvatarURL == "" {
+		return nil
+	}
+	return &r.user.AvatarURL
+}
+
+func (r *userResolver) CreatedAt() *graphql.Time {
+	if r.public {
+		return nil
+	}
+	return &graphql.Time{Time: r.user.CreatedAt}
+}
+
+func (r *userResolver) UpdatedAt() *graphql.Time {
+	if r.public {
+		return nil
+	}
+	return &graphql.Time{Time: r.user.UpdatedAt}
+}
+
+// userPageResolver resolves a UserPage
+type userPageResolver struct {
+	users    []*userResolver
+	page     int32
+	pageSize int32
+	total    int32
+}
+
+func (r *userPageResolver) Users() []*userResolver {
+	return r.users
+}
+
+func (r *userPageResolver) Page() int32 {
+	return r.page
+}
+
+func (r *userPageResolver) PageSize() int32 {
+	return r.pageSize
+}
+
+func (r *userPageResolver) Total() int32 {
+	return r.total
+}
@@ -0,0 +1,158 @@
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+)
+
+// MaxQueryDepth limits how deeply a query may nest selections
+const MaxQueryDepth = 10
+
+const maxUserAgentLength = 255
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// NewSchema parses the GraphQL schema and binds it to the resolvers
+func NewSchema() *graphql.Schema {
+	return graphql.MustParseSchema(schemaSDL, &Resolver{}, graphql.MaxDepth(MaxQueryDepth))
+}
+
+// request is a GraphQL request as sent by standard clients
+type request struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// caller identifies the authenticated user making a request, along with the
+// client details recorded in audit entries
+type caller struct {
+	userID    uint
+	orgScope  func(*gorm.DB) *gorm.DB // Limits users queries to the caller's organization
+	canWrite  bool                    // Whether the caller has the users:write scope mutations need
+	canAdmin  bool                    // Whether the caller has the admin scope an admin's powers need
+	ip        string
+	userAgent string
+}
+
+type callerKey struct{}
+
+// Handler executes GraphQL requests against schema. It must run after
+// AuthMiddleware, whose user ID is passed to the resolvers in the context.
+// Malformed requests get the standard error envelope; errors raised while
+// executing a query are reported in the GraphQL "errors" list instead.
+func Handler(schema *graphql.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				respondError(c, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge, "Request body too large")
+				return
+			}
+			respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid GraphQL request")
+			return
+		}
+
+		orgScope, err := middleware.OrgScope(c)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to authorize request")
+			return
+		}
+
+		userAgent := c.Request.UserAgent()
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+		}
+		ctx := context.WithValue(c.Request.Context(), callerKey{}, caller{
+			userID:    userID,
+			orgScope:  orgScope,
+			canWrite:  middleware.HasScope(c, models.ScopeUsersWrite),
+			canAdmin:  middleware.HasScope(c, models.ScopeAdmin),
+			ip:        c.ClientIP(),
+			userAgent: userAgent,
+		})
+
+		c.JSON(http.StatusOK, schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	}
+}
+
+// respondError writes the standard JSON error envelope, including the request ID when enabled
+func respondError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, apierror.Response{Error: middleware.LocalizeError(c, apierror.Body{
+		Code:      code,
+		Message:   message,
+		RequestID: middleware.ErrorRequestID(c),
+	})})
+}
+
+// callerFrom returns the authenticated caller stored by Handler
+func callerFrom(ctx context.Context) (caller, error) {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	if !ok {
+		return caller{}, &apiError{code: apierror.CodeUnauthorized, message: "Unauthorized"}
+	}
+	return c, nil
+}
+
+// requireWrite rejects mutations from callers without the users:write scope
+func (c caller) requireWrite() error {
+	if !c.canWrite {
+		return &apiError{code: apierror.CodeInsufficientScope, message: fmt.Sprintf("The %s scope is required", models.ScopeUsersWrite)}
+	}
+	return nil
+}
+
+// apiError is a resolver error. Its code (and any field errors) are reported
+// in the GraphQL error's extensions, using the same codes as the REST API.
+type apiError struct {
+	code    string
+	message string
+	fields  []apierror.FieldError
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+// Extensions returns the error details included in the GraphQL response
+func (e *apiError) Extensions() map[string]any {
+	extensions := map[string]any{"code": e.code}
+	if len(e.fields) > 0 {
+		extensions["fields"] = e.fields
+	}
+	return extensions
+}
+
+// internalError maps an unexpected error to an API error, reporting deadline
+// and cancellation as such rather than as internal errors
+func internalError(ctx context.Context, err error, message string) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &apiError{code: apierror.CodeTimeout, message: middleware.TimeoutMessage}
+	case errors.Is(ctx.Err(), context.Canceled):
+		return &apiError{code: apierror.CodeTimeout, message: "Request canceled"}
+	}
+	log.Printf("GraphQL: %s: %v", message, err)
+	return &apiError{code: apierror.CodeInternal, message: message}
+}
@@ -0,0 +1,535 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/grpcapi/userv1"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/loginthrottle"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+	"go-crud-app/internal/webhook"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+const maxUserAgentLength = 255
+
+// Server implements the gRPC UserService on top of the same models, database,
+// validation and utils packages as the REST handlers
+type Server struct {
+	userv1.UnimplementedUserServiceServer
+	jwtConfig utils.JWTConfig
+}
+
+// NewServer creates a UserService implementation
+func NewServer(jwtConfig utils.JWTConfig) *Server {
+	return &Server{jwtConfig: jwtConfig}
+}
+
+// NewGRPCServer creates a gRPC server with the UserService registered
+func NewGRPCServer(jwtConfig utils.JWTConfig, opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(opts...)
+	userv1.RegisterUserServiceServer(srv, NewServer(jwtConfig))
+	return srv
+}
+
+// Register creates an account and returns a token pair
+func (s *Server) Register(ctx context.Context, req *userv1.RegisterRequest) (*userv1.AuthResponse, error) {
+	if !handlers.RegistrationOpen {
+		return nil, status.Error(codes.PermissionDenied, "Registration is closed")
+	}
+	// RegisterRequest has no invite, invite code or organization to join
+	if handlers.OrgRegistration != handlers.OrgRegistrationNone {
+		return nil, status.Error(codes.FailedPrecondition, "Registration with an organization is only available over REST")
+	}
+	if handlers.Invites.Required {
+		return nil, status.Error(codes.FailedPrecondition, "Registration with an invite is only available over REST")
+	}
+	// Strict registration privacy answers without tokens, which AuthResponse can't express
+	if handlers.RegistrationPrivacy.Strict() {
+		return nil, status.Error(codes.FailedPrecondition, "Registration with email confirmation is only available over REST")
+	}
+
+	username := validation.NormalizeUsername(req.GetUsername())
+	email := validation.NormalizeEmail(req.GetEmail())
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	if !validation.ValidUsername(username) {
+		violations = append(violations, fieldViolation("username", validation.UsernameMessage))
+	}
+	if !validation.ValidEmail(email) {
+		violations = append(violations, fieldViolation("email", validation.EmailMessage))
+	}
+	if err := utils.ValidatePassword(req.GetPassword()); err != nil {
+		violations = append(violations, fieldViolation("password", err.Error()))
+	}
+	if len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+	if !validation.Usernames.PermitsUsername(username) {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("username", validation.ReservedUsernameMessage),
+		})
+	}
+	if !validation.EmailDomains.PermitsEmail(email) {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("email", validation.EmailDomainMessage),
+		})
+	}
+
+	passwordHash, err := utils.HashPassword(req.GetPassword())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to hash password")
+	}
+
+	db := database.DB.WithContext(ctx)
+	if handlers.ReactivateDeletedAccounts {
+		user, err := handlers.ReactivateDeletedUser(db, username, email, passwordHash, nil)
+		switch {
+		case err == nil:
+			recordAuditOrLog(ctx, db, models.AuditUserReactivated, &user.ID, userTarget(user.ID))
+			handlers.Webhooks.Notify(ctx, webhook.EventUserReactivated, user.ID)
+			return s.authResponse(ctx, db, user)
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, status.Error(codes.AlreadyExists, handlers.RegistrationPrivacy.DuplicateMessage())
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, statusFromError(ctx, err, "Failed to reactivate user")
+		}
+	}
+
+	now := time.Now()
+	user := models.User{
+		Username:          username,
+		Email:             email,
+		PasswordHash:      passwordHash,
+		PasswordChangedAt: &now,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, status.Error(codes.AlreadyExists, handlers.RegistrationPrivacy.DuplicateMessage())
+		}
+		return nil, statusFromError(ctx, err, "Failed to create user")
+	}
+	handlers.Webhooks.Notify(ctx, webhook.EventUserRegistered, user.ID)
+
+	return s.authResponse(ctx, db, &user)
+}
+
+// loginThrottledError refuses a login attempt made before the throttle's
+// wait has passed
+func loginThrottledError(wait time.Duration) error {
+	return status.Errorf(codes.ResourceExhausted,
+		"Too many failed login attempts. Try again in %d seconds", int(math.Ceil(wait.Seconds())))
+}
+
+// Login exchanges credentials for a token pair
+func (s *Server) Login(ctx context.Context, req *userv1.LoginRequest) (*userv1.AuthResponse, error) {
+	db := database.DB.WithContext(ctx)
+	email := validation.NormalizeEmail(req.GetEmail())
+
+	var user models.User
+	now := time.Now()
+	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, statusFromError(ctx, err, "Failed to log in")
+		}
+		// Throttle unknown emails and take as long as a wrong password
+		// would, as the REST login does
+		wait, err := loginthrottle.Current.WaitUnknown(db, email, now)
+		if err != nil {
+			log.Printf("Failed to check failed logins for an unknown email: %v", err)
+		}
+		if wait > 0 {
+			return nil, loginThrottledError(wait)
+		}
+		utils.DummyCheckPassword(req.GetPassword())
+		if _, err := loginthrottle.Current.RecordUnknownFailure(db, email, now); err != nil {
+			log.Printf("Failed to record failed login for an unknown email: %v", err)
+		}
+		recordAuditOrLog(ctx, db, models.AuditLoginFailed, nil, "email:"+email)
+		return nil, status.Error(codes.Unauthenticated, "Invalid email or password")
+	}
+
+	// Refuse attempts while the account is throttled, as the REST login does
+	if wait := loginthrottle.Current.Wait(user, now); wait > 0 {
+		return nil, loginThrottledError(wait)
+	}
+
+	if !utils.CheckPassword(req.GetPassword(), user.PasswordHash) {
+		if _, err := loginthrottle.Current.RecordFailure(db, &user, now); err != nil {
+			log.Printf("Failed to record failed login for user %d: %v", user.ID, err)
+		}
+		recordAuditOrLog(ctx, db, models.AuditLoginFailed, &user.ID, userTarget(user.ID))
+		return nil, status.Error(codes.Unauthenticated, "Invalid email or password")
+	}
+	if err := loginthrottle.RecordSuccess(db, &user); err != nil {
+		log.Printf("Failed to reset failed logins for user %d: %v", user.ID, err)
+	}
+	if user.Status == models.StatusSuspended {
+		return nil, status.Error(codes.PermissionDenied, middleware.AccountSuspendedMessage)
+	}
+	if user.Status == models.StatusPending {
+		return nil, status.Error(codes.PermissionDenied, handlers.ConfirmationRequiredMessage)
+	}
+
+	resp, err := s.authResponse(ctx, db, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record activity for the retention sweeper and inactivity reports; a
+	// failure here must not block login
+	now = time.Now()
+	if err := db.Model(&user).UpdateColumns(map[string]interface{}{"last_login_at": now, "last_seen_at": now}).Error; err != nil {
+		log.Printf("Failed to record login time for user %d: %v", user.ID, err)
+	}
+
+	// Move the stored hash to the configured algorithm now that the password is known
+	if utils.PasswordNeedsRehash(user.PasswordHash) {
+		if err := rehashPassword(db, &user, req.GetPassword()); err != nil {
+			log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		}
+	}
+
+	recordAuditOrLog(ctx, db, models.AuditLogin, &user.ID, userTarget(user.ID))
+
+	return resp, nil
+}
+
+// GetUser returns a user, reduced to public fields for other users unless
+// ProfileVisibility or the admin role allows more
+func (s *Server) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	claims, err := s.authenticate(ctx, models.ScopeUsersRead)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := findUser(ctx, claims, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == claims.UserID {
+		return toProto(user), nil
+	}
+
+	fullProfiles, err := canViewFullProfiles(ctx, claims)
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to fetch user")
+	}
+	if !fullProfiles {
+		return toPublicProto(user), nil
+	}
+	return toProto(user), nil
+}
+
+// UpdateUser partially updates the caller's own profile; unset fields are left unchanged
+func (s *Server) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.User, error) {
+	claims, err := s.authenticate(ctx, models.ScopeUsersWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := findUser(ctx, claims, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if user.ID != claims.UserID {
+		return nil, status.Error(codes.PermissionDenied, "You can only update your own profile")
+	}
+
+	updates := make(map[string]interface{})
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.Username != nil {
+		username := validation.NormalizeUsername(req.GetUsername())
+		if !validation.ValidUsername(username) {
+			violations = append(violations, fieldViolation("username", validation.UsernameMessage))
+		}
+		updates["username"] = username
+	}
+	if req.Email != nil {
+		email := validation.NormalizeEmail(req.GetEmail())
+		if !validation.ValidEmail(email) {
+			violations = append(violations, fieldViolation("email", validation.EmailMessage))
+		}
+		updates["email"] = email
+	}
+	if len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+	// As over REST, users may keep a reserved username they already hold
+	if username, ok := updates["username"].(string); ok && username != user.Username && !validation.Usernames.PermitsUsername(username) {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("username", validation.ReservedUsernameMessage),
+		})
+	}
+	if len(updates) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "No fields to update")
+	}
+
+	if err := handlers.SaveProfile(database.DB.WithContext(ctx), user, updates); err != nil {
+		if errors.Is(err, handlers.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "The user was modified by another request; fetch it again and retry")
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, status.Error(codes.AlreadyExists, "Username or email is already taken")
+		}
+		return nil, statusFromError(ctx, err, "Failed to update user")
+	}
+	handlers.Webhooks.Notify(ctx, webhook.EventUserUpdated, user.ID)
+
+	return toProto(user), nil
+}
+
+// DeleteUser soft deletes the caller's own account
+func (s *Server) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*userv1.DeleteUserResponse, error) {
+	claims, err := s.authenticate(ctx, models.ScopeUsersWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := findUser(ctx, claims, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if user.ID != claims.UserID {
+		return nil, status.Error(codes.PermissionDenied, "You can only delete your own profile")
+	}
+
+	// Soft delete user, recording the deletion in the same transaction
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(user).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, models.AuditUserDeleted, &claims.UserID, userTarget(user.ID))
+	})
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to delete user")
+	}
+	handlers.Webhooks.Notify(ctx, webhook.EventUserDeleted, user.ID)
+
+	return &userv1.DeleteUserResponse{}, nil
+}
+
+// authenticate verifies the bearer token in the "authorization" metadata and
+// that it was granted scope
+func (s *Server) authenticate(ctx context.Context, scope string) (*utils.Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "Authorization metadata required")
+	}
+
+	tokenString, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "Invalid authorization format. Use: Bearer <token>")
+	}
+
+	claims, err := middleware.VerifyToken(ctx, tokenString, s.jwtConfig.SecretKey)
+	switch {
+	case errors.Is(err, middleware.ErrAccountSuspended):
+		return nil, status.Error(codes.PermissionDenied, middleware.AccountSuspendedMessage)
+	case errors.Is(err, middleware.ErrPasswordExpired):
+		return nil, status.Error(codes.PermissionDenied, middleware.PasswordExpiredMessage)
+	case errors.Is(err, middleware.ErrPasswordChangeRequired):
+		// The gRPC API has no password change call, so nothing is allowed
+		return nil, status.Error(codes.PermissionDenied, "You must change your password before continuing")
+	case errors.Is(err, utils.ErrExpiredToken):
+		return nil, status.Error(codes.Unauthenticated, "Token has expired")
+	case errors.Is(err, utils.ErrRevokedToken):
+		return nil, status.Error(codes.Unauthenticated, "Token has been revoked")
+	case errors.Is(err, utils.ErrInvalidToken):
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	case err != nil:
+		return nil, statusFromError(ctx, err, "Failed to verify token")
+	}
+	if !claims.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "The %s scope is required", scope)
+	}
+	return claims, nil
+}
+
+// authResponse starts a session for the caller and issues a fresh token
+// pair in it
+func (s *Server) authResponse(ctx context.Context, db *gorm.DB, user *models.User) (*userv1.AuthResponse, error) {
+	ip, userAgent := clientInfo(ctx)
+	session, err := handlers.StartSession(db, user, ip, userAgent, s.jwtConfig)
+	if err != nil {
+		return nil, statusFromError(ctx, err, "Failed to start session")
+	}
+	token, refreshToken, err := handlers.GenerateTokenPair(user, session.JTI, nil, s.jwtConfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to generate token")
+	}
+	return &userv1.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         toProto(user),
+	}, nil
+}
+
+// findUser loads a user by ID from those the caller may see, returning
+// NotFound for users of other organizations as for missing ones
+func findUser(ctx context.Context, claims *utils.Claims, id uint64) (*models.User, error) {
+	if id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid user ID")
+	}
+
+	// Super-admins see every organization, given the admin scope
+	var caller models.User
+	if err := database.DB.WithContext(ctx).Select("role").First(&caller, claims.UserID).Error; err != nil {
+		return nil, statusFromError(ctx, err, "Failed to fetch user")
+	}
+
+	var user models.User
+	role := models.ScopedRole(caller.Role, claims.HasScope(models.ScopeAdmin))
+	if err := database.DB.WithContext(ctx).Scopes(models.OrgScope(role, claims.OrgID)).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "User not found")
+		}
+		return nil, statusFromError(ctx, err, "Failed to fetch user")
+	}
+	return &user, nil
+}
+
+// canViewFullProfiles reports whether the caller may see other users' private
+// fields, which takes an admin whose token has the admin scope
+func canViewFullProfiles(ctx context.Context, claims *utils.Claims) (bool, error) {
+	if handlers.ProfileVisibility == handlers.ProfileVisibilityFull {
+		return true, nil
+	}
+
+	var caller models.User
+	if err := database.DB.WithContext(ctx).Select("role").First(&caller, claims.UserID).Error; err != nil {
+		return false, err
+	}
+	return models.IsAdminRole(models.ScopedRole(caller.Role, claims.HasScope(models.ScopeAdmin))), nil
+}
+
+// statusFromError maps an unexpected error to a gRPC status, reporting
+// deadline and cancellation as such rather than as internal errors
+func statusFromError(ctx context.Context, err error, message string) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "Request timed out")
+	case errors.Is(ctx.Err(), context.Canceled):
+		return status.Error(codes.Canceled, "Request canceled")
+	}
+	log.Printf("gRPC: %s: %v", message, err)
+	return status.Error(codes.Internal, message)
+}
+
+// fieldViolation describes a single invalid field
+func fieldViolation(field, message string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Description: message}
+}
+
+// invalidArgument returns an InvalidArgument status carrying the field violations as details
+func invalidArgument(violations []*errdetails.BadRequest_FieldViolation) error {
+	st := status.New(codes.InvalidArgument, "Validation failed")
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// recordAudit writes an audit entry for the current call through db, taking
+// the client address from the peer and the user agent from the metadata
+func recordAudit(ctx context.Context, db *gorm.DB, action string, actorID *uint, target string) error {
+	ip, userAgent := clientInfo(ctx)
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+
+	return db.Create(&models.AuditLog{
+		ActorID:   actorID,
+		Action:    action,
+		Target:    target,
+		IP:        ip,
+		UserAgent: userAgent,
+	}).Error
+}
+
+// clientInfo returns the caller's address from the peer and its user agent
+// from the metadata
+func clientInfo(ctx context.Context) (ip, userAgent string) {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+	return ip, userAgent
+}
+
+// rehashPassword replaces a user's password hash with one from the
+// configured hasher and pepper, leaving the token version alone since the password
+// itself hasn't changed
+func rehashPassword(db *gorm.DB, user *models.User, password string) error {
+	hash, err := utils.RehashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := db.Model(user).UpdateColumn("password_hash", hash).Error; err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	return nil
+}
+
+// recordAuditOrLog records an audit entry outside a transaction; a failure is
+// logged rather than failing the call
+func recordAuditOrLog(ctx context.Context, db *gorm.DB, action string, actorID *uint, target string) {
+	if err := recordAudit(ctx, db, action, actorID, target); err != nil {
+		log.Printf("Failed to record audit event %s: %v", action, err)
+	}
+}
+
+// userTarget returns the audit target for a user
+func userTarget(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+// toProto converts a user to its full protobuf representation
+func toProto(user *models.User) *userv1.User {
+	return &userv1.User{
+		Id:        uint64(user.ID),
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		AvatarUrl: user.AvatarURL,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+	}
+}
+
+// toPublicProto converts a user to the public profile, leaving private fields empty
+func toPublicProto(user *models.User) *userv1.User {
+	return &userv1.User{
+		Id:        uint64(user.ID),
+		Username:  user.Username,
+		AvatarUrl: user.AvatarURL,
+	}
+}
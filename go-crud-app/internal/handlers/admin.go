@@ -0,0 +1,601 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+	"go-crud-app/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DefaultBulkDeleteMaxBatch is the default maximum number of IDs per bulk delete
+const DefaultBulkDeleteMaxBatch = 100
+
+// BulkDeleteRequest represents the bulk delete request payload
+type BulkDeleteRequest struct {
+	IDs               []uint `json:"ids" binding:"required,min=1"`
+	ConfirmSelfDelete bool   `json:"confirm_self_delete"`
+}
+
+// BulkDeleteResult reports the outcome for a single ID in a bulk delete
+type BulkDeleteResult struct {
+	ID     uint   `json:"id"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkDeleteUsers soft-deletes a batch of users in a single transaction and
+// responds with 207 Multi-Status listing the outcome for each ID. Missing
+// users, super-admins (unless the caller is one) and the caller's own account
+// (unless confirm_self_delete is set) are reported as failures without
+// aborting the batch; a database error rolls the whole batch back.
+func BulkDeleteUsers(maxBatch int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req BulkDeleteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+
+		if maxBatch > 0 && len(req.IDs) > maxBatch {
+			respondUnprocessable(c, []FieldError{{
+				Field:   "ids",
+				Message: fmt.Sprintf("ids must contain at most %d entries", maxBatch),
+			}})
+			return
+		}
+
+		// Admins only manage users of their own organization
+		scope, ok := orgScope(c, "Failed to delete users")
+		if !ok {
+			return
+		}
+		// Only super-admins may delete super-admins, as for suspension
+		superAdmin, err := middleware.IsSuperAdmin(c)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete users")
+			return
+		}
+
+		var results []BulkDeleteResult
+		err = database.RetryTransaction(requestDB(c), func(tx *gorm.DB) error {
+			// A retry starts the batch over
+			results = make([]BulkDeleteResult, 0, len(req.IDs))
+			seen := make(map[uint]bool, len(req.IDs))
+			for _, id := range req.IDs {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+
+				if id == adminID && !req.ConfirmSelfDelete {
+					results = append(results, BulkDeleteResult{
+						ID:     id,
+						Status: http.StatusConflict,
+						Error:  "Refusing to delete your own account without confirm_self_delete",
+					})
+					continue
+				}
+
+				query := tx.Scopes(scope)
+				if !superAdmin {
+					query = query.Where("role <> ?", models.RoleSuperAdmin)
+				}
+				result := query.Delete(&models.User{}, id)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					miss, err := bulkDeleteMiss(tx, scope, superAdmin, id)
+					if err != nil {
+						return err
+					}
+					results = append(results, miss)
+					continue
+				}
+				if err := recordAudit(tx, c, models.AuditAdminBulkDelete, &adminID, userTarget(id)); err != nil {
+					return err
+				}
+				results = append(results, BulkDeleteResult{ID: id, Status: http.StatusOK})
+			}
+			return nil
+		})
+		if errors.Is(err, database.ErrWriteConflict) {
+			respondWriteConflict(c)
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete users")
+			return
+		}
+
+		// Notify only once the batch has committed
+		for _, result := range results {
+			if result.Status == http.StatusOK {
+				Webhooks.Notify(c.Request.Context(), webhook.EventUserDeleted, result.ID)
+			}
+		}
+
+		c.JSON(http.StatusMultiStatus, gin.H{
+			"results": results,
+		})
+	}
+}
+
+// bulkDeleteMiss reports why id wasn't deleted: it is a super-admin the
+// caller may not delete, or there is no such user in the caller's scope
+func bulkDeleteMiss(tx *gorm.DB, scope func(*gorm.DB) *gorm.DB, superAdmin bool, id uint) (BulkDeleteResult, error) {
+	if !superAdmin {
+		var count int64
+		if err := tx.Model(&models.User{}).Scopes(scope).Where("id = ? AND role = ?", id, models.RoleSuperAdmin).Count(&count).Error; err != nil {
+			return BulkDeleteResult{}, err
+		}
+		if count > 0 {
+			return BulkDeleteResult{ID: id, Status: http.StatusForbidden, Error: "Super-admin access required"}, nil
+		}
+	}
+	return BulkDeleteResult{ID: id, Status: http.StatusNotFound, Error: "User not found"}, nil
+}
+
+// CreateUserRequest represents the admin create-user request payload
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role"` // Defaults to user
+	// OrgID places the user in another organization; only super-admins may
+	// set it, and it defaults to the admin's own
+	OrgID *uint `json:"org_id"`
+	// MustChangePassword treats the password as temporary; defaults to true
+	MustChangePassword *bool `json:"must_change_password"`
+}
+
+// CreateUserResponse represents the admin create-user response
+type CreateUserResponse struct {
+	User               models.UserResponse `json:"user"`
+	MustChangePassword bool                `json:"must_change_password"`
+}
+
+// CreateUser lets an admin provision an account on someone else's behalf.
+// Unlike Register it works while registration is closed, can set the role,
+// and returns no token: the new user logs in themselves, and by default must
+// replace the admin-chosen password before using the API. The user joins the
+// admin's organization; only super-admins may choose another one or create
+// other super-admins.
+func CreateUser(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	role, orgID, fieldErrors, ok := adminGrant(c, req.Role, req.OrgID, "Failed to create user")
+	if !ok {
+		return
+	}
+
+	req.Username = validation.NormalizeUsername(req.Username)
+	if !validation.ValidUsername(req.Username) {
+		fieldErrors = append(fieldErrors, FieldError{Field: "username", Message: validation.UsernameMessage})
+	}
+
+	req.Email = validation.NormalizeEmail(req.Email)
+	if !validation.ValidEmail(req.Email) {
+		fieldErrors = append(fieldErrors, FieldError{Field: "email", Message: validation.EmailMessage})
+	}
+
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		fieldErrors = append(fieldErrors, FieldError{Field: "password", Message: err.Error()})
+	}
+
+	if len(fieldErrors) > 0 {
+		respondUnprocessable(c, fieldErrors)
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
+		return
+	}
+
+	now := time.Now()
+	user := models.User{
+		Username:           req.Username,
+		Email:              req.Email,
+		PasswordHash:       passwordHash,
+		PasswordChangedAt:  &now,
+		Role:               role,
+		OrgID:              orgID,
+		MustChangePassword: req.MustChangePassword == nil || *req.MustChangePassword,
+	}
+
+	err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditAdminUserCreated, &adminID, userTarget(user.ID))
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			respondError(c, http.StatusConflict, apierror.CodeConflict, "User with this email or username already exists")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create user")
+		return
+	}
+	Webhooks.Notify(c.Request.Context(), webhook.EventUserRegistered, user.ID)
+
+	c.Header("Location", models.UserPath(user.ID))
+	c.JSON(http.StatusCreated, CreateUserResponse{
+		User:               user.ToResponse(),
+		MustChangePassword: user.MustChangePassword,
+	})
+}
+
+// adminGrant checks the role (default user) and organization an admin gives
+// a new account, returning them with field errors for an unknown role or
+// organization. The organization defaults to the admin's own; only
+// super-admins may choose another one or grant the super-admin role. It
+// responds with 403, or 500 and message, and returns false otherwise.
+func adminGrant(c *gin.Context, role string, orgID *uint, message string) (string, *uint, []FieldError, bool) {
+	superAdmin, err := middleware.IsSuperAdmin(c)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+		return "", nil, nil, false
+	}
+	if !superAdmin && (orgID != nil || role == models.RoleSuperAdmin) {
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Super-admin access required")
+		return "", nil, nil, false
+	}
+
+	var fieldErrors []FieldError
+	if role == "" {
+		role = models.RoleUser
+	}
+	if role != models.RoleUser && !models.IsAdminRole(role) {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   "role",
+			Message: fmt.Sprintf("role must be %s, %s or %s", models.RoleUser, models.RoleAdmin, models.RoleSuperAdmin),
+		})
+	}
+
+	if orgID == nil {
+		return role, middleware.GetOrgID(c), fieldErrors, true
+	}
+	var count int64
+	if err := requestDB(c).Model(&models.Organization{}).Where("id = ?", *orgID).Count(&count).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+		return "", nil, nil, false
+	}
+	if count == 0 {
+		fieldErrors = append(fieldErrors, FieldError{Field: "org_id", Message: "organization does not exist"})
+	}
+	return role, orgID, fieldErrors, true
+}
+
+// UpdateUserStatusRequest represents the admin user status request payload
+type UpdateUserStatusRequest struct {
+	Status string `json:"status" binding:"required"` // active or suspended
+}
+
+// UpdateUserStatus lets an admin suspend a user of their organization, or
+// make a suspended one active again. Suspended users keep their data but
+// can't log in, and every token issued to them is revoked, so they stay
+// logged out after reactivation. Admins can't suspend themselves, and only
+// super-admins may suspend super-admins.
+func UpdateUserStatus(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	if req.Status != models.StatusActive && req.Status != models.StatusSuspended {
+		respondUnprocessable(c, []FieldError{{
+			Field:   "status",
+			Message: fmt.Sprintf("status must be %s or %s", models.StatusActive, models.StatusSuspended),
+		}})
+		return
+	}
+
+	if id == adminID && req.Status == models.StatusSuspended {
+		respondError(c, http.StatusConflict, apierror.CodeConflict, "Refusing to suspend your own account")
+		return
+	}
+
+	scope, ok := orgScope(c, "Failed to fetch user")
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := requestDB(c).Scopes(scope).First(&user, id).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
+		return
+	}
+
+	if user.Role == models.RoleSuperAdmin {
+		superAdmin, err := middleware.IsSuperAdmin(c)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update user status")
+			return
+		}
+		if !superAdmin {
+			respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Super-admin access required")
+			return
+		}
+	}
+
+	if user.Status != req.Status {
+		action := models.AuditAdminUserActivated
+		if req.Status == models.StatusSuspended {
+			action = models.AuditAdminUserSuspended
+		}
+
+		err := requestDB(c).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(map[string]interface{}{"status": req.Status, "version": models.NextVersion()}).Error; err != nil {
+				return err
+			}
+			if req.Status == models.StatusSuspended {
+				if err := revokeTokens(tx, user.ID); err != nil {
+					return err
+				}
+			}
+			if err := recordAudit(tx, c, action, &adminID, userTarget(user.ID)); err != nil {
+				return err
+			}
+			return tx.First(&user, user.ID).Error
+		})
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update user status")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": user.ToAdminResponse(),
+	})
+}
+
+// AdminResetPasswordRequest represents the admin password reset request
+// payload; an empty body generates a temporary password
+type AdminResetPasswordRequest struct {
+	// Password is the new password; a temporary one is generated when empty
+	Password string `json:"password"`
+	// MustChangePassword treats an admin-chosen password as temporary;
+	// defaults to true, and generated passwords are always temporary
+	MustChangePassword *bool `json:"must_change_password"`
+}
+
+// AdminResetPasswordResponse represents the admin password reset response
+type AdminResetPasswordResponse struct {
+	User               models.UserResponse `json:"user"`
+	MustChangePassword bool                `json:"must_change_password"`
+	// TemporaryPassword is the generated password, shown only this once
+	TemporaryPassword string `json:"temporary_password,omitempty"`
+}
+
+// AdminResetPassword lets an admin set a new password for a user of their
+// organization who can't use the email reset flow, or generate a temporary
+// one. Every token issued to the user is revoked and any login throttle is
+// lifted. By default the user must replace the new password at their next
+// login. Admins change their own password with ChangePassword instead, and
+// only super-admins may reset a super-admin's password.
+func AdminResetPassword(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	// An empty body, chunked ones included, asks for a generated password
+	var req AdminResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondBindingError(c, err)
+		return
+	}
+
+	if id == adminID {
+		respondError(c, http.StatusConflict, apierror.CodeConflict, "Use PUT /api/users/me/password to change your own password")
+		return
+	}
+
+	scope, ok := orgScope(c, "Failed to fetch user")
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := requestDB(c).Scopes(scope).First(&user, id).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
+		return
+	}
+
+	if user.Role == models.RoleSuperAdmin {
+		superAdmin, err := middleware.IsSuperAdmin(c)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to reset password")
+			return
+		}
+		if !superAdmin {
+			respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Super-admin access required")
+			return
+		}
+	}
+
+	password := req.Password
+	mustChange := req.MustChangePassword == nil || *req.MustChangePassword
+	generated := password == ""
+	if generated {
+		var err error
+		if password, err = utils.GenerateTemporaryPassword(); err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to reset password")
+			return
+		}
+		mustChange = true
+	} else {
+		if err := utils.ValidatePassword(password); err != nil {
+			respondUnprocessable(c, []FieldError{{Field: "password", Message: err.Error()}})
+			return
+		}
+		reused, err := passwordRecentlyUsed(requestDB(c), user.ID, user.PasswordHash, password)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to reset password")
+			return
+		}
+		if reused {
+			respondUnprocessable(c, []FieldError{{Field: "password", Message: passwordReusedMessage()}})
+			return
+		}
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
+		return
+	}
+
+	previousHash := user.PasswordHash
+	err = database.RetryTransaction(requestDB(c), func(tx *gorm.DB) error {
+		if err := rememberPassword(tx, user.ID, previousHash); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password_hash":         passwordHash,
+			"password_changed_at":   time.Now(),
+			"must_change_password":  mustChange,
+			"failed_login_attempts": 0,
+			"next_login_allowed_at": nil,
+			"version":               models.NextVersion(),
+		}).Error; err != nil {
+			return err
+		}
+		if err := revokeTokens(tx, user.ID); err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, models.AuditAdminPasswordReset, &adminID, userTarget(user.ID)); err != nil {
+			return err
+		}
+		return tx.First(&user, user.ID).Error
+	})
+	if errors.Is(err, database.ErrWriteConflict) {
+		respondWriteConflict(c)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to reset password")
+		return
+	}
+
+	resp := AdminResetPasswordResponse{
+		User:               user.ToAdminResponse(),
+		MustChangePassword: user.MustChangePassword,
+	}
+	if generated {
+		resp.TemporaryPassword = password
+		c.Header("Cache-Control", "no-store")
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// InactiveUsersQuery holds the threshold and pagination parameters for
+// listing inactive users
+type InactiveUsersQuery struct {
+	Days    int `form:"days" binding:"required,min=1,max=36500"`
+	Page    int `form:"page" binding:"omitempty,min=1"`
+	PerPage int `form:"per_page" binding:"omitempty,min=1,max=100"`
+}
+
+// ListInactiveUsers returns the users of the admin's organization who
+// haven't logged in or made an authenticated request for more than days
+// days, least recently seen first, with page-based pagination. Users who
+// were never seen count from when their account was created. Last-seen
+// times are refreshed at most every middleware.LastSeenTouchInterval, which
+// is far below a day.
+func ListInactiveUsers(c *gin.Context) {
+	var query InactiveUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PerPage == 0 {
+		query.PerPage = DefaultUserPageSize
+	}
+
+	scope, ok := orgScope(c, "Failed to fetch inactive users")
+	if !ok {
+		return
+	}
+
+	// Spelled out rather than COALESCE so the last_seen_at index can be used
+	cutoff := time.Now().AddDate(0, 0, -query.Days)
+	db := requestDB(c).Model(&models.User{}).Scopes(scope).
+		Where("last_seen_at < ? OR (last_seen_at IS NULL AND created_at < ?)", cutoff, cutoff)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch inactive users")
+		return
+	}
+
+	var users []models.User
+	if err := db.Order("COALESCE(last_seen_at, created_at), id").
+		Limit(query.PerPage).Offset((query.Page - 1) * query.PerPage).Find(&users).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch inactive users")
+		return
+	}
+
+	responses := make([]models.UserResponse, len(users))
+	for i := range users {
+		responses[i] = users[i].ToAdminResponse()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"users":    responses,
+		"days":     query.Days,
+		"page":     query.Page,
+		"per_page": query.PerPage,
+		"total":    total,
+	})
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// apiKeyHintLength is how much of a key is kept to tell keys apart
+const apiKeyHintLength = len(models.APIKeyPrefix) + 8
+
+// CreateAPIKeyRequest represents the admin create-API-key request payload
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required"`
+	// UserID is the user the key acts as; defaults to the admin
+	UserID    *uint      `json:"user_id"`
+	ExpiresAt *time.Time `json:"expires_at"` // Never expires when omitted
+}
+
+// CreateAPIKeyResponse represents the admin create-API-key response. The key
+// is only ever returned here.
+type CreateAPIKeyResponse struct {
+	Key    string                `json:"key"`
+	APIKey models.APIKeyResponse `json:"api_key"`
+}
+
+// CreateAPIKey lets an admin mint a long-lived API key that acts as a user
+// of their organization, for callers that can't log in interactively. Only
+// super-admins may mint keys acting as super-admins.
+func CreateAPIKey(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	var fieldErrors []FieldError
+	if strings.TrimSpace(req.Name) == "" {
+		fieldErrors = append(fieldErrors, FieldError{Field: "name", Message: "name must not be blank"})
+	}
+	fieldErrors = append(fieldErrors, validateScopes(req.Scopes)...)
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		fieldErrors = append(fieldErrors, FieldError{Field: "expires_at", Message: "expires_at must be in the future"})
+	}
+
+	ownerID := adminID
+	if req.UserID != nil {
+		ownerID = *req.UserID
+	}
+
+	scope, ok := orgScope(c, "Failed to create API key")
+	if !ok {
+		return
+	}
+
+	var owner models.User
+	if err := requestDB(c).Scopes(scope).Select("id", "role").First(&owner, ownerID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create API key")
+			return
+		}
+		fieldErrors = append(fieldErrors, FieldError{Field: "user_id", Message: "user_id does not exist"})
+	} else {
+		fieldErrors = append(fieldErrors, validateKeyScopes(c, req.Scopes, owner.Role)...)
+	}
+
+	if len(fieldErrors) > 0 {
+		respondUnprocessable(c, fieldErrors)
+		return
+	}
+
+	if owner.Role == models.RoleSuperAdmin {
+		superAdmin, err := middleware.IsSuperAdmin(c)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create API key")
+			return
+		}
+		if !superAdmin {
+			respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Super-admin access required")
+			return
+		}
+	}
+
+	secret, err := utils.GenerateSecureToken()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create API key")
+		return
+	}
+	key := models.APIKeyPrefix + secret
+
+	slices.Sort(req.Scopes)
+	apiKey := models.APIKey{
+		Name:      validation.NormalizeText(req.Name),
+		KeyHash:   utils.HashToken(key),
+		Hint:      key[:apiKeyHintLength],
+		UserID:    owner.ID,
+		Scopes:    strings.Join(slices.Compact(req.Scopes), " "),
+		CreatedBy: adminID,
+		ExpiresAt: req.ExpiresAt,
+	}
+	err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&apiKey).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditAdminAPIKeyCreated, &adminID, apiKeyTarget(apiKey.ID))
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{
+		Key:    key,
+		APIKey: apiKey.ToResponse(),
+	})
+}
+
+// ListAPIKeys returns the API keys acting as users of the admin's
+// organization, revoked and expired ones included, newest first
+func ListAPIKeys(c *gin.Context) {
+	scope, ok := orgScope(c, "Failed to fetch API keys")
+	if !ok {
+		return
+	}
+
+	var keys []models.APIKey
+	if err := apiKeysInScope(requestDB(c), scope).Order("id DESC").Find(&keys).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch API keys")
+		return
+	}
+
+	responses := make([]models.APIKeyResponse, len(keys))
+	for i := range keys {
+		responses[i] = keys[i].ToResponse()
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": responses})
+}
+
+// RevokeAPIKey revokes an API key of the admin's organization; requests
+// with it fail from then on. Revoking a revoked key changes nothing.
+func RevokeAPIKey(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid API key ID")
+		return
+	}
+
+	scope, ok := orgScope(c, "Failed to revoke API key")
+	if !ok {
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := apiKeysInScope(requestDB(c), scope).First(&apiKey, id).Error; err != nil {
+		respondLookupError(c, err, "API key not found", "Failed to revoke API key")
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		apiKey.RevokedAt = &now
+		err := requestDB(c).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+				return err
+			}
+			return recordAudit(tx, c, models.AuditAdminAPIKeyRevoked, &adminID, apiKeyTarget(apiKey.ID))
+		})
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to revoke API key")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_key": apiKey.ToResponse()})
+}
+
+// apiKeysInScope limits an API keys query to keys acting as users within
+// scope, a users query scope from orgScope
+func apiKeysInScope(db *gorm.DB, scope func(*gorm.DB) *gorm.DB) *gorm.DB {
+	return db.Where("user_id IN (?)", db.Session(&gorm.Session{NewDB: true}).Model(&models.User{}).Scopes(scope).Select("id"))
+}
+
+// apiKeyTarget is the audit target of an API key
+func apiKeyTarget(id uint) string {
+	return fmt.Sprintf("api_key:%d", id)
+}
+
+// validateKeyScopes checks that a key for a user with ownerRole gets no
+// scope the caller's own token or key lacks, nor one the owner's role lacks
+func validateKeyScopes(c *gin.Context, scopes []string, ownerRole string) []FieldError {
+	var fieldErrors []FieldError
+	ownerScopes := models.RoleScopes(ownerRole)
+	for _, scope := range scopes {
+		if !slices.Contains(models.KnownScopes, scope) {
+			continue
+		}
+		if !middleware.HasScope(c, scope) {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "scopes",
+				Message: fmt.Sprintf("scope %q is not granted to your token", scope),
+			})
+		} else if !slices.Contains(ownerScopes, scope) {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "scopes",
+				Message: fmt.Sprintf("scope %q is not granted to the key's user", scope),
+			})
+		}
+	}
+	return fieldErrors
+}
+
+// validateScopes checks that scopes, as asked for by an API key or a login,
+// names at least one scope and only known ones
+func validateScopes(scopes []string) []FieldError {
+	var fieldErrors []FieldError
+	if len(scopes) == 0 {
+		fieldErrors = append(fieldErrors, FieldError{Field: "scopes", Message: "scopes must not be empty"})
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(models.KnownScopes, scope) {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "scopes",
+				Message: fmt.Sprintf("unknown scope %q; must be one of %s", scope, strings.Join(models.KnownScopes, ", ")),
+			})
+		}
+	}
+	return fieldErrors
+}
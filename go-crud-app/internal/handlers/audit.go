@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultAuditLogPageSize is the number of audit entries returned per page by default
+	DefaultAuditLogPageSize = 50
+	// MaxAuditLogPageSize caps the per_page query parameter
+	MaxAuditLogPageSize = 100
+
+	maxUserAgentLength = 255
+)
+
+// AuditLogQuery holds the pagination and filter parameters for listing audit logs
+type AuditLogQuery struct {
+	Page    int    `form:"page" binding:"omitempty,min=1"`
+	PerPage int    `form:"per_page" binding:"omitempty,min=1,max=100"`
+	Action  string `form:"action"`
+	ActorID *uint  `form:"actor_id"`
+}
+
+// recordAudit writes an audit entry for the current request through db, so
+// callers inside a transaction commit or roll back the entry with their change.
+// Only identifiers are recorded, never passwords or tokens.
+func recordAudit(db *gorm.DB, c *gin.Context, action string, actorID *uint, target string) error {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+
+	return db.Create(&models.AuditLog{
+		ActorID:   actorID,
+		Action:    action,
+		Target:    target,
+		IP:        c.ClientIP(),
+		UserAgent: userAgent,
+	}).Error
+}
+
+// recordAuditOrLog records an audit entry outside a transaction; a failure is
+// logged rather than failing the request
+func recordAuditOrLog(c *gin.Context, action string, actorID *uint, target string) {
+	if err := recordAudit(requestDB(c), c, action, actorID, target); err != nil {
+		log.Printf("Failed to record audit event %s: %v", action, err)
+	}
+}
+
+// userTarget returns the audit target for a user
+func userTarget(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+// ListAuditLogs returns audit entries newest first, optionally filtered by
+// action and actor, with page-based pagination. Admins of an organization
+// only see entries whose actor is one of its members.
+func ListAuditLogs(c *gin.Context) {
+	var query AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PerPage == 0 {
+		query.PerPage = DefaultAuditLogPageSize
+	}
+
+	superAdmin, err := middleware.IsSuperAdmin(c)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch audit logs")
+		return
+	}
+
+	db := requestDB(c).Model(&models.AuditLog{})
+	if orgID := middleware.GetOrgID(c); orgID != nil && !superAdmin {
+		// Include members who have since been deleted
+		members := requestDB(c).Unscoped().Model(&models.User{}).Scopes(models.InOrg(orgID)).Select("id")
+		db = db.Where("actor_id IN (?)", members)
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.ActorID != nil {
+		db = db.Where("actor_id = ?", *query.ActorID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch audit logs")
+		return
+	}
+
+	logs := make([]models.AuditLog, 0, query.PerPage)
+	if err := db.Order("id DESC").Limit(query.PerPage).Offset((query.Page - 1) * query.PerPage).Find(&logs).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch audit logs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": logs,
+		"page":       query.Page,
+		"per_page":   query.PerPage,
+		"total":      total,
+	})
+}
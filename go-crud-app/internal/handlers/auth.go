@@ -1,118 +1,414 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
-	"regexp"
-	"strings"
+	"slices"
+	"time"
 
-	"go-crud-app/internal/database"
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/loginthrottle"
+	"go-crud-app/internal/middleware"
 	"go-crud-app/internal/models"
 	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+	"go-crud-app/internal/webhook"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var (
-	// Email validation regex
-	emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-	// Username validation regex (alphanumeric and underscore, 3-50 chars)
-	usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]{3,50}$`)
-)
+// RegistrationOpen controls whether self-registration accepts new accounts
+var RegistrationOpen = true
+
+// ReactivateDeletedAccounts makes registering with the email of a
+// soft-deleted account restore that account instead of creating a new one
+var ReactivateDeletedAccounts = false
 
 // RegisterRequest represents the registration request payload
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// InviteCode joins an existing organization, and Organization names a new
+	// one; both are ignored unless OrgRegistration enables organizations
+	InviteCode   string `json:"invite_code"`
+	Organization string `json:"organization"`
+	// InviteToken is an admin-issued invite; see Invites
+	InviteToken string `json:"invite_token"`
+}
+
+// RegisterQuery represents the registration query parameters
+type RegisterQuery struct {
+	// ValidateOnly runs every check without creating the user
+	ValidateOnly bool `form:"validate_only"`
 }
 
 // LoginRequest represents the login request payload
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Scopes narrows the issued tokens to some of the user's role scopes;
+	// nil grants all of them
+	Scopes []string `json:"scopes"`
+}
+
+// RefreshRequest represents the token refresh request payload
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // AuthResponse represents the authentication response
 type AuthResponse struct {
-	Token string              `json:"token"`
-	User  models.UserResponse `json:"user"`
+	Token        string              `json:"token"`
+	RefreshToken string              `json:"refresh_token"`
+	User         models.UserResponse `json:"user"`
+	// MustChangePassword means the token only works for changing the password
+	MustChangePassword bool `json:"must_change_password,omitempty"`
+	// PasswordExpired means the password must be changed because it expired
+	PasswordExpired bool `json:"password_expired,omitempty"`
+}
+
+// UserTokenOptions describes the access tokens issued to user in the given
+// session, granted the scopes of requested the user's role allows (every
+// role scope when requested is nil)
+func UserTokenOptions(user *models.User, sessionID string, requested []string) utils.TokenOptions {
+	return utils.TokenOptions{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		OrgID:        user.OrgID,
+		TokenVersion: user.TokenVersion,
+		SessionID:    sessionID,
+		Scopes:       models.GrantedScopes(user.Role, requested),
+	}
+}
+
+// GenerateTokenPair issues an access and refresh token pair for user in the
+// given session, narrowed to the requested scopes. The refresh token keeps
+// the request rather than the granted scopes, so a refresh narrows the
+// user's role scopes of the time the same way.
+func GenerateTokenPair(user *models.User, sessionID string, requested []string, jwtConfig utils.JWTConfig) (token, refreshToken string, err error) {
+	options := UserTokenOptions(user, sessionID, requested)
+	token, err = utils.GenerateToken(options, jwtConfig)
+	if err != nil {
+		return "", "", err
+	}
+	options.Scopes = requested
+	refreshToken, err = utils.GenerateRefreshToken(options, jwtConfig)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+// newAuthResponse issues a fresh access and refresh token pair for the user
+// in the given session, narrowed to the requested scopes
+func newAuthResponse(user *models.User, sessionID string, requested []string, jwtConfig utils.JWTConfig) (AuthResponse, error) {
+	token, refreshToken, err := GenerateTokenPair(user, sessionID, requested, jwtConfig)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	expired := utils.PasswordExpired(user.PasswordChangedAt)
+	return AuthResponse{
+		Token:              token,
+		RefreshToken:       refreshToken,
+		User:               user.ToResponse(),
+		MustChangePassword: user.MustChangePassword || expired,
+		PasswordExpired:    expired,
+	}, nil
+}
+
+// newSessionAuthResponse starts a session for the requesting client and
+// issues a token pair in it, narrowed to the requested scopes
+func newSessionAuthResponse(c *gin.Context, user *models.User, requested []string, jwtConfig utils.JWTConfig) (AuthResponse, error) {
+	session, err := StartSession(requestDB(c), user, c.ClientIP(), c.Request.UserAgent(), jwtConfig)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	return newAuthResponse(user, session.JTI, requested, jwtConfig)
+}
+
+// respondAuth writes an auth response, also storing the access token in the
+// auth cookie when cookie auth is enabled, along with the matching CSRF
+// token header when CSRF protection is on
+func respondAuth(c *gin.Context, status int, resp AuthResponse, jwtConfig utils.JWTConfig) {
+	if middleware.AuthCookie.Enabled() {
+		middleware.SetAuthCookie(c, resp.Token, time.Duration(jwtConfig.ExpirationHours)*time.Hour)
+		if middleware.CSRFProtection {
+			c.Header(middleware.CSRFTokenHeader, middleware.CSRFToken(jwtConfig.SecretKey, resp.Token))
+		}
+	}
+	c.JSON(status, resp)
+}
+
+// GetCSRFToken returns the CSRF token for the current cookie session, for
+// clients that didn't keep the one sent with the auth response
+func GetCSRFToken(jwtSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := middleware.RequestCSRFToken(c, jwtSecret)
+		if !ok {
+			respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "CSRF tokens are only needed for cookie authentication")
+			return
+		}
+
+		c.Header(middleware.CSRFTokenHeader, token)
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, gin.H{"csrf_token": token})
+	}
 }
 
 // Register handles user registration
 func Register(jwtConfig utils.JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !RegistrationOpen {
+			respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Registration is closed")
+			return
+		}
+
+		var query RegisterQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+
 		var req RegisterRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid request payload",
-			})
+			respondBindingError(c, err)
 			return
 		}
 
+		var fieldErrors []FieldError
+
 		// Validate username
-		req.Username = strings.TrimSpace(req.Username)
-		if !usernameRegex.MatchString(req.Username) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Username must be 3-50 characters and contain only letters, numbers, and underscores",
+		req.Username = validation.NormalizeUsername(req.Username)
+		if !validation.ValidUsername(req.Username) {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "username",
+				Message: validation.UsernameMessage,
 			})
-			return
 		}
 
 		// Validate email
-		req.Email = strings.TrimSpace(strings.ToLower(req.Email))
-		if !emailRegex.MatchString(req.Email) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid email format",
+		req.Email = validation.NormalizeEmail(req.Email)
+		if !validation.ValidEmail(req.Email) {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "email",
+				Message: validation.EmailMessage,
 			})
+		}
+
+		// Validate password policy
+		if err := utils.ValidatePassword(req.Password); err != nil {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "password",
+				Message: err.Error(),
+			})
+		}
+
+		if len(fieldErrors) > 0 {
+			respondUnprocessable(c, fieldErrors)
 			return
 		}
 
-		// Check if user already exists
-		var existingUser models.User
-		if err := database.DB.Where("email = ? OR username = ?", req.Email, req.Username).First(&existingUser).Error; err == nil {
-			c.JSON(http.StatusConflict, gin.H{
-				"error": "User with this email or username already exists",
+		// Only well-formed usernames reach the reserved list
+		if !validation.Usernames.PermitsUsername(req.Username) {
+			respondReservedUsername(c)
+			return
+		}
+
+		// Only otherwise valid registrations look up the invite and organization
+		invite, inviteErrors, err := findInvite(requestDB(c), req.InviteToken, req.Email, jwtConfig.SecretKey)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create user")
+			return
+		}
+		if len(inviteErrors) > 0 {
+			respondUnprocessable(c, inviteErrors)
+			return
+		}
+
+		// An invite into an organization stands in for the invite code
+		var org *models.Organization
+		if invite == nil || invite.OrgID == nil {
+			var orgErrors []FieldError
+			org, orgErrors, err = RegistrationOrg(requestDB(c), req.InviteCode, req.Organization)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create user")
+				return
+			}
+			if len(orgErrors) > 0 {
+				respondUnprocessable(c, orgErrors)
+				return
+			}
+		}
+
+		// Only well-formed emails reach the domain allow/deny lists
+		if !validation.EmailDomains.PermitsEmail(req.Email) {
+			respond(c, http.StatusUnprocessableEntity, apierror.Body{
+				Code:    apierror.CodeEmailDomainNotAllowed,
+				Message: validation.EmailDomainMessage,
+				Fields:  []FieldError{{Field: "email", Message: validation.EmailDomainMessage}},
 			})
 			return
 		}
 
+		// Strict mode never says whether the details are taken, and a dry run
+		// must not send the owner a notice
+		if query.ValidateOnly && RegistrationPrivacy.Strict() {
+			respondRegistrationValid(c)
+			return
+		}
+
+		// Check if user already exists; the email wins so strict mode can
+		// notify its owner
+		var existingUser models.User
+		if err := requestDB(c).Where("email = ? OR username = ?", req.Email, req.Username).
+			Order(clause.Expr{SQL: "email = ? DESC", Vars: []interface{}{req.Email}}).First(&existingUser).Error; err == nil {
+			respondDuplicateUser(c, req.Email, req.Username, req.Password, &existingUser)
+			return
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create user")
+			return
+		}
+
+		if query.ValidateOnly {
+			respondRegistrationValid(c)
+			return
+		}
+
 		// Hash password
 		passwordHash, err := utils.HashPassword(req.Password)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": err.Error(),
-			})
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
 			return
 		}
 
+		// Restore a soft-deleted account with this email when enabled, in the
+		// organization resolved above; an invite always creates a new
+		// account, and strict mode never tells a reactivation apart from a new
+		// account
+		if ReactivateDeletedAccounts && invite == nil && !RegistrationPrivacy.Strict() {
+			user, err := ReactivateDeletedUser(requestDB(c), req.Username, req.Email, passwordHash, org)
+			switch {
+			case err == nil:
+				recordAuditOrLog(c, models.AuditUserReactivated, &user.ID, userTarget(user.ID))
+				Webhooks.Notify(c.Request.Context(), webhook.EventUserReactivated, user.ID)
+
+				resp, err := newSessionAuthResponse(c, user, nil, jwtConfig)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
+					return
+				}
+				respondAuth(c, http.StatusOK, resp, jwtConfig)
+				return
+			case errors.Is(err, gorm.ErrDuplicatedKey):
+				respondDuplicateUser(c, req.Email, req.Username, req.Password, nil)
+				return
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to reactivate user")
+				return
+			}
+		}
+
 		// Create user
+		now := time.Now()
 		user := models.User{
-			Username:     req.Username,
-			Email:        req.Email,
-			PasswordHash: passwordHash,
+			Username:          req.Username,
+			Email:             req.Email,
+			PasswordHash:      passwordHash,
+			PasswordChangedAt: &now,
 		}
 
-		if err := database.DB.Create(&user).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to create user",
-			})
+		// The existence check above is racy; the unique indexes are the final
+		// word. In strict mode the account waits for its email to be confirmed.
+		create := CreateRegisteredUser
+		if RegistrationPrivacy.Strict() {
+			create = createPendingUser
+		}
+		if err := create(requestDB(c), &user, org, invite); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				respondDuplicateUser(c, req.Email, req.Username, req.Password, nil)
+				return
+			}
+			if errors.Is(err, errInviteUsed) {
+				respondUnprocessable(c, inviteFieldError("invite_token has already been used"))
+				return
+			}
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create user")
+			return
+		}
+		if RegistrationPrivacy.Strict() {
+			respondRegistrationPending(c)
 			return
 		}
+		Webhooks.Notify(c.Request.Context(), webhook.EventUserRegistered, user.ID)
 
-		// Generate JWT token
-		token, err := utils.GenerateToken(user.ID, user.Username, user.Email, jwtConfig)
+		// Generate JWT tokens
+		resp, err := newSessionAuthResponse(c, &user, nil, jwtConfig)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to generate token",
-			})
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
 			return
 		}
 
-		c.JSON(http.StatusCreated, AuthResponse{
-			Token: token,
-			User:  user.ToResponse(),
-		})
+		c.Header("Location", models.UserPath(user.ID))
+		respondAuth(c, http.StatusCreated, resp, jwtConfig)
+	}
+}
+
+// ReactivateDeletedUser restores the most recently soft-deleted account with
+// the given email, giving it the new username and password hash. Like a new
+// registration, the account joins org (nil for none), as returned by
+// RegistrationOrg, rather than the organization it was deleted from, and
+// becomes active with the user role. The token version is bumped, so tokens
+// issued before the deletion stay revoked. Returns gorm.ErrRecordNotFound
+// when no soft-deleted account has the email; active accounts are never
+// modified.
+func ReactivateDeletedUser(db *gorm.DB, username, email, passwordHash string, org *models.Organization) (*models.User, error) {
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("email = ? AND deleted_at IS NOT NULL", email).
+			Order("deleted_at DESC").First(&user).Error; err != nil {
+			return err
+		}
+
+		user.DeletedAt = gorm.DeletedAt{}
+		user.Username = username
+		user.PasswordHash = passwordHash
+		user.Role = models.RoleUser
+		user.OrgID = nil
+		user.Status = models.StatusActive
+		user.MustChangePassword = false
+		now := time.Now()
+		user.PasswordChangedAt = &now
+		user.TokenVersion++
+		user.Version++
+		if org != nil {
+			if err := joinRegistrationOrg(tx, &user, org); err != nil {
+				return err
+			}
+		}
+
+		// Guard on deleted_at so a concurrent reactivation can't apply twice
+		result := tx.Unscoped().Model(&user).Where("deleted_at IS NOT NULL").
+			Select("deleted_at", "username", "password_hash", "password_changed_at", "role", "org_id", "status", "must_change_password", "token_version", "version").
+			Updates(&user)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &user, nil
 }
 
 // Login handles user login
@@ -120,44 +416,240 @@ func Login(jwtConfig utils.JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid request payload",
-			})
+			respondBindingError(c, err)
 			return
 		}
 
+		// Scopes are optional, but a login that names them must name some
+		if req.Scopes != nil {
+			if fieldErrors := validateScopes(req.Scopes); len(fieldErrors) > 0 {
+				respondUnprocessable(c, fieldErrors)
+				return
+			}
+		}
+
 		// Normalize email
-		req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+		req.Email = validation.NormalizeEmail(req.Email)
 
 		// Find user by email
 		var user models.User
-		if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid email or password",
-			})
+		now := time.Now()
+		if err := requestDB(c).Where("email = ?", req.Email).First(&user).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to log in")
+				return
+			}
+			// Throttle unknown emails like accounts, so a 429 doesn't
+			// reveal that an account exists
+			wait, err := loginthrottle.Current.WaitUnknown(requestDB(c), req.Email, now)
+			if err != nil {
+				log.Printf("Failed to check failed logins for an unknown email: %v", err)
+			}
+			if wait > 0 {
+				respondLoginThrottled(c, now, wait)
+				return
+			}
+			// Take as long as a wrong password would so unknown emails
+			// can't be found by timing the response
+			utils.DummyCheckPassword(req.Password)
+			if _, err := loginthrottle.Current.RecordUnknownFailure(requestDB(c), req.Email, now); err != nil {
+				log.Printf("Failed to record failed login for an unknown email: %v", err)
+			}
+			recordAuditOrLog(c, models.AuditLoginFailed, nil, "email:"+req.Email)
+			respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or password")
+			return
+		}
+
+		// Refuse attempts while the account is throttled, before checking
+		// the password so guesses made during the wait are worthless
+		if wait := loginthrottle.Current.Wait(user, now); wait > 0 {
+			respondLoginThrottled(c, now, wait)
 			return
 		}
 
 		// Check password
 		if !utils.CheckPassword(req.Password, user.PasswordHash) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid email or password",
-			})
+			if _, err := loginthrottle.Current.RecordFailure(requestDB(c), &user, now); err != nil {
+				log.Printf("Failed to record failed login for user %d: %v", user.ID, err)
+			}
+			recordAuditOrLog(c, models.AuditLoginFailed, &user.ID, userTarget(user.ID))
+			respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or password")
 			return
 		}
+		if err := loginthrottle.RecordSuccess(requestDB(c), &user); err != nil {
+			log.Printf("Failed to reset failed logins for user %d: %v", user.ID, err)
+		}
 
-		// Generate JWT token
-		token, err := utils.GenerateToken(user.ID, user.Username, user.Email, jwtConfig)
+		// Only say the account is suspended or unconfirmed to someone who
+		// knows its password
+		if user.Status == models.StatusSuspended {
+			respondError(c, http.StatusForbidden, apierror.CodeAccountSuspended, middleware.AccountSuspendedMessage)
+			return
+		}
+		if user.Status == models.StatusPending {
+			respondError(c, http.StatusForbidden, apierror.CodeConfirmationRequired, ConfirmationRequiredMessage)
+			return
+		}
+
+		// Only say which scopes the role lacks to someone who knows the
+		// password
+		roleScopes := models.RoleScopes(user.Role)
+		for _, scope := range req.Scopes {
+			if !slices.Contains(roleScopes, scope) {
+				respondUnprocessable(c, []FieldError{{
+					Field:   "scopes",
+					Message: fmt.Sprintf("scope %q is not granted to your account", scope),
+				}})
+				return
+			}
+		}
+
+		// Generate JWT tokens
+		resp, err := newSessionAuthResponse(c, &user, req.Scopes, jwtConfig)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to generate token",
-			})
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
+			return
+		}
+
+		// Record activity for the retention sweeper and inactivity reports; a
+		// failure here must not block login
+		now = time.Now()
+		if err := requestDB(c).Model(&user).UpdateColumns(map[string]interface{}{"last_login_at": now, "last_seen_at": now}).Error; err != nil {
+			log.Printf("Failed to record login time for user %d: %v", user.ID, err)
+		}
+
+		// Move the stored hash to the configured algorithm now that the password is known
+		if utils.PasswordNeedsRehash(user.PasswordHash) {
+			if err := rehashPassword(requestDB(c), &user, req.Password); err != nil {
+				log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+			}
+		}
+
+		recordAuditOrLog(c, models.AuditLogin, &user.ID, userTarget(user.ID))
+
+		respondAuth(c, http.StatusOK, resp, jwtConfig)
+	}
+}
+
+// respondLoginThrottled refuses a login attempt made before the throttle's
+// wait has passed
+func respondLoginThrottled(c *gin.Context, now time.Time, wait time.Duration) {
+	c.Header("Retry-After", middleware.FormatRetryAfter(middleware.RetryAfterMode, now, wait))
+	respondError(c, http.StatusTooManyRequests, apierror.CodeLoginThrottled,
+		fmt.Sprintf("Too many failed login attempts. Try again in %d seconds", int(math.Ceil(wait.Seconds()))))
+}
+
+// rehashPassword replaces a user's password hash with one from the
+// configured hasher and pepper, leaving the token version alone since the password
+// itself hasn't changed
+func rehashPassword(db *gorm.DB, user *models.User, password string) error {
+	hash, err := utils.RehashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := db.Model(user).UpdateColumn("password_hash", hash).Error; err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	return nil
+}
+
+// Refresh exchanges a valid refresh token for a new access and refresh token
+// pair. Refresh tokens are revoked along with access tokens when the user's
+// token version is bumped.
+func Refresh(jwtConfig utils.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindingError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, AuthResponse{
-			Token: token,
-			User:  user.ToResponse(),
-		})
+		claims, err := utils.ValidateToken(req.RefreshToken, jwtConfig.SecretKey)
+		if err != nil {
+			if errors.Is(err, utils.ErrExpiredToken) {
+				respondError(c, http.StatusUnauthorized, apierror.CodeTokenExpired, "Refresh token has expired")
+				return
+			}
+			respondError(c, http.StatusUnauthorized, apierror.CodeTokenInvalid, "Invalid refresh token")
+			return
+		}
+		if !claims.IsRefresh() {
+			respondError(c, http.StatusUnauthorized, apierror.CodeTokenInvalid, "Invalid refresh token")
+			return
+		}
+
+		var user models.User
+		if err := requestDB(c).First(&user, claims.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				respondError(c, http.StatusUnauthorized, apierror.CodeTokenInvalid, "Invalid refresh token")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to verify refresh token")
+			return
+		}
+		if user.Status == models.StatusSuspended {
+			respondError(c, http.StatusForbidden, apierror.CodeAccountSuspended, middleware.AccountSuspendedMessage)
+			return
+		}
+		if claims.TokenVersion < user.TokenVersion {
+			respondError(c, http.StatusUnauthorized, apierror.CodeTokenInvalid, "Refresh token has been revoked")
+			return
+		}
+
+		// Stay in the refresh token's session; tokens from before sessions
+		// existed start one
+		var resp AuthResponse
+		if claims.ID != "" {
+			err = refreshSession(requestDB(c), claims, jwtConfig)
+			if errors.Is(err, errSessionRevoked) {
+				respondError(c, http.StatusUnauthorized, apierror.CodeTokenInvalid, "Refresh token has been revoked")
+				return
+			}
+			if err == nil {
+				resp, err = newAuthResponse(&user, claims.ID, claims.Scopes, jwtConfig)
+			}
+		} else {
+			resp, err = newSessionAuthResponse(c, &user, claims.Scopes, jwtConfig)
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
+			return
+		}
+
+		respondAuth(c, http.StatusOK, resp, jwtConfig)
 	}
 }
+
+// LogoutAll revokes every token issued to the current user, including the one
+// used for this request, by bumping their token version
+func LogoutAll(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	err := requestDB(c).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("token_version", gorm.Expr("token_version + ?", 1))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recordAudit(tx, c, models.AuditLogoutAll, &userID, userTarget(userID))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondNotFound(c, "User not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to log out sessions")
+		return
+	}
+
+	middleware.ClearAuthCookie(c)
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions have been logged out"})
+}
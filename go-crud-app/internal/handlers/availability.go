@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AvailabilityDetailed makes the availability check report each field
+// separately; otherwise only the combined "available" flag is returned
+var AvailabilityDetailed = false
+
+// AvailabilityQuery holds the values to check before registering
+type AvailabilityQuery struct {
+	Username *string `form:"username"`
+	Email    *string `form:"email"`
+}
+
+// CheckAvailability reports whether a username and/or email could be used to
+// register, normalizing and matching them exactly as Register does
+func CheckAvailability(c *gin.Context) {
+	if !RegistrationOpen {
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Registration is closed")
+		return
+	}
+
+	var query AvailabilityQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	if query.Username == nil && query.Email == nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Provide a username, an email, or both")
+		return
+	}
+
+	// Malformed values can't be registered, so there is no answer to give
+	var fieldErrors []FieldError
+	if query.Username != nil {
+		*query.Username = validation.NormalizeUsername(*query.Username)
+		if !validation.ValidUsername(*query.Username) {
+			fieldErrors = append(fieldErrors, FieldError{Field: "username", Message: validation.UsernameMessage})
+		}
+	}
+	if query.Email != nil {
+		*query.Email = validation.NormalizeEmail(*query.Email)
+		if !validation.ValidEmail(*query.Email) {
+			fieldErrors = append(fieldErrors, FieldError{Field: "email", Message: validation.EmailMessage})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		respondValidationErrors(c, fieldErrors)
+		return
+	}
+
+	resp := gin.H{}
+	available := true
+	if query.Username != nil {
+		// Reserved usernames can't be registered even though nobody holds them
+		free := validation.Usernames.PermitsUsername(*query.Username)
+		if free {
+			var err error
+			if free, err = valueAvailable(requestDB(c), "username", *query.Username); err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check availability")
+				return
+			}
+		}
+		resp["username"] = free
+		available = available && free
+	}
+	if query.Email != nil {
+		free, err := valueAvailable(requestDB(c), "email", *query.Email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check availability")
+			return
+		}
+		resp["email"] = free
+		available = available && free
+	}
+
+	if !AvailabilityDetailed {
+		resp = gin.H{}
+	}
+	resp["available"] = available
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// valueAvailable reports whether no live user has the value in column
+func valueAvailable(db *gorm.DB, column, value string) (bool, error) {
+	var count int64
+	if err := db.Model(&models.User{}).Where(column+" = ?", value).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/storage"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultAvatarMaxBytes is the default maximum avatar upload size (5 MB)
+const DefaultAvatarMaxBytes int64 = 5 << 20
+
+// allowedAvatarTypes are the image types accepted for upload, detected from
+// the file's magic bytes rather than its name or declared content type
+var allowedAvatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// AvatarConfig holds avatar upload configuration
+type AvatarConfig struct {
+	Store    storage.Store
+	MaxBytes int64
+	Image    utils.ImageConfig
+}
+
+// UploadAvatar accepts a multipart image upload in the "avatar" field, re-encodes
+// it and stores it as the current user's profile image
+func UploadAvatar(config AvatarConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		fileHeader, err := c.FormFile("avatar")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				respondError(c, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge, "Avatar image too large")
+				return
+			}
+			respondValidationErrors(c, []FieldError{{Field: "avatar", Message: "An image file is required"}})
+			return
+		}
+		if fileHeader.Size > config.MaxBytes {
+			respondError(c, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge, "Avatar image too large")
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to read upload")
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, config.MaxBytes+1))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to read upload")
+			return
+		}
+		if int64(len(data)) > config.MaxBytes {
+			respondError(c, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge, "Avatar image too large")
+			return
+		}
+
+		if !allowedAvatarTypes[http.DetectContentType(data)] {
+			respondError(c, http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMediaType, "Avatar must be a JPEG, PNG or GIF image")
+			return
+		}
+
+		processed, contentType, err := utils.ProcessImage(data, config.Image)
+		if err != nil {
+			if errors.Is(err, utils.ErrInvalidImage) || errors.Is(err, utils.ErrImageTooLarge) {
+				respondUnprocessable(c, []FieldError{{Field: "avatar", Message: err.Error()}})
+				return
+			}
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to process image")
+			return
+		}
+
+		var user models.User
+		if err := requestDB(c).First(&user, userID).Error; err != nil {
+			respondLookupError(c, err, "User not found", "Failed to fetch user")
+			return
+		}
+
+		// A fresh key per upload lets clients and caches treat avatars as immutable
+		suffix, err := utils.GenerateSecureToken()
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to store avatar")
+			return
+		}
+		key := fmt.Sprintf("avatars/%d-%s%s", user.ID, suffix[:16], avatarExtension(contentType))
+
+		if err := config.Store.Put(c.Request.Context(), key, processed, contentType); err != nil {
+			log.Printf("Failed to store avatar for user %d: %v", user.ID, err)
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to store avatar")
+			return
+		}
+
+		previousKey := user.AvatarKey
+		if err := requestDB(c).Model(&user).Updates(map[string]interface{}{
+			"avatar_key": key,
+			"avatar_url": models.UserPath(user.ID) + "/avatar",
+			"version":    models.NextVersion(),
+		}).Error; err != nil {
+			_ = config.Store.Delete(c.Request.Context(), key)
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update avatar")
+			return
+		}
+
+		if previousKey != "" {
+			if err := config.Store.Delete(c.Request.Context(), previousKey); err != nil {
+				log.Printf("Failed to delete previous avatar %s: %v", previousKey, err)
+			}
+		}
+
+		c.JSON(http.StatusOK, user.ToResponse())
+	}
+}
+
+// GetAvatar serves a user's profile image
+func GetAvatar(store storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		var user models.User
+		if err := requestDB(c).Select("id", "avatar_key").First(&user, id).Error; err != nil {
+			respondLookupError(c, err, "Avatar not found", "Failed to load avatar")
+			return
+		}
+		if user.AvatarKey == "" {
+			respondNotFound(c, "Avatar not found")
+			return
+		}
+
+		// Keys change on every upload, so the key doubles as a strong ETag
+		etag := `"` + path.Base(user.AvatarKey) + `"`
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", "public, no-cache")
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		body, err := store.Get(c.Request.Context(), user.AvatarKey)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				respondNotFound(c, "Avatar not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load avatar")
+			return
+		}
+		defer body.Close()
+
+		c.DataFromReader(http.StatusOK, -1, avatarContentType(user.AvatarKey), body, nil)
+	}
+}
+
+// avatarExtension returns the file extension for a processed image content type
+func avatarExtension(contentType string) string {
+	if contentType == "image/png" {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// avatarContentType returns the content type for a stored avatar key
+func avatarContentType(key string) string {
+	if path.Ext(key) == ".png" {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// TokenDeliveryHeader means clients send the access token in the Authorization header
+	TokenDeliveryHeader = "header"
+	// DefaultPublicConfigMaxAge is how long clients may cache the public config
+	DefaultPublicConfigMaxAge = 5 * time.Minute
+)
+
+// PublicConfigOptions holds the live settings the public config endpoint reports
+type PublicConfigOptions struct {
+	JWT            utils.JWTConfig
+	PasswordReset  PasswordResetConfig
+	OAuthProviders []string // Enabled third-party sign-in providers
+	MaxAge         time.Duration
+}
+
+// PublicConfigResponse lists the non-sensitive settings clients need to self-configure
+type PublicConfigResponse struct {
+	PasswordPolicy   utils.PasswordPolicy `json:"password_policy"`
+	RegistrationOpen bool                 `json:"registration_open"`
+	OrgRegistration  string               `json:"org_registration"` // Whether registration takes an invite code or organization name
+	InviteOnly       bool                 `json:"invite_only"`      // Whether registration requires an invite_token
+	OAuthProviders   []string             `json:"oauth_providers"`
+	Token            TokenSettings        `json:"token"`
+}
+
+// TokenSettings describes how tokens are issued and delivered
+type TokenSettings struct {
+	Delivery                     string `json:"delivery"`
+	AccessTokenTTLSeconds        int64  `json:"access_token_ttl_seconds"`
+	RefreshTokenTTLSeconds       int64  `json:"refresh_token_ttl_seconds"`
+	PasswordResetTokenTTLSeconds int64  `json:"password_reset_token_ttl_seconds"`
+}
+
+// PublicConfig serves the public client configuration. The document is built
+// once from the live settings when the route is registered, so it must be
+// created after configuration has been loaded; it is served with an ETag and
+// Cache-Control so clients can cache it.
+func PublicConfig(options PublicConfigOptions) gin.HandlerFunc {
+	providers := options.OAuthProviders
+	if providers == nil {
+		providers = []string{}
+	}
+
+	body, err := json.Marshal(PublicConfigResponse{
+		PasswordPolicy:   utils.PasswordRules,
+		RegistrationOpen: RegistrationOpen,
+		OrgRegistration:  OrgRegistration,
+		InviteOnly:       Invites.Required,
+		OAuthProviders:   providers,
+		Token: TokenSettings{
+			Delivery:                     TokenDeliveryHeader,
+			AccessTokenTTLSeconds:        int64((time.Duration(options.JWT.ExpirationHours) * time.Hour).Seconds()),
+			RefreshTokenTTLSeconds:       int64((time.Duration(options.JWT.RefreshExpirationHours) * time.Hour).Seconds()),
+			PasswordResetTokenTTLSeconds: int64(options.PasswordReset.TokenTTL.Seconds()),
+		},
+	})
+	if err != nil {
+		panic(fmt.Sprintf("handlers: failed to encode public config: %v", err))
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(options.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", cacheControl)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
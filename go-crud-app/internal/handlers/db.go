@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"go-crud-app/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// requestDB returns the database handle bound to the request context, so
+// queries are cancelled when the client disconnects or the deadline passes.
+// The context tracks writes, so once the request has written, its reads go
+// to the primary rather than a possibly lagging replica.
+func requestDB(c *gin.Context) *gorm.DB {
+	ctx := database.TrackWrites(c.Request.Context())
+	if ctx != c.Request.Context() {
+		c.Request = c.Request.WithContext(ctx)
+	}
+	return database.WithContext(ctx)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/mailer"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DefaultEmailChangeTokenTTL is how long an email change confirmation link stays valid
+const DefaultEmailChangeTokenTTL = 24 * time.Hour
+
+// errChangeTokenUsed aborts a confirmation whose token a concurrent
+// confirmation used first
+var errChangeTokenUsed = errors.New("email change token already used")
+
+// EmailChangeConfig holds email change confirmation configuration
+type EmailChangeConfig struct {
+	Confirm    bool // Hold a new email until it is confirmed; false applies it immediately
+	Mailer     mailer.Mailer
+	TokenTTL   time.Duration
+	ConfirmURL string // URL the confirmation token is appended to
+}
+
+// EmailChange configures how users change their email address. By default a
+// new email is only applied once confirmed, so a hijacked session can't
+// quietly move the account to an attacker's address.
+var EmailChange = EmailChangeConfig{
+	Confirm:    true,
+	Mailer:     mailer.LogMailer{},
+	TokenTTL:   DefaultEmailChangeTokenTTL,
+	ConfirmURL: "http://localhost:8080/api/auth/confirm-email",
+}
+
+// ConfirmEmailQuery represents the confirm-email query parameters
+type ConfirmEmailQuery struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// SaveProfile applies a user's changes to their own profile with
+// UpdateProfile. When EmailChange requires confirmation, a new email is not
+// applied: it is stored as the pending email, a confirmation link is sent to
+// it and the current address is told about the request. Asking for the
+// current email cancels a pending change. An email that belongs to another
+// account fails with gorm.ErrDuplicatedKey.
+func SaveProfile(db *gorm.DB, user *models.User, updates map[string]interface{}) error {
+	email, ok := updates["email"].(string)
+	if !ok || !EmailChange.Confirm {
+		return UpdateProfile(db, user, updates)
+	}
+	delete(updates, "email")
+
+	if email == user.Email {
+		updates["pending_email"] = ""
+		return UpdateProfile(db, user, updates)
+	}
+
+	var taken int64
+	if err := db.Model(&models.User{}).Where("email = ? AND id <> ?", email, user.ID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("email belongs to another account: %w", gorm.ErrDuplicatedKey)
+	}
+
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	updates["pending_email"] = email
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := UpdateProfile(tx, user, updates); err != nil {
+			return err
+		}
+		// Only the latest requested email can be confirmed
+		if err := tx.Model(&models.EmailChangeToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.EmailChangeToken{
+			UserID:    user.ID,
+			Email:     email,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: now.Add(EmailChange.TokenTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Use the link below to confirm %s as your new email address. It expires in %s.\n\n%s?token=%s",
+		email, EmailChange.TokenTTL, EmailChange.ConfirmURL, token)
+	if err := EmailChange.Mailer.Send(email, "Confirm your new email address", body); err != nil {
+		log.Printf("Failed to send email change confirmation to user %d: %v", user.ID, err)
+	}
+	notice := fmt.Sprintf("A change of your account's email address to %s was requested. It takes effect once confirmed "+
+		"from that address. If you didn't request it, change your password now.", email)
+	if err := EmailChange.Mailer.Send(user.Email, "Your email address is being changed", notice); err != nil {
+		log.Printf("Failed to send email change notice to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ConfirmEmail applies a pending email change using the token sent to the new address
+func ConfirmEmail(c *gin.Context) {
+	var query ConfirmEmailQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	var changeToken models.EmailChangeToken
+	if err := requestDB(c).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(query.Token), time.Now()).
+		First(&changeToken).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to confirm email")
+			return
+		}
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid or expired confirmation token")
+		return
+	}
+
+	// The change may have been cancelled, or the account deleted, since the
+	// token was sent
+	var user models.User
+	if err := requestDB(c).First(&user, changeToken.UserID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to confirm email")
+			return
+		}
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid or expired confirmation token")
+		return
+	}
+	if user.PendingEmail != changeToken.Email {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid or expired confirmation token")
+		return
+	}
+
+	// A retry starts from the user as loaded, not as a rolled-back attempt left it
+	loaded := user
+	err := database.RetryTransaction(requestDB(c), func(tx *gorm.DB) error {
+		user = loaded
+		// Claim the token first, so of two concurrent confirmations with it
+		// only one goes through
+		result := tx.Model(&models.EmailChangeToken{}).Where("id = ? AND used_at IS NULL", changeToken.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errChangeTokenUsed
+		}
+		if err := UpdateProfile(tx, &user, map[string]interface{}{
+			"email":         changeToken.Email,
+			"pending_email": "",
+		}); err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditEmailChanged, &user.ID, userTarget(user.ID))
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errChangeTokenUsed):
+			respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid or expired confirmation token")
+		case errors.Is(err, ErrVersionConflict):
+			respondVersionConflict(c)
+		case errors.Is(err, database.ErrWriteConflict):
+			respondWriteConflict(c)
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			// Someone else registered the address while the change was pending
+			respondError(c, http.StatusConflict, apierror.CodeConflict, "Email is already taken")
+		default:
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to confirm email")
+		}
+		return
+	}
+
+	Webhooks.Notify(c.Request.Context(), webhook.EventUserUpdated, user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email address has been changed",
+	})
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// FieldError describes a validation failure for a single request field
+type FieldError = apierror.FieldError
+
+func init() {
+	// Report binding errors using JSON field names instead of Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// respondError writes the standard JSON error envelope, including the request
+// ID when enabled. Failures caused by the request deadline passing are
+// reported as 504.
+func respondError(c *gin.Context, status int, code, message string) {
+	respond(c, status, apierror.Body{Code: code, Message: message})
+}
+
+// respondNotFound writes a 404 for a resource that does not exist
+func respondNotFound(c *gin.Context, message string) {
+	respondError(c, http.StatusNotFound, apierror.CodeNotFound, message)
+}
+
+// respondLookupError writes a 404 when a lookup failed because the record
+// doesn't exist, and a 500 for any other database error so outages aren't
+// reported as missing records
+func respondLookupError(c *gin.Context, err error, notFoundMessage, failureMessage string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondNotFound(c, notFoundMessage)
+		return
+	}
+	respondError(c, http.StatusInternalServerError, apierror.CodeInternal, failureMessage)
+}
+
+// respondValidationErrors writes a 400 response listing the given field
+// errors, for requests that are malformed: missing or mistyped fields and
+// bad query parameters
+func respondValidationErrors(c *gin.Context, fieldErrors []FieldError) {
+	respond(c, http.StatusBadRequest, apierror.Body{
+		Code:    apierror.CodeValidationFailed,
+		Message: "Validation failed",
+		Fields:  fieldErrors,
+	})
+}
+
+// respondUnprocessable writes a 422 response listing the given field errors,
+// for request bodies that are well-formed but break a business rule (e.g. a
+// weak password or a malformed email address)
+func respondUnprocessable(c *gin.Context, fieldErrors []FieldError) {
+	respond(c, http.StatusUnprocessableEntity, apierror.Body{
+		Code:    apierror.CodeValidationFailed,
+		Message: "Validation failed",
+		Fields:  fieldErrors,
+	})
+}
+
+// respondValidationMessage writes a 400 response for a non-field validation error
+func respondValidationMessage(c *gin.Context, message string) {
+	respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, message)
+}
+
+// respond writes an error envelope in the client's language, filling in
+// the request ID
+func respond(c *gin.Context, status int, body apierror.Body) {
+	if middleware.TimedOut(c) {
+		status, body = http.StatusGatewayTimeout, apierror.Body{Code: apierror.CodeTimeout, Message: middleware.TimeoutMessage}
+	}
+
+	body.RequestID = middleware.ErrorRequestID(c)
+	c.JSON(status, apierror.Response{Error: middleware.LocalizeError(c, body)})
+}
+
+// WriteConflictRetryAfter is how long clients are asked to wait after a
+// transaction kept conflicting with concurrent writes
+var WriteConflictRetryAfter = time.Second
+
+// respondWriteConflict writes a 503 for a transaction that still conflicted
+// with concurrent writes after database.RetryTransaction's retries. Nothing
+// was changed, so the client can safely repeat the request.
+func respondWriteConflict(c *gin.Context) {
+	c.Header("Retry-After", middleware.FormatRetryAfter(middleware.RetryAfterMode, time.Now(), WriteConflictRetryAfter))
+	respondError(c, http.StatusServiceUnavailable, apierror.CodeServiceUnavailable, "Too many concurrent updates. Please try again.")
+}
+
+// respondReservedUsername writes a 422 for a username the username policy reserves
+func respondReservedUsername(c *gin.Context) {
+	respond(c, http.StatusUnprocessableEntity, apierror.Body{
+		Code:    apierror.CodeUsernameReserved,
+		Message: validation.ReservedUsernameMessage,
+		Fields:  []FieldError{{Field: "username", Message: validation.ReservedUsernameMessage}},
+	})
+}
+
+// respondBindingError writes a 400 response for a request that failed to bind,
+// mapping gin's validator errors to their fields where possible
+func respondBindingError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondError(c, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge, "Request body too large")
+		return
+	}
+	if errors.Is(err, middleware.ErrJSONTooDeep) {
+		respondValidationMessage(c, "Request body is nested too deeply")
+		return
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request payload")
+		return
+	}
+
+	fieldErrors := make([]FieldError, len(validationErrs))
+	for i, fe := range validationErrs {
+		fieldErrors[i] = FieldError{
+			Field:   fe.Field(),
+			Message: bindingErrorMessage(fe),
+		}
+	}
+	respondValidationErrors(c, fieldErrors)
+}
+
+// bindingErrorMessage returns a human readable message for a validator error
+func bindingErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "This field is required"
+	default:
+		return "Invalid value"
+	}
+}
+
+// NoRoute responds to requests for unknown routes, using a distinct code so
+// clients can tell a mistyped URL from a missing record
+func NoRoute(c *gin.Context) {
+	respondError(c, http.StatusNotFound, apierror.CodeRouteNotFound, "Route not found")
+}
+
+// NoMethod responds to requests for a known route with an unsupported method.
+// Gin has already set the Allow header to the route's methods.
+func NoMethod(c *gin.Context) {
+	respondError(c, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed,
+		fmt.Sprintf("Method %s is not allowed; allowed methods: %s", c.Request.Method, c.Writer.Header().Get("Allow")))
+}
+
+// ConfigureRouting makes routing strict and predictable: paths must match
+// exactly (no trailing-slash or case-fixing redirects), a known path with the
+// wrong method gets a 405 and anything else a structured 404
+func ConfigureRouting(router *gin.Engine) {
+	router.RedirectTrailingSlash = false
+	router.RedirectFixedPath = false
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(NoRoute)
+	router.NoMethod(NoMethod)
+}
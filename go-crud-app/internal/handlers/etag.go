@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondConditionally sets the ETag and answers 304 Not Modified when the
+// request's If-None-Match already names it. It reports whether the response
+// was written.
+func respondConditionally(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if etagListMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// Representations of a user, which give its ETags different variants
+const (
+	userViewFull   = "full"
+	userViewAdmin  = "admin"
+	userViewPublic = "public"
+)
+
+// userETag returns the user's ETag for a view reduced to fields. The admin
+// view shows login and last-seen times, which are recorded without bumping
+// the version, so they are part of its variant.
+func userETag(user *models.User, view string, fields FieldSelection) string {
+	variant := view + "?fields=" + fields.Key()
+	if view == userViewAdmin {
+		variant += fmt.Sprintf(";login=%d;seen=%d", unixNano(user.LastLoginAt), unixNano(user.LastSeenAt))
+	}
+	return user.ETag(variant)
+}
+
+// unixNano returns t in Unix nanoseconds, or 0 when it is unset
+func unixNano(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// ifMatchesUser reports whether an If-Match header names the user's current
+// version in any representation; "*" matches any, and weak tags never match
+func ifMatchesUser(header string, user *models.User) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || user.ETagMatchesVersion(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// etagListMatches reports whether a comma-separated If-None-Match header
+// lists etag; "*" matches any. The comparison is weak, ignoring W/ prefixes.
+func etagListMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondPreconditionFailed writes a 412 for an update whose If-Match ETag
+// no longer names the current version
+func respondPreconditionFailed(c *gin.Context) {
+	respondError(c, http.StatusPreconditionFailed, apierror.CodePreconditionFailed,
+		"The user has changed since it was fetched; fetch it again and retry")
+}
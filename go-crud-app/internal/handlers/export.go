@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/retention"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportedUser is the full account record included in a data export. It
+// carries fields the normal response omits, but never credentials.
+type ExportedUser struct {
+	ID           uint       `json:"id"`
+	Username     string     `json:"username"`
+	Email        string     `json:"email"`
+	PendingEmail string     `json:"pending_email,omitempty"`
+	Role         string     `json:"role"`
+	AvatarURL    string     `json:"avatar_url,omitempty"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	LastSeenAt   *time.Time `json:"last_seen_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ExportedPasswordReset describes a password reset request without its token
+type ExportedPasswordReset struct {
+	RequestedAt time.Time  `json:"requested_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+}
+
+// UserExport is everything stored about the current user, for data portability
+type UserExport struct {
+	ExportedAt     time.Time               `json:"exported_at"`
+	User           ExportedUser            `json:"user"`
+	Retention      RetentionResponse       `json:"retention"`
+	Providers      []LinkedProvider        `json:"providers"`
+	PasswordResets []ExportedPasswordReset `json:"password_resets"`
+	AuditLogs      []models.AuditLog       `json:"audit_logs"` // Events the user performed or that targeted them
+}
+
+// ExportCurrentUser returns all of the current user's data as a downloadable
+// JSON file
+func ExportCurrentUser(policy retention.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		db := requestDB(c)
+
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			respondLookupError(c, err, "User not found", "Failed to fetch user")
+			return
+		}
+
+		providers, err := linkedProviders(db, user)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export user data")
+			return
+		}
+
+		var resets []models.PasswordResetToken
+		if err := db.Where("user_id = ?", user.ID).Order("created_at").Find(&resets).Error; err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export user data")
+			return
+		}
+		passwordResets := make([]ExportedPasswordReset, len(resets))
+		for i, reset := range resets {
+			passwordResets[i] = ExportedPasswordReset{
+				RequestedAt: reset.CreatedAt,
+				ExpiresAt:   reset.ExpiresAt,
+				UsedAt:      reset.UsedAt,
+			}
+		}
+
+		auditLogs := []models.AuditLog{}
+		if err := db.Where("actor_id = ? OR target = ?", user.ID, userTarget(user.ID)).
+			Order("created_at, id").Find(&auditLogs).Error; err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export user data")
+			return
+		}
+
+		recordAuditOrLog(c, models.AuditUserExported, &user.ID, userTarget(user.ID))
+
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.json"`, user.ID))
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, UserExport{
+			ExportedAt: time.Now().UTC(),
+			User: ExportedUser{
+				ID:           user.ID,
+				Username:     user.Username,
+				Email:        user.Email,
+				PendingEmail: user.PendingEmail,
+				Role:         user.Role,
+				AvatarURL:    user.AvatarURL,
+				LastLoginAt:  user.LastLoginAt,
+				LastSeenAt:   user.LastSeenAt,
+				CreatedAt:    user.CreatedAt,
+				UpdatedAt:    user.UpdatedAt,
+			},
+			Retention:      retentionResponse(policy, user),
+			Providers:      providers,
+			PasswordResets: passwordResets,
+			AuditLogs:      auditLogs,
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldsParam is the query parameter clients use to request a subset of
+// response fields, e.g. ?fields=id,username
+const FieldsParam = "fields"
+
+// userResponseFields are the fields that can be selected on user responses
+var userResponseFields = jsonFieldNames(models.UserResponse{})
+
+// FieldSelection is the set of fields a client asked for; a nil selection
+// keeps every field
+type FieldSelection map[string]bool
+
+// parseFieldSelection reads the fields query parameter, accepting only the
+// allowed JSON field names. An absent or empty parameter selects every field.
+// Unknown fields are rejected with a 400 and false is returned.
+func parseFieldSelection(c *gin.Context, allowed []string) (FieldSelection, bool) {
+	param := c.Query(FieldsParam)
+	if strings.TrimSpace(param) == "" {
+		return nil, true
+	}
+
+	selection := make(FieldSelection)
+	var unknown []string
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(allowed, field) {
+			unknown = append(unknown, field)
+			continue
+		}
+		selection[field] = true
+	}
+
+	if len(unknown) > 0 {
+		respondValidationErrors(c, []FieldError{{
+			Field:   FieldsParam,
+			Message: "Unknown fields: " + strings.Join(unknown, ", ") + "; allowed fields: " + strings.Join(allowed, ", "),
+		}})
+		return nil, false
+	}
+	if len(selection) == 0 {
+		return nil, true
+	}
+	return selection, true
+}
+
+// Apply returns v reduced to the selected fields, or v itself when every
+// field is selected. Selected fields v doesn't have (such as private fields
+// on a public profile) are left out.
+func (s FieldSelection) Apply(v any) (any, error) {
+	if s == nil {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name := range fields {
+		if !s[name] {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// Key returns the selected fields in a canonical form, empty when every
+// field is selected, so equal selections share an ETag variant
+func (s FieldSelection) Key() string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+// jsonFieldNames returns the JSON names of v's struct fields, sorted
+func jsonFieldNames(v any) []string {
+	t := reflect.TypeOf(v)
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.SplitN(t.Field(i).Tag.Get("json"), ",", 2)[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
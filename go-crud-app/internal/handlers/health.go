@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"go-crud-app/internal/supervisor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthDetails reports background worker liveness and the goroutine count,
+// returning 503 when any worker is unhealthy
+func HealthDetails(workers *supervisor.Supervisor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := "healthy"
+		code := http.StatusOK
+		if !workers.Healthy() {
+			status = "degraded"
+			code = http.StatusServiceUnavailable
+		}
+
+		c.JSON(code, gin.H{
+			"status":     status,
+			"time":       time.Now().Format(time.RFC3339),
+			"workers":    workers.Status(),
+			"goroutines": runtime.NumGoroutine(),
+		})
+	}
+}
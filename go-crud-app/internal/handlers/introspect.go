@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntrospectRequest is an RFC 7662 token introspection request. It is
+// normally form-encoded, but JSON is accepted too.
+type IntrospectRequest struct {
+	Token         string `form:"token" json:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"` // Accepted and ignored; only access tokens can be active
+}
+
+// IntrospectResponse describes a token per RFC 7662. Inactive tokens carry
+// nothing but "active": false.
+type IntrospectResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Email     string `json:"email,omitempty"`
+	OrgID     *uint  `json:"org_id,omitempty"`
+	Scope     string `json:"scope,omitempty"` // Space-separated; absent for tokens issued before scopes existed
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+}
+
+// IntrospectToken lets other services check an access token without knowing
+// the signing secret. The token gets the same checks as on the API itself,
+// so expired, revoked and tampered tokens, refresh tokens, and tokens of
+// deleted or suspended users are reported as inactive rather than as an
+// error. Tokens of users who must change their password are inactive too,
+// since they only work for the password change. The route must be protected
+// with RequireServiceKey.
+func IntrospectToken(jwtSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req IntrospectRequest
+		if err := c.ShouldBind(&req); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+
+		claims, err := middleware.VerifyToken(c.Request.Context(), req.Token, jwtSecret)
+		switch {
+		case errors.Is(err, utils.ErrExpiredToken), errors.Is(err, utils.ErrInvalidToken),
+			errors.Is(err, utils.ErrRevokedToken), errors.Is(err, middleware.ErrAccountSuspended),
+			errors.Is(err, middleware.ErrPasswordChangeRequired):
+			c.JSON(http.StatusOK, IntrospectResponse{Active: false})
+			return
+		case err != nil:
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to introspect token")
+			return
+		}
+
+		resp := IntrospectResponse{
+			Active:    true,
+			Sub:       strconv.FormatUint(uint64(claims.UserID), 10),
+			Username:  claims.Username,
+			Email:     claims.Email,
+			OrgID:     claims.OrgID,
+			Scope:     strings.Join(claims.Scopes, " "),
+			TokenType: "Bearer",
+			JTI:       claims.ID,
+		}
+		if claims.ExpiresAt != nil {
+			resp.Exp = claims.ExpiresAt.Unix()
+		}
+		if claims.IssuedAt != nil {
+			resp.Iat = claims.IssuedAt.Unix()
+		}
+		if claims.NotBefore != nil {
+			resp.Nbf = claims.NotBefore.Unix()
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DefaultInviteTTL is how long an invite stays valid
+const DefaultInviteTTL = 7 * 24 * time.Hour
+
+// errInviteUsed is returned by consumeInvite when another registration used
+// the invite first
+var errInviteUsed = errors.New("invite already used")
+
+// InviteConfig holds invite configuration
+type InviteConfig struct {
+	Required bool // Self-registration only accepts users with an invite
+	TTL      time.Duration
+}
+
+// Invites configures invites. They are optional unless Required is set, but
+// an invite presented at registration is always checked and consumed.
+var Invites = InviteConfig{TTL: DefaultInviteTTL}
+
+// CreateInviteRequest represents the admin create-invite request payload
+type CreateInviteRequest struct {
+	Email string `json:"email"` // Only this address may use the invite; empty for anyone
+	Role  string `json:"role"`  // Defaults to user
+	// OrgID invites into another organization; only super-admins may set
+	// it, and it defaults to the admin's own
+	OrgID *uint `json:"org_id"`
+}
+
+// CreateInviteResponse represents the admin create-invite response. The
+// token is only ever returned here.
+type CreateInviteResponse struct {
+	InviteToken string        `json:"invite_token"`
+	Invite      models.Invite `json:"invite"`
+}
+
+// CreateInvite lets an admin invite someone to register, with the role and
+// organization the new account will get, as CreateUser would give them. The
+// invite token is signed with secret and can be used once before it expires.
+func CreateInvite(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req CreateInviteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+
+		role, orgID, fieldErrors, ok := adminGrant(c, req.Role, req.OrgID, "Failed to create invite")
+		if !ok {
+			return
+		}
+
+		if req.Email != "" {
+			req.Email = validation.NormalizeEmail(req.Email)
+			if !validation.ValidEmail(req.Email) {
+				fieldErrors = append(fieldErrors, FieldError{Field: "email", Message: validation.EmailMessage})
+			}
+		}
+
+		if len(fieldErrors) > 0 {
+			respondUnprocessable(c, fieldErrors)
+			return
+		}
+
+		signed, token, err := utils.GenerateInviteToken(secret)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create invite")
+			return
+		}
+
+		invite := models.Invite{
+			TokenHash: utils.HashToken(token),
+			Email:     req.Email,
+			Role:      role,
+			OrgID:     orgID,
+			CreatedBy: adminID,
+			ExpiresAt: time.Now().Add(Invites.TTL),
+		}
+		err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&invite).Error; err != nil {
+				return err
+			}
+			return recordAudit(tx, c, models.AuditAdminInviteCreated, &adminID, inviteTarget(invite.ID))
+		})
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create invite")
+			return
+		}
+
+		c.JSON(http.StatusCreated, CreateInviteResponse{
+			InviteToken: signed,
+			Invite:      invite,
+		})
+	}
+}
+
+// findInvite returns the invite for a signed invite token presented when
+// registering with email. A missing (when Invites.Required), forged,
+// unknown, used or expired invite, or one for another email, is reported as
+// field errors. It returns nil without a token when invites are optional.
+func findInvite(db *gorm.DB, signed, email, secret string) (*models.Invite, []FieldError, error) {
+	if signed == "" {
+		if Invites.Required {
+			return nil, inviteFieldError("invite_token is required"), nil
+		}
+		return nil, nil, nil
+	}
+
+	token, err := utils.VerifyInviteToken(signed, secret)
+	if err != nil {
+		return nil, inviteFieldError("invite_token is not valid"), nil
+	}
+
+	var invite models.Invite
+	if err := db.Where("token_hash = ?", utils.HashToken(token)).First(&invite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, inviteFieldError("invite_token is not valid"), nil
+		}
+		return nil, nil, err
+	}
+
+	switch {
+	case invite.UsedAt != nil:
+		return nil, inviteFieldError("invite_token has already been used"), nil
+	case !time.Now().Before(invite.ExpiresAt):
+		return nil, inviteFieldError("invite_token has expired"), nil
+	case invite.Email != "" && invite.Email != email:
+		return nil, inviteFieldError("invite_token was issued for a different email"), nil
+	}
+	return &invite, nil, nil
+}
+
+// consumeInvite marks the invite as used by userID, returning errInviteUsed
+// when a concurrent registration used it first
+func consumeInvite(tx *gorm.DB, invite *models.Invite, userID uint) error {
+	now := time.Now()
+	result := tx.Model(&models.Invite{}).Where("id = ? AND used_at IS NULL", invite.ID).
+		Updates(map[string]interface{}{"used_at": now, "used_by": userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInviteUsed
+	}
+	invite.UsedAt, invite.UsedBy = &now, &userID
+	return nil
+}
+
+// inviteFieldError reports a problem with the registration's invite token
+func inviteFieldError(message string) []FieldError {
+	return []FieldError{{Field: "invite_token", Message: message}}
+}
+
+// inviteTarget formats an invite as an audit log target
+func inviteTarget(id uint) string {
+	return fmt.Sprintf("invite:%d", id)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsFormatAuto serves OpenMetrics to scrapers that ask for it via the
+// Accept header and the classic Prometheus format to everyone else
+const MetricsFormatAuto = "auto"
+
+// Metrics exposes the application metrics in the configured format:
+// metrics.FormatPrometheus, metrics.FormatOpenMetrics or MetricsFormatAuto
+func Metrics(format string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		selected := format
+		if selected == MetricsFormatAuto {
+			selected = metrics.FormatPrometheus
+			if strings.Contains(c.GetHeader("Accept"), "application/openmetrics-text") {
+				selected = metrics.FormatOpenMetrics
+			}
+		}
+
+		contentType := metrics.PrometheusContentType
+		if selected == metrics.FormatOpenMetrics {
+			contentType = metrics.OpenMetricsContentType
+		}
+
+		var buf bytes.Buffer
+		if err := metrics.DefaultRegistry.Write(&buf, selected); err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to collect metrics")
+			return
+		}
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+	}
+}
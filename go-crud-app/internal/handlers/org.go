@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// OrgRegistrationNone registers users without an organization
+	OrgRegistrationNone = "none"
+	// OrgRegistrationInvite requires an organization's invite code to register
+	OrgRegistrationInvite = "invite"
+	// OrgRegistrationOpen joins the organization of the invite code, or
+	// without one creates a new organization administered by the new user
+	OrgRegistrationOpen = "open"
+)
+
+// maxOrgNameLength matches the organizations.name column
+const maxOrgNameLength = 100
+
+// OrgRegistration decides which organization self-registered users belong to
+var OrgRegistration = OrgRegistrationNone
+
+// ValidOrgRegistration reports whether mode is a known OrgRegistration mode
+func ValidOrgRegistration(mode string) bool {
+	switch mode {
+	case OrgRegistrationNone, OrgRegistrationInvite, OrgRegistrationOpen:
+		return true
+	}
+	return false
+}
+
+// RegistrationOrg returns the organization a new user joins under
+// OrgRegistration, given the invite code and organization name they
+// registered with. It returns nil when users register without one. With
+// OrgRegistrationOpen and no invite code the organization is new and not yet
+// saved (its ID is 0); CreateRegisteredUser saves it. A missing or unknown
+// invite code or organization name is reported as field errors.
+func RegistrationOrg(db *gorm.DB, inviteCode, orgName string) (*models.Organization, []FieldError, error) {
+	if OrgRegistration == OrgRegistrationNone {
+		return nil, nil, nil
+	}
+
+	inviteCode = strings.TrimSpace(inviteCode)
+	if inviteCode != "" {
+		var org models.Organization
+		if err := db.Where("invite_code = ?", inviteCode).First(&org).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, []FieldError{{Field: "invite_code", Message: "invite_code is not valid"}}, nil
+			}
+			return nil, nil, err
+		}
+		return &org, nil, nil
+	}
+
+	if OrgRegistration == OrgRegistrationInvite {
+		return nil, []FieldError{{Field: "invite_code", Message: "invite_code is required"}}, nil
+	}
+
+	orgName = validation.NormalizeText(orgName)
+	switch {
+	case strings.TrimSpace(orgName) == "":
+		return nil, []FieldError{{Field: "organization", Message: "organization or invite_code is required"}}, nil
+	case len([]rune(orgName)) > maxOrgNameLength:
+		return nil, []FieldError{{Field: "organization", Message: fmt.Sprintf("organization must be at most %d characters", maxOrgNameLength)}}, nil
+	}
+	return &models.Organization{Name: orgName}, nil, nil
+}
+
+// CreateRegisteredUser creates a self-registered user in org (nil for none),
+// as returned by RegistrationOrg, consuming invite (nil for none). The
+// user gets the invite's role and organization. A new organization is
+// created along with the user, who becomes its admin. It returns
+// errInviteUsed when another registration used the invite first.
+func CreateRegisteredUser(db *gorm.DB, user *models.User, org *models.Organization, invite *models.Invite) error {
+	if org == nil && invite == nil {
+		return db.Create(user).Error
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if invite != nil {
+			user.Role = invite.Role
+			user.OrgID = invite.OrgID
+		}
+		if org != nil {
+			if err := joinRegistrationOrg(tx, user, org); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if invite != nil {
+			return consumeInvite(tx, invite, user.ID)
+		}
+		return nil
+	})
+}
+
+// joinRegistrationOrg puts user in org, as returned by RegistrationOrg. A new
+// organization is saved first and the user becomes its admin.
+func joinRegistrationOrg(tx *gorm.DB, user *models.User, org *models.Organization) error {
+	if org.ID == 0 {
+		inviteCode, err := utils.GenerateSecureToken()
+		if err != nil {
+			return err
+		}
+		org.InviteCode = inviteCode
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		user.Role = models.RoleAdmin
+	}
+	user.OrgID = &org.ID
+	return nil
+}
+
+// GetCurrentOrg returns the current user's organization. Its admins also get
+// the invite code for adding members.
+func GetCurrentOrg(c *gin.Context) {
+	orgID := middleware.GetOrgID(c)
+	if orgID == nil {
+		respondNotFound(c, "You don't belong to an organization")
+		return
+	}
+
+	isAdmin, err := middleware.IsAdmin(c)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch organization")
+		return
+	}
+
+	var org models.Organization
+	if err := requestDB(c).First(&org, *orgID).Error; err != nil {
+		respondLookupError(c, err, "Organization not found", "Failed to fetch organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, org.ToResponse(isAdmin))
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/mailer"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// forgotPasswordMessage is returned for every forgot-password request so the
+// response never reveals whether an account exists
+const forgotPasswordMessage = "If an account with that email exists, a password reset link has been sent"
+
+// errResetTokenUsed aborts a reset whose token a concurrent reset used first
+var errResetTokenUsed = errors.New("reset token already used")
+
+// PasswordResetConfig holds password reset configuration
+type PasswordResetConfig struct {
+	Mailer             mailer.Mailer
+	TokenTTL           time.Duration
+	MaxRequestsPerHour int    // Maximum reset emails per account per hour (0 disables the cap)
+	ResetURL           string // Frontend URL the reset token is appended to
+}
+
+// ForgotPasswordRequest represents the forgot-password request payload
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResetPasswordRequest represents the reset-password request payload
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ChangePasswordRequest represents the change-password request payload
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// revokeTokens bumps the user's token version so every previously issued
+// token is rejected. It is called inside password-update transactions.
+func revokeTokens(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + ?", 1)).Error
+}
+
+// ForgotPassword issues a password reset token and emails it to the user
+func ForgotPassword(config PasswordResetConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+
+		// Normalize email
+		req.Email = validation.NormalizeEmail(req.Email)
+
+		// Unknown emails get the same response to avoid account enumeration
+		var user models.User
+		if err := requestDB(c).Where("email = ?", req.Email).First(&user).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to process password reset")
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
+			return
+		}
+
+		// Silently drop requests beyond the hourly cap so the endpoint can't be
+		// used to flood a victim's inbox
+		if config.MaxRequestsPerHour > 0 {
+			var count int64
+			if err := requestDB(c).Model(&models.PasswordResetToken{}).
+				Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-time.Hour)).
+				Count(&count).Error; err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to process password reset")
+				return
+			}
+			if count >= int64(config.MaxRequestsPerHour) {
+				c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
+				return
+			}
+		}
+
+		token, err := utils.GenerateSecureToken()
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to process password reset")
+			return
+		}
+
+		now := time.Now()
+		err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+			// Only one reset token may be active at a time
+			if err := tx.Model(&models.PasswordResetToken{}).
+				Where("user_id = ? AND used_at IS NULL", user.ID).
+				Update("used_at", now).Error; err != nil {
+				return err
+			}
+
+			return tx.Create(&models.PasswordResetToken{
+				UserID:    user.ID,
+				TokenHash: utils.HashToken(token),
+				ExpiresAt: now.Add(config.TokenTTL),
+			}).Error
+		})
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to process password reset")
+			return
+		}
+
+		body := fmt.Sprintf("Use the link below to reset your password. It expires in %s.\n\n%s?token=%s",
+			config.TokenTTL, config.ResetURL, token)
+		if err := config.Mailer.Send(user.Email, "Reset your password", body); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
+	}
+}
+
+// ResetPassword sets a new password using a valid reset token
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	// Validate password policy
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		respondUnprocessable(c, []FieldError{{
+			Field:   "password",
+			Message: err.Error(),
+		}})
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	if err := requestDB(c).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(req.Token), time.Now()).
+		First(&resetToken).Error; err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid or expired reset token")
+		return
+	}
+
+	var user models.User
+	if err := requestDB(c).Select("id", "password_hash").First(&user, resetToken.UserID).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
+		return
+	}
+
+	reused, err := passwordRecentlyUsed(requestDB(c), user.ID, user.PasswordHash, req.Password)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to reset password")
+		return
+	}
+	if reused {
+		respondUnprocessable(c, []FieldError{{Field: "password", Message: passwordReusedMessage()}})
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
+		return
+	}
+
+	// Existing tokens may be in an attacker's hands, so revoke them with the reset
+	err = database.RetryTransaction(requestDB(c), func(tx *gorm.DB) error {
+		// Claim the token first, so of two concurrent resets with it only
+		// one goes through
+		result := tx.Model(&models.PasswordResetToken{}).Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenUsed
+		}
+		// The user chose this password, so any forced change is satisfied
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", resetToken.UserID).
+			Updates(map[string]interface{}{"password_hash": passwordHash, "password_changed_at": time.Now(), "must_change_password": false, "version": models.NextVersion()}).Error; err != nil {
+			return err
+		}
+		// The reset link proves the address, which confirms a pending account
+		if err := tx.Model(&models.User{}).Where("id = ? AND status = ?", resetToken.UserID, models.StatusPending).
+			Update("status", models.StatusActive).Error; err != nil {
+			return err
+		}
+		if err := rememberPassword(tx, resetToken.UserID, user.PasswordHash); err != nil {
+			return err
+		}
+		if err := revokeTokens(tx, resetToken.UserID); err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditPasswordReset, &resetToken.UserID, userTarget(resetToken.UserID))
+	})
+	if errors.Is(err, errResetTokenUsed) {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid or expired reset token")
+		return
+	}
+	if errors.Is(err, database.ErrWriteConflict) {
+		respondWriteConflict(c)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password has been reset successfully",
+	})
+}
+
+// ChangePassword sets a new password for the current user after verifying the
+// current one. All existing tokens are revoked; the caller receives a fresh
+// token so this session stays logged in.
+func ChangePassword(jwtConfig utils.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+
+		if err := utils.ValidatePassword(req.NewPassword); err != nil {
+			respondUnprocessable(c, []FieldError{{
+				Field:   "new_password",
+				Message: err.Error(),
+			}})
+			return
+		}
+
+		var user models.User
+		if err := requestDB(c).First(&user, userID).Error; err != nil {
+			respondLookupError(c, err, "User not found", "Failed to fetch user")
+			return
+		}
+
+		if !utils.CheckPassword(req.CurrentPassword, user.PasswordHash) {
+			respondUnprocessable(c, []FieldError{{
+				Field:   "current_password",
+				Message: "Current password is incorrect",
+			}})
+			return
+		}
+
+		reused, err := passwordRecentlyUsed(requestDB(c), user.ID, user.PasswordHash, req.NewPassword)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to change password")
+			return
+		}
+		if reused {
+			respondUnprocessable(c, []FieldError{{Field: "new_password", Message: passwordReusedMessage()}})
+			return
+		}
+
+		passwordHash, err := utils.HashPassword(req.NewPassword)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
+			return
+		}
+
+		// The update sets user's hash, so a retry must remember the one loaded
+		previousHash := user.PasswordHash
+		err = database.RetryTransaction(requestDB(c), func(tx *gorm.DB) error {
+			if err := rememberPassword(tx, user.ID, previousHash); err != nil {
+				return err
+			}
+			if err := tx.Model(&user).Updates(map[string]interface{}{"password_hash": passwordHash, "password_changed_at": time.Now(), "must_change_password": false, "version": models.NextVersion()}).Error; err != nil {
+				return err
+			}
+			if err := revokeTokens(tx, user.ID); err != nil {
+				return err
+			}
+			if err := recordAudit(tx, c, models.AuditPasswordChanged, &user.ID, userTarget(user.ID)); err != nil {
+				return err
+			}
+			return tx.Select("token_version", "must_change_password", "password_changed_at").First(&user, user.ID).Error
+		})
+		if errors.Is(err, database.ErrWriteConflict) {
+			respondWriteConflict(c)
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to change password")
+			return
+		}
+
+		resp, err := newSessionAuthResponse(c, &user, nil, jwtConfig)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
+			return
+		}
+
+		respondAuth(c, http.StatusOK, resp, jwtConfig)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// MaxPasswordHistorySize caps PasswordHistorySize. Every remembered password
+// costs a full hash comparison on each password change and reset.
+const MaxPasswordHistorySize = 10
+
+// PasswordHistorySize is how many of a user's most recent passwords, the
+// current one included, can't be chosen again; zero disables the check
+var PasswordHistorySize int
+
+// passwordReusedMessage explains why a recently used password was refused
+func passwordReusedMessage() string {
+	if PasswordHistorySize == 1 {
+		return "New password must differ from the current password"
+	}
+	return fmt.Sprintf("New password must differ from your last %d passwords", PasswordHistorySize)
+}
+
+// passwordRecentlyUsed reports whether password matches the user's current
+// password hash or one of the replaced hashes remembered for them
+func passwordRecentlyUsed(db *gorm.DB, userID uint, currentHash, password string) (bool, error) {
+	if PasswordHistorySize == 0 {
+		return false, nil
+	}
+
+	hashes := []string{currentHash}
+	if PasswordHistorySize > 1 {
+		var previous []string
+		if err := db.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).
+			Order("id DESC").Limit(PasswordHistorySize-1).Pluck("password_hash", &previous).Error; err != nil {
+			return false, err
+		}
+		hashes = append(hashes, previous...)
+	}
+
+	for _, hash := range hashes {
+		if hash != "" && utils.CheckPassword(password, hash) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rememberPassword records the hash of a password being replaced, then
+// forgets hashes beyond the history size (all of them when history is off)
+func rememberPassword(tx *gorm.DB, userID uint, replacedHash string) error {
+	keep := max(PasswordHistorySize-1, 0)
+	if keep > 0 && replacedHash != "" {
+		if err := tx.Create(&models.PasswordHistory{UserID: userID, PasswordHash: replacedHash}).Error; err != nil {
+			return err
+		}
+	}
+
+	kept := tx.Model(&models.PasswordHistory{}).Select("id").Where("user_id = ?", userID).Order("id DESC").Limit(keep)
+	return tx.Where("user_id = ? AND id NOT IN (?)", userID, kept).Delete(&models.PasswordHistory{}).Error
+}
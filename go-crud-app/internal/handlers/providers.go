@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LinkedProvider represents a sign-in method linked to a user
+type LinkedProvider struct {
+	Provider string     `json:"provider"`
+	LinkedAt *time.Time `json:"linked_at,omitempty"`
+}
+
+// GetLinkedProviders lists the sign-in methods linked to the current user
+func GetLinkedProviders(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var user models.User
+	if err := requestDB(c).First(&user, userID).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
+		return
+	}
+
+	providers, err := linkedProviders(requestDB(c), user)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch providers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"providers": providers,
+		"count":     len(providers),
+	})
+}
+
+// UnlinkProvider removes a sign-in method from the current user, refusing to
+// remove the last one so the user can't lock themselves out
+func UnlinkProvider(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var user models.User
+	if err := requestDB(c).First(&user, userID).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
+		return
+	}
+
+	providers, err := linkedProviders(requestDB(c), user)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch providers")
+		return
+	}
+
+	provider := c.Param("provider")
+	linked := false
+	for _, p := range providers {
+		if p.Provider == provider {
+			linked = true
+			break
+		}
+	}
+	if !linked {
+		respondNotFound(c, "Provider not linked")
+		return
+	}
+
+	if len(providers) == 1 {
+		respondError(c, http.StatusConflict, apierror.CodeConflict, "Cannot remove the last sign-in method")
+		return
+	}
+
+	if provider == models.LocalProvider {
+		// Without a password there is nothing left to expire
+		err = requestDB(c).Model(&user).Updates(map[string]interface{}{"password_hash": "", "password_changed_at": nil, "version": models.NextVersion()}).Error
+	} else {
+		err = requestDB(c).Where("user_id = ? AND provider = ?", user.ID, provider).Delete(&models.AuthIdentity{}).Error
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to unlink provider")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Provider unlinked successfully",
+	})
+}
+
+// linkedProviders returns the local password provider (if set) followed by
+// any linked external identities
+func linkedProviders(db *gorm.DB, user models.User) ([]LinkedProvider, error) {
+	var identities []models.AuthIdentity
+	if err := db.Where("user_id = ?", user.ID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	providers := make([]LinkedProvider, 0, len(identities)+1)
+	if user.PasswordHash != "" {
+		providers = append(providers, LinkedProvider{Provider: models.LocalProvider})
+	}
+	for _, identity := range identities {
+		linkedAt := identity.CreatedAt
+		providers = append(providers, LinkedProvider{
+			Provider: identity.Provider,
+			LinkedAt: &linkedAt,
+		})
+	}
+	return providers, nil
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/mailer"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// How registration answers for a taken email or username
+const (
+	// DuplicateResponseVerbose answers 409 saying the email or username is taken
+	DuplicateResponseVerbose = "verbose"
+	// DuplicateResponseGeneric answers 409 without saying what conflicted
+	DuplicateResponseGeneric = "generic"
+	// DuplicateResponseStrict answers 202 whether or not anything is taken;
+	// new accounts must be confirmed from their email address, and the owner
+	// of a taken address is told about the attempt by email instead
+	DuplicateResponseStrict = "strict"
+)
+
+// DefaultRegistrationTokenTTL is how long a registration confirmation link stays valid
+const DefaultRegistrationTokenTTL = 24 * time.Hour
+
+const (
+	// duplicateUserMessage is the verbose answer to a taken email or username
+	duplicateUserMessage = "User with this email or username already exists"
+	// genericDuplicateMessage doesn't say which field conflicted
+	genericDuplicateMessage = "Registration could not be completed with these details"
+	// RegistrationPendingMessage answers every valid registration in strict mode
+	RegistrationPendingMessage = "Check your email to complete your registration"
+	// ConfirmationRequiredMessage is shown to users logging in before confirming their email
+	ConfirmationRequiredMessage = "Confirm your email address before logging in"
+)
+
+// RegistrationPrivacyConfig controls what registration reveals about
+// existing accounts
+type RegistrationPrivacyConfig struct {
+	DuplicateResponse string // DuplicateResponseVerbose, DuplicateResponseGeneric or DuplicateResponseStrict
+	// The rest is only used in strict mode
+	Mailer     mailer.Mailer
+	TokenTTL   time.Duration
+	ConfirmURL string // URL the confirmation token is appended to
+}
+
+// RegistrationPrivacy configures registration's duplicate response. By
+// default a taken email or username is reported as such, which lets anyone
+// check whether an address has an account.
+var RegistrationPrivacy = RegistrationPrivacyConfig{
+	DuplicateResponse: DuplicateResponseVerbose,
+	Mailer:            mailer.LogMailer{},
+	TokenTTL:          DefaultRegistrationTokenTTL,
+	ConfirmURL:        "http://localhost:8080/api/auth/confirm-registration",
+}
+
+// Validate rejects unknown duplicate responses
+func (c RegistrationPrivacyConfig) Validate() error {
+	switch c.DuplicateResponse {
+	case DuplicateResponseVerbose, DuplicateResponseGeneric, DuplicateResponseStrict:
+	default:
+		return fmt.Errorf("duplicate response must be %s, %s or %s, got %q",
+			DuplicateResponseVerbose, DuplicateResponseGeneric, DuplicateResponseStrict, c.DuplicateResponse)
+	}
+	if c.DuplicateResponse == DuplicateResponseStrict && c.TokenTTL <= 0 {
+		return fmt.Errorf("registration confirmation TTL must be positive, got %s", c.TokenTTL)
+	}
+	return nil
+}
+
+// Strict reports whether registration hides which accounts exist
+func (c RegistrationPrivacyConfig) Strict() bool {
+	return c.DuplicateResponse == DuplicateResponseStrict
+}
+
+// DuplicateMessage is the 409 message for a taken email or username
+func (c RegistrationPrivacyConfig) DuplicateMessage() string {
+	if c.DuplicateResponse == DuplicateResponseGeneric {
+		return genericDuplicateMessage
+	}
+	return duplicateUserMessage
+}
+
+// ConfirmRegistrationQuery represents the confirm-registration query parameters
+type ConfirmRegistrationQuery struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// respondDuplicateUser answers a registration whose email or username is
+// taken by existing, as configured by RegistrationPrivacy
+func respondDuplicateUser(c *gin.Context, email, username, password string, existing *models.User) {
+	if !RegistrationPrivacy.Strict() {
+		respondError(c, http.StatusConflict, apierror.CodeConflict, RegistrationPrivacy.DuplicateMessage())
+		return
+	}
+
+	// Take as long as creating an account would
+	utils.DummyCheckPassword(password)
+
+	// Only the owner of the address learns why nothing was created
+	switch {
+	case existing == nil:
+		// A concurrent registration won the race; its own email goes out
+	case existing.Email == email:
+		notice := "Someone tried to register a new account with this email address, which already has one. " +
+			"If it was you, log in or reset your password instead. Otherwise you can ignore this email."
+		if err := RegistrationPrivacy.Mailer.Send(email, "You already have an account", notice); err != nil {
+			log.Printf("Failed to send duplicate registration notice to user %d: %v", existing.ID, err)
+		}
+	default:
+		notice := fmt.Sprintf("The username %s is already taken, so your account was not created. "+
+			"Register again with another username.", username)
+		if err := RegistrationPrivacy.Mailer.Send(email, "Your registration could not be completed", notice); err != nil {
+			log.Printf("Failed to send taken username notice: %v", err)
+		}
+	}
+	respondRegistrationPending(c)
+}
+
+// respondRegistrationValid answers a validate_only registration that passed
+// every check
+func respondRegistrationValid(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
+
+// respondRegistrationPending gives every strict-mode registration the same answer
+func respondRegistrationPending(c *gin.Context) {
+	c.JSON(http.StatusAccepted, gin.H{"message": RegistrationPendingMessage})
+}
+
+// createPendingUser creates a user who must confirm their email before
+// logging in, and emails them the confirmation link
+func createPendingUser(db *gorm.DB, user *models.User, org *models.Organization, invite *models.Invite) error {
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	user.Status = models.StatusPending
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := CreateRegisteredUser(tx, user, org, invite); err != nil {
+			return err
+		}
+		return tx.Create(&models.RegistrationToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(RegistrationPrivacy.TokenTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Use the link below to confirm your email address and activate your account. It expires in %s. "+
+		"If you didn't register, you can ignore this email.\n\n%s?token=%s",
+		RegistrationPrivacy.TokenTTL, RegistrationPrivacy.ConfirmURL, token)
+	if err := RegistrationPrivacy.Mailer.Send(user.Email, "Confirm your email address", body); err != nil {
+		log.Printf("Failed to send registration confirmation to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ConfirmRegistration activates a pending account using the token emailed at
+// registration, and logs the user in
+func ConfirmRegistration(jwtConfig utils.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query ConfirmRegistrationQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+
+		var registrationToken models.RegistrationToken
+		if err := requestDB(c).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(query.Token), time.Now()).
+			First(&registrationToken).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to confirm registration")
+				return
+			}
+			respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid or expired confirmation token")
+			return
+		}
+
+		var user models.User
+		err := database.RetryTransaction(requestDB(c), func(tx *gorm.DB) error {
+			// Guard on the status so the account is activated once, and not
+			// if it was deleted in the meantime
+			result := tx.Model(&models.User{}).Where("id = ? AND status = ?", registrationToken.UserID, models.StatusPending).
+				Updates(map[string]interface{}{"status": models.StatusActive, "version": models.NextVersion()})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			if err := tx.Model(&registrationToken).Update("used_at", time.Now()).Error; err != nil {
+				return err
+			}
+			if err := recordAudit(tx, c, models.AuditUserConfirmed, &registrationToken.UserID, userTarget(registrationToken.UserID)); err != nil {
+				return err
+			}
+			return tx.First(&user, registrationToken.UserID).Error
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid or expired confirmation token")
+				return
+			}
+			if errors.Is(err, database.ErrWriteConflict) {
+				respondWriteConflict(c)
+				return
+			}
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to confirm registration")
+			return
+		}
+		Webhooks.Notify(c.Request.Context(), webhook.EventUserRegistered, user.ID)
+
+		resp, err := newSessionAuthResponse(c, &user, nil, jwtConfig)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
+			return
+		}
+		respondAuth(c, http.StatusOK, resp, jwtConfig)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/retention"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionRequest represents the retention preference payload. A null or
+// omitted value clears the preference.
+type RetentionRequest struct {
+	RetentionDays *int `json:"retention_days"`
+}
+
+// RetentionResponse describes the current user's retention settings
+type RetentionResponse struct {
+	RetentionDays *int `json:"retention_days"`
+	EffectiveDays int  `json:"effective_days"` // 0 means data is kept indefinitely
+	MinDays       int  `json:"min_days"`
+	MaxDays       int  `json:"max_days"`
+}
+
+// GetRetention returns the current user's data retention preference
+func GetRetention(policy retention.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		var user models.User
+		if err := requestDB(c).First(&user, userID).Error; err != nil {
+			respondLookupError(c, err, "User not found", "Failed to fetch user")
+			return
+		}
+
+		c.JSON(http.StatusOK, retentionResponse(policy, user))
+	}
+}
+
+// UpdateRetention sets how long the current user's data is kept after they
+// become inactive, within the configured bounds
+func UpdateRetention(policy retention.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req RetentionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+
+		if req.RetentionDays != nil {
+			if err := policy.Validate(*req.RetentionDays); err != nil {
+				respondUnprocessable(c, []FieldError{{
+					Field:   "retention_days",
+					Message: err.Error(),
+				}})
+				return
+			}
+		}
+
+		var user models.User
+		if err := requestDB(c).First(&user, userID).Error; err != nil {
+			respondLookupError(c, err, "User not found", "Failed to fetch user")
+			return
+		}
+
+		if err := requestDB(c).Model(&user).Updates(map[string]interface{}{"retention_days": req.RetentionDays, "version": models.NextVersion()}).Error; err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update retention preference")
+			return
+		}
+		user.RetentionDays = req.RetentionDays
+
+		c.JSON(http.StatusOK, retentionResponse(policy, user))
+	}
+}
+
+// retentionResponse builds the retention settings response for a user
+func retentionResponse(policy retention.Policy, user models.User) RetentionResponse {
+	return RetentionResponse{
+		RetentionDays: user.RetentionDays,
+		EffectiveDays: policy.EffectiveDays(user),
+		MinDays:       policy.MinDays,
+		MaxDays:       policy.MaxDays,
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errSessionRevoked is returned by refreshSession when the refresh token's
+// session has been revoked or no longer exists
+var errSessionRevoked = errors.New("session revoked")
+
+// StartSession records a new login session for the user. The session lasts
+// as long as its refresh token, and each refresh extends it.
+func StartSession(db *gorm.DB, user *models.User, ip, userAgent string, jwtConfig utils.JWTConfig) (*models.Session, error) {
+	jti, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+
+	now := utils.TokenClock()
+	session := models.Session{
+		UserID:       user.ID,
+		JTI:          jti,
+		TokenVersion: user.TokenVersion,
+		UserAgent:    userAgent,
+		IP:           ip,
+		IssuedAt:     now,
+		LastSeenAt:   now,
+		ExpiresAt:    now.Add(time.Duration(jwtConfig.RefreshExpirationHours) * time.Hour),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// refreshSession extends the session a refresh token belongs to, returning
+// errSessionRevoked when it has been revoked
+func refreshSession(db *gorm.DB, claims *utils.Claims, jwtConfig utils.JWTConfig) error {
+	now := utils.TokenClock()
+	result := db.Model(&models.Session{}).
+		Where("jti = ? AND user_id = ? AND revoked_at IS NULL", claims.ID, claims.UserID).
+		UpdateColumns(map[string]interface{}{
+			"last_seen_at": now,
+			"expires_at":   now.Add(time.Duration(jwtConfig.RefreshExpirationHours) * time.Hour),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errSessionRevoked
+	}
+	return nil
+}
+
+// activeSessions scopes a query to the user's sessions that can still be
+// used: not revoked, not expired, and not ended by a token version bump
+func activeSessions(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Where("token_version >= (?)", db.Model(&models.User{}).Select("token_version").Where("id = ?", userID))
+}
+
+// ListSessions returns the current user's active sessions, most recently
+// used first, marking the one the request was made with
+func ListSessions(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var sessions []models.Session
+	if err := activeSessions(requestDB(c), userID).Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch sessions")
+		return
+	}
+
+	current := middleware.GetSessionID(c)
+	responses := make([]models.SessionResponse, len(sessions))
+	for i := range sessions {
+		responses[i] = sessions[i].ToResponse(current)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": responses,
+		"count":    len(responses),
+	})
+}
+
+// RevokeSession ends one of the current user's sessions. Its tokens,
+// including the refresh token, are rejected from then on.
+func RevokeSession(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid session ID")
+		return
+	}
+
+	var session models.Session
+	err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := activeSessions(tx, userID).Where("id = ?", id).First(&session).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&session).UpdateColumn("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditSessionRevoked, &userID, userTarget(userID))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondNotFound(c, "Session not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to revoke session")
+		return
+	}
+
+	// Revoking the session in use logs this client out
+	if session.JTI == middleware.GetSessionID(c) {
+		middleware.ClearAuthCookie(c)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
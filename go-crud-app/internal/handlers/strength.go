@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordStrengthRequest holds a candidate password and, optionally, the
+// username and email it would be used with
+type PasswordStrengthRequest struct {
+	Password string `json:"password" binding:"required"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// CheckPasswordStrength scores a candidate password from 0 to 4 with
+// feedback, for strength meters. The score is advisory: registration and
+// password changes still only enforce the password policy, and a strong
+// score doesn't mean the policy is met. Passwords that contain the username
+// or email score lower.
+func CheckPasswordStrength(c *gin.Context) {
+	var req PasswordStrengthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	// Such passwords can never be set, and estimating them costs more
+	if len(req.Password) > utils.MaxPasswordBytes {
+		respondUnprocessable(c, []FieldError{{Field: "password", Message: utils.ErrPasswordTooLong.Error()}})
+		return
+	}
+
+	var userInputs []string
+	for _, input := range []string{req.Username, req.Email} {
+		if input != "" {
+			userInputs = append(userInputs, input)
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, utils.EstimatePasswordStrength(req.Password, userInputs...))
+}
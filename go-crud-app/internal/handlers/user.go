@@ -1,211 +1,500 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
-	"go-crud-app/internal/database"
+	"go-crud-app/internal/apierror"
 	"go-crud-app/internal/middleware"
 	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+	"go-crud-app/internal/validation"
+	"go-crud-app/internal/webhook"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// UpdateUserRequest represents the user update request payload
+// UpdateUserRequest represents the user update request payload. Fields are
+// pointers so an omitted field (nil) can be told apart from one sent empty.
 type UpdateUserRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+	// Version, when sent, must be the user's current version
+	Version *int `json:"version"`
 }
 
-// GetCurrentUser returns the currently authenticated user
+// ErrVersionConflict is returned by UpdateProfile when the user changed after
+// it was read
+var ErrVersionConflict = errors.New("user was modified concurrently")
+
+// UpdateProfile applies updates to user only if its version is still the one
+// that was read, bumping the version and reading the updated row back into
+// user. It returns ErrVersionConflict if another update got there first.
+func UpdateProfile(db *gorm.DB, user *models.User, updates map[string]interface{}) error {
+	updates["version"] = models.NextVersion()
+	result := db.Model(user).Clauses(clause.Returning{}).Where("version = ?", user.Version).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+const (
+	// ProfileVisibilityRestricted shows non-admins only the public profile of other users
+	ProfileVisibilityRestricted = "restricted"
+	// ProfileVisibilityFull shows every authenticated user the full profile of other users
+	ProfileVisibilityFull = "full"
+)
+
+// ProfileVisibility controls what non-admins see when viewing other users.
+// Users always see their own full profile and admins always see everyone's.
+var ProfileVisibility = ProfileVisibilityRestricted
+
+// Webhooks notifies downstream systems of user lifecycle events; nil disables them
+var Webhooks *webhook.Notifier
+
+// canViewFullProfiles reports whether the current user may see other users' private fields
+func canViewFullProfiles(c *gin.Context) (bool, error) {
+	if ProfileVisibility == ProfileVisibilityFull {
+		return true, nil
+	}
+	return middleware.IsAdmin(c)
+}
+
+// orgScope returns the scope limiting users queries to the caller's
+// organization (see middleware.OrgScope), responding with 500 and message
+// when the caller's role can't be read
+func orgScope(c *gin.Context, message string) (func(*gorm.DB) *gorm.DB, bool) {
+	scope, err := middleware.OrgScope(c)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+		return nil, false
+	}
+	return scope, true
+}
+
+// parseUserID parses the :id URL parameter, responding with 400 when it isn't a valid ID
+func parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid user ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetCurrentUser returns the currently authenticated user, limited to the
+// fields named in the fields query parameter when given
 func GetCurrentUser(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
+	fields, ok := parseFieldSelection(c, userResponseFields)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, user.ToResponse())
-}
-
-// GetAllUsers returns all registered users except the current user
-func GetAllUsers(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+	var user models.User
+	if err := requestDB(c).First(&user, userID).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
 		return
 	}
 
-	var users []models.User
-	// Exclude the current user from the list
-	if err := database.DB.Where("id != ?", userID).Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch users",
-		})
+	if respondConditionally(c, userETag(&user, userViewFull, fields)) {
 		return
 	}
 
-	// Convert to response format
-	userResponses := make([]models.UserResponse, len(users))
-	for i, user := range users {
-		userResponses[i] = user.ToResponse()
+	response, err := fields.Apply(user.ToResponse())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+		return
 	}
+	c.JSON(http.StatusOK, response)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"users": userResponses,
-		"count": len(userResponses),
-	})
+// UserListConfig holds the page size limits for listing users and the
+// secret that signs pagination cursors
+type UserListConfig struct {
+	DefaultPageSize int
+	MaxPageSize     int
+	CursorSecret    string
 }
 
-// GetUserByID returns a specific user by ID
+const (
+	// DefaultUserPageSize is the number of users returned per page by default
+	DefaultUserPageSize = 20
+	// DefaultMaxUserPageSize caps the limit and per_page query parameters by default
+	DefaultMaxUserPageSize = 100
+)
+
+// UserListQuery holds the pagination parameters for listing users. Users are
+// paged by cursor unless page or per_page is given.
+type UserListQuery struct {
+	Cursor  string `form:"cursor"`
+	Limit   int    `form:"limit" binding:"omitempty,min=1"`
+	Page    int    `form:"page" binding:"omitempty,min=1"`
+	PerPage int    `form:"per_page" binding:"omitempty,min=1"`
+}
+
+// GetAllUsers returns registered users except the current user, oldest
+// first. By default it returns a page of limit users and a signed
+// next_cursor for the following page; page and per_page select offset
+// pagination instead. The fields query parameter limits which fields each
+// user includes; admins also see each user's login activity.
+func GetAllUsers(config UserListConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := middleware.GetUserID(c)
+		if !exists {
+			respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		fields, ok := parseFieldSelection(c, userResponseFields)
+		if !ok {
+			return
+		}
+
+		var query UserListQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			respondBindingError(c, err)
+			return
+		}
+		offset := query.Page != 0 || query.PerPage != 0
+		if offset && (query.Cursor != "" || query.Limit != 0) {
+			respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Use either cursor and limit or page and per_page")
+			return
+		}
+
+		size, sizeField := query.Limit, "limit"
+		if offset {
+			size, sizeField = query.PerPage, "per_page"
+		}
+		if size == 0 {
+			size = config.DefaultPageSize
+		}
+		if size > config.MaxPageSize {
+			respondValidationErrors(c, []FieldError{{
+				Field:   sizeField,
+				Message: fmt.Sprintf("Must be at most %d", config.MaxPageSize),
+			}})
+			return
+		}
+
+		var afterID uint
+		if query.Cursor != "" {
+			var err error
+			if afterID, err = utils.DecodeCursor(query.Cursor, config.CursorSecret); err != nil {
+				respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid cursor")
+				return
+			}
+		}
+
+		fullProfiles, err := canViewFullProfiles(c)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch users")
+			return
+		}
+		admin, err := middleware.IsAdmin(c)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch users")
+			return
+		}
+
+		scope, ok := orgScope(c, "Failed to fetch users")
+		if !ok {
+			return
+		}
+
+		// Exclude the current user from the list and users of other
+		// organizations; ordering by ID keeps pages stable while users are added
+		db := requestDB(c).Model(&models.User{}).Scopes(scope).Where("id != ?", userID).Order("id")
+
+		var users []models.User
+		resp := gin.H{}
+		if offset {
+			if query.Page == 0 {
+				query.Page = 1
+			}
+			var total int64
+			if err := db.Count(&total).Error; err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch users")
+				return
+			}
+			if err := db.Limit(size).Offset((query.Page - 1) * size).Find(&users).Error; err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch users")
+				return
+			}
+			resp["page"], resp["per_page"], resp["total"] = query.Page, size, total
+		} else {
+			// Fetch one extra row to learn whether another page follows
+			if err := db.Where("id > ?", afterID).Limit(size + 1).Find(&users).Error; err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch users")
+				return
+			}
+			var nextCursor *string
+			if len(users) > size {
+				users = users[:size]
+				cursor := utils.EncodeCursor(users[size-1].ID, config.CursorSecret)
+				nextCursor = &cursor
+			}
+			resp["next_cursor"] = nextCursor
+		}
+
+		// Convert to response format
+		userResponses := make([]interface{}, len(users))
+		for i, user := range users {
+			var response interface{} = user.ToPublicResponse()
+			switch {
+			case admin:
+				response = user.ToAdminResponse()
+			case fullProfiles:
+				response = user.ToResponse()
+			}
+			if userResponses[i], err = fields.Apply(response); err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch users")
+				return
+			}
+		}
+
+		resp["users"], resp["count"] = userResponses, len(userResponses)
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// GetUserByID returns a specific user by ID. Other users' profiles are
+// reduced to public fields unless ProfileVisibility or the admin role allows
+// more; admins also see when the user last logged in and was last seen. The
+// fields query parameter limits which fields are included.
 func GetUserByID(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldSelection(c, userResponseFields)
+	if !ok {
+		return
+	}
+
+	scope, ok := orgScope(c, "Failed to fetch user")
+	if !ok {
+		return
+	}
 
 	var user models.User
-	if err := database.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
+	if err := requestDB(c).Scopes(scope).First(&user, id).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
 		return
 	}
 
-	c.JSON(http.StatusOK, user.ToResponse())
+	admin, err := middleware.IsAdmin(c)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+		return
+	}
+
+	var response interface{} = user.ToResponse()
+	view := userViewFull
+	if admin {
+		response, view = user.ToAdminResponse(), userViewAdmin
+	} else if currentID, _ := middleware.GetUserID(c); currentID != user.ID {
+		fullProfiles, err := canViewFullProfiles(c)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+			return
+		}
+		if !fullProfiles {
+			response, view = user.ToPublicResponse(), userViewPublic
+		}
+	}
+
+	if respondConditionally(c, userETag(&user, view, fields)) {
+		return
+	}
+
+	response, err = fields.Apply(response)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// UpdateUser updates the current user's information
+// UpdateUser partially updates the current user's information. It serves both
+// PUT and PATCH; only the fields present in the body are changed.
 func UpdateUser(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Get the ID from URL parameter
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID",
-		})
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	scope, ok := orgScope(c, "Failed to fetch user")
+	if !ok {
 		return
 	}
 
 	// Find the user by ID
 	var user models.User
-	if err := database.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
+	if err := requestDB(c).Scopes(scope).First(&user, id).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
 		return
 	}
 
 	// Users can only update their own profile
 	if user.ID != userID {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": "You can only update your own profile",
-		})
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "You can only update your own profile")
+		return
+	}
+
+	// An If-Match ETag must name the current version, so a client editing a
+	// stale copy can't overwrite someone else's change
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch != "" && !ifMatchesUser(ifMatch, &user) {
+		respondPreconditionFailed(c)
 		return
 	}
 
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request payload",
-		})
+		respondBindingError(c, err)
 		return
 	}
 
-	// Update fields if provided
+	// Only provided fields are normalized, validated and updated. Username and
+	// email are required, so sending them empty fails validation.
 	updates := make(map[string]interface{})
-	if req.Username != "" {
-		if !usernameRegex.MatchString(req.Username) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Username must be 3-50 characters and contain only letters, numbers, and underscores",
+	var fieldErrors []FieldError
+	if req.Username != nil {
+		username := validation.NormalizeUsername(*req.Username)
+		if !validation.ValidUsername(username) {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "username",
+				Message: validation.UsernameMessage,
 			})
-			return
 		}
-		updates["username"] = req.Username
+		updates["username"] = username
 	}
-	if req.Email != "" {
-		if !emailRegex.MatchString(req.Email) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid email format",
+	if req.Email != nil {
+		email := validation.NormalizeEmail(*req.Email)
+		if !validation.ValidEmail(email) {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "email",
+				Message: validation.EmailMessage,
 			})
-			return
 		}
-		updates["email"] = req.Email
+		updates["email"] = email
+	}
+
+	if len(fieldErrors) > 0 {
+		respondUnprocessable(c, fieldErrors)
+		return
+	}
+
+	// Users who already hold a reserved username (e.g. a seeded admin) may keep it
+	if username, ok := updates["username"].(string); ok && username != user.Username && !validation.Usernames.PermitsUsername(username) {
+		respondReservedUsername(c)
+		return
 	}
 
 	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No fields to update",
-		})
+		respondError(c, http.StatusUnprocessableEntity, apierror.CodeValidationFailed, "No fields to update")
 		return
 	}
 
-	// Update user
-	if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to update user",
-		})
+	if req.Version != nil && *req.Version != user.Version {
+		respondVersionConflict(c)
 		return
 	}
 
-	// Fetch updated user
-	database.DB.First(&user, userID)
+	// The update only applies to the version read above, so a concurrent
+	// change between the read and the write is caught too
+	if err := SaveProfile(requestDB(c), &user, updates); err != nil {
+		switch {
+		case errors.Is(err, ErrVersionConflict) && ifMatch != "":
+			respondPreconditionFailed(c)
+		case errors.Is(err, ErrVersionConflict):
+			respondVersionConflict(c)
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			respondError(c, http.StatusConflict, apierror.CodeConflict, "Username or email is already taken")
+		default:
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update user")
+		}
+		return
+	}
 
+	Webhooks.Notify(c.Request.Context(), webhook.EventUserUpdated, user.ID)
+	c.Header("ETag", userETag(&user, userViewFull, nil))
 	c.JSON(http.StatusOK, user.ToResponse())
 }
 
+// respondVersionConflict writes a 409 for an update that lost a race with
+// another update, or named a version that is no longer current
+func respondVersionConflict(c *gin.Context) {
+	respondError(c, http.StatusConflict, apierror.CodeVersionConflict,
+		"The user was modified by another request; fetch it again and retry")
+}
+
 // DeleteUser deletes the current user's account
 func DeleteUser(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Get the ID from URL parameter
-	id := c.Param("id")
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	scope, ok := orgScope(c, "Failed to fetch user")
+	if !ok {
+		return
+	}
 
 	var user models.User
-	if err := database.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
+	if err := requestDB(c).Scopes(scope).First(&user, id).Error; err != nil {
+		respondLookupError(c, err, "User not found", "Failed to fetch user")
 		return
 	}
 
 	// Users can only delete their own profile
 	if user.ID != userID {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": "You can only delete your own profile",
-		})
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "You can only delete your own profile")
 		return
 	}
 
-	// Soft delete user
-	if err := database.DB.Delete(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete user",
-		})
+	// Soft delete user, recording the deletion in the same transaction
+	err := requestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUserDeleted, &userID, userTarget(user.ID))
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete user")
 		return
 	}
 
+	Webhooks.Notify(c.Request.Context(), webhook.EventUserDeleted, user.ID)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User deleted successfully",
 	})
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/migrations"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BuildInfo identifies the running build. The values are injected at build
+// time with -ldflags; development builds carry placeholders.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// VersionResponse describes the running build and the database schema it is using
+type VersionResponse struct {
+	BuildInfo
+	GoVersion string `json:"go_version"`
+	// SchemaVersion is the last applied migration; empty when the schema was
+	// auto-migrated without recording history
+	SchemaVersion string `json:"schema_version"`
+	// LatestSchemaVersion is the newest migration this build ships
+	LatestSchemaVersion string `json:"latest_schema_version"`
+}
+
+// Version reports the build version, commit and date, the Go version, and
+// the applied database migration
+func Version(build BuildInfo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schemaVersion, err := database.SchemaVersion(c.Request.Context())
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to read schema version")
+			return
+		}
+
+		c.JSON(http.StatusOK, VersionResponse{
+			BuildInfo:           build,
+			GoVersion:           runtime.Version(),
+			SchemaVersion:       schemaVersion,
+			LatestSchemaVersion: migrations.Latest(),
+		})
+	}
+}
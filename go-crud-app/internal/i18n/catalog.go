@@ -0,0 +1,89 @@
+package i18n
+
+import "golang.org/x/text/language"
+
+// messages maps each supported language to translations of the English
+// validation, authentication and rate-limit messages. Keys may contain %d or
+// %s verbs; their translations take the arguments as %s, in the same order.
+var messages = map[language.Tag]map[string]string{
+	language.Spanish: {
+		// Validation
+		"Validation failed":                 "La validación ha fallado",
+		"This field is required":            "Este campo es obligatorio",
+		"Invalid value":                     "Valor no válido",
+		"Invalid request payload":           "El cuerpo de la solicitud no es válido",
+		"Request body too large":            "El cuerpo de la solicitud es demasiado grande",
+		"Request body is nested too deeply": "El cuerpo de la solicitud está anidado demasiado profundamente",
+		"No fields to update":               "No hay campos para actualizar",
+		"Invalid email format":              "El formato del correo electrónico no es válido",
+		"This username is reserved":         "Este nombre de usuario está reservado",
+		"Username must be 3-50 characters and contain only letters, numbers, and underscores":  "El nombre de usuario debe tener entre 3 y 50 caracteres y contener solo letras, números y guiones bajos",
+		"password must be at least %d characters":                                              "la contraseña debe tener al menos %s caracteres",
+		"password must be at least %d characters and contain uppercase, lowercase, and number": "la contraseña debe tener al menos %s caracteres y contener mayúsculas, minúsculas y números",
+		"password must be at most %d bytes":                                                    "la contraseña debe tener como máximo %s bytes",
+		"Current password is incorrect":                                                        "La contraseña actual es incorrecta",
+		"Must be at most %d":                                                                   "Debe ser como máximo %s",
+
+		// Authentication
+		"Unauthorized":                                             "No autorizado",
+		"Invalid email or password":                                "Correo electrónico o contraseña incorrectos",
+		"Authorization header required":                            "Se requiere la cabecera Authorization",
+		"Invalid authorization header format. Use: Bearer <token>": "Formato de cabecera Authorization no válido. Use: Bearer <token>",
+		"Invalid token":                                            "Token no válido",
+		"Token has expired":                                        "El token ha caducado",
+		"Token has been revoked":                                   "El token ha sido revocado",
+		"Refresh tokens cannot be used to access the API":          "Los tokens de actualización no se pueden usar para acceder a la API",
+		"Invalid refresh token":                                    "Token de actualización no válido",
+		"Refresh token has expired":                                "El token de actualización ha caducado",
+		"Refresh token has been revoked":                           "El token de actualización ha sido revocado",
+		"Admin access required":                                    "Se requiere acceso de administrador",
+		"Registration is closed":                                   "El registro está cerrado",
+		"CSRF token required":                                      "Se requiere un token CSRF",
+		"Invalid CSRF token":                                       "Token CSRF no válido",
+		"You must change your password before continuing":          "Debe cambiar su contraseña antes de continuar",
+
+		// Rate limiting
+		"Rate limit exceeded. Please try again later.":            "Se ha superado el límite de solicitudes. Inténtelo de nuevo más tarde.",
+		"Too many failed login attempts. Try again in %d seconds": "Demasiados intentos de inicio de sesión fallidos. Inténtelo de nuevo en %s segundos",
+	},
+	language.French: {
+		// Validation
+		"Validation failed":                 "La validation a échoué",
+		"This field is required":            "Ce champ est obligatoire",
+		"Invalid value":                     "Valeur non valide",
+		"Invalid request payload":           "Le corps de la requête n'est pas valide",
+		"Request body too large":            "Le corps de la requête est trop volumineux",
+		"Request body is nested too deeply": "Le corps de la requête est trop profondément imbriqué",
+		"No fields to update":               "Aucun champ à mettre à jour",
+		"Invalid email format":              "Le format de l'adresse e-mail n'est pas valide",
+		"This username is reserved":         "Ce nom d'utilisateur est réservé",
+		"Username must be 3-50 characters and contain only letters, numbers, and underscores":  "Le nom d'utilisateur doit comporter de 3 à 50 caractères et ne contenir que des lettres, des chiffres et des tirets bas",
+		"password must be at least %d characters":                                              "le mot de passe doit comporter au moins %s caractères",
+		"password must be at least %d characters and contain uppercase, lowercase, and number": "le mot de passe doit comporter au moins %s caractères et contenir une majuscule, une minuscule et un chiffre",
+		"password must be at most %d bytes":                                                    "le mot de passe doit comporter au plus %s octets",
+		"Current password is incorrect":                                                        "Le mot de passe actuel est incorrect",
+		"Must be at most %d":                                                                   "Doit être au plus %s",
+
+		// Authentication
+		"Unauthorized":                                             "Non autorisé",
+		"Invalid email or password":                                "Adresse e-mail ou mot de passe incorrect",
+		"Authorization header required":                            "L'en-tête Authorization est requis",
+		"Invalid authorization header format. Use: Bearer <token>": "Format d'en-tête Authorization non valide. Utilisez : Bearer <token>",
+		"Invalid token":                                            "Jeton non valide",
+		"Token has expired":                                        "Le jeton a expiré",
+		"Token has been revoked":                                   "Le jeton a été révoqué",
+		"Refresh tokens cannot be used to access the API":          "Les jetons de rafraîchissement ne permettent pas d'accéder à l'API",
+		"Invalid refresh token":                                    "Jeton de rafraîchissement non valide",
+		"Refresh token has expired":                                "Le jeton de rafraîchissement a expiré",
+		"Refresh token has been revoked":                           "Le jeton de rafraîchissement a été révoqué",
+		"Admin access required":                                    "Un accès administrateur est requis",
+		"Registration is closed":                                   "Les inscriptions sont fermées",
+		"CSRF token required":                                      "Un jeton CSRF est requis",
+		"Invalid CSRF token":                                       "Jeton CSRF non valide",
+		"You must change your password before continuing":          "Vous devez changer votre mot de passe avant de continuer",
+
+		// Rate limiting
+		"Rate limit exceeded. Please try again later.":            "Limite de requêtes dépassée. Veuillez réessayer plus tard.",
+		"Too many failed login attempts. Try again in %d seconds": "Trop de tentatives de connexion échouées. Réessayez dans %s secondes",
+	},
+}
@@ -0,0 +1,114 @@
+// Package i18n localizes user-facing error messages. Messages are looked up
+// by their English text, so code keeps writing English and anything missing
+// from a catalog falls back to it.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Default is the language used when the client asks for none we support
+var Default = language.English
+
+// Supported lists the languages with a message catalog, Default first
+var Supported = []language.Tag{language.English, language.Spanish, language.French}
+
+var matcher = language.NewMatcher(Supported)
+
+// template is a catalog message with %d or %s verbs, matched against
+// already-formatted messages so their arguments can be carried over
+type template struct {
+	pattern     *regexp.Regexp
+	translation string
+}
+
+// compiled holds each catalog split into exact messages and templates
+type compiled struct {
+	exact     map[string]string
+	templates []template
+}
+
+var catalogs = compileCatalogs(messages)
+
+// Match picks the supported language for a request from the lang query
+// parameter, which wins when valid, then the Accept-Language header
+func Match(lang, acceptLanguage string) language.Tag {
+	var preferred []language.Tag
+	if tag, err := language.Parse(lang); err == nil {
+		preferred = append(preferred, tag)
+	}
+	if tags, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil {
+		preferred = append(preferred, tags...)
+	}
+	if len(preferred) == 0 {
+		return Default
+	}
+
+	_, index, confidence := matcher.Match(preferred...)
+	if confidence == language.No {
+		return Default
+	}
+	return Supported[index]
+}
+
+// Translate returns message in the given language, or message unchanged when
+// the catalog has no translation for it
+func Translate(tag language.Tag, message string) string {
+	catalog, ok := catalogs[tag]
+	if !ok {
+		return message
+	}
+	if translation, ok := catalog.exact[message]; ok {
+		return translation
+	}
+	for _, t := range catalog.templates {
+		if match := t.pattern.FindStringSubmatch(message); match != nil {
+			args := make([]any, len(match)-1)
+			for i, arg := range match[1:] {
+				args[i] = arg
+			}
+			return fmt.Sprintf(t.translation, args...)
+		}
+	}
+	return message
+}
+
+// compileCatalogs turns message templates into patterns matching their
+// formatted output. Translations take every argument as %s.
+func compileCatalogs(messages map[language.Tag]map[string]string) map[language.Tag]compiled {
+	verb := regexp.MustCompile(`%[ds]`)
+	result := make(map[language.Tag]compiled, len(messages))
+	for tag, catalog := range messages {
+		c := compiled{exact: make(map[string]string)}
+		for message, translation := range catalog {
+			if !verb.MatchString(message) {
+				c.exact[message] = translation
+				continue
+			}
+
+			var pattern strings.Builder
+			pattern.WriteString("^")
+			last := 0
+			for _, loc := range verb.FindAllStringIndex(message, -1) {
+				pattern.WriteString(regexp.QuoteMeta(message[last:loc[0]]))
+				if message[loc[0]+1] == 'd' {
+					pattern.WriteString(`(-?\d+)`)
+				} else {
+					pattern.WriteString(`(.+?)`)
+				}
+				last = loc[1]
+			}
+			pattern.WriteString(regexp.QuoteMeta(message[last:]) + "$")
+			c.templates = append(c.templates, template{
+				pattern:     regexp.MustCompile(pattern.String()),
+				translation: translation,
+			})
+		}
+		result[tag] = c
+	}
+	return result
+}
@@ -0,0 +1,20 @@
+package mailer
+
+import (
+	"log"
+)
+
+// Mailer sends transactional emails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer writes emails to the application log instead of sending them.
+// It is intended for local development only.
+type LogMailer struct{}
+
+// Send logs the email
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("Email to %s: %s\n%s", to, subject, body)
+	return nil
+}
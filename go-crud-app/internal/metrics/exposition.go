@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FormatPrometheus is the classic Prometheus text exposition format
+	FormatPrometheus = "prometheus"
+	// FormatOpenMetrics is the OpenMetrics 1.0 text exposition format
+	FormatOpenMetrics = "openmetrics"
+
+	// PrometheusContentType is the content type of the Prometheus text format
+	PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+	// OpenMetricsContentType is the content type of the OpenMetrics text format
+	OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Write renders all metrics in the given format (FormatPrometheus or FormatOpenMetrics)
+func (r *Registry) Write(w io.Writer, format string) error {
+	openMetrics := format == FormatOpenMetrics
+	bw := bufio.NewWriter(w)
+
+	for _, c := range r.snapshot() {
+		writeFamily(bw, c.describe(), c.collect(), openMetrics)
+	}
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+// writeFamily renders one metric family. In Prometheus format counters are
+// named with their _total suffix; in OpenMetrics the family name omits it and
+// units are declared with a UNIT line.
+func writeFamily(w *bufio.Writer, d desc, samples []series, openMetrics bool) {
+	familyName := d.name
+	if d.typ == typeCounter && !openMetrics {
+		familyName += "_total"
+	}
+
+	fmt.Fprintf(w, "# TYPE %s %s\n", familyName, d.typ)
+	if openMetrics && d.unit != "" {
+		fmt.Fprintf(w, "# UNIT %s %s\n", familyName, d.unit)
+	}
+	if d.help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", familyName, escapeHelp(d.help, openMetrics))
+	}
+
+	for _, s := range samples {
+		switch d.typ {
+		case typeCounter:
+			writeSample(w, d.name+"_total", d.labels, s.labelValues, "", "", s.value, nil)
+		case typeGauge:
+			writeSample(w, d.name, d.labels, s.labelValues, "", "", s.value, nil)
+		case typeHistogram:
+			for i, bound := range s.upperBounds {
+				var exemplar *Exemplar
+				if openMetrics {
+					exemplar = s.exemplars[i]
+				}
+				writeSample(w, d.name+"_bucket", d.labels, s.labelValues, "le", formatBound(bound, openMetrics),
+					float64(s.buckets[i]), exemplar)
+			}
+			writeSample(w, d.name+"_count", d.labels, s.labelValues, "", "", float64(s.count), nil)
+			writeSample(w, d.name+"_sum", d.labels, s.labelValues, "", "", s.sum, nil)
+		}
+	}
+}
+
+// writeSample renders a single sample line, with an optional extra label
+// (histogram "le") and an optional OpenMetrics exemplar
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, value float64, exemplar *Exemplar) {
+	w.WriteString(name)
+
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, label+`="`+escapeLabelValue(values[i])+`"`)
+	}
+	if extraLabel != "" {
+		pairs = append(pairs, extraLabel+`="`+extraValue+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatFloat(value))
+
+	if exemplar != nil {
+		keys := make([]string, 0, len(exemplar.Labels))
+		for key := range exemplar.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		exemplarPairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			exemplarPairs = append(exemplarPairs, key+`="`+escapeLabelValue(exemplar.Labels[key])+`"`)
+		}
+		timestamp := float64(exemplar.Timestamp.UnixNano()) / 1e9
+		fmt.Fprintf(w, " # {%s} %s %s", strings.Join(exemplarPairs, ","), formatFloat(exemplar.Value),
+			strconv.FormatFloat(timestamp, 'f', 3, 64))
+	}
+
+	w.WriteString("\n")
+}
+
+// formatFloat renders a sample value
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatBound renders a histogram bucket bound. OpenMetrics requires the
+// canonical float form, so whole numbers are written as e.g. "1.0".
+func formatBound(v float64, openMetrics bool) string {
+	s := formatFloat(v)
+	if openMetrics && !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// escapeLabelValue escapes backslashes, quotes and newlines in label values
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// escapeHelp escapes HELP text; OpenMetrics additionally escapes quotes
+func escapeHelp(v string, openMetrics bool) string {
+	if openMetrics {
+		return escapeLabelValue(v)
+	}
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRegistry holds the application's metrics
+var DefaultRegistry = NewRegistry()
+
+// DefaultDurationBuckets are histogram buckets (in seconds) suited to HTTP latencies
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+func init() {
+	DefaultRegistry.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", "", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+// metricType is the exposition type of a metric family
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// desc describes a metric family. The name never includes the _total suffix
+// of counters; it must end with the unit (e.g. _seconds) when one is set.
+type desc struct {
+	name   string
+	help   string
+	unit   string
+	typ    metricType
+	labels []string
+}
+
+// collector is implemented by every metric family
+type collector interface {
+	describe() desc
+	collect() []series
+}
+
+// series is a snapshot of one labelled member of a family
+type series struct {
+	labelValues []string
+	value       float64     // counters and gauges
+	buckets     []uint64    // histograms: cumulative count per upper bound
+	exemplars   []*Exemplar // histograms: latest exemplar per bucket (may be nil)
+	upperBounds []float64
+	sum         float64
+	count       uint64
+}
+
+// Exemplar links an observation to request-scoped labels (e.g. a request ID)
+type Exemplar struct {
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time
+}
+
+// Registry holds metric families in registration order
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]bool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register adds a collector, panicking on duplicate or malformed names since
+// both are programming errors caught at startup
+func (r *Registry) register(c collector) {
+	d := c.describe()
+	if d.unit != "" && !strings.HasSuffix(d.name, "_"+d.unit) {
+		panic(fmt.Sprintf("metrics: %q must end with its unit suffix _%s", d.name, d.unit))
+	}
+	if d.typ == typeCounter && strings.HasSuffix(d.name, "_total") {
+		panic(fmt.Sprintf("metrics: counter %q must not include the _total suffix", d.name))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[d.name] {
+		panic(fmt.Sprintf("metrics: %q is already registered", d.name))
+	}
+	r.names[d.name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// snapshot returns the registered collectors
+func (r *Registry) snapshot() []collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]collector(nil), r.collectors...)
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels
+type CounterVec struct {
+	desc   desc
+	mu     sync.Mutex
+	values map[string]*series
+}
+
+// NewCounterVec registers a counter. The _total suffix is added on export.
+func (r *Registry) NewCounterVec(name, help, unit string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, unit: unit, typ: typeCounter, labels: labels},
+		values: make(map[string]*series),
+	}
+	r.register(c)
+	return c
+}
+
+// Add increments the counter for the given label values
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	getSeries(c.values, c.desc, labelValues).value += delta
+}
+
+// Inc increments the counter for the given label values by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) describe() desc { return c.desc }
+
+func (c *CounterVec) collect() []series {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return sortedSeries(c.values)
+}
+
+// gaugeFunc is a gauge whose value is read on every scrape
+type gaugeFunc struct {
+	desc desc
+	fn   func() float64
+}
+
+// NewGaugeFunc registers an unlabelled gauge backed by fn
+func (r *Registry) NewGaugeFunc(name, help, unit string, fn func() float64) {
+	r.register(&gaugeFunc{desc: desc{name: name, help: help, unit: unit, typ: typeGauge}, fn: fn})
+}
+
+func (g *gaugeFunc) describe() desc { return g.desc }
+
+func (g *gaugeFunc) collect() []series {
+	return []series{{value: g.fn()}}
+}
+
+// HistogramVec counts observations into buckets, partitioned by labels
+type HistogramVec struct {
+	desc        desc
+	upperBounds []float64
+	mu          sync.Mutex
+	values      map[string]*series
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds
+func (r *Registry) NewHistogramVec(name, help, unit string, buckets []float64, labels ...string) *HistogramVec {
+	upperBounds := append([]float64(nil), buckets...)
+	sort.Float64s(upperBounds)
+	if len(upperBounds) == 0 || !math.IsInf(upperBounds[len(upperBounds)-1], 1) {
+		upperBounds = append(upperBounds, math.Inf(1))
+	}
+
+	h := &HistogramVec{
+		desc:        desc{name: name, help: help, unit: unit, typ: typeHistogram, labels: labels},
+		upperBounds: upperBounds,
+		values:      make(map[string]*series),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records a value for the given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.ObserveWithExemplar(value, nil, labelValues...)
+}
+
+// ObserveWithExemplar records a value and attaches the exemplar labels to the
+// bucket it falls into (exemplars are only exported in OpenMetrics format)
+func (h *HistogramVec) ObserveWithExemplar(value float64, exemplar map[string]string, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := getSeries(h.values, h.desc, labelValues)
+	if s.buckets == nil {
+		s.upperBounds = h.upperBounds
+		s.buckets = make([]uint64, len(h.upperBounds))
+		s.exemplars = make([]*Exemplar, len(h.upperBounds))
+	}
+
+	for i, bound := range h.upperBounds {
+		if value <= bound {
+			s.buckets[i]++
+			if len(exemplar) > 0 {
+				s.exemplars[i] = &Exemplar{Labels: exemplar, Value: value, Timestamp: time.Now()}
+			}
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (h *HistogramVec) describe() desc { return h.desc }
+
+func (h *HistogramVec) collect() []series {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := sortedSeries(h.values)
+	for i := range out {
+		// Buckets are stored per-bucket; exposition formats want cumulative counts
+		cumulative := make([]uint64, len(out[i].buckets))
+		var total uint64
+		for j, n := range out[i].buckets {
+			total += n
+			cumulative[j] = total
+		}
+		out[i].buckets = cumulative
+		out[i].exemplars = append([]*Exemplar(nil), out[i].exemplars...)
+	}
+	return out
+}
+
+// getSeries returns the series for the label values, creating it if needed
+func getSeries(values map[string]*series, d desc, labelValues []string) *series {
+	if len(labelValues) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %q expects %d label values, got %d", d.name, len(d.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := values[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		values[key] = s
+	}
+	return s
+}
+
+// sortedSeries copies the series in a stable order so scrapes are deterministic
+func sortedSeries(values map[string]*series) []series {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]series, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, *values[key])
+	}
+	return out
+}
@@ -1,46 +1,75 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/models"
 	"go-crud-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware validates JWT tokens
+// SessionTouchInterval is how stale a session's last-seen time may get before
+// a request refreshes it, so busy clients don't write on every request
+var SessionTouchInterval = time.Minute
+
+// LastSeenTouchInterval is how stale a user's last-seen time may get before
+// a request refreshes it. Inactivity is measured in days, so it can be far
+// coarser than SessionTouchInterval.
+var LastSeenTouchInterval = 5 * time.Minute
+
+// AuthMiddleware validates JWT tokens from the Authorization header or, when
+// the header is absent and cookie auth is enabled, from the auth cookie. API
+// keys are accepted too, in the X-API-Key header or with the ApiKey scheme.
 func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header required",
-			})
-			c.Abort()
+		if key, ok := requestAPIKey(c); ok {
+			authenticateAPIKey(c, key)
 			return
 		}
 
-		// Check if it's a Bearer token
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid authorization header format. Use: Bearer <token>",
-			})
-			c.Abort()
+		tokenString, ok := requestToken(c)
+		if !ok {
 			return
 		}
 
-		tokenString := parts[1]
-
-		// Validate token
-		claims, err := utils.ValidateToken(tokenString, jwtSecret)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid or expired token",
-			})
-			c.Abort()
+		claims, err := VerifyToken(c.Request.Context(), tokenString, jwtSecret)
+		if errors.Is(err, ErrAccountSuspended) {
+			abortWithError(c, http.StatusForbidden, apierror.CodeAccountSuspended, AccountSuspendedMessage)
+			return
+		}
+		if errors.Is(err, ErrPasswordChangeRequired) {
+			// PasswordChangeGate decides which routes are still allowed
+			c.Set(passwordChangeRequiredKey, true)
+			c.Set(passwordExpiredKey, errors.Is(err, ErrPasswordExpired))
+			err = nil
+		}
+		switch {
+		case errors.Is(err, utils.ErrExpiredToken):
+			abortUnauthorized(c, apierror.CodeTokenExpired, "Token has expired")
+			return
+		case errors.Is(err, utils.ErrRevokedToken):
+			abortUnauthorized(c, apierror.CodeTokenInvalid, "Token has been revoked")
+			return
+		case errors.Is(err, errRefreshToken):
+			abortUnauthorized(c, apierror.CodeTokenInvalid, "Refresh tokens cannot be used to access the API")
+			return
+		case errors.Is(err, utils.ErrInvalidToken):
+			// Clients only see "Invalid token"; the log keeps the cause
+			log.Printf("Rejected token for %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			abortUnauthorized(c, apierror.CodeTokenInvalid, "Invalid token")
+			return
+		case err != nil:
+			abortWithError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to verify token")
 			return
 		}
 
@@ -48,11 +77,170 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("email", claims.Email)
+		c.Set("org_id", claims.OrgID)
+		c.Set("session_id", claims.ID)
+		c.Set(scopesContextKey, claims.Scopes)
 
 		c.Next()
 	}
 }
 
+// requestToken returns the access token sent with the request. The
+// Authorization header takes precedence over the auth cookie. It aborts with
+// a 401 and returns false when neither carries a usable token.
+func requestToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		if token, ok := authCookieToken(c); ok {
+			c.Set(authViaCookieKey, true)
+			return token, true
+		}
+		c.Header("WWW-Authenticate", "Bearer")
+		abortWithError(c, http.StatusUnauthorized, apierror.CodeTokenInvalid, "Authorization header required")
+		return "", false
+	}
+
+	// Check if it's a Bearer token
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		abortUnauthorized(c, apierror.CodeTokenInvalid, "Invalid authorization header format. Use: Bearer <token>")
+		return "", false
+	}
+	return parts[1], true
+}
+
+// errRefreshToken is returned by VerifyToken when given a refresh token; it
+// wraps ErrInvalidToken so callers that don't care can treat it as invalid
+var errRefreshToken = fmt.Errorf("%w: refresh tokens cannot be used to access the API", utils.ErrInvalidToken)
+
+// ErrPasswordChangeRequired is returned by VerifyToken, along with the
+// token's claims, when the token is valid but the user must replace a
+// temporary password before using the API
+var ErrPasswordChangeRequired = errors.New("password change required")
+
+// ErrPasswordExpired is returned by VerifyToken, along with the token's
+// claims, when the user's password is older than utils.PasswordMaxAge. It
+// wraps ErrPasswordChangeRequired, as the same routes stay allowed.
+var ErrPasswordExpired = fmt.Errorf("%w: password expired", ErrPasswordChangeRequired)
+
+// ErrAccountSuspended is returned by VerifyToken for tokens of a suspended user
+var ErrAccountSuspended = errors.New("account suspended")
+
+// AccountSuspendedMessage is shown to suspended users on every login and request
+const AccountSuspendedMessage = "Your account has been suspended"
+
+// VerifyToken validates an access token and checks it against the user's
+// current token version and session. It returns utils.ErrExpiredToken,
+// utils.ErrInvalidToken (also for refresh tokens and deleted users),
+// utils.ErrRevokedToken (also for revoked sessions and users who changed
+// organization), ErrAccountSuspended, or a database error. For users with a pending forced
+// password change it returns the claims together with ErrPasswordChangeRequired,
+// or ErrPasswordExpired when their password has expired. The claims of
+// minimal tokens get the user's current username and email.
+func VerifyToken(ctx context.Context, tokenString, jwtSecret string) (*utils.Claims, error) {
+	claims, err := utils.ValidateToken(tokenString, jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh tokens may only be exchanged at the refresh endpoint
+	if claims.IsRefresh() {
+		return nil, errRefreshToken
+	}
+
+	// Reject tokens issued before the user's last logout-all or password
+	// change, and tokens for users that no longer exist
+	var user models.User
+	if err := database.DB.WithContext(ctx).Select("id", "username", "email", "token_version", "must_change_password", "password_changed_at", "org_id", "status", "last_seen_at").First(&user, claims.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrInvalidToken
+		}
+		return nil, err
+	}
+	// Suspension also bumps the token version, but say why the token stopped
+	// working while it lasts
+	if user.Status == models.StatusSuspended {
+		return nil, ErrAccountSuspended
+	}
+	if claims.TokenVersion < user.TokenVersion {
+		return nil, utils.ErrRevokedToken
+	}
+	// A user moved to another organization must log in again
+	if !models.SameOrg(claims.OrgID, user.OrgID) {
+		return nil, utils.ErrRevokedToken
+	}
+	// Tokens issued before sessions existed have no jti and no session
+	if claims.ID != "" {
+		if err := checkSession(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+	// Minimal tokens carry only the user ID; the rest comes from the user
+	if claims.Username == "" && claims.Email == "" {
+		claims.Username = user.Username
+		claims.Email = user.Email
+	}
+	touchLastSeen(ctx, &user)
+	if user.MustChangePassword {
+		return claims, ErrPasswordChangeRequired
+	}
+	if utils.PasswordExpired(user.PasswordChangedAt) {
+		return claims, ErrPasswordExpired
+	}
+
+	return claims, nil
+}
+
+// checkSession rejects tokens whose session has been revoked, and records
+// that the session was seen
+func checkSession(ctx context.Context, claims *utils.Claims) error {
+	db := database.DB.WithContext(ctx)
+
+	var session models.Session
+	if err := db.Select("id", "last_seen_at", "revoked_at").
+		Where("jti = ? AND user_id = ?", claims.ID, claims.UserID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.ErrRevokedToken
+		}
+		return err
+	}
+	if session.RevokedAt != nil {
+		return utils.ErrRevokedToken
+	}
+
+	// A failed touch only leaves last-seen stale, so it doesn't fail the request
+	if now := time.Now(); now.Sub(session.LastSeenAt) >= SessionTouchInterval {
+		if err := db.Model(&session).UpdateColumn("last_seen_at", now).Error; err != nil {
+			log.Printf("Failed to update last seen time of session %d: %v", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// touchLastSeen records that user was seen, at most once per
+// LastSeenTouchInterval. The update only matches a stale row, so concurrent
+// requests don't all write; a failure only leaves last-seen stale, so it
+// doesn't fail the request.
+func touchLastSeen(ctx context.Context, user *models.User) {
+	now := time.Now()
+	if user.LastSeenAt != nil && now.Sub(*user.LastSeenAt) < LastSeenTouchInterval {
+		return
+	}
+	err := database.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND (last_seen_at IS NULL OR last_seen_at < ?)", user.ID, now.Add(-LastSeenTouchInterval)).
+		UpdateColumn("last_seen_at", now).Error
+	if err != nil {
+		log.Printf("Failed to update last seen time of user %d: %v", user.ID, err)
+	}
+}
+
+// abortUnauthorized rejects a bad token with a 401 and an RFC 6750
+// WWW-Authenticate challenge describing the failure
+func abortUnauthorized(c *gin.Context, code, message string) {
+	c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, message))
+	abortWithError(c, http.StatusUnauthorized, code, message)
+}
+
 // GetUserID retrieves the user ID from the context
 func GetUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get("user_id")
@@ -61,3 +249,87 @@ func GetUserID(c *gin.Context) (uint, bool) {
 	}
 	return userID.(uint), true
 }
+
+// GetOrgID returns the organization of the request's token, or nil for users
+// without one
+func GetOrgID(c *gin.Context) *uint {
+	orgID, _ := c.Get("org_id")
+	id, _ := orgID.(*uint)
+	return id
+}
+
+// GetSessionID returns the session (jti) of the request's token, or "" for
+// tokens issued before sessions existed
+func GetSessionID(c *gin.Context) string {
+	return c.GetString("session_id")
+}
+
+// Role returns the authenticated user's current role. The role is read from
+// the database rather than the token so that a demotion takes effect
+// immediately; the result is cached for the request. An admin whose token
+// or API key lacks the admin scope acts with the user role.
+func Role(c *gin.Context) (string, error) {
+	if role, ok := c.Get("role"); ok {
+		return role.(string), nil
+	}
+
+	userID, exists := GetUserID(c)
+	if !exists {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	var user models.User
+	if err := database.DB.WithContext(c.Request.Context()).Select("role").First(&user, userID).Error; err != nil {
+		return "", err
+	}
+	role := models.ScopedRole(user.Role, HasScope(c, models.ScopeAdmin))
+	c.Set("role", role)
+	return role, nil
+}
+
+// IsAdmin reports whether the authenticated user currently has the admin or
+// super-admin role
+func IsAdmin(c *gin.Context) (bool, error) {
+	role, err := Role(c)
+	if err != nil {
+		return false, err
+	}
+	return models.IsAdminRole(role), nil
+}
+
+// IsSuperAdmin reports whether the authenticated user currently has the
+// super-admin role
+func IsSuperAdmin(c *gin.Context) (bool, error) {
+	role, err := Role(c)
+	if err != nil {
+		return false, err
+	}
+	return role == models.RoleSuperAdmin, nil
+}
+
+// OrgScope returns the scope limiting a users query to the organization of
+// the authenticated user, or to no organization for super-admins
+func OrgScope(c *gin.Context) (func(*gorm.DB) *gorm.DB, error) {
+	role, err := Role(c)
+	if err != nil {
+		return nil, err
+	}
+	return models.OrgScope(role, GetOrgID(c)), nil
+}
+
+// RequireAdmin restricts a route to admins. It must run after AuthMiddleware.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		isAdmin, err := IsAdmin(c)
+		if err != nil {
+			abortWithError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		if !isAdmin {
+			abortWithError(c, http.StatusForbidden, apierror.CodeForbidden, "Admin access required")
+			return
+		}
+
+		c.Next()
+	}
+}
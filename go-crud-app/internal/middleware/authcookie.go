@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultAuthCookiePath scopes the auth cookie to the API routes
+const DefaultAuthCookiePath = "/api"
+
+// AuthCookieConfig configures carrying the access token in a cookie, for
+// browser clients that keep it out of reach of scripts
+type AuthCookieConfig struct {
+	// Name is the cookie name; cookie auth is disabled when it is empty
+	Name     string
+	Domain   string
+	Path     string
+	Secure   bool
+	HTTPOnly bool
+	SameSite http.SameSite
+}
+
+// Enabled reports whether cookie auth is turned on
+func (config AuthCookieConfig) Enabled() bool {
+	return config.Name != ""
+}
+
+// AuthCookie configures cookie auth. It is disabled by default, so only the
+// Authorization header is accepted.
+var AuthCookie AuthCookieConfig
+
+// AuthCookieConfigFromEnv builds the auth cookie configuration from
+// AUTH_COOKIE_NAME, AUTH_COOKIE_DOMAIN, AUTH_COOKIE_PATH, AUTH_COOKIE_SECURE,
+// AUTH_COOKIE_HTTP_ONLY and AUTH_COOKIE_SAMESITE. The cookie is Secure,
+// HttpOnly and SameSite=Strict unless configured otherwise.
+func AuthCookieConfigFromEnv() (AuthCookieConfig, error) {
+	config := AuthCookieConfig{
+		Name:     strings.TrimSpace(os.Getenv("AUTH_COOKIE_NAME")),
+		Domain:   os.Getenv("AUTH_COOKIE_DOMAIN"),
+		Path:     os.Getenv("AUTH_COOKIE_PATH"),
+		Secure:   true,
+		HTTPOnly: true,
+	}
+	if config.Path == "" {
+		config.Path = DefaultAuthCookiePath
+	}
+	if value, err := strconv.ParseBool(os.Getenv("AUTH_COOKIE_SECURE")); err == nil {
+		config.Secure = value
+	}
+	if value, err := strconv.ParseBool(os.Getenv("AUTH_COOKIE_HTTP_ONLY")); err == nil {
+		config.HTTPOnly = value
+	}
+
+	sameSite, err := ParseSameSite(os.Getenv("AUTH_COOKIE_SAMESITE"))
+	if err != nil {
+		return AuthCookieConfig{}, err
+	}
+	config.SameSite = sameSite
+
+	// Browsers drop SameSite=None cookies that aren't Secure
+	if config.SameSite == http.SameSiteNoneMode && !config.Secure {
+		return AuthCookieConfig{}, errors.New("AUTH_COOKIE_SAMESITE=none requires AUTH_COOKIE_SECURE=true")
+	}
+	return config, nil
+}
+
+// ParseSameSite parses a SameSite setting of strict, lax or none; an empty
+// value means strict
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("SameSite must be strict, lax or none, got %q", value)
+	}
+}
+
+// SetAuthCookie stores the access token in the auth cookie, expiring along
+// with the token. It does nothing when cookie auth is disabled.
+func SetAuthCookie(c *gin.Context, token string, maxAge time.Duration) {
+	if !AuthCookie.Enabled() {
+		return
+	}
+	http.SetCookie(c.Writer, AuthCookie.cookie(token, int(maxAge.Seconds())))
+}
+
+// ClearAuthCookie tells the browser to delete the auth cookie. It does
+// nothing when cookie auth is disabled.
+func ClearAuthCookie(c *gin.Context) {
+	if !AuthCookie.Enabled() {
+		return
+	}
+	http.SetCookie(c.Writer, AuthCookie.cookie("", -1))
+}
+
+// cookie builds the auth cookie with the configured attributes
+func (config AuthCookieConfig) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     config.Name,
+		Value:    value,
+		Path:     config.Path,
+		Domain:   config.Domain,
+		MaxAge:   maxAge,
+		Secure:   config.Secure,
+		HttpOnly: config.HTTPOnly,
+		SameSite: config.SameSite,
+	}
+}
+
+// authCookieToken returns the access token from the auth cookie, if cookie
+// auth is enabled and the cookie is present
+func authCookieToken(c *gin.Context) (string, bool) {
+	if !AuthCookie.Enabled() {
+		return "", false
+	}
+	token, err := c.Cookie(AuthCookie.Name)
+	if err != nil || token == "" {
+		return "", false
+	}
+	return token, true
+}
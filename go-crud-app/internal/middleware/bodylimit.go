@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultMaxBodyBytes is the default maximum request body size (1 MB)
+	DefaultMaxBodyBytes int64 = 1 << 20
+	// DefaultMaxJSONDepth is the default maximum nesting depth of JSON bodies
+	DefaultMaxJSONDepth = 32
+)
+
+// ErrJSONTooDeep is returned when reading a JSON body nested deeper than allowed
+var ErrJSONTooDeep = errors.New("json nesting too deep")
+
+// originalBodyKey stores the unwrapped request body so a route-level limit can
+// replace (rather than stack on top of) the global one
+const originalBodyKey = "body_limit_original_body"
+
+// BodyLimitMiddleware caps the request body at maxBytes and the JSON nesting
+// depth at maxDepth (0 disables the depth check). Bodies that exceed the limit
+// surface as *http.MaxBytesError from the reader.
+//
+// Apply it globally and again on individual routes to override the limit.
+func BodyLimitMiddleware(maxBytes int64, maxDepth int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, ok := c.Get(originalBodyKey)
+		if !ok {
+			body = c.Request.Body
+			c.Set(originalBodyKey, body)
+		}
+
+		var reader io.ReadCloser = http.MaxBytesReader(c.Writer, body.(io.ReadCloser), maxBytes)
+		if maxDepth > 0 {
+			reader = &jsonDepthReader{ReadCloser: reader, maxDepth: maxDepth}
+		}
+		c.Request.Body = reader
+
+		c.Next()
+	}
+}
+
+// jsonDepthReader fails with ErrJSONTooDeep once the JSON stream nests
+// objects/arrays deeper than maxDepth, without buffering the body
+type jsonDepthReader struct {
+	io.ReadCloser
+	maxDepth int
+	depth    int
+	inString bool
+	escaped  bool
+	err      error
+}
+
+// Read reads from the underlying body while tracking nesting depth
+func (r *jsonDepthReader) Read(p []byte) (int, error) {
+	// The error is sticky: JSON decoders may ignore an error returned
+	// alongside data, so it must be reported again on the next read
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	for i, b := range p[:n] {
+		switch {
+		case r.escaped:
+			r.escaped = false
+		case r.inString && b == '\\':
+			r.escaped = true
+		case b == '"':
+			r.inString = !r.inString
+		case r.inString:
+		case b == '{' || b == '[':
+			r.depth++
+			if r.depth > r.maxDepth {
+				r.err = ErrJSONTooDeep
+				return i, r.err
+			}
+		case b == '}' || b == ']':
+			r.depth--
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,329 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultCompressionLevel balances speed against size for dynamic responses
+	DefaultCompressionLevel = 5
+	// DefaultCompressionMinBytes is the size below which responses are sent
+	// uncompressed, since compressing them saves too little to be worth it
+	DefaultCompressionMinBytes = 1024
+)
+
+// Supported content codings, in order of preference
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// CompressionConfig configures response compression
+type CompressionConfig struct {
+	Level    int // 1 (fastest) to 9 (smallest), for both gzip and brotli; 0 disables compression
+	MinBytes int // Responses smaller than this are sent uncompressed
+}
+
+// Validate rejects levels gzip doesn't support and negative sizes
+func (c CompressionConfig) Validate() error {
+	if c.Level < 0 || c.Level > gzip.BestCompression {
+		return fmt.Errorf("compression level must be between 0 and %d, got %d", gzip.BestCompression, c.Level)
+	}
+	if c.MinBytes < 0 {
+		return fmt.Errorf("compression minimum size must not be negative, got %d", c.MinBytes)
+	}
+	return nil
+}
+
+// CompressionMiddleware compresses responses with brotli or gzip, as
+// negotiated via Accept-Encoding. Responses smaller than MinBytes, responses
+// that already have a Content-Encoding, and content types that don't
+// compress well (such as images) are sent as they are. Strong ETags of
+// compressed responses get a "-gzip" or "-br" suffix, which is removed again
+// from If-Match and If-None-Match so handlers keep comparing their own tags.
+func CompressionMiddleware(config CompressionConfig) gin.HandlerFunc {
+	if config.Level == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	pools := map[string]*sync.Pool{
+		encodingGzip: {New: func() any {
+			// The level was validated, so this can't fail
+			w, _ := gzip.NewWriterLevel(io.Discard, config.Level)
+			return w
+		}},
+		encodingBrotli: {New: func() any {
+			return brotli.NewWriterLevel(io.Discard, config.Level)
+		}},
+	}
+
+	return func(c *gin.Context) {
+		revalidated := stripETagEncoding(c.Request.Header, "If-None-Match")
+		stripETagEncoding(c.Request.Header, "If-Match")
+
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			pool:           pools[encoding],
+			minBytes:       config.MinBytes,
+			revalidated:    revalidated,
+		}
+		c.Writer = w
+		completed := false
+		defer func() {
+			// A panic drops the buffered output, so the recovery middleware
+			// can still answer with a 500
+			if completed {
+				w.finish()
+			}
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+		completed = true
+	}
+}
+
+// resettableWriter is implemented by both gzip and brotli writers
+type resettableWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// compressWriter buffers a response until it reaches the minimum size, then
+// either compresses it or passes it through unchanged
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding    string
+	pool        *sync.Pool
+	minBytes    int
+	revalidated bool // The request's If-None-Match named a compressed ETag
+
+	buf     []byte
+	decided bool
+	encoder resettableWriter // Set once the response is being compressed
+}
+
+// Write buffers p until the response is large enough to decide on compression
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minBytes {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// WriteString writes s like Write
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow is deferred until compression is decided, as it changes the headers
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Written reports whether anything was written, including buffered output
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends what was written so far, compressing it if it is large enough
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(len(w.buf) >= w.minBytes); err != nil {
+			return
+		}
+	}
+	if w.encoder != nil {
+		if err := w.encoder.Flush(); err != nil {
+			return
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide starts compressing if the response is large and compressible, and
+// writes out the buffered output
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	buffered := w.buf
+	w.buf = nil
+
+	if !large || !w.compressible() {
+		if len(buffered) == 0 {
+			return nil
+		}
+		_, err := w.ResponseWriter.Write(buffered)
+		return err
+	}
+
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", w.encoding)
+	if etag := header.Get("ETag"); etag != "" {
+		header.Set("ETag", encodedETag(etag, w.encoding))
+	}
+	w.encoder = w.pool.Get().(resettableWriter)
+	w.encoder.Reset(w.ResponseWriter)
+	_, err := w.encoder.Write(buffered)
+	return err
+}
+
+// compressible reports whether the response may be compressed
+func (w *compressWriter) compressible() bool {
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent ||
+		status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	return header.Get("Content-Encoding") == "" && compressibleType(header.Get("Content-Type"))
+}
+
+// finish writes out a response too small to compress, or completes the
+// compressed stream
+func (w *compressWriter) finish() {
+	if !w.decided {
+		// A 304 for a compressed representation must name its ETag
+		if w.Status() == http.StatusNotModified && w.revalidated {
+			if etag := w.Header().Get("ETag"); etag != "" {
+				w.Header().Set("ETag", encodedETag(etag, w.encoding))
+			}
+		}
+		if err := w.decide(false); err != nil {
+			return
+		}
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
+
+// negotiateEncoding picks the preferred supported coding from an
+// Accept-Encoding header, or "" to send the response uncompressed
+func negotiateEncoding(accept string) string {
+	best, bestQ := "", 0.0
+	wildcardQ := -1.0
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcardQ = q
+		} else {
+			qualities[name] = q
+		}
+	}
+
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcardQ
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressibleType reports whether a content type is worth compressing.
+// Images, archives and other binary formats are already compressed.
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// encodedETag marks an ETag as naming the compressed representation
+func encodedETag(etag, encoding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// stripETagEncoding removes the suffixes added by encodedETag from the ETags
+// listed in a request header, reporting whether there were any
+func stripETagEncoding(header http.Header, name string) bool {
+	value := header.Get(name)
+	if value == "" {
+		return false
+	}
+
+	stripped := false
+	tags := strings.Split(value, ",")
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		for _, encoding := range []string{encodingBrotli, encodingGzip} {
+			if trimmed, ok := strings.CutSuffix(tag, "-"+encoding+`"`); ok {
+				tag = trimmed + `"`
+				stripped = true
+				break
+			}
+		}
+		tags[i] = tag
+	}
+	if stripped {
+		header.Set(name, strings.Join(tags, ", "))
+	}
+	return stripped
+}
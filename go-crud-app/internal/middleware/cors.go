@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+)
+
+var (
+	// defaultCORSMethods are the allowed methods when CORS_ALLOW_METHODS is unset
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	// defaultCORSHeaders are the allowed headers when CORS_ALLOW_HEADERS is unset
+	defaultCORSHeaders = []string{"Origin", "Content-Type", "Authorization", RequestIDHeader, IdempotencyKeyHeader, CSRFTokenHeader, "If-Match", "If-None-Match"}
+)
+
+// CORSConfigFromEnv builds the CORS configuration from environment variables.
+//
+// CORS_ORIGIN is a comma-separated list of origins. An entry may be "*" to
+// allow any origin, or contain a single "*" wildcard to match subdomains
+// (e.g. "https://*.example.com"). Because browsers reject a wildcard origin
+// with credentials, credentials are dropped (and an error is logged) when
+// "*" is configured.
+func CORSConfigFromEnv() cors.Config {
+	config := cors.Config{
+		AllowMethods:     parseList(os.Getenv("CORS_ALLOW_METHODS"), defaultCORSMethods),
+		AllowHeaders:     parseList(os.Getenv("CORS_ALLOW_HEADERS"), defaultCORSHeaders),
+		ExposeHeaders:    []string{"Content-Length", RequestIDHeader, IdempotentReplayedHeader, CSRFTokenHeader, "ETag", RateLimitWarningHeader},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}
+
+	if value, err := strconv.ParseBool(os.Getenv("CORS_ALLOW_CREDENTIALS")); err == nil {
+		config.AllowCredentials = value
+	}
+
+	var patterns []string
+	for _, origin := range parseList(os.Getenv("CORS_ORIGIN"), []string{"*"}) {
+		switch {
+		case origin == "*":
+			config.AllowAllOrigins = true
+		case strings.Contains(origin, "*"):
+			patterns = append(patterns, origin)
+		default:
+			config.AllowOrigins = append(config.AllowOrigins, origin)
+		}
+	}
+
+	if config.AllowAllOrigins {
+		if config.AllowCredentials {
+			log.Println("ERROR: CORS_ORIGIN=* cannot be combined with credentials; disabling credentials")
+			config.AllowCredentials = false
+		}
+		config.AllowOrigins = nil
+		return config
+	}
+
+	if len(patterns) > 0 {
+		config.AllowOriginFunc = func(origin string) bool {
+			for _, pattern := range patterns {
+				if matchOriginPattern(pattern, origin) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	return config
+}
+
+// matchOriginPattern reports whether origin matches a pattern containing a
+// single "*" wildcard, which must match a non-empty run of host characters
+func matchOriginPattern(pattern, origin string) bool {
+	prefix, suffix, found := strings.Cut(pattern, "*")
+	if !found || len(origin) <= len(prefix)+len(suffix) {
+		return false
+	}
+	if !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+
+	wildcard := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(wildcard, "/:@")
+}
+
+// parseList splits a comma-separated value into trimmed, non-empty entries,
+// returning the default when the value is empty
+func parseList(value string, defaultValue []string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return defaultValue
+	}
+	return items
+}
@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON field naming styles
+const (
+	// FieldNamingSnake keeps the field names handlers write (e.g. created_at)
+	FieldNamingSnake = "snake_case"
+	// FieldNamingCamel rewrites field names to camelCase (e.g. createdAt)
+	FieldNamingCamel = "camelCase"
+)
+
+// fieldNamingKey marks a request whose JSON responses are rewritten to
+// camelCase, so the recovery middleware can answer a panic the same way
+const fieldNamingKey = "field_naming"
+
+// ValidFieldNaming reports whether naming is a supported field naming style
+func ValidFieldNaming(naming string) bool {
+	return naming == FieldNamingSnake || naming == FieldNamingCamel
+}
+
+// FieldNamingMiddleware rewrites the field names of JSON responses to the
+// requested naming style. A client picks the style with a profile parameter
+// in its Accept header (e.g. application/json; profile="camelCase");
+// otherwise defaultNaming applies. Handlers keep writing snake_case, so only
+// camelCase responses are rewritten. Requests to the skipped paths (such as
+// GraphQL, whose field names are chosen by the query) are left alone.
+func FieldNamingMiddleware(defaultNaming string, skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if skip[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept")
+		if negotiateFieldNaming(c.GetHeader("Accept"), defaultNaming) != FieldNamingCamel {
+			c.Next()
+			return
+		}
+
+		c.Set(fieldNamingKey, FieldNamingCamel)
+		w := &camelCaseWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		completed := false
+		defer func() {
+			// A panic drops the buffered output, so an outer middleware can
+			// still answer
+			if completed {
+				w.finish()
+			}
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+		completed = true
+	}
+}
+
+// negotiateFieldNaming returns the naming style requested by an Accept
+// header's profile parameter, or defaultNaming when none is requested
+func negotiateFieldNaming(accept, defaultNaming string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType != "application/json" && mediaType != "*/*" {
+			continue
+		}
+		if profile := params["profile"]; ValidFieldNaming(profile) {
+			return profile
+		}
+	}
+	return defaultNaming
+}
+
+// camelCaseWriter buffers JSON responses so their field names can be
+// rewritten once complete; other responses pass through unchanged
+type camelCaseWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	decided   bool
+	buffering bool // The response is JSON and is being buffered
+}
+
+// Write buffers p if the response is JSON, and writes it through otherwise
+func (w *camelCaseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.decide()
+	}
+	if w.buffering {
+		return w.buf.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// WriteString writes s like Write
+func (w *camelCaseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow is deferred for JSON responses, as their length changes
+func (w *camelCaseWriter) WriteHeaderNow() {
+	if w.decided && !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Written reports whether anything was written, including buffered output
+func (w *camelCaseWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Flush writes out non-JSON responses; JSON can't be rewritten until complete
+func (w *camelCaseWriter) Flush() {
+	if w.decided && !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// decide starts buffering if the response is JSON
+func (w *camelCaseWriter) decide() {
+	w.decided = true
+	mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return
+	}
+	w.buffering = true
+	w.Header().Set("Content-Type", mediaType+`; charset=utf-8; profile="`+FieldNamingCamel+`"`)
+	w.Header().Del("Content-Length")
+}
+
+// finish writes out the rewritten JSON, or just the header if nothing was written
+func (w *camelCaseWriter) finish() {
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	body, err := camelCaseKeys(w.buf.Bytes())
+	if err != nil {
+		// Not valid JSON after all; send it as it was written
+		body = w.buf.Bytes()
+	}
+	w.ResponseWriter.Write(body)
+}
+
+// jsonContainer tracks the position within an object or array while rewriting
+type jsonContainer struct {
+	object  bool
+	keyNext bool // The next token in this object is a key
+	count   int  // Keys or elements written so far
+}
+
+// camelCaseKeys rewrites every object key in data to camelCase, keeping the
+// order of keys and leaving values untouched
+func camelCaseKeys(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var out bytes.Buffer
+	var stack []*jsonContainer
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Closing an object or array completes a value of its parent
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			out.WriteByte(byte(delim))
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 && stack[len(stack)-1].object {
+				stack[len(stack)-1].keyNext = true
+			}
+			continue
+		}
+
+		var parent *jsonContainer
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+			if !parent.object || parent.keyNext {
+				if parent.count > 0 {
+					out.WriteByte(',')
+				}
+				parent.count++
+			}
+		}
+
+		if parent != nil && parent.object && parent.keyNext {
+			key, err := json.Marshal(camelCase(tok.(string)))
+			if err != nil {
+				return nil, err
+			}
+			out.Write(key)
+			out.WriteByte(':')
+			parent.keyNext = false
+			continue
+		}
+
+		if delim, ok := tok.(json.Delim); ok {
+			out.WriteByte(byte(delim))
+			stack = append(stack, &jsonContainer{object: delim == '{', keyNext: delim == '{'})
+			continue
+		}
+		value, err := json.Marshal(tok)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(value)
+		if parent != nil && parent.object {
+			parent.keyNext = true
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// camelCase converts a snake_case name to camelCase (created_at -> createdAt)
+func camelCase(name string) string {
+	if !strings.Contains(strings.Trim(name, "_"), "_") {
+		return name
+	}
+	parts := strings.Split(name, "_")
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 && part != "" {
+			part = strings.ToUpper(part[:1]) + part[1:]
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
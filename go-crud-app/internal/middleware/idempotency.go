@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"go-crud-app/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client's idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the idempotency store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyTTL is how long a stored response is replayed for by default
+	DefaultIdempotencyTTL = 15 * time.Minute
+)
+
+// idempotencyKeyRegex limits keys to a safe charset and length (UUIDs fit comfortably)
+var idempotencyKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9._:\-]{1,255}$`)
+
+// idempotentResponse is a stored response, or a placeholder while the first
+// request for the key is still being processed
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header // Headers set while the request was processed
+	body        []byte
+	expiresAt   time.Time
+}
+
+// IdempotencyStore keeps responses keyed by route and Idempotency-Key in memory
+type IdempotencyStore struct {
+	responses map[string]*idempotentResponse
+	mu        sync.Mutex
+	ttl       time.Duration
+}
+
+// NewIdempotencyStore creates a store that replays responses for ttl. Run
+// Cleanup in the background (typically via the supervisor) to prune expired entries.
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		responses: make(map[string]*idempotentResponse),
+		ttl:       ttl,
+	}
+}
+
+// Cleanup removes expired entries every minute until ctx is cancelled
+func (s *IdempotencyStore) Cleanup(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		now := time.Now()
+		for key, resp := range s.responses {
+			if resp.done && now.After(resp.expiresAt) {
+				delete(s.responses, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// begin returns the stored response for key, or reserves the key and returns
+// nil when the request should be processed
+func (s *IdempotencyStore) begin(key string, fingerprint [sha256.Size]byte) *idempotentResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if resp, ok := s.responses[key]; ok && (!resp.done || time.Now().Before(resp.expiresAt)) {
+		return resp
+	}
+	s.responses[key] = &idempotentResponse{fingerprint: fingerprint}
+	return nil
+}
+
+// finish stores the response for key, or releases the key so the request can
+// be retried when the response shouldn't be replayed
+func (s *IdempotencyStore) finish(key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp, ok := s.responses[key]
+	if !ok {
+		return
+	}
+
+	// Server errors, timeouts and rate limiting are transient; let a retry reprocess them
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		delete(s.responses, key)
+		return
+	}
+
+	resp.done = true
+	resp.status = status
+	resp.header = header
+	resp.body = body
+	resp.expiresAt = time.Now().Add(s.ttl)
+}
+
+// release forgets a reserved key so the request can be retried
+func (s *IdempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.responses, key)
+}
+
+// IdempotencyMiddleware replays the stored response when a request is retried
+// with the same Idempotency-Key header, instead of processing it again. Keys
+// are scoped to the method and route, and bound to the request body and query
+// string: reusing a key with a different request (such as a validate_only
+// registration followed by the real one) is rejected rather than replayed. Requests without
+// the header are processed normally. A replay carries the headers set while
+// the original request was processed, such as Location, Set-Cookie and the
+// CSRF token; headers set before it, such as the request ID, are the retry's.
+func IdempotencyMiddleware(store *IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+		if !idempotencyKeyRegex.MatchString(idempotencyKey) {
+			abortWithError(c, http.StatusBadRequest, apierror.CodeBadRequest,
+				"Idempotency-Key must be 1-255 letters, digits or . _ : - characters")
+			return
+		}
+
+		// Buffer the body to fingerprint it, then hand the handler an identical
+		// reader. A read failure (e.g. body too large) is passed on to the
+		// handler, and such requests are never stored.
+		body, readErr := io.ReadAll(c.Request.Body)
+		if readErr != nil {
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errorReader{readErr}))
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := c.Request.Method + " " + c.FullPath() + " " + idempotencyKey
+		fingerprint := sha256.Sum256(append([]byte(c.Request.URL.RawQuery+"?"), body...))
+
+		if stored := store.begin(key, fingerprint); stored != nil {
+			switch {
+			case stored.fingerprint != fingerprint:
+				abortWithError(c, http.StatusUnprocessableEntity, apierror.CodeIdempotencyMismatch,
+					"Idempotency-Key was already used with a different request")
+			case !stored.done:
+				abortWithError(c, http.StatusConflict, apierror.CodeIdempotencyConflict,
+					"A request with this Idempotency-Key is still being processed")
+			default:
+				header := c.Writer.Header()
+				for name, values := range stored.header {
+					header[name] = slices.Clone(values)
+				}
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(stored.status, stored.header.Get("Content-Type"), stored.body)
+				c.Abort()
+			}
+			return
+		}
+
+		before := c.Writer.Header().Clone()
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			// A panic or a response left for an outer middleware to write
+			// (e.g. a timeout) releases the key instead of storing it
+			if !completed || !writer.Written() {
+				store.release(key)
+				return
+			}
+			store.finish(key, writer.Status(), addedHeaders(before, writer.Header()), writer.body.Bytes())
+		}()
+
+		c.Next()
+		completed = true
+	}
+}
+
+// addedHeaders returns the headers in after that aren't the same in before.
+// Content-Length is left out, as it is set again for the replayed body.
+func addedHeaders(before, after http.Header) http.Header {
+	added := make(http.Header)
+	for name, values := range after {
+		if name != "Content-Length" && !slices.Equal(values, before[name]) {
+			added[name] = slices.Clone(values)
+		}
+	}
+	return added
+}
+
+// capturingWriter records the response body while writing it through
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write records and writes b
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString records and writes s
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// errorReader returns err from every read
+type errorReader struct {
+	err error
+}
+
+// Read returns the stored error
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
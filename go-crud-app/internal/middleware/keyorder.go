@@ -0,0 +1,44 @@
+package middleware
+
+import "container/list"
+
+// keyOrder tracks limiter keys from most to least recently used, so a
+// limiter can evict the least recently used client once it holds too many
+type keyOrder struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newKeyOrder() *keyOrder {
+	return &keyOrder{order: list.New(), elements: make(map[string]*list.Element)}
+}
+
+// touch marks key as the most recently used, adding it if it is new
+func (o *keyOrder) touch(key string) {
+	if element, ok := o.elements[key]; ok {
+		o.order.MoveToFront(element)
+		return
+	}
+	o.elements[key] = o.order.PushFront(key)
+}
+
+// remove forgets key
+func (o *keyOrder) remove(key string) {
+	if element, ok := o.elements[key]; ok {
+		o.order.Remove(element)
+		delete(o.elements, key)
+	}
+}
+
+// evictOver removes least recently used keys until at most max remain,
+// calling evict for each; max <= 0 means no limit
+func (o *keyOrder) evictOver(max int, evict func(key string)) {
+	if max <= 0 {
+		return
+	}
+	for o.order.Len() > max {
+		key := o.order.Remove(o.order.Back()).(string)
+		delete(o.elements, key)
+		evict(key)
+	}
+}
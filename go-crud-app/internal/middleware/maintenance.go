@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceMiddleware rejects requests with 503 and a Retry-After header
+// while maintenance mode is enabled. Paths in exempt (e.g. health checks) are
+// always served.
+func MaintenanceMiddleware(enabled bool, retryAfter time.Duration, exempt ...string) gin.HandlerFunc {
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if !enabled || exemptPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		setRetryAfter(c, retryAfter)
+		abortWithError(c, http.StatusServiceUnavailable, apierror.CodeServiceUnavailable, "Service is under maintenance. Please try again later.")
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-crud-app/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	httpRequests = metrics.DefaultRegistry.NewCounterVec(
+		"http_requests", "Total number of HTTP requests.", "",
+		"method", "route", "status",
+	)
+	httpRequestDuration = metrics.DefaultRegistry.NewHistogramVec(
+		"http_request_duration_seconds", "HTTP request latency in seconds.", "seconds",
+		metrics.DefaultDurationBuckets, "method", "route",
+	)
+)
+
+// MetricsMiddleware records request counts and latencies. Requests are
+// labelled by route template (not raw path) to keep cardinality bounded, and
+// latency observations carry the request ID as an OpenMetrics exemplar. A
+// request that panics is counted as the 500 the recovery middleware answers
+// it with once this one has unwound.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		completed := false
+		defer func() {
+			route := c.FullPath()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			var exemplar map[string]string
+			if requestID := GetRequestID(c); requestID != "" {
+				exemplar = map[string]string{"request_id": requestID}
+			}
+
+			status := c.Writer.Status()
+			if !completed {
+				status = http.StatusInternalServerError
+			}
+			httpRequests.Inc(c.Request.Method, route, strconv.Itoa(status))
+			httpRequestDuration.ObserveWithExemplar(time.Since(start).Seconds(), exemplar, c.Request.Method, route)
+		}()
+
+		c.Next()
+		completed = true
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-crud-app/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// passwordChangeRequiredKey marks requests from users who must change a
+// temporary password before using the API
+const passwordChangeRequiredKey = "password_change_required"
+
+// passwordExpiredKey marks forced password changes due to password expiry
+const passwordExpiredKey = "password_expired"
+
+// PasswordExpiredMessage is shown to users whose password has expired
+const PasswordExpiredMessage = "Your password has expired; change it before continuing"
+
+// PasswordChangeRequired reports whether the authenticated user must change
+// their password before using the API
+func PasswordChangeRequired(c *gin.Context) bool {
+	return c.GetBool(passwordChangeRequiredKey)
+}
+
+// PasswordChangeGate rejects requests from users with a pending forced
+// password change or an expired password with 403, except on the routes in
+// allowed (matched against the route pattern, e.g. the password change route
+// itself). It must run after AuthMiddleware.
+func PasswordChangeGate(allowed ...string) gin.HandlerFunc {
+	allowedRoutes := make(map[string]bool, len(allowed))
+	for _, route := range allowed {
+		allowedRoutes[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if !PasswordChangeRequired(c) || allowedRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		message := "You must change your password before continuing"
+		if c.GetBool(passwordExpiredKey) {
+			message = PasswordExpiredMessage
+		}
+		abortWithError(c, http.StatusForbidden, apierror.CodePasswordChangeRequired, message)
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrustedProxiesFromEnv returns the proxy IPs and CIDRs listed in the
+// comma-separated TRUSTED_PROXIES variable, or nil when it is unset
+func TrustedProxiesFromEnv() []string {
+	return parseList(os.Getenv("TRUSTED_PROXIES"), nil)
+}
+
+// ConfigureTrustedProxies makes c.ClientIP() honor X-Forwarded-For and
+// X-Real-IP only on requests arriving from one of proxies. With no proxies the
+// headers are ignored and the client IP is always the connection's remote
+// address, so clients cannot choose their own rate-limit key by forging them.
+func ConfigureTrustedProxies(router *gin.Engine, proxies []string) error {
+	return router.SetTrustedProxies(proxies)
+}
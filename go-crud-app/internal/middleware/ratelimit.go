@@ -1,109 +1,196 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"go-crud-app/internal/apierror"
+
 	"github.com/gin-gonic/gin"
 )
 
-// RateLimiter implements a simple in-memory rate limiter
+// Limiter decides whether the client identified by key may make a request
+type Limiter interface {
+	// AllowWithRetry reports whether the request is allowed and, when it
+	// isn't, how long the client should wait before retrying
+	AllowWithRetry(key string) (bool, time.Duration)
+	// AllowWithUsage is AllowWithRetry that also reports how much of the
+	// client's quota is used once the request is counted, from 0 to 1
+	AllowWithUsage(key string) (bool, time.Duration, float64)
+	// Cleanup prunes stale state until ctx is cancelled
+	Cleanup(ctx context.Context)
+}
+
+// DefaultCleanupInterval is how often the rate limiters prune stale state
+const DefaultCleanupInterval = 1 * time.Minute
+
+// RateLimitWarningHeader warns a client that it is close to being rate limited
+const RateLimitWarningHeader = "X-RateLimit-Warning"
+
+// RateLimitWarningPercent is the share of a client's quota, from 1 to 100,
+// at which allowed requests get a RateLimitWarningHeader so well-behaved
+// clients can slow down before they are blocked; 0 disables warnings
+var RateLimitWarningPercent = 0
+
+// RateLimitClock returns the current time used by the rate limiters
+var RateLimitClock = time.Now
+
+// DefaultRateLimitMaxKeys is how many clients each rate limiter tracks before
+// it evicts the least recently used one
+const DefaultRateLimitMaxKeys = 100000
+
+// slidingWindow holds the times of one client's requests in the current
+// window in a ring buffer sized to the limit, so it never grows however many
+// requests the client makes
+type slidingWindow struct {
+	times []time.Time
+	start int // Index of the oldest request
+	count int
+}
+
+// prune drops requests that have left the window
+func (w *slidingWindow) prune(now time.Time, window time.Duration) {
+	for w.count > 0 && now.Sub(w.times[w.start]) >= window {
+		w.start = (w.start + 1) % len(w.times)
+		w.count--
+	}
+}
+
+// RateLimiter implements a simple in-memory sliding window rate limiter
 type RateLimiter struct {
-	requests map[string][]time.Time
+	requests map[string]*slidingWindow
+	keys     *keyOrder
 	mu       sync.Mutex
 	limit    int
 	window   time.Duration
+	// CleanupInterval is how often Cleanup prunes stale entries; set it
+	// before starting Cleanup
+	CleanupInterval time.Duration
+	// MaxKeys caps how many clients are tracked; past it the least recently
+	// seen client is forgotten, which bounds memory when a flood of distinct
+	// IPs arrives between cleanups. 0 means no cap.
+	MaxKeys int
 }
 
-// NewRateLimiter creates a new rate limiter
+// NewRateLimiter creates a new rate limiter. Run Cleanup in the background
+// (typically via the supervisor, which cancels it on shutdown) to prune
+// stale entries.
 func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		requests: make(map[string][]time.Time),
-		limit:    limit,
-		window:   window,
+	return &RateLimiter{
+		requests:        make(map[string]*slidingWindow),
+		keys:            newKeyOrder(),
+		limit:           limit,
+		window:          window,
+		CleanupInterval: DefaultCleanupInterval,
+		MaxKeys:         DefaultRateLimitMaxKeys,
 	}
-
-	// Cleanup old entries every minute
-	go rl.cleanup()
-
-	return rl
 }
 
-// cleanup removes old entries from the rate limiter
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(1 * time.Minute)
+// Cleanup removes old entries from the rate limiter every CleanupInterval
+// until ctx is cancelled
+func (rl *RateLimiter) Cleanup(ctx context.Context) {
+	ticker := time.NewTicker(rl.CleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		rl.mu.Lock()
-		now := time.Now()
-		for key, times := range rl.requests {
-			// Remove timestamps older than the window
-			validTimes := []time.Time{}
-			for _, t := range times {
-				if now.Sub(t) < rl.window {
-					validTimes = append(validTimes, t)
-				}
-			}
-			if len(validTimes) == 0 {
+		now := RateLimitClock()
+		for key, requests := range rl.requests {
+			requests.prune(now, rl.window)
+			if requests.count == 0 {
 				delete(rl.requests, key)
-			} else {
-				rl.requests[key] = validTimes
+				rl.keys.remove(key)
 			}
 		}
 		rl.mu.Unlock()
 	}
 }
 
+// Len returns how many clients the limiter is tracking
+func (rl *RateLimiter) Len() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return len(rl.requests)
+}
+
 // Allow checks if a request should be allowed
 func (rl *RateLimiter) Allow(key string) bool {
+	allowed, _ := rl.AllowWithRetry(key)
+	return allowed
+}
+
+// AllowWithRetry checks if a request should be allowed and, when it isn't,
+// how long until the oldest request leaves the window
+func (rl *RateLimiter) AllowWithRetry(key string) (bool, time.Duration) {
+	allowed, retryAfter, _ := rl.AllowWithUsage(key)
+	return allowed, retryAfter
+}
+
+// AllowWithUsage is AllowWithRetry that also reports the share of the limit
+// used by requests in the window, including this one
+func (rl *RateLimiter) AllowWithUsage(key string) (bool, time.Duration, float64) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	now := time.Now()
+	now := RateLimitClock()
 
-	// Get existing requests for this key
-	times, exists := rl.requests[key]
+	requests, exists := rl.requests[key]
 	if !exists {
-		rl.requests[key] = []time.Time{now}
-		return true
+		requests = &slidingWindow{times: make([]time.Time, rl.limit)}
+		rl.requests[key] = requests
 	}
-
-	// Filter out old requests
-	validTimes := []time.Time{}
-	for _, t := range times {
-		if now.Sub(t) < rl.window {
-			validTimes = append(validTimes, t)
-		}
+	rl.keys.touch(key)
+	if !exists {
+		rl.keys.evictOver(rl.MaxKeys, func(evicted string) { delete(rl.requests, evicted) })
 	}
 
+	requests.prune(now, rl.window)
+
 	// Check if limit exceeded
-	if len(validTimes) >= rl.limit {
-		return false
+	if requests.count >= rl.limit {
+		return false, requests.times[requests.start].Add(rl.window).Sub(now), 1
 	}
 
 	// Add current request
-	validTimes = append(validTimes, now)
-	rl.requests[key] = validTimes
+	requests.times[(requests.start+requests.count)%len(requests.times)] = now
+	requests.count++
 
-	return true
+	return true, 0, float64(requests.count) / float64(rl.limit)
 }
 
 // RateLimitMiddleware creates a rate limiting middleware
-func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+func RateLimitMiddleware(limiter Limiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Use IP address as the key
 		key := c.ClientIP()
 
-		if !limiter.Allow(key) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded. Please try again later.",
-			})
-			c.Abort()
+		allowed, retryAfter, usage := limiter.AllowWithUsage(key)
+		if !allowed {
+			setRetryAfter(c, retryAfter)
+			abortWithError(c, http.StatusTooManyRequests, apierror.CodeRateLimited, "Rate limit exceeded. Please try again later.")
 			return
 		}
+		if RateLimitWarningPercent > 0 && reachesPercent(usage, RateLimitWarningPercent) {
+			c.Header(RateLimitWarningHeader, fmt.Sprintf("%d%% of the rate limit used", int(usage*100+usageEpsilon)))
+		}
 
 		c.Next()
 	}
 }
+
+// usageEpsilon absorbs floating-point error in usage ratios such as 4/5
+const usageEpsilon = 1e-9
+
+// reachesPercent reports whether usage, from 0 to 1, is at least percent
+func reachesPercent(usage float64, percent int) bool {
+	return usage*100+usageEpsilon >= float64(percent)
+}
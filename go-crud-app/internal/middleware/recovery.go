@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"go-crud-app/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InternalErrorMessage is the error returned for a recovered panic
+const InternalErrorMessage = "Internal server error"
+
+// RecoveryMiddleware recovers from panics in later handlers, logs the panic
+// with the request ID and stack trace, and answers with a 500 in the standard
+// error envelope. The stack is only included in the response when
+// exposeStack is set, which is meant for development.
+func RecoveryMiddleware(exposeStack bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// net/http uses this panic to abort a response on purpose
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			stack := debug.Stack()
+			log.Printf("Panic recovered: request_id=%s method=%s path=%s error=%v\n%s",
+				GetRequestID(c), c.Request.Method, c.Request.URL.Path, recovered, stack)
+
+			// A client that went away can't be answered
+			if brokenConnection(recovered) {
+				c.Abort()
+				return
+			}
+			// Part of the response may already be on its way; it can't be replaced
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			// Drop headers describing a response that was never sent
+			header := c.Writer.Header()
+			for _, name := range []string{"Content-Type", "Content-Encoding", "Content-Length", "ETag"} {
+				header.Del(name)
+			}
+			body := apierror.Body{
+				Code:      apierror.CodeInternal,
+				Message:   InternalErrorMessage,
+				RequestID: ErrorRequestID(c),
+			}
+			if exposeStack {
+				body.Stack = string(stack)
+			}
+			resp := apierror.Response{Error: LocalizeError(c, body)}
+
+			// The panic unwound the field naming middleware, so its naming
+			// is applied here
+			if c.GetString(fieldNamingKey) == FieldNamingCamel {
+				if data, err := json.Marshal(resp); err == nil {
+					if data, err = camelCaseKeys(data); err == nil {
+						c.Abort()
+						c.Data(http.StatusInternalServerError, `application/json; charset=utf-8; profile="`+FieldNamingCamel+`"`, data)
+						return
+					}
+				}
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, resp)
+		}()
+
+		c.Next()
+	}
+}
+
+// brokenConnection reports whether a panic was caused by writing to a
+// connection the client has closed
+func brokenConnection(recovered any) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	message := strings.ToLower(syscallErr.Error())
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
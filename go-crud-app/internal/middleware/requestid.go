@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"go-crud-app/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the header used to read and propagate the request ID
+const RequestIDHeader = "X-Request-ID"
+
+// IncludeRequestIDInErrors controls whether error response bodies carry the request ID
+var IncludeRequestIDInErrors = true
+
+// requestIDRegex limits client-supplied request IDs to a safe charset and length
+var requestIDRegex = regexp.MustCompile(`^[a-zA-Z0-9._\-]{1,128}$`)
+
+// RequestIDMiddleware assigns a request ID to every request, reusing a valid
+// client-supplied X-Request-ID header or otherwise the trace ID (when the
+// request is traced) or a new random ID. The ID is recorded on the request's
+// span so logs and traces can be correlated.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		span := trace.SpanFromContext(c.Request.Context())
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDRegex.MatchString(requestID) {
+			if spanContext := span.SpanContext(); spanContext.HasTraceID() {
+				requestID = spanContext.TraceID().String()
+			} else {
+				requestID = newRequestID()
+			}
+		}
+		span.SetAttributes(attribute.String("request.id", requestID))
+
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// GetRequestID retrieves the request ID from the context
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+// ErrorRequestID returns the request ID to embed in error responses, or an
+// empty string when disabled
+func ErrorRequestID(c *gin.Context) string {
+	if !IncludeRequestIDInErrors {
+		return ""
+	}
+	return GetRequestID(c)
+}
+
+// abortWithError aborts the request with the standard JSON error envelope
+func abortWithError(c *gin.Context, status int, code, message string) {
+	// A failure caused by the request deadline is reported as a timeout
+	if TimedOut(c) {
+		status, code, message = http.StatusGatewayTimeout, apierror.CodeTimeout, TimeoutMessage
+	}
+
+	c.AbortWithStatusJSON(status, apierror.Response{Error: LocalizeError(c, apierror.Body{
+		Code:      code,
+		Message:   message,
+		RequestID: ErrorRequestID(c),
+	})})
+}
+
+// newRequestID generates a random 16-byte hex request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
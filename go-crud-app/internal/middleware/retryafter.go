@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetryAfterFormat selects how the Retry-After header is formatted
+type RetryAfterFormat string
+
+const (
+	// RetryAfterSeconds formats Retry-After as delta-seconds (e.g. "120")
+	RetryAfterSeconds RetryAfterFormat = "seconds"
+	// RetryAfterHTTPDate formats Retry-After as an HTTP-date (e.g. "Wed, 21 Oct 2015 07:28:00 GMT")
+	RetryAfterHTTPDate RetryAfterFormat = "http-date"
+)
+
+// RetryAfterMode is the format used for Retry-After headers on 429 and 503 responses
+var RetryAfterMode = RetryAfterSeconds
+
+// FormatRetryAfter formats a retry delay from now as a Retry-After header value.
+// Delta-seconds are rounded up so clients never retry early.
+func FormatRetryAfter(format RetryAfterFormat, now time.Time, delay time.Duration) string {
+	if delay < 0 {
+		delay = 0
+	}
+
+	if format == RetryAfterHTTPDate {
+		return now.Add(delay).UTC().Format(http.TimeFormat)
+	}
+	return strconv.FormatInt(int64(math.Ceil(delay.Seconds())), 10)
+}
+
+// setRetryAfter sets the Retry-After header using the configured format
+func setRetryAfter(c *gin.Context, delay time.Duration) {
+	c.Header("Retry-After", FormatRetryAfter(RetryAfterMode, time.Now(), delay))
+}
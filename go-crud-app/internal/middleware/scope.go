@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"go-crud-app/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scopesContextKey holds the scopes of the request's access token
+const scopesContextKey = "scopes"
+
+// HasScope reports whether the request's access token or API key was granted
+// scope. Tokens issued before scopes existed are granted every scope.
+func HasScope(c *gin.Context, scope string) bool {
+	if key, ok := GetAPIKey(c); ok {
+		return key.HasScope(scope)
+	}
+	scopes, _ := c.Get(scopesContextKey)
+	granted, _ := scopes.([]string)
+	return granted == nil || slices.Contains(granted, scope)
+}
+
+// RequireScope restricts a route to access tokens and API keys granted
+// scope, answering others with a 403 and an RFC 6750 insufficient_scope
+// challenge. It must run after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasScope(c, scope) {
+			scheme := "Bearer"
+			if _, ok := GetAPIKey(c); ok {
+				scheme = APIKeyScheme
+			}
+			c.Header("WWW-Authenticate", fmt.Sprintf(`%s error="insufficient_scope", scope=%q`, scheme, scope))
+			abortWithError(c, http.StatusForbidden, apierror.CodeInsufficientScope, fmt.Sprintf("The %s scope is required", scope))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"go-crud-app/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceKeysFromEnv returns the service keys listed in the comma-separated
+// INTROSPECTION_API_KEYS variable, or nil when it is unset
+func ServiceKeysFromEnv() []string {
+	return parseList(os.Getenv("INTROSPECTION_API_KEYS"), nil)
+}
+
+// RequireServiceKey only lets through requests whose X-API-Key header holds
+// one of keys, answering others with 401. Keys are compared as SHA-256
+// digests in constant time, so response timing doesn't reveal how much of a
+// guess was right.
+func RequireServiceKey(keys []string) gin.HandlerFunc {
+	digests := make([][sha256.Size]byte, len(keys))
+	for i, key := range keys {
+		digests[i] = sha256.Sum256([]byte(key))
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		digest := sha256.Sum256([]byte(key))
+
+		match := 0
+		for _, allowed := range digests {
+			match |= subtle.ConstantTimeCompare(digest[:], allowed[:])
+		}
+		if key == "" || match != 1 {
+			abortWithError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "A valid "+APIKeyHeader+" header is required")
+			return
+		}
+
+		c.Next()
+	}
+}
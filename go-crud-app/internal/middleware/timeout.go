@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go-crud-app/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultRequestTimeout is the default per-request deadline
+	DefaultRequestTimeout = 10 * time.Second
+	// TimeoutMessage is the error returned when a request exceeds its deadline
+	TimeoutMessage = "Request timed out"
+)
+
+// TimeoutMiddleware attaches a deadline to the request context. Handlers must
+// pass c.Request.Context() down (e.g. DB.WithContext) so that slow queries are
+// cancelled; if the deadline passes before a response is written, a 504 is
+// returned. A timeout of 0 disables the middleware.
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if TimedOut(c) && !c.Writer.Written() {
+			abortWithError(c, http.StatusGatewayTimeout, apierror.CodeTimeout, TimeoutMessage)
+		}
+	}
+}
+
+// TimedOut reports whether the request's deadline has passed
+func TimedOut(c *gin.Context) bool {
+	return errors.Is(c.Request.Context().Err(), context.DeadlineExceeded)
+}
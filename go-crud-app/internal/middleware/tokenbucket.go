@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket is the state of one client's bucket
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucketLimiter implements an in-memory token bucket rate limiter. Each
+// client starts with a full bucket of burst tokens, spends one per request and
+// regains one every refillEvery, so short bursts are allowed while the
+// long-run rate stays at one request per refillEvery.
+type TokenBucketLimiter struct {
+	buckets     map[string]*tokenBucket
+	keys        *keyOrder
+	mu          sync.Mutex
+	burst       int
+	refillEvery time.Duration
+	// CleanupInterval is how often Cleanup prunes idle buckets; set it
+	// before starting Cleanup
+	CleanupInterval time.Duration
+	// MaxKeys caps how many buckets are kept, evicting the least recently
+	// used; an evicted client starts again with a full bucket. 0 means no cap.
+	MaxKeys int
+}
+
+// NewTokenBucketLimiter creates a token bucket limiter holding up to burst
+// tokens and refilling one every refillEvery; both must be positive. Run
+// Cleanup in the background (typically via the supervisor, which cancels it
+// on shutdown) to prune idle buckets.
+func NewTokenBucketLimiter(burst int, refillEvery time.Duration) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		buckets:         make(map[string]*tokenBucket),
+		keys:            newKeyOrder(),
+		burst:           burst,
+		refillEvery:     refillEvery,
+		CleanupInterval: DefaultCleanupInterval,
+		MaxKeys:         DefaultRateLimitMaxKeys,
+	}
+}
+
+// Cleanup removes buckets that have refilled completely every CleanupInterval
+// until ctx is cancelled; a full bucket is the same as no bucket
+func (tb *TokenBucketLimiter) Cleanup(ctx context.Context) {
+	ticker := time.NewTicker(tb.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		tb.mu.Lock()
+		now := RateLimitClock()
+		for key, bucket := range tb.buckets {
+			if tb.refill(bucket, now) >= float64(tb.burst) {
+				delete(tb.buckets, key)
+				tb.keys.remove(key)
+			}
+		}
+		tb.mu.Unlock()
+	}
+}
+
+// AllowWithRetry checks if a request should be allowed and, when it isn't,
+// how long until the next token is available
+func (tb *TokenBucketLimiter) AllowWithRetry(key string) (bool, time.Duration) {
+	allowed, retryAfter, _ := tb.AllowWithUsage(key)
+	return allowed, retryAfter
+}
+
+// AllowWithUsage is AllowWithRetry that also reports the share of the bucket
+// spent once this request takes its token
+func (tb *TokenBucketLimiter) AllowWithUsage(key string) (bool, time.Duration, float64) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := RateLimitClock()
+
+	bucket, exists := tb.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(tb.burst), last: now}
+		tb.buckets[key] = bucket
+	}
+	tb.keys.touch(key)
+	if !exists {
+		tb.keys.evictOver(tb.MaxKeys, func(evicted string) { delete(tb.buckets, evicted) })
+	}
+
+	if tokens := tb.refill(bucket, now); tokens < 1 {
+		return false, time.Duration((1 - tokens) * float64(tb.refillEvery)), 1
+	}
+
+	bucket.tokens--
+	return true, 0, 1 - bucket.tokens/float64(tb.burst)
+}
+
+// refill adds the tokens earned since the bucket was last updated, capped at
+// the burst size, and returns the new token count
+func (tb *TokenBucketLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += float64(elapsed) / float64(tb.refillEvery)
+		if bucket.tokens > float64(tb.burst) {
+			bucket.tokens = float64(tb.burst)
+		}
+		bucket.last = now
+	}
+	return bucket.tokens
+}
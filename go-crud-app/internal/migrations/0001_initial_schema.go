@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// Table shapes as of the initial schema
+
+type user0001 struct {
+	ID            uint   `gorm:"primarykey"`
+	Username      string `gorm:"uniqueIndex;not null;size:50"`
+	Email         string `gorm:"uniqueIndex;not null;size:100"`
+	PasswordHash  string `gorm:"not null;size:255"`
+	Role          string `gorm:"not null;size:20;default:user"`
+	TokenVersion  int    `gorm:"not null;default:0"`
+	RetentionDays *int
+	LastLoginAt   *time.Time
+	AvatarKey     string `gorm:"size:255"`
+	AvatarURL     string `gorm:"size:255"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+}
+
+func (user0001) TableName() string { return "users" }
+
+type passwordResetToken0001 struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"index;not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (passwordResetToken0001) TableName() string { return "password_reset_tokens" }
+
+type authIdentity0001 struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"uniqueIndex:idx_auth_identity_user_provider;not null"`
+	Provider  string `gorm:"uniqueIndex:idx_auth_identity_user_provider;not null;size:50"`
+	Subject   string `gorm:"not null;size:255"`
+	CreatedAt time.Time
+}
+
+func (authIdentity0001) TableName() string { return "auth_identities" }
+
+type auditLog0001 struct {
+	ID        uint      `gorm:"primarykey"`
+	ActorID   *uint     `gorm:"index"`
+	Action    string    `gorm:"index;not null;size:50"`
+	Target    string    `gorm:"size:255"`
+	IP        string    `gorm:"size:45"`
+	UserAgent string    `gorm:"size:255"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (auditLog0001) TableName() string { return "audit_logs" }
+
+// initialSchema creates the users table and its companion tables. It uses
+// AutoMigrate so databases created before versioned migrations existed are
+// brought up to date rather than failing on existing tables.
+func initialSchema() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0001_initial_schema",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&user0001{}, &passwordResetToken0001{}, &authIdentity0001{}, &auditLog0001{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&auditLog0001{}, &authIdentity0001{}, &passwordResetToken0001{}, &user0001{})
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0002 narrows the username and email unique indexes to live rows
+type user0002 struct {
+	ID        uint           `gorm:"primarykey"`
+	Username  string         `gorm:"uniqueIndex:idx_users_username,where:deleted_at IS NULL;not null;size:50"`
+	Email     string         `gorm:"uniqueIndex:idx_users_email,where:deleted_at IS NULL;not null;size:100"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (user0002) TableName() string { return "users" }
+
+// userUniqueIndexes are the indexes rebuilt by partialUserUniqueIndexes
+var userUniqueIndexes = []string{"idx_users_username", "idx_users_email"}
+
+// partialUserUniqueIndexes makes the username and email unique indexes skip
+// soft-deleted rows, so a deleted account no longer holds on to its username
+// and email. Rolling back fails if a live and a deleted row share either.
+func partialUserUniqueIndexes() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0002_partial_user_unique_indexes",
+		Migrate: func(tx *gorm.DB) error {
+			return rebuildIndexes(tx, &user0001{}, &user0002{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return rebuildIndexes(tx, &user0002{}, &user0001{})
+		},
+	}
+}
+
+// rebuildIndexes drops the user unique indexes as declared by from and
+// recreates them as declared by to
+func rebuildIndexes(tx *gorm.DB, from, to interface{}) error {
+	migrator := tx.Migrator()
+	for _, name := range userUniqueIndexes {
+		if err := migrator.DropIndex(from, name); err != nil {
+			return err
+		}
+		if err := migrator.CreateIndex(to, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
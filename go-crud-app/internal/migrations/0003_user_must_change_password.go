@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0003 adds the forced password change flag
+type user0003 struct {
+	MustChangePassword bool `gorm:"not null;default:false"`
+}
+
+func (user0003) TableName() string { return "users" }
+
+// userMustChangePassword adds users.must_change_password, set when an admin
+// creates an account with a temporary password
+func userMustChangePassword() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0003_user_must_change_password",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&user0003{}, "MustChangePassword")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// Plain DROP COLUMN; GORM's SQLite migrator rebuilds the table
+			// and would lose the partial unique indexes
+			return tx.Exec("ALTER TABLE users DROP COLUMN must_change_password").Error
+		},
+	}
+}
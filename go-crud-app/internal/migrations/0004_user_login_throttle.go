@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0004 adds the failed login tracking columns
+type user0004 struct {
+	FailedLoginAttempts int `gorm:"not null;default:0"`
+	NextLoginAllowedAt  *time.Time
+}
+
+func (user0004) TableName() string { return "users" }
+
+// userLoginThrottle adds users.failed_login_attempts and
+// users.next_login_allowed_at for throttling repeated failed logins
+func userLoginThrottle() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0004_user_login_throttle",
+		Migrate: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.AddColumn(&user0004{}, "FailedLoginAttempts"); err != nil {
+				return err
+			}
+			return migrator.AddColumn(&user0004{}, "NextLoginAllowedAt")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// Plain DROP COLUMN, as in userMustChangePassword
+			if err := tx.Exec("ALTER TABLE users DROP COLUMN next_login_allowed_at").Error; err != nil {
+				return err
+			}
+			return tx.Exec("ALTER TABLE users DROP COLUMN failed_login_attempts").Error
+		},
+	}
+}
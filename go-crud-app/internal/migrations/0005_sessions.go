@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type session0005 struct {
+	ID           uint      `gorm:"primarykey"`
+	UserID       uint      `gorm:"index;not null"`
+	JTI          string    `gorm:"uniqueIndex;not null;size:64"`
+	TokenVersion int       `gorm:"not null;default:0"`
+	UserAgent    string    `gorm:"size:255"`
+	IP           string    `gorm:"size:45"`
+	IssuedAt     time.Time `gorm:"not null"`
+	LastSeenAt   time.Time `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	RevokedAt    *time.Time
+}
+
+func (session0005) TableName() string { return "sessions" }
+
+// sessions creates the sessions table recording each login's device
+func sessions() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0005_sessions",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&session0005{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&session0005{})
+		},
+	}
+}
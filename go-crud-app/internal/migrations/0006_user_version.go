@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0006 adds the optimistic locking version
+type user0006 struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (user0006) TableName() string { return "users" }
+
+// userVersion adds users.version, bumped on every profile change so
+// concurrent updates can detect each other
+func userVersion() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0006_user_version",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&user0006{}, "Version")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// Plain DROP COLUMN, as in userMustChangePassword
+			return tx.Exec("ALTER TABLE users DROP COLUMN version").Error
+		},
+	}
+}
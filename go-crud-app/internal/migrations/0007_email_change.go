@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0007 adds the email address awaiting confirmation
+type user0007 struct {
+	PendingEmail string `gorm:"size:100"`
+}
+
+func (user0007) TableName() string { return "users" }
+
+type emailChangeToken0007 struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"index;not null"`
+	Email     string    `gorm:"not null;size:100"`
+	TokenHash string    `gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (emailChangeToken0007) TableName() string { return "email_change_tokens" }
+
+// emailChange adds users.pending_email and the email_change_tokens table, so
+// a new email only takes effect once it is confirmed
+func emailChange() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0007_email_change",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&user0007{}, "PendingEmail"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&emailChangeToken0007{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&emailChangeToken0007{}); err != nil {
+				return err
+			}
+			// Plain DROP COLUMN, as in userMustChangePassword
+			return tx.Exec("ALTER TABLE users DROP COLUMN pending_email").Error
+		},
+	}
+}
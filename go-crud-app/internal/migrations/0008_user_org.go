@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0008 adds the organization a user belongs to
+type user0008 struct {
+	OrgID *uint `gorm:"index"`
+}
+
+func (user0008) TableName() string { return "users" }
+
+// userOrg adds users.org_id for multi-tenant deployments; existing users
+// belong to no organization
+func userOrg() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0008_user_org",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&user0008{}, "OrgID"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&user0008{}, "OrgID")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&user0008{}, "OrgID"); err != nil {
+				return err
+			}
+			// Plain DROP COLUMN, as in userMustChangePassword
+			return tx.Exec("ALTER TABLE users DROP COLUMN org_id").Error
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type organization0009 struct {
+	ID         uint   `gorm:"primarykey"`
+	Name       string `gorm:"not null;size:100"`
+	InviteCode string `gorm:"uniqueIndex;not null;size:64"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (organization0009) TableName() string { return "organizations" }
+
+// organizations creates the organizations table that users.org_id refers to
+func organizations() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0009_organizations",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&organization0009{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&organization0009{})
+		},
+	}
+}
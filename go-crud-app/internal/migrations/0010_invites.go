@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type invite0010 struct {
+	ID        uint   `gorm:"primarykey"`
+	TokenHash string `gorm:"uniqueIndex;not null;size:64"`
+	Email     string `gorm:"size:100"`
+	Role      string `gorm:"not null;size:20;default:user"`
+	OrgID     *uint
+	CreatedBy uint      `gorm:"index;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	UsedBy    *uint
+	CreatedAt time.Time
+}
+
+func (invite0010) TableName() string { return "invites" }
+
+// invites creates the invites table for invite-only registration
+func invites() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0010_invites",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&invite0010{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&invite0010{})
+		},
+	}
+}
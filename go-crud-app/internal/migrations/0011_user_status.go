@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0011 adds the account status
+type user0011 struct {
+	Status string `gorm:"not null;size:20;default:active"`
+}
+
+func (user0011) TableName() string { return "users" }
+
+// userStatus adds users.status, letting admins suspend an account without
+// deleting it; existing users are active
+func userStatus() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0011_user_status",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&user0011{}, "Status")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// Plain DROP COLUMN, as in userMustChangePassword
+			return tx.Exec("ALTER TABLE users DROP COLUMN status").Error
+		},
+	}
+}
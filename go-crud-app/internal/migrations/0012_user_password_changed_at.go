@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0012 adds the time of the last password change
+type user0012 struct {
+	PasswordChangedAt *time.Time
+}
+
+func (user0012) TableName() string { return "users" }
+
+// userPasswordChangedAt adds users.password_changed_at for password expiry.
+// Existing passwords are dated to the migration, so enabling expiry gives
+// their users a full period rather than expiring them all at once.
+func userPasswordChangedAt() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0012_user_password_changed_at",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&user0012{}, "PasswordChangedAt"); err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE users SET password_changed_at = ? WHERE password_hash <> ''", time.Now()).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// Plain DROP COLUMN, as in userMustChangePassword
+			return tx.Exec("ALTER TABLE users DROP COLUMN password_changed_at").Error
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type passwordHistory0013 struct {
+	ID           uint   `gorm:"primarykey"`
+	UserID       uint   `gorm:"index;not null"`
+	PasswordHash string `gorm:"not null;size:255"`
+	CreatedAt    time.Time
+}
+
+func (passwordHistory0013) TableName() string { return "password_histories" }
+
+// passwordHistories creates the password_histories table, used to refuse
+// recently used passwords
+func passwordHistories() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0013_password_histories",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&passwordHistory0013{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&passwordHistory0013{})
+		},
+	}
+}
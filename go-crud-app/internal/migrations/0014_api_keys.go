@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type apiKey0014 struct {
+	ID         uint   `gorm:"primarykey"`
+	Name       string `gorm:"not null;size:100"`
+	KeyHash    string `gorm:"uniqueIndex;not null;size:64"`
+	Hint       string `gorm:"not null;size:16"`
+	UserID     uint   `gorm:"index;not null"`
+	Scopes     string `gorm:"not null;size:255"`
+	CreatedBy  uint   `gorm:"not null"`
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func (apiKey0014) TableName() string { return "api_keys" }
+
+// apiKeys creates the api_keys table for service-to-service authentication
+func apiKeys() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0014_api_keys",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&apiKey0014{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiKey0014{})
+		},
+	}
+}
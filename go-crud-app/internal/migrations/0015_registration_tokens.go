@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type registrationToken0015 struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"index;not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (registrationToken0015) TableName() string { return "registration_tokens" }
+
+// registrationTokens creates the registration_tokens table, used to confirm
+// accounts registered in strict privacy mode
+func registrationTokens() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0015_registration_tokens",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&registrationToken0015{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&registrationToken0015{})
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// user0016 adds the time a user was last seen
+type user0016 struct {
+	LastSeenAt *time.Time `gorm:"index"`
+}
+
+func (user0016) TableName() string { return "users" }
+
+// userLastSeen adds users.last_seen_at for finding inactive accounts.
+// Existing users are dated to their last login, the only activity recorded
+// before.
+func userLastSeen() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0016_user_last_seen",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&user0016{}, "LastSeenAt"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(&user0016{}, "LastSeenAt"); err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE users SET last_seen_at = last_login_at").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&user0016{}, "LastSeenAt"); err != nil {
+				return err
+			}
+			// Plain DROP COLUMN, as in userMustChangePassword
+			return tx.Exec("ALTER TABLE users DROP COLUMN last_seen_at").Error
+		},
+	}
+}
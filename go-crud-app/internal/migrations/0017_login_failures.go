@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type loginFailure0017 struct {
+	ID                  uint   `gorm:"primarykey"`
+	EmailHash           string `gorm:"uniqueIndex;not null;size:64"`
+	FailedLoginAttempts int    `gorm:"not null;default:0"`
+	NextLoginAllowedAt  *time.Time
+	UpdatedAt           time.Time
+}
+
+func (loginFailure0017) TableName() string { return "login_failures" }
+
+// loginFailures creates the login_failures table, which throttles logins to
+// emails without an account the same way as logins to real accounts
+func loginFailures() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "0017_login_failures",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&loginFailure0017{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&loginFailure0017{})
+		},
+	}
+}
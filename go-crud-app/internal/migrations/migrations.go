@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// TableName is the table recording which migrations have been applied
+const TableName = "migrations"
+
+// All returns every versioned migration in the order they are applied.
+// Never edit or reorder a migration once it has shipped; add a new one.
+// Migrations declare the table shapes they work with as their own types
+// rather than using the models package, so later model changes cannot
+// rewrite history.
+func All() []*gormigrate.Migration {
+	return []*gormigrate.Migration{
+		initialSchema(),
+		partialUserUniqueIndexes(),
+		userMustChangePassword(),
+		userLoginThrottle(),
+		sessions(),
+		userVersion(),
+		emailChange(),
+		userOrg(),
+		organizations(),
+		invites(),
+		userStatus(),
+		userPasswordChangedAt(),
+		passwordHistories(),
+		apiKeys(),
+		registrationTokens(),
+		userLastSeen(),
+		loginFailures(),
+	}
+}
+
+// Latest returns the ID of the newest migration this build knows about
+func Latest() string {
+	all := All()
+	return all[len(all)-1].ID
+}
+
+// Options returns the gormigrate options used for every run
+func Options() *gormigrate.Options {
+	options := *gormigrate.DefaultOptions
+	options.TableName = TableName
+	options.UseTransaction = true
+	// Refuse to run against a database migrated by a newer build
+	options.ValidateUnknownMigrations = true
+	return &options
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognize
+const APIKeyPrefix = "gca_"
+
+// APIKey lets a service call the API as its owner without logging in. Only a
+// hash of the key is stored.
+type APIKey struct {
+	ID         uint   `gorm:"primarykey"`
+	Name       string `gorm:"not null;size:100"`
+	KeyHash    string `gorm:"uniqueIndex;not null;size:64"` // SHA-256 of the key, never the key itself
+	Hint       string `gorm:"not null;size:16"`             // The key's first characters, to tell keys apart
+	UserID     uint   `gorm:"index;not null"`               // The user the key acts as
+	Scopes     string `gorm:"not null;size:255"`            // Space-separated scopes
+	CreatedBy  uint   `gorm:"not null"`
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// ScopeList returns the key's scopes
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.ScopeList(), scope)
+}
+
+// Active reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyResponse represents an API key in API responses, without its hash
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Hint       string     `json:"hint"`
+	UserID     uint       `json:"user_id"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  uint       `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ToResponse converts APIKey to APIKeyResponse
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Hint:       k.Hint,
+		UserID:     k.UserID,
+		Scopes:     k.ScopeList(),
+		CreatedBy:  k.CreatedBy,
+		ExpiresAt:  k.ExpiresAt,
+		RevokedAt:  k.RevokedAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit actions recorded for security-sensitive events
+const (
+	AuditLogin              = "auth.login"
+	AuditLoginFailed        = "auth.login_failed"
+	AuditLogoutAll          = "auth.logout_all"
+	AuditSessionRevoked     = "auth.session_revoked"
+	AuditPasswordChanged    = "auth.password_changed"
+	AuditPasswordReset      = "auth.password_reset"
+	AuditEmailChanged       = "user.email_changed"
+	AuditUserDeleted        = "user.deleted"
+	AuditUserReactivated    = "user.reactivated"
+	AuditUserConfirmed      = "user.registration_confirmed"
+	AuditUserExported       = "user.exported"
+	AuditAdminBulkDelete    = "admin.bulk_delete"
+	AuditAdminUserCreated   = "admin.user_created"
+	AuditAdminInviteCreated = "admin.invite_created"
+	AuditAdminUserSuspended = "admin.user_suspended"
+	AuditAdminUserActivated = "admin.user_activated"
+	AuditAdminPasswordReset = "admin.password_reset"
+	AuditAdminAPIKeyCreated = "admin.api_key_created"
+	AuditAdminAPIKeyRevoked = "admin.api_key_revoked"
+)
+
+// AuditLog records a security-sensitive action. It never stores passwords or tokens.
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ActorID   *uint     `gorm:"index" json:"actor_id"` // Nil when the actor is unknown (e.g. a failed login for an unknown email)
+	Action    string    `gorm:"index;not null;size:50" json:"action"`
+	Target    string    `gorm:"size:255" json:"target,omitempty"` // e.g. "user:42" or "email:someone@example.com"
+	IP        string    `gorm:"size:45" json:"ip"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
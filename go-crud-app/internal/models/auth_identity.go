@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// LocalProvider is the provider name for email/password sign-in
+const LocalProvider = "local"
+
+// AuthIdentity links a user to an external sign-in provider (e.g. Google)
+type AuthIdentity struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"uniqueIndex:idx_auth_identity_user_provider;not null"`
+	Provider  string `gorm:"uniqueIndex:idx_auth_identity_user_provider;not null;size:50"`
+	Subject   string `gorm:"not null;size:255"` // Provider-specific user ID
+	CreatedAt time.Time
+}
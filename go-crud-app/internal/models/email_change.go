@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailChangeToken represents a request to change a user's email address,
+// confirmed from the new address before it is applied
+type EmailChangeToken struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"index;not null"`
+	Email     string     `gorm:"not null;size:100"`            // The requested new address
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64"` // SHA-256 of the emailed token, never the token itself
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // Set when the token is consumed or superseded by a newer one
+	CreatedAt time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Invite lets one person register, optionally only with a given email, and
+// gives the new account its role and organization
+type Invite struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64" json:"-"` // SHA-256 of the invite token, never the token itself
+	Email     string     `gorm:"size:100" json:"email,omitempty"`       // Only this address may register with the invite; empty for anyone
+	Role      string     `gorm:"not null;size:20;default:user" json:"role"`
+	OrgID     *uint      `json:"org_id,omitempty"`
+	CreatedBy uint       `gorm:"index;not null" json:"created_by"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	UsedBy    *uint      `json:"used_by,omitempty"` // The user who registered with the invite
+	CreatedAt time.Time  `json:"created_at"`
+}
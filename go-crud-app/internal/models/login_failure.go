@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginFailure counts failed logins to an email that has no account, so
+// those logins are throttled exactly like logins to a real account and the
+// response doesn't reveal which emails are registered
+type LoginFailure struct {
+	ID                  uint       `gorm:"primarykey"`
+	EmailHash           string     `gorm:"uniqueIndex;not null;size:64"` // SHA-256 of the normalized email, never the email itself
+	FailedLoginAttempts int        `gorm:"not null;default:0"`
+	NextLoginAllowedAt  *time.Time // Set while further attempts must wait
+	UpdatedAt           time.Time
+}
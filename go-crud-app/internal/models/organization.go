@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Organization groups users in multi-tenant deployments. Members only see
+// each other, and its admins only manage its members.
+type Organization struct {
+	ID         uint   `gorm:"primarykey"`
+	Name       string `gorm:"not null;size:100"`
+	InviteCode string `gorm:"uniqueIndex;not null;size:64"` // Lets new users join the organization when registering
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// OrganizationResponse represents an organization in API responses. The
+// invite code is only included for the organization's admins.
+type OrganizationResponse struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	InviteCode string    `json:"invite_code,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ToResponse converts an Organization to an OrganizationResponse, with the
+// invite code only when withInviteCode is set
+func (o *Organization) ToResponse(withInviteCode bool) OrganizationResponse {
+	resp := OrganizationResponse{
+		ID:        o.ID,
+		Name:      o.Name,
+		CreatedAt: o.CreatedAt,
+	}
+	if withInviteCode {
+		resp.InviteCode = o.InviteCode
+	}
+	return resp
+}
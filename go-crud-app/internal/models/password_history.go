@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordHistory records a hash of a password a user has replaced, so
+// recent passwords can't be chosen again
+type PasswordHistory struct {
+	ID           uint      `gorm:"primarykey"`
+	UserID       uint      `gorm:"index;not null"`
+	PasswordHash string    `gorm:"not null;size:255"`
+	CreatedAt    time.Time // When the password was replaced
+}
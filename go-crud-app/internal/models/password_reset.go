@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetToken represents a password reset request for a user
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"index;not null"`
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64"` // SHA-256 of the emailed token, never the token itself
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // Set when the token is consumed or superseded by a newer one
+	CreatedAt time.Time  `gorm:"index"`
+}
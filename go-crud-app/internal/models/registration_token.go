@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// RegistrationToken confirms a self-registered account from its email
+// address before the account can be used
+type RegistrationToken struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"index;not null"`
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64"` // SHA-256 of the emailed token, never the token itself
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // Set when the account is confirmed
+	CreatedAt time.Time
+}
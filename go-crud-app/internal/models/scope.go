@@ -0,0 +1,46 @@
+package models
+
+import "slices"
+
+// Scopes limit what an access token or API key may do, on top of its user's role
+const (
+	ScopeUsersRead  = "users:read"  // Read users and the caller's own account
+	ScopeUsersWrite = "users:write" // Change users and the caller's own account
+	ScopeAdmin      = "admin"       // Admin routes, if the user is an admin
+)
+
+// KnownScopes lists every scope tokens and API keys may carry
+var KnownScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeAdmin}
+
+// RoleScopes returns the scopes granted to tokens of a user with role
+func RoleScopes(role string) []string {
+	if IsAdminRole(role) {
+		return []string{ScopeUsersRead, ScopeUsersWrite, ScopeAdmin}
+	}
+	return []string{ScopeUsersRead, ScopeUsersWrite}
+}
+
+// ScopedRole returns the role a caller with role acts with. Admins whose
+// token or API key lacks the admin scope act as plain users.
+func ScopedRole(role string, hasAdminScope bool) string {
+	if IsAdminRole(role) && !hasAdminScope {
+		return RoleUser
+	}
+	return role
+}
+
+// GrantedScopes returns the scopes granted to tokens of a user with role who
+// asked for requested: every role scope when requested is nil, otherwise
+// those of requested the role grants
+func GrantedScopes(role string, requested []string) []string {
+	if requested == nil {
+		return RoleScopes(role)
+	}
+	granted := []string{}
+	for _, scope := range RoleScopes(role) {
+		if slices.Contains(requested, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Session records a login so users can see where they are signed in and
+// revoke individual devices. Tokens carry the session's JTI as their jti.
+type Session struct {
+	ID           uint       `gorm:"primarykey"`
+	UserID       uint       `gorm:"index;not null"`
+	JTI          string     `gorm:"uniqueIndex;not null;size:64"`
+	TokenVersion int        `gorm:"not null;default:0"` // User's token version at login; bumping it ends the session
+	UserAgent    string     `gorm:"size:255"`
+	IP           string     `gorm:"size:45"`
+	IssuedAt     time.Time  `gorm:"not null"`
+	LastSeenAt   time.Time  `gorm:"not null"`
+	ExpiresAt    time.Time  `gorm:"not null"` // Expiry of the session's latest refresh token
+	RevokedAt    *time.Time // Set when the user revokes the session
+}
+
+// SessionResponse represents a session in API responses, without its JTI
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	IssuedAt   time.Time `json:"issued_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // The session of the token used for the request
+}
+
+// ToResponse converts Session to SessionResponse, marking it current when
+// its JTI matches currentJTI
+func (s *Session) ToResponse(currentJTI string) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		IssuedAt:   s.IssuedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    currentJTI != "" && s.JTI == currentJTI,
+	}
+}
@@ -1,38 +1,204 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const (
+	// RoleUser is the default role for registered users
+	RoleUser = "user"
+	// RoleAdmin grants access to administrative endpoints, limited to the
+	// admin's own organization in multi-tenant deployments
+	RoleAdmin = "admin"
+	// RoleSuperAdmin is an admin of every organization
+	RoleSuperAdmin = "superadmin"
+)
+
+const (
+	// StatusActive is the status of users who may log in and use the API
+	StatusActive = "active"
+	// StatusSuspended blocks an account's logins and tokens while keeping
+	// its data, unlike deleting it
+	StatusSuspended = "suspended"
+	// StatusPending is the status of self-registered users who haven't yet
+	// confirmed their email address, when registration requires it
+	StatusPending = "pending"
+)
+
+// IsAdminRole reports whether role grants access to administrative endpoints
+func IsAdminRole(role string) bool {
+	return role == RoleAdmin || role == RoleSuperAdmin
+}
+
 // User represents a user in the system
 type User struct {
-	ID           uint           `gorm:"primarykey" json:"id"`
-	Username     string         `gorm:"uniqueIndex;not null;size:50" json:"username"`
-	Email        string         `gorm:"uniqueIndex;not null;size:100" json:"email"`
-	PasswordHash string         `gorm:"not null;size:255" json:"-"` // Never expose password hash in JSON
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"` // Soft delete support
+	ID                  uint           `gorm:"primarykey" json:"id"`
+	Username            string         `gorm:"uniqueIndex:idx_users_username,where:deleted_at IS NULL;not null;size:50" json:"username"`
+	Email               string         `gorm:"uniqueIndex:idx_users_email,where:deleted_at IS NULL;not null;size:100" json:"email"`
+	PendingEmail        string         `gorm:"size:100" json:"-"`          // Requested new email, applied once confirmed from that address
+	PasswordHash        string         `gorm:"not null;size:255" json:"-"` // Never expose password hash in JSON
+	Role                string         `gorm:"not null;size:20;default:user" json:"role"`
+	OrgID               *uint          `gorm:"index" json:"org_id,omitempty"` // References organizations.id in multi-tenant deployments; nil for none
+	Status              string         `gorm:"not null;size:20;default:active" json:"status"`
+	TokenVersion        int            `gorm:"not null;default:0" json:"-"` // Bumped to revoke every token issued before it
+	RetentionDays       *int           `json:"-"`                           // Inactivity window before the user is purged (nil uses the server default)
+	LastLoginAt         *time.Time     `json:"-"`
+	LastSeenAt          *time.Time     `gorm:"index" json:"-"`    // Last login or authenticated request, refreshed at most every few minutes
+	AvatarKey           string         `gorm:"size:255" json:"-"` // Storage key of the processed avatar image
+	AvatarURL           string         `gorm:"size:255" json:"avatar_url,omitempty"`
+	MustChangePassword  bool           `gorm:"not null;default:false" json:"-"`   // Set for admin-issued temporary passwords; blocks the API until changed
+	PasswordChangedAt   *time.Time     `json:"-"`                                 // When the password was last set, for expiry; nil without a password
+	FailedLoginAttempts int            `gorm:"not null;default:0" json:"-"`       // Consecutive failed logins, reset on success
+	NextLoginAllowedAt  *time.Time     `json:"-"`                                 // Logins are refused until then after repeated failures
+	Version             int            `gorm:"not null;default:1" json:"version"` // Bumped on every profile change for optimistic locking
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"` // Soft delete support
+}
+
+// UserPath returns the canonical API path of a user resource
+func UserPath(id uint) string {
+	return fmt.Sprintf("/api/users/%d", id)
+}
+
+// ETag returns a strong entity tag for the user's current version as served
+// in one representation. variant names the representation (the view and any
+// field selection), since a strong tag promises identical bytes. The tag is
+// "<version>.<variant>" hashes, so it changes with every update to the user.
+func (u *User) ETag(variant string) string {
+	variantSum := sha256.Sum256([]byte(variant))
+	return `"` + u.versionTag() + "." + hex.EncodeToString(variantSum[:4]) + `"`
+}
+
+// ETagMatchesVersion reports whether etag, a strong tag from ETag, names the
+// user's current version in any representation; If-Match only cares that
+// the user is unchanged, not which view the client fetched
+func (u *User) ETagMatchesVersion(etag string) bool {
+	tag, ok := strings.CutPrefix(etag, `"`)
+	if !ok {
+		return false
+	}
+	version, _, ok := strings.Cut(tag, ".")
+	return ok && version == u.versionTag()
+}
+
+// versionTag hashes the user's ID and version
+func (u *User) versionTag() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", u.ID, u.Version)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// NextVersion is the update expression bumping a user's version. Every
+// update through Updates or Update must set it, as they also change
+// updated_at, which every full representation includes; UpdateColumn writes
+// to fields no representation shows may leave it alone.
+func NextVersion() clause.Expr {
+	return gorm.Expr("version + ?", 1)
+}
+
+// InOrg scopes a users query to the organization orgID. Users without an
+// organization only see each other, so single-tenant deployments, where no
+// user has one, are unaffected.
+func InOrg(orgID *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if orgID == nil {
+			return db.Where("org_id IS NULL")
+		}
+		return db.Where("org_id = ?", *orgID)
+	}
+}
+
+// OrgScope scopes a users query to what a user with the given role and
+// organization may see: every organization for super-admins, otherwise
+// their own as in InOrg
+func OrgScope(role string, orgID *uint) func(*gorm.DB) *gorm.DB {
+	if role == RoleSuperAdmin {
+		return func(db *gorm.DB) *gorm.DB { return db }
+	}
+	return InOrg(orgID)
+}
+
+// SameOrg reports whether two organization IDs name the same organization,
+// treating two nils as the same
+func SameOrg(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// UserLinks holds hypermedia links for a user resource
+type UserLinks struct {
+	Self string `json:"self"`
 }
 
 // UserResponse represents the user data returned in API responses (without sensitive fields)
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint       `json:"id"`
+	Username     string     `json:"username"`
+	Email        string     `json:"email"`
+	PendingEmail string     `json:"pending_email,omitempty"` // Requested new email awaiting confirmation
+	Role         string     `json:"role"`
+	OrgID        *uint      `json:"org_id,omitempty"`
+	Status       string     `json:"status"`
+	AvatarURL    string     `json:"avatar_url,omitempty"`
+	Version      int        `json:"version"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"` // Only in admin responses
+	LastSeenAt   *time.Time `json:"last_seen_at,omitempty"`  // Only in admin responses
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Links        UserLinks  `json:"links"`
 }
 
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
+		ID:           u.ID,
+		Username:     u.Username,
+		Email:        u.Email,
+		PendingEmail: u.PendingEmail,
+		Role:         u.Role,
+		OrgID:        u.OrgID,
+		Status:       u.Status,
+		AvatarURL:    u.AvatarURL,
+		Version:      u.Version,
+		CreatedAt:    u.CreatedAt,
+		UpdatedAt:    u.UpdatedAt,
+		Links:        UserLinks{Self: UserPath(u.ID)},
+	}
+}
+
+// ToAdminResponse converts User to the UserResponse shown to admins, which
+// adds when the user last logged in and was last seen
+func (u *User) ToAdminResponse() UserResponse {
+	response := u.ToResponse()
+	response.LastLoginAt = u.LastLoginAt
+	response.LastSeenAt = u.LastSeenAt
+	return response
+}
+
+// PublicUserResponse is the reduced profile shown to other users; it omits
+// private fields such as the email address
+type PublicUserResponse struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	Links     UserLinks `json:"links"`
+}
+
+// ToPublicResponse converts User to PublicUserResponse
+func (u *User) ToPublicResponse() PublicUserResponse {
+	return PublicUserResponse{
 		ID:        u.ID,
 		Username:  u.Username,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		AvatarURL: u.AvatarURL,
+		Links:     UserLinks{Self: UserPath(u.ID)},
 	}
 }
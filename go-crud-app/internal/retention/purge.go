@@ -0,0 +1,96 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// purgeBatchSize is how many users Purge deletes per transaction, so a large
+// backlog doesn't hold one long transaction
+const purgeBatchSize = 100
+
+// Purger periodically and permanently deletes users who were soft-deleted
+// longer ago than After, with everything stored under their ID except audit
+// entries, which outlive the accounts they mention
+type Purger struct {
+	After    time.Duration // How long soft-deleted users are kept
+	Interval time.Duration
+}
+
+// Run purges on every interval until ctx is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := p.Purge(ctx, time.Now())
+		// A purge cut short by shutdown is finished on the next start
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Deleted user purge failed: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("Deleted user purge removed %d users", purged)
+		}
+	}
+}
+
+// Purge permanently deletes every user soft-deleted before now minus After,
+// in batches, and returns the number of users deleted. It stops between
+// batches when ctx is cancelled, keeping the batches already committed.
+func (p *Purger) Purge(ctx context.Context, now time.Time) (int, error) {
+	db := database.DB.WithContext(ctx)
+	cutoff := now.Add(-p.After)
+
+	purged := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		var ids []uint
+		if err := db.Unscoped().Model(&models.User{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("id").Limit(purgeBatchSize).Pluck("id", &ids).Error; err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		var deleted int64
+		err := database.RetryTransaction(db, func(tx *gorm.DB) error {
+			for _, model := range []interface{}{
+				&models.AuthIdentity{},
+				&models.PasswordResetToken{},
+				&models.Session{},
+				&models.EmailChangeToken{},
+				&models.RegistrationToken{},
+				&models.PasswordHistory{},
+				&models.APIKey{},
+			} {
+				if err := tx.Where("user_id IN ?", ids).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+			result := tx.Unscoped().Where("id IN ?", ids).Delete(&models.User{})
+			deleted = result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += int(deleted)
+	}
+}
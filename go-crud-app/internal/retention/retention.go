@@ -0,0 +1,166 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Policy bounds the data retention preference users may choose
+type Policy struct {
+	MinDays     int // Shortest retention window a user may choose
+	MaxDays     int // Longest retention window a user may choose (admin-enforced maximum)
+	DefaultDays int // Window for users without a preference (0 keeps their data indefinitely)
+}
+
+// DefaultPolicy returns the default retention bounds
+func DefaultPolicy() Policy {
+	return Policy{
+		MinDays:     30,
+		MaxDays:     730,
+		DefaultDays: 0,
+	}
+}
+
+// Validate checks a user's retention preference against the configured bounds
+func (p Policy) Validate(days int) error {
+	if days < p.MinDays || days > p.MaxDays {
+		return fmt.Errorf("retention_days must be between %d and %d", p.MinDays, p.MaxDays)
+	}
+	return nil
+}
+
+// EffectiveDays returns the retention window that applies to the user, or 0
+// if their data is kept indefinitely. A preference above the current maximum
+// (e.g. after an admin lowered it) is capped at the maximum.
+func (p Policy) EffectiveDays(user models.User) int {
+	if user.RetentionDays == nil {
+		return p.DefaultDays
+	}
+	return min(*user.RetentionDays, p.MaxDays)
+}
+
+// Sweeper periodically anonymizes and deletes users who have been inactive
+// for longer than their retention window
+type Sweeper struct {
+	Policy   Policy
+	Interval time.Duration
+}
+
+// Run sweeps on every interval until ctx is cancelled
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := s.Sweep(ctx, time.Now())
+		if err != nil {
+			log.Printf("Retention sweep failed: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("Retention sweep purged %d inactive users", purged)
+		}
+	}
+}
+
+// Sweep purges every user whose last activity (last login, or sign-up if they
+// never logged in) is older than their effective retention window. It returns
+// the number of users purged.
+func (s *Sweeper) Sweep(ctx context.Context, now time.Time) (int, error) {
+	db := database.DB.WithContext(ctx)
+
+	query := db.Model(&models.User{})
+	if s.Policy.DefaultDays == 0 {
+		// Only users who opted into a retention window can expire
+		query = query.Where("retention_days IS NOT NULL")
+	}
+
+	var expired []models.User
+	var batch []models.User
+	err := query.FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for _, user := range batch {
+			days := s.Policy.EffectiveDays(user)
+			if days <= 0 {
+				continue
+			}
+
+			lastActive := user.CreatedAt
+			if user.LastLoginAt != nil {
+				lastActive = *user.LastLoginAt
+			}
+			if now.Sub(lastActive) > time.Duration(days)*24*time.Hour {
+				expired = append(expired, user)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, user := range expired {
+		if err := purge(db, user); err != nil {
+			return purged, fmt.Errorf("purging user %d: %w", user.ID, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purge removes the user's personal data and soft-deletes the account. The
+// username and email are replaced so they no longer identify the user (and
+// can be registered again).
+func purge(db *gorm.DB, user models.User) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.AuthIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.EmailChangeToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RegistrationToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"username":       fmt.Sprintf("deleted_%d", user.ID),
+			"email":          fmt.Sprintf("deleted_%d@deleted.invalid", user.ID),
+			"pending_email":  "",
+			"password_hash":  "",
+			"retention_days": nil,
+			"last_login_at":  nil,
+			"last_seen_at":   nil,
+			"version":        models.NextVersion(),
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+}
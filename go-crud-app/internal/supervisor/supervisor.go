@@ -0,0 +1,123 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WorkerFunc is a long-running background task. It must return once ctx is cancelled.
+type WorkerFunc func(ctx context.Context)
+
+// WorkerStatus reports the liveness of a supervised worker
+type WorkerStatus struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	Restarts  int    `json:"restarts"`
+	LastPanic string `json:"last_panic,omitempty"`
+}
+
+// Supervisor runs background workers, restarting them if they panic
+type Supervisor struct {
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	mu           sync.Mutex
+	workers      map[string]*WorkerStatus
+	restartDelay time.Duration
+}
+
+// New creates a supervisor that waits restartDelay before restarting a panicked worker
+func New(restartDelay time.Duration) *Supervisor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Supervisor{
+		ctx:          ctx,
+		cancel:       cancel,
+		workers:      make(map[string]*WorkerStatus),
+		restartDelay: restartDelay,
+	}
+}
+
+// Go starts a named worker under supervision
+func (s *Supervisor) Go(name string, fn WorkerFunc) {
+	s.mu.Lock()
+	s.workers[name] = &WorkerStatus{Name: name, Healthy: true}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			panicked := s.run(name, fn)
+			if !panicked || s.ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(s.restartDelay):
+			}
+
+			s.mu.Lock()
+			s.workers[name].Healthy = true
+			s.workers[name].Restarts++
+			s.mu.Unlock()
+			log.Printf("Restarting worker %s", name)
+		}
+	}()
+}
+
+// run executes the worker once, reporting whether it panicked
+func (s *Supervisor) run(name string, fn WorkerFunc) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			log.Printf("Worker %s panicked: %v\n%s", name, r, debug.Stack())
+
+			s.mu.Lock()
+			s.workers[name].Healthy = false
+			s.workers[name].LastPanic = fmt.Sprint(r)
+			s.mu.Unlock()
+		}
+	}()
+
+	fn(s.ctx)
+	return false
+}
+
+// Status returns the status of all workers sorted by name
+func (s *Supervisor) Status() []WorkerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]WorkerStatus, 0, len(s.workers))
+	for _, status := range s.workers {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Healthy reports whether every worker is healthy
+func (s *Supervisor) Healthy() bool {
+	for _, status := range s.Status() {
+		if !status.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Stop cancels all workers and waits for them to exit
+func (s *Supervisor) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
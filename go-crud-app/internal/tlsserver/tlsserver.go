@@ -0,0 +1,243 @@
+package tlsserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CertReloader serves a certificate from files on disk and reloads it when
+// either file changes, so a rotated certificate is picked up without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+// NewCertReloader loads the certificate and key, failing if they can't be used
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.modTimes = modTimes
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, reloading it first if the
+// files have changed. A failed reload, e.g. while the files are half written,
+// keeps serving the previous certificate. It fits tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.stat()
+	if err == nil && modTimes != r.modTimes {
+		// A failed version is remembered too, so it is retried once the files
+		// change again rather than on every handshake
+		err = r.load()
+		r.modTimes = modTimes
+	}
+	if err != nil {
+		log.Printf("Failed to reload TLS certificate, serving the previous one: %v", err)
+	}
+	return r.cert, nil
+}
+
+// load reads the key pair from disk
+func (r *CertReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	return nil
+}
+
+// stat returns the modification times of the certificate and key files
+func (r *CertReloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return modTimes, fmt.Errorf("failed to read TLS certificate: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// Settings restricts the protocol versions and algorithms the server
+// negotiates. The zero value accepts TLS 1.2 and later with Go's default
+// cipher suites and curves, which exclude every known-insecure suite.
+type Settings struct {
+	// MinVersion is the oldest TLS version accepted; TLS 1.2 when zero
+	MinVersion uint16
+	// CipherSuites limits the TLS 1.2 cipher suites; Go's defaults when
+	// empty. TLS 1.3 suites are not configurable.
+	CipherSuites []uint16
+	// CurvePreferences lists the key exchange curves in order of
+	// preference; Go's defaults when empty
+	CurvePreferences []tls.CurveID
+}
+
+// tlsVersions maps the accepted TLS_MIN_VERSION values to versions. TLS 1.0
+// and 1.1 are recognized only so they can be rejected by name.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// curves are the key exchange curves that can be configured
+var curves = []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// http2CipherSuites are the TLS 1.2 suites HTTP/2 requires at least one of
+// (RFC 7540, section 9.2.2)
+var http2CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+
+// ParseSettings parses a minimum version ("1.2" or "1.3"; empty for 1.2), a
+// comma-separated list of cipher suite names as in the IANA registry (e.g.
+// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256") and a comma-separated list of
+// curve names (e.g. "X25519,P256"), and validates the result. Empty lists
+// keep Go's defaults.
+func ParseSettings(minVersion, cipherSuites, curvePreferences string) (Settings, error) {
+	var settings Settings
+	if minVersion = strings.TrimSpace(minVersion); minVersion != "" {
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return Settings{}, fmt.Errorf("unknown TLS version %q (use 1.2 or 1.3)", minVersion)
+		}
+		settings.MinVersion = version
+	}
+
+	for _, name := range splitList(cipherSuites) {
+		id, err := cipherSuiteID(name)
+		if err != nil {
+			return Settings{}, err
+		}
+		settings.CipherSuites = append(settings.CipherSuites, id)
+	}
+
+	for _, name := range splitList(curvePreferences) {
+		id, ok := curveID(name)
+		if !ok {
+			return Settings{}, fmt.Errorf("unknown curve %q", name)
+		}
+		settings.CurvePreferences = append(settings.CurvePreferences, id)
+	}
+
+	if err := settings.Validate(); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+// Validate rejects versions older than TLS 1.2, cipher suites Go considers
+// insecure, and suite lists HTTP/2 can't be served with
+func (s Settings) Validate() error {
+	if s.MinVersion != 0 && s.MinVersion < tls.VersionTLS12 {
+		return fmt.Errorf("TLS minimum version %s is insecure; use 1.2 or later", tls.VersionName(s.MinVersion))
+	}
+	if len(s.CipherSuites) == 0 {
+		return nil
+	}
+	if s.MinVersion == tls.VersionTLS13 {
+		return errors.New("cipher suites only apply to TLS 1.2 and have no effect with a TLS 1.3 minimum")
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if slices.Contains(s.CipherSuites, suite.ID) {
+			return fmt.Errorf("cipher suite %s is insecure", suite.Name)
+		}
+	}
+	if !slices.ContainsFunc(s.CipherSuites, func(id uint16) bool { return slices.Contains(http2CipherSuites, id) }) {
+		return fmt.Errorf("cipher suites must include %s or %s, which HTTP/2 requires",
+			tls.CipherSuiteName(http2CipherSuites[0]), tls.CipherSuiteName(http2CipherSuites[1]))
+	}
+	return nil
+}
+
+// cipherSuiteID looks up a cipher suite by name. TLS 1.3 suites are rejected
+// since Go doesn't allow them to be configured.
+func cipherSuiteID(name string) (uint16, error) {
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if !strings.EqualFold(suite.Name, name) {
+			continue
+		}
+		if slices.Equal(suite.SupportedVersions, []uint16{tls.VersionTLS13}) {
+			return 0, fmt.Errorf("cipher suite %s is a TLS 1.3 suite, which can't be configured", suite.Name)
+		}
+		return suite.ID, nil
+	}
+	return 0, fmt.Errorf("unknown cipher suite %q", name)
+}
+
+// curveID looks up a curve by its Go name (e.g. "CurveP256"), with or without
+// the "Curve" prefix
+func curveID(name string) (tls.CurveID, bool) {
+	for _, id := range curves {
+		goName := id.String()
+		if strings.EqualFold(goName, name) || strings.EqualFold(strings.TrimPrefix(goName, "Curve"), name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// splitList splits a comma-separated list, dropping blank entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Config returns a TLS configuration that serves the reloader's certificate
+// with settings. http.Server adds HTTP/2 to it when serving TLS.
+func Config(reloader *CertReloader, settings Settings) *tls.Config {
+	minVersion := settings.MinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	return &tls.Config{
+		MinVersion:       minVersion,
+		CipherSuites:     settings.CipherSuites,
+		CurvePreferences: settings.CurvePreferences,
+		GetCertificate:   reloader.GetCertificate,
+	}
+}
+
+// RedirectHandler permanently redirects every request to the same host and
+// path over HTTPS on httpsPort
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			// No port, possibly a bracketed IPv6 address
+			host = strings.Trim(r.Host, "[]")
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register GIF decoder
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// ImageFormatJPEG re-encodes images as JPEG
+	ImageFormatJPEG = "jpeg"
+	// ImageFormatPNG re-encodes images as PNG
+	ImageFormatPNG = "png"
+)
+
+var (
+	// ErrInvalidImage is returned when the data cannot be decoded as a supported image
+	ErrInvalidImage = errors.New("file is not a valid JPEG, PNG or GIF image")
+	// ErrImageTooLarge is returned when the image exceeds the maximum dimensions
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+)
+
+// ImageConfig controls how uploaded images are validated and re-encoded
+type ImageConfig struct {
+	MaxDimension int    // Reject images wider or taller than this (0 disables the check)
+	ResizeTo     int    // Downscale so the longest side fits within this (0 keeps the original size)
+	Format       string // Canonical output format: ImageFormatJPEG or ImageFormatPNG
+	JPEGQuality  int    // JPEG quality (1-100)
+}
+
+// DefaultImageConfig returns sane defaults for profile images
+func DefaultImageConfig() ImageConfig {
+	return ImageConfig{
+		MaxDimension: 4096,
+		ResizeTo:     512,
+		Format:       ImageFormatJPEG,
+		JPEGQuality:  85,
+	}
+}
+
+// ProcessImage decodes an image, validates its dimensions, optionally
+// downscales it and re-encodes it in the canonical format. Re-encoding drops
+// all metadata (EXIF, ICC profiles, comments) from the original file.
+// It returns the encoded image and its content type.
+func ProcessImage(data []byte, config ImageConfig) ([]byte, string, error) {
+	// Check dimensions from the header before decoding the full image so
+	// huge images are rejected without allocating their pixel buffers
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	if config.MaxDimension > 0 && (header.Width > config.MaxDimension || header.Height > config.MaxDimension) {
+		return nil, "", ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+
+	if config.ResizeTo > 0 {
+		img = downscale(img, config.ResizeTo)
+	}
+
+	var buf bytes.Buffer
+	switch config.Format {
+	case ImageFormatPNG:
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	default:
+		// JPEG has no alpha channel, so flatten transparency onto white
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+		if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: config.JPEGQuality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+}
+
+// downscale shrinks img so its longest side is at most maxSize, preserving the
+// aspect ratio. Each destination pixel is the average of the source pixels it
+// covers (box filter). Images already within the limit are returned unchanged.
+func downscale(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxSize && srcH <= maxSize {
+		return img
+	}
+
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = max(1, srcH*maxSize/srcW)
+	} else {
+		dstW = max(1, srcW*maxSize/srcH)
+	}
+
+	dst := image.NewNRGBA64(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := bounds.Min.Y + max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := bounds.Min.X + max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA64(x, y, color.NRGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
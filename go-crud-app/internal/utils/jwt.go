@@ -2,6 +2,8 @@ package utils
 
 import (
 	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,36 +14,133 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 	// ErrExpiredToken is returned when token has expired
 	ErrExpiredToken = errors.New("token has expired")
+	// ErrRevokedToken is returned when a token predates the user's current token version
+	ErrRevokedToken = errors.New("token has been revoked")
+	// ErrInvalidExpiration is returned when asked to issue a token with a non-positive lifetime
+	ErrInvalidExpiration = errors.New("token expiration must be positive")
 )
 
+// Token types distinguish short-lived access tokens from refresh tokens
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// TokenClock returns the current time used when issuing tokens
+var TokenClock = time.Now
+
 // Claims represents the JWT claims
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	UserID uint `json:"user_id"`
+	// Username and Email are left out of minimal tokens (see
+	// JWTConfig.MinimalClaims); VerifyToken fills them in from the database
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	// OrgID scopes the user to an organization in multi-tenant deployments;
+	// it is omitted for users without one
+	OrgID *uint `json:"org_id,omitempty"`
+	// TokenVersion must match the user's current version for the token to be accepted
+	TokenVersion int `json:"token_version"`
+	// TokenType is "access" or "refresh"; tokens issued before refresh tokens existed have none and are access tokens
+	TokenType string `json:"token_type,omitempty"`
+	// Scopes limit what a token may do; tokens issued before scopes existed
+	// have none and may do anything their user's role allows, while an empty
+	// list grants nothing
+	Scopes []string `json:"scopes,omitzero"`
+	// RegisteredClaims.ID (jti) names the login session the token belongs to;
+	// tokens issued before sessions existed have none
 	jwt.RegisteredClaims
 }
 
+// IsRefresh reports whether the claims belong to a refresh token
+func (c *Claims) IsRefresh() bool {
+	return c.TokenType == TokenTypeRefresh
+}
+
+// HasScope reports whether the token was granted scope. Tokens without a
+// scopes claim predate them and are granted every scope.
+func (c *Claims) HasScope(scope string) bool {
+	return c.Scopes == nil || slices.Contains(c.Scopes, scope)
+}
+
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	SecretKey       string
-	ExpirationHours int
+	SecretKey              string
+	ExpirationHours        int // Access token lifetime
+	RefreshExpirationHours int // Refresh token lifetime
+	// MinimalClaims leaves the username and email out of tokens, so no
+	// personal data travels with every request or sits in client storage
+	MinimalClaims bool
 }
 
-// GenerateToken generates a new JWT token for a user
-func GenerateToken(userID uint, username, email string, config JWTConfig) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(config.ExpirationHours) * time.Hour)
+// Validate rejects lifetimes that would produce already-expired tokens
+func (c JWTConfig) Validate() error {
+	if c.ExpirationHours <= 0 {
+		return fmt.Errorf("JWT expiration must be a positive number of hours, got %d", c.ExpirationHours)
+	}
+	if c.RefreshExpirationHours <= 0 {
+		return fmt.Errorf("JWT refresh expiration must be a positive number of hours, got %d", c.RefreshExpirationHours)
+	}
+	if c.RefreshExpirationHours < c.ExpirationHours {
+		return fmt.Errorf("JWT refresh expiration (%dh) must not be shorter than access expiration (%dh)",
+			c.RefreshExpirationHours, c.ExpirationHours)
+	}
+	return nil
+}
 
+// TokenOptions describes the token to issue. New claims go here rather than
+// into the GenerateToken signature, so adding one doesn't touch every caller.
+type TokenOptions struct {
+	UserID       uint
+	Username     string
+	Email        string
+	OrgID        *uint  // The user's organization; nil for none
+	TokenVersion int    // The user's current token version
+	SessionID    string // The session the token belongs to, as its jti; empty for none
+	// Scopes limit what the token may do; nil leaves the claim out, granting
+	// every scope the user's role allows, and an empty list grants none
+	Scopes []string
+}
+
+// GenerateToken generates a new access token
+func GenerateToken(options TokenOptions, config JWTConfig) (string, error) {
+	return generateToken(options, TokenTypeAccess, config.ExpirationHours, config)
+}
+
+// GenerateRefreshToken generates a refresh token, which can only be exchanged
+// for new tokens. Its scopes are those the login asked for, which the new
+// access token is narrowed to; without any it gets the user's role scopes
+// at the time of the refresh.
+func GenerateRefreshToken(options TokenOptions, config JWTConfig) (string, error) {
+	return generateToken(options, TokenTypeRefresh, config.RefreshExpirationHours, config)
+}
+
+// generateToken signs a token of the given type expiring after the given
+// number of hours. Minimal tokens leave out the username and email.
+func generateToken(options TokenOptions, tokenType string, hours int, config JWTConfig) (string, error) {
+	if hours <= 0 {
+		return "", ErrInvalidExpiration
+	}
+
+	now := TokenClock()
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
+		UserID:       options.UserID,
+		Username:     options.Username,
+		Email:        options.Email,
+		OrgID:        options.OrgID,
+		TokenVersion: options.TokenVersion,
+		TokenType:    tokenType,
+		Scopes:       options.Scopes,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        options.SessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(hours) * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
+	if config.MinimalClaims {
+		claims.Username, claims.Email = "", ""
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(config.SecretKey))
@@ -59,16 +158,19 @@ func ValidateToken(tokenString string, secretKey string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		// Verify signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
 		}
 		return []byte(secretKey), nil
 	})
 
+	// Wrap the jwt error so the cause (e.g. jwt.ErrTokenMalformed,
+	// jwt.ErrTokenSignatureInvalid, jwt.ErrTokenNotValidYet) can be logged,
+	// while callers keep matching on our sentinels
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrExpiredToken
+			return nil, fmt.Errorf("%w: %w", ErrExpiredToken, err)
 		}
-		return nil, ErrInvalidToken
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
 	}
 
 	if !token.Valid {
@@ -2,58 +2,224 @@ package utils
 
 import (
 	"errors"
-	"regexp"
-
-	"golang.org/x/crypto/bcrypt"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
 	// BcryptCost is the cost factor for bcrypt hashing (12 is a good balance of security and performance)
 	BcryptCost = 12
-	// MinPasswordLength is the minimum required password length
+	// MinPasswordLength is the minimum required password length, in characters
 	MinPasswordLength = 8
+	// MaxPasswordBytes is the longest password accepted. bcrypt ignores
+	// everything past 72 bytes, so longer passwords are rejected rather than
+	// silently truncated; the limit applies to argon2id too so hashes can move
+	// between algorithms.
+	MaxPasswordBytes = 72
 )
 
 var (
 	// ErrWeakPassword is returned when password doesn't meet security requirements
 	ErrWeakPassword = errors.New("password must be at least 8 characters and contain uppercase, lowercase, and number")
+	// ErrPasswordTooLong is returned when password is longer than MaxPasswordBytes
+	ErrPasswordTooLong = fmt.Errorf("password must be at most %d bytes", MaxPasswordBytes)
 )
 
-// HashPassword generates a bcrypt hash of the password
+// PasswordPolicy controls the strength requirements for new passwords
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+}
+
+// DefaultPasswordPolicy is the password policy used when none is configured
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:        MinPasswordLength,
+	RequireUppercase: true,
+	RequireLowercase: true,
+	RequireDigit:     true,
+}
+
+// PasswordRules is the password policy enforced on registration and password reset
+var PasswordRules = DefaultPasswordPolicy
+
+// PasswordMaxAge is how long a password may be used before it must be
+// changed; zero, the default, disables expiry
+var PasswordMaxAge time.Duration
+
+// PasswordExpired reports whether a password last set at changedAt is older
+// than PasswordMaxAge. Passwords without a recorded change time never expire.
+func PasswordExpired(changedAt *time.Time) bool {
+	return PasswordMaxAge > 0 && changedAt != nil && time.Since(*changedAt) > PasswordMaxAge
+}
+
+// weakPasswordError describes the configured policy while matching ErrWeakPassword
+type weakPasswordError struct {
+	message string
+}
+
+func (e *weakPasswordError) Error() string { return e.message }
+
+func (e *weakPasswordError) Is(target error) bool { return target == ErrWeakPassword }
+
+// Describe returns a human-readable summary of the policy
+func (p PasswordPolicy) Describe() string {
+	var classes []string
+	if p.RequireUppercase {
+		classes = append(classes, "uppercase")
+	}
+	if p.RequireLowercase {
+		classes = append(classes, "lowercase")
+	}
+	if p.RequireDigit {
+		classes = append(classes, "number")
+	}
+
+	message := fmt.Sprintf("password must be at least %d characters", p.MinLength)
+	switch len(classes) {
+	case 0:
+		return message
+	case 1:
+		return message + " and contain " + classes[0]
+	default:
+		return message + " and contain " + strings.Join(classes[:len(classes)-1], ", ") + ", and " + classes[len(classes)-1]
+	}
+}
+
+// HashPassword hashes the password with the configured Hasher and pepper
 func HashPassword(password string) (string, error) {
 	if err := ValidatePassword(password); err != nil {
 		return "", err
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
-	if err != nil {
-		return "", err
-	}
+	return pepperedHash(password)
+}
 
-	return string(hash), nil
+// RehashPassword hashes a password that has already been accepted, such as
+// one just verified at login, with the configured Hasher and pepper. Unlike
+// HashPassword it doesn't apply the current password policy.
+func RehashPassword(password string) (string, error) {
+	return pepperedHash(password)
 }
 
-// CheckPassword compares a password with a hash
+// CheckPassword compares a password with a hash produced by any supported
+// algorithm, with or without a pepper. Hashes made with a pepper that is no
+// longer configured never match. A hash that can't be compared, such as the
+// empty hash of an account without a password, still costs a comparison
+// against a dummy hash, so the result can't be told apart by timing.
 func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	passwordComparisons.Add(1)
+	match, compared := verifyPassword(password, hash)
+	if !compared {
+		verifyPassword(password, dummyHash())
+	}
+	return match
+}
+
+// DummyCheckPassword takes as long as CheckPassword does against a current
+// hash and always reports false. Logins for unknown accounts call it so they
+// take as long as wrong passwords for real ones, which would otherwise let
+// anyone probe which emails are registered by timing responses.
+func DummyCheckPassword(password string) bool {
+	passwordComparisons.Add(1)
+	verifyPassword(password, dummyHash())
+	return false
+}
+
+// PasswordComparisons returns how many password checks have run, real or
+// dummy, letting tests confirm every login path pays for one
+func PasswordComparisons() uint64 {
+	return passwordComparisons.Load()
+}
+
+var passwordComparisons atomic.Uint64
+
+// verifyPassword compares a password with a hash; compared is false when the
+// hash's algorithm or pepper is unknown and no comparison ran
+func verifyPassword(password, hash string) (match, compared bool) {
+	if id, inner := splitPepper(hash); id != "" {
+		pepper, ok := findPepper(id)
+		if !ok {
+			return false, false
+		}
+		password, hash = pepper.apply(password), inner
+	}
+
+	for _, verifier := range verifiers {
+		if verifier.Recognizes(hash) {
+			return verifier.Verify(password, hash), true
+		}
+	}
+	return false, false
 }
 
-// ValidatePassword checks if password meets security requirements
+var (
+	dummyHashMu sync.Mutex
+	// dummyHashes caches the dummy hash per hasher and pepper configuration
+	dummyHashes = make(map[string]string)
+)
+
+// dummyHash returns a hash of a fixed password made with the configured
+// Hasher and pepper, so comparing against it costs the same as checking an
+// up-to-date password hash
+func dummyHash() string {
+	key := fmt.Sprintf("%#v", Hasher)
+	if CurrentPepper != nil {
+		key += "$" + CurrentPepper.ID
+	}
+
+	dummyHashMu.Lock()
+	defer dummyHashMu.Unlock()
+	if hash, ok := dummyHashes[key]; ok {
+		return hash
+	}
+	hash, err := pepperedHash("timing-equalization-dummy-password")
+	if err != nil {
+		return ""
+	}
+	dummyHashes[key] = hash
+	return hash
+}
+
+// ValidatePassword checks if password meets the configured security
+// requirements. Character classes follow Unicode, so "Пароль2024" has both
+// cases, and the length is counted in code points: an emoji counts once, but
+// a letter written with a combining accent (e + U+0301) counts twice. The
+// password is not normalized, so it must be typed the same way every time.
 func ValidatePassword(password string) error {
-	if len(password) < MinPasswordLength {
-		return ErrWeakPassword
+	policy := PasswordRules
+
+	if len(password) > MaxPasswordBytes {
+		return ErrPasswordTooLong
 	}
 
-	// Check for at least one uppercase letter
-	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	// Check for at least one lowercase letter
-	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-	// Check for at least one digit
-	hasDigit := regexp.MustCompile(`[0-9]`).MatchString(password)
+	var hasUpper, hasLower, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsNumber(r):
+			hasDigit = true
+		}
+	}
 
-	if !hasUpper || !hasLower || !hasDigit {
-		return ErrWeakPassword
+	weak := utf8.RuneCountInString(password) < policy.MinLength ||
+		(policy.RequireUppercase && !hasUpper) ||
+		(policy.RequireLowercase && !hasLower) ||
+		(policy.RequireDigit && !hasDigit)
+	if weak {
+		if policy == DefaultPasswordPolicy {
+			return ErrWeakPassword
+		}
+		return &weakPasswordError{message: policy.Describe()}
 	}
 
 	return nil
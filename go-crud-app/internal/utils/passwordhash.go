@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms selectable with PASSWORD_HASH_ALGORITHM
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+// PasswordHasher hashes and verifies passwords with one algorithm. Hashes
+// carry an algorithm prefix, so hashes written by any hasher can be verified
+// after the configured algorithm changes.
+type PasswordHasher interface {
+	// Hash returns an encoded hash of the password
+	Hash(password string) (string, error)
+	// Verify reports whether password matches an encoded hash
+	Verify(password, hash string) bool
+	// Recognizes reports whether an encoded hash was produced by this algorithm
+	Recognizes(hash string) bool
+	// NeedsRehash reports whether a hash should be replaced because it uses
+	// another algorithm or weaker parameters than this hasher
+	NeedsRehash(hash string) bool
+}
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash returns a bcrypt hash of the password
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify compares a password with a bcrypt hash
+func (h BcryptHasher) Verify(password, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Recognizes reports whether hash is a bcrypt hash
+func (h BcryptHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// NeedsRehash reports whether hash isn't a bcrypt hash at this cost or higher
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	if !h.Recognizes(hash) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.Cost
+}
+
+// Argon2idHasher hashes passwords with argon2id, encoding the parameters in
+// the hash as $argon2id$v=19$m=<KiB>,t=<passes>,p=<threads>$<salt>$<key>
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2idHasher uses the second recommended parameter set of RFC 9106
+var DefaultArgon2idHasher = Argon2idHasher{
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// Hash returns an argon2id hash of the password with a random salt
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify compares a password with an argon2id hash, using the parameters
+// recorded in the hash
+func (h Argon2idHasher) Verify(password, hash string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	actual := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1
+}
+
+// Recognizes reports whether hash is an argon2id hash
+func (h Argon2idHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+// NeedsRehash reports whether hash isn't an argon2id hash with at least
+// these parameters
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params.Time < h.Time || params.Memory < h.Memory || params.Threads < h.Threads || uint32(len(key)) < h.KeyLen
+}
+
+// decodeArgon2id parses an encoded argon2id hash
+func decodeArgon2id(hash string) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordHashArgon2id {
+		return params, nil, nil, fmt.Errorf("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id key")
+	}
+	params.SaltLen = uint32(len(salt))
+	params.KeyLen = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// Hasher hashes new and changed passwords. Existing hashes are verified with
+// whichever algorithm produced them.
+var Hasher PasswordHasher = BcryptHasher{Cost: BcryptCost}
+
+// verifiers are tried in order to find the algorithm of a stored hash
+var verifiers = []PasswordHasher{BcryptHasher{Cost: BcryptCost}, DefaultArgon2idHasher}
+
+// NewPasswordHasher returns the hasher for a PASSWORD_HASH_ALGORITHM value;
+// an empty value means bcrypt
+func NewPasswordHasher(algorithm string) (PasswordHasher, error) {
+	switch strings.ToLower(strings.TrimSpace(algorithm)) {
+	case "", PasswordHashBcrypt:
+		return BcryptHasher{Cost: BcryptCost}, nil
+	case PasswordHashArgon2id:
+		return DefaultArgon2idHasher, nil
+	default:
+		return nil, fmt.Errorf("password hash algorithm must be %s or %s, got %q", PasswordHashBcrypt, PasswordHashArgon2id, algorithm)
+	}
+}
+
+// PasswordNeedsRehash reports whether a stored hash should be replaced with
+// one from the configured Hasher and pepper, e.g. after switching to argon2id
+// or rotating the pepper
+func PasswordNeedsRehash(hash string) bool {
+	id, inner := splitPepper(hash)
+	current := ""
+	if CurrentPepper != nil {
+		current = CurrentPepper.ID
+	}
+	return id != current || Hasher.NeedsRehash(inner)
+}
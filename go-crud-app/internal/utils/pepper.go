@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pepperPrefix marks a peppered hash as $pepper$<id> followed by the
+// underlying hash, e.g. $pepper$2024$2a$12$...
+const pepperPrefix = "$pepper$"
+
+// pepperIDPattern restricts pepper IDs to characters that can't be confused
+// with the hash separators
+var pepperIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Pepper is a server-side secret mixed into passwords before hashing, so the
+// hashes can't be cracked offline without it. The ID is stored with each
+// hash so the pepper can be rotated.
+type Pepper struct {
+	ID     string
+	Secret string
+}
+
+// CurrentPepper peppers new and changed hashes; nil hashes passwords
+// without a pepper
+var CurrentPepper *Pepper
+
+// PreviousPeppers are retired peppers still accepted when verifying hashes
+// made with them. Those hashes move to CurrentPepper on the next login.
+var PreviousPeppers []Pepper
+
+// apply mixes the pepper into the password. The HMAC is base64 encoded so
+// bcrypt sees 43 printable bytes, well within its 72-byte limit.
+func (p Pepper) apply(password string) string {
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Validate checks that the pepper has a usable ID and a secret
+func (p Pepper) Validate() error {
+	if !pepperIDPattern.MatchString(p.ID) {
+		return fmt.Errorf("pepper ID must be 1-32 letters, digits, underscores or hyphens, got %q", p.ID)
+	}
+	if p.Secret == "" {
+		return fmt.Errorf("pepper %s has an empty secret", p.ID)
+	}
+	return nil
+}
+
+// ParsePeppers parses PEPPER_PREVIOUS, a comma-separated list of id:secret
+// pairs
+func ParsePeppers(value string) ([]Pepper, error) {
+	var peppers []Pepper
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("pepper %q must be given as id:secret", entry)
+		}
+		pepper := Pepper{ID: id, Secret: secret}
+		if err := pepper.Validate(); err != nil {
+			return nil, err
+		}
+		peppers = append(peppers, pepper)
+	}
+	return peppers, nil
+}
+
+// findPepper returns the configured pepper with the given ID
+func findPepper(id string) (Pepper, bool) {
+	if CurrentPepper != nil && CurrentPepper.ID == id {
+		return *CurrentPepper, true
+	}
+	for _, pepper := range PreviousPeppers {
+		if pepper.ID == id {
+			return pepper, true
+		}
+	}
+	return Pepper{}, false
+}
+
+// splitPepper separates a stored hash into its pepper ID and underlying
+// hash; the ID is empty for unpeppered hashes
+func splitPepper(hash string) (id, inner string) {
+	rest, ok := strings.CutPrefix(hash, pepperPrefix)
+	if !ok {
+		return "", hash
+	}
+	id, inner, ok = strings.Cut(rest, "$")
+	if !ok {
+		return "", hash
+	}
+	return id, "$" + inner
+}
+
+// pepperedHash hashes the password with Hasher, peppering it first when a
+// pepper is configured
+func pepperedHash(password string) (string, error) {
+	if CurrentPepper == nil {
+		return Hasher.Hash(password)
+	}
+	hash, err := Hasher.Hash(CurrentPepper.apply(password))
+	if err != nil {
+		return "", err
+	}
+	return pepperPrefix + CurrentPepper.ID + hash, nil
+}
@@ -0,0 +1,772 @@
+package utils
+
+import (
+	_ "embed"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Password strength scores returned by EstimatePasswordStrength
+const (
+	StrengthTooGuessable      = 0 // Risky: guessable within about a thousand guesses
+	StrengthVeryGuessable     = 1 // Protects only against throttled online attacks
+	StrengthSomewhatGuessable = 2 // Protects against unthrottled online attacks
+	StrengthSafelyUnguessable = 3 // Moderate protection against offline attacks
+	StrengthVeryUnguessable   = 4 // Strong protection against offline attacks
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+//go:embed common_words.txt
+var commonWordList string
+
+// PasswordStrength is an advisory estimate of how hard a password is to
+// guess. Unlike ValidatePassword it rejects nothing; it drives strength
+// meters and hints.
+type PasswordStrength struct {
+	Score        int              `json:"score"`         // 0 (too guessable) to 4 (very unguessable)
+	GuessesLog10 float64          `json:"guesses_log10"` // Estimated guesses needed, as a power of ten
+	Feedback     StrengthFeedback `json:"feedback"`
+}
+
+// StrengthFeedback explains a weak password score
+type StrengthFeedback struct {
+	Warning     string   `json:"warning,omitempty"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// rankedDictionary maps lowercase words to their popularity rank, 1 being
+// the most common
+type rankedDictionary struct {
+	name  string
+	ranks map[string]int
+}
+
+const (
+	dictionaryPasswords  = "passwords"
+	dictionaryWords      = "words"
+	dictionaryUserInputs = "user_inputs"
+)
+
+var (
+	bundledDictionariesOnce sync.Once
+	bundledDictionaries     []rankedDictionary
+)
+
+// strengthDictionaries returns the bundled dictionaries followed by one of
+// userInputs, such as the username and email, and the words within them
+func strengthDictionaries(userInputs []string) []rankedDictionary {
+	bundledDictionariesOnce.Do(func() {
+		bundledDictionaries = []rankedDictionary{
+			{name: dictionaryPasswords, ranks: parseRankedList(commonPasswordList)},
+			{name: dictionaryWords, ranks: parseRankedList(commonWordList)},
+		}
+	})
+
+	inputs := rankedDictionary{name: dictionaryUserInputs, ranks: make(map[string]int)}
+	add := func(word string) {
+		if len([]rune(word)) >= 3 {
+			if _, ok := inputs.ranks[word]; !ok {
+				inputs.ranks[word] = len(inputs.ranks) + 1
+			}
+		}
+	}
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		add(input)
+		for _, part := range strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}) {
+			add(part)
+		}
+	}
+	return append(append([]rankedDictionary(nil), bundledDictionaries...), inputs)
+}
+
+// parseRankedList parses an embedded word list, one word per line in
+// popularity order, skipping blank lines and # comments
+func parseRankedList(list string) map[string]int {
+	ranks := make(map[string]int)
+	for _, line := range strings.Split(list, "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = len(ranks) + 1
+		}
+	}
+	return ranks
+}
+
+// strengthMatch is a part of the password, password[i:j+1] in runes, that
+// an attacker could guess as a unit
+type strengthMatch struct {
+	i, j       int
+	pattern    string // dictionary, spatial, repeat, sequence, date or bruteforce
+	token      string
+	guesses    float64
+	dictionary string // The dictionary of a dictionary match
+	rank       int
+	reversed   bool
+	l33t       bool
+	repeatUnit string // The repeated part of a repeat match
+	yearOnly   bool   // A date match that is just a year
+}
+
+const (
+	// minSubmatchGuesses keep short matches from looking cheaper than
+	// brute-forcing a character or two
+	minSubmatchGuessesSingleChar = 10
+	minSubmatchGuessesMultiChar  = 50
+	// minGuessesBeforeGrowingSequence penalizes splitting a password into
+	// many matches, so a long run of patterns still counts for something
+	minGuessesBeforeGrowingSequence = 10000
+	bruteforceCardinality           = 10
+	minYearSpace                    = 20
+)
+
+// EstimatePasswordStrength estimates how many guesses a password would take
+// in the manner of zxcvbn: it finds the cheapest way to build the password
+// from common passwords and words (also reversed, capitalized or with l33t
+// substitutions), the user's own inputs, keyboard rows, repeats, sequences,
+// dates and brute force, and scores the result from 0 to 4. userInputs,
+// such as the username and email, count as very common words.
+func EstimatePasswordStrength(password string, userInputs ...string) PasswordStrength {
+	estimator := &strengthEstimator{
+		dictionaries: strengthDictionaries(userInputs),
+		repeatUnits:  make(map[string]float64),
+	}
+	guesses, sequence := estimator.mostGuessable([]rune(password))
+	score := strengthScore(guesses)
+	return PasswordStrength{
+		Score:        score,
+		GuessesLog10: math.Round(math.Log10(guesses)*100) / 100,
+		Feedback:     strengthFeedback(score, sequence, len([]rune(password))),
+	}
+}
+
+// strengthScore maps a guess estimate to a 0-4 score
+func strengthScore(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return StrengthTooGuessable
+	case guesses < 1e6+delta:
+		return StrengthVeryGuessable
+	case guesses < 1e8+delta:
+		return StrengthSomewhatGuessable
+	case guesses < 1e10+delta:
+		return StrengthSafelyUnguessable
+	default:
+		return StrengthVeryUnguessable
+	}
+}
+
+// strengthEstimator holds the dictionaries for one estimate and the guesses
+// of repeated units already estimated
+type strengthEstimator struct {
+	dictionaries []rankedDictionary
+	repeatUnits  map[string]float64
+}
+
+// mostGuessable returns the fewest guesses needed to produce password and
+// the matches that need them
+func (e *strengthEstimator) mostGuessable(password []rune) (float64, []strengthMatch) {
+	n := len(password)
+	if n == 0 {
+		return 1, nil
+	}
+
+	matches := e.matches(password)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			matches = append(matches, strengthMatch{
+				i: i, j: j, pattern: "bruteforce", token: string(password[i : j+1]),
+				guesses: bruteforceGuesses(j - i + 1),
+			})
+		}
+	}
+
+	// best[k][l] is the cheapest product of guesses covering password[:k+1]
+	// with l matches; zxcvbn's most_guessable_match_sequence
+	type step struct {
+		product float64
+		match   int // Index of the last match, or -1 when unreachable
+	}
+	best := make([][]step, n)
+	for k := range best {
+		best[k] = make([]step, n+1)
+		for l := range best[k] {
+			best[k][l].match = -1
+		}
+	}
+	for k := 0; k < n; k++ {
+		for idx, m := range matches {
+			if m.j != k {
+				continue
+			}
+			consider := func(l int, product float64) {
+				if current := best[k][l]; current.match < 0 || product < current.product {
+					best[k][l] = step{product: product, match: idx}
+				}
+			}
+			if m.i == 0 {
+				consider(1, m.guesses)
+				continue
+			}
+			for l, previous := range best[m.i-1] {
+				if previous.match >= 0 {
+					consider(l+1, previous.product*m.guesses)
+				}
+			}
+		}
+	}
+
+	guesses, count := math.Inf(1), 0
+	for l, s := range best[n-1] {
+		if s.match < 0 {
+			continue
+		}
+		total := factorial(l)*s.product + math.Pow(minGuessesBeforeGrowingSequence, float64(l-1))
+		if total < guesses {
+			guesses, count = total, l
+		}
+	}
+
+	sequence := make([]strengthMatch, count)
+	for k, l := n-1, count; l > 0; l-- {
+		s := best[k][l]
+		sequence[l-1] = matches[s.match]
+		k = matches[s.match].i - 1
+	}
+	return guesses, sequence
+}
+
+// matches finds every non-bruteforce match in password
+func (e *strengthEstimator) matches(password []rune) []strengthMatch {
+	lower := make([]rune, len(password))
+	for i, r := range password {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var matches []strengthMatch
+	matches = append(matches, e.dictionaryMatches(password, lower)...)
+	matches = append(matches, spatialMatches(password, lower)...)
+	matches = append(matches, e.repeatMatches(password)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+
+	for k := range matches {
+		minimum := float64(minSubmatchGuessesMultiChar)
+		if matches[k].j == matches[k].i {
+			minimum = minSubmatchGuessesSingleChar
+		}
+		matches[k].guesses = math.Max(matches[k].guesses, minimum)
+	}
+	return matches
+}
+
+// l33tTables undo common character substitutions; two tables cover the
+// ambiguous ones
+var l33tTables = []map[rune]rune{
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '9': 'g', '1': 'l', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '%': 'x'},
+}
+
+// dictionaryMatches finds dictionary words in password as typed, reversed
+// and with l33t substitutions undone
+func (e *strengthEstimator) dictionaryMatches(password, lower []rune) []strengthMatch {
+	n := len(lower)
+	reversed := make([]rune, n)
+	for i, r := range lower {
+		reversed[n-1-i] = r
+	}
+
+	var matches []strengthMatch
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			token := string(password[i : j+1])
+			word := string(lower[i : j+1])
+			// Index of the same runes in the reversed password
+			reversedWord := string(reversed[n-1-j : n-i])
+
+			for _, dictionary := range e.dictionaries {
+				if rank, ok := dictionary.ranks[word]; ok {
+					matches = append(matches, strengthMatch{
+						i: i, j: j, pattern: "dictionary", token: token, dictionary: dictionary.name, rank: rank,
+						guesses: float64(rank) * uppercaseVariations(password[i:j+1]),
+					})
+				}
+				if rank, ok := dictionary.ranks[reversedWord]; ok && reversedWord != word {
+					matches = append(matches, strengthMatch{
+						i: i, j: j, pattern: "dictionary", token: token, dictionary: dictionary.name, rank: rank, reversed: true,
+						guesses: float64(rank) * uppercaseVariations(password[i:j+1]) * 2,
+					})
+				}
+				for _, table := range l33tTables {
+					unl33ted, subs := unl33t(lower[i:j+1], table)
+					if len(subs) == 0 {
+						continue
+					}
+					if rank, ok := dictionary.ranks[string(unl33ted)]; ok {
+						matches = append(matches, strengthMatch{
+							i: i, j: j, pattern: "dictionary", token: token, dictionary: dictionary.name, rank: rank, l33t: true,
+							guesses: float64(rank) * uppercaseVariations(password[i:j+1]) * l33tVariations(lower[i:j+1], unl33ted, subs),
+						})
+					}
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// unl33t undoes the substitutions in table, returning the result and the
+// substituted characters
+func unl33t(word []rune, table map[rune]rune) ([]rune, map[rune]rune) {
+	out := make([]rune, len(word))
+	subs := make(map[rune]rune)
+	for k, r := range word {
+		if letter, ok := table[r]; ok {
+			out[k] = letter
+			subs[r] = letter
+		} else {
+			out[k] = r
+		}
+	}
+	return out, subs
+}
+
+// uppercaseVariations estimates how many capitalizations of a word an
+// attacker tries before this one
+func uppercaseVariations(word []rune) float64 {
+	var upper, lower int
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	// First or last letter capitalized, or all caps, are tried first
+	first, last := word[0], word[len(word)-1]
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(first) || unicode.IsUpper(last))) {
+		return 2
+	}
+	var variations float64
+	for k := 1; k <= min(upper, lower); k++ {
+		variations += binomial(upper+lower, k)
+	}
+	return variations
+}
+
+// l33tVariations estimates how many substitution patterns an attacker tries
+// before the one in word
+func l33tVariations(word, unl33ted []rune, subs map[rune]rune) float64 {
+	variations := 1.0
+	for sub, letter := range subs {
+		var subbed, unsubbed int
+		for k, r := range word {
+			switch {
+			case r == sub:
+				subbed++
+			case unl33ted[k] == letter:
+				unsubbed++
+			}
+		}
+		if subbed == 0 || unsubbed == 0 {
+			variations *= 2
+			continue
+		}
+		var possibilities float64
+		for k := 1; k <= min(subbed, unsubbed); k++ {
+			possibilities += binomial(subbed+unsubbed, k)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+// keyboardRows are the QWERTY letter rows
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+const (
+	keyboardStartingPositions = 47  // Keys on a QWERTY keyboard
+	keyboardAverageDegree     = 4.6 // Average neighbours of a QWERTY key
+)
+
+// spatialMatches finds runs of three or more neighbouring keys on a
+// keyboard row, in either direction
+func spatialMatches(password, lower []rune) []strengthMatch {
+	var matches []strengthMatch
+	for _, row := range keyboardRows {
+		position := make(map[rune]int, len(row))
+		for k, r := range row {
+			position[r] = k
+		}
+
+		for i := 0; i < len(lower); {
+			j, turns, direction := i, 0, 0
+			for j+1 < len(lower) {
+				a, okA := position[lower[j]]
+				b, okB := position[lower[j+1]]
+				if !okA || !okB || (b-a != 1 && a-b != 1) {
+					break
+				}
+				if b-a != direction {
+					turns++
+					direction = b - a
+				}
+				j++
+			}
+			if j-i+1 >= 3 {
+				matches = append(matches, strengthMatch{
+					i: i, j: j, pattern: "spatial", token: string(password[i : j+1]),
+					guesses: spatialGuesses(j-i+1, turns),
+				})
+			}
+			if j > i {
+				i = j
+			} else {
+				i++
+			}
+		}
+	}
+	return matches
+}
+
+// spatialGuesses counts keyboard patterns of up to length keys with up to
+// turns changes of direction
+func spatialGuesses(length, turns int) float64 {
+	var guesses float64
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * keyboardStartingPositions * math.Pow(keyboardAverageDegree, float64(j))
+		}
+	}
+	return guesses
+}
+
+// repeatMatches finds a part of password repeated back to back, such as
+// "aaa" or "abcabc", taking the longest repeat from each position
+func (e *strengthEstimator) repeatMatches(password []rune) []strengthMatch {
+	n := len(password)
+	var matches []strengthMatch
+	for i := 0; i < n; i++ {
+		bestUnit, bestCount := 0, 0
+		for unit := 1; i+2*unit <= n; unit++ {
+			count := 1
+			for i+(count+1)*unit <= n && string(password[i+count*unit:i+(count+1)*unit]) == string(password[i:i+unit]) {
+				count++
+			}
+			if count >= 2 && unit*count > bestUnit*bestCount {
+				bestUnit, bestCount = unit, count
+			}
+		}
+		if bestCount < 2 {
+			continue
+		}
+
+		unit := string(password[i : i+bestUnit])
+		guesses, ok := e.repeatUnits[unit]
+		if !ok {
+			guesses, _ = e.mostGuessable([]rune(unit))
+			e.repeatUnits[unit] = guesses
+		}
+		matches = append(matches, strengthMatch{
+			i: i, j: i + bestUnit*bestCount - 1, pattern: "repeat", token: string(password[i : i+bestUnit*bestCount]),
+			repeatUnit: unit, guesses: guesses * float64(bestCount),
+		})
+	}
+	return matches
+}
+
+// sequenceMatches finds runs of three or more consecutive characters, such
+// as "abc", "6543" or "XYZ"
+func sequenceMatches(password []rune) []strengthMatch {
+	var matches []strengthMatch
+	for i := 0; i < len(password); {
+		j := i
+		delta := 0
+		if i+1 < len(password) {
+			delta = int(password[i+1]) - int(password[i])
+		}
+		if delta == 1 || delta == -1 {
+			for j+1 < len(password) && int(password[j+1])-int(password[j]) == delta {
+				j++
+			}
+		}
+		if j-i+1 >= 3 {
+			matches = append(matches, strengthMatch{
+				i: i, j: j, pattern: "sequence", token: string(password[i : j+1]),
+				guesses: sequenceGuesses(password[i], delta < 0) * float64(j-i+1),
+			})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+// sequenceGuesses estimates the choices of a sequence's first character
+func sequenceGuesses(first rune, descending bool) float64 {
+	var base float64
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		base = 4 // Obvious starting points
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if descending {
+		base *= 2
+	}
+	return base
+}
+
+// datePatterns match a year alone and dates with or without separators
+var (
+	yearPattern          = regexp.MustCompile(`^(19|20)\d\d$`)
+	separatedDatePattern = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+	digitsPattern        = regexp.MustCompile(`^\d{4,8}$`)
+)
+
+// dateMatches finds years and dates, which are often personal and easy to
+// guess
+func dateMatches(password []rune) []strengthMatch {
+	var matches []strengthMatch
+	for i := 0; i < len(password); i++ {
+		for j := i + 3; j < len(password) && j-i < 10; j++ {
+			token := string(password[i : j+1])
+			if yearPattern.MatchString(token) {
+				year, _ := strconv.Atoi(token)
+				matches = append(matches, strengthMatch{
+					i: i, j: j, pattern: "date", token: token, yearOnly: true,
+					guesses: yearSpace(year),
+				})
+				continue
+			}
+			if year, ok := parseDate(token); ok {
+				matches = append(matches, strengthMatch{
+					i: i, j: j, pattern: "date", token: token,
+					guesses: yearSpace(year) * 365,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// parseDate reports whether token is a day, month and year in any common
+// order, returning the year
+func parseDate(token string) (int, bool) {
+	var parts [][3]string
+	if m := separatedDatePattern.FindStringSubmatch(token); m != nil {
+		if m[2] != m[4] {
+			return 0, false
+		}
+		parts = [][3]string{{m[1], m[3], m[5]}}
+	} else if digitsPattern.MatchString(token) && len(token) >= 6 {
+		// Try every split into three numbers
+		for a := 1; a <= 4 && a < len(token)-1; a++ {
+			for b := a + 1; b <= a+4 && b < len(token); b++ {
+				parts = append(parts, [3]string{token[:a], token[a:b], token[b:]})
+			}
+		}
+	} else {
+		return 0, false
+	}
+
+	for _, p := range parts {
+		var numbers [3]int
+		for k, s := range p {
+			numbers[k], _ = strconv.Atoi(s)
+		}
+		// Year first or last, then day and month in either order
+		for _, order := range [][3]int{{2, 0, 1}, {2, 1, 0}, {0, 1, 2}, {0, 2, 1}} {
+			yearText := p[order[0]]
+			if len(yearText) != 2 && len(yearText) != 4 {
+				continue
+			}
+			year := numbers[order[0]]
+			if len(yearText) == 2 {
+				year = twoDigitYear(year)
+			}
+			month, day := numbers[order[1]], numbers[order[2]]
+			if year >= 1000 && year <= 2050 && month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+				return year, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// twoDigitYear expands a two-digit year to the closest plausible century
+func twoDigitYear(year int) int {
+	if year > 50 {
+		return 1900 + year
+	}
+	return 2000 + year
+}
+
+// yearSpace estimates the years an attacker tries before year
+func yearSpace(year int) float64 {
+	space := year - time.Now().Year()
+	if space < 0 {
+		space = -space
+	}
+	return float64(max(space, minYearSpace))
+}
+
+// bruteforceGuesses estimates the guesses for length characters with no
+// pattern
+func bruteforceGuesses(length int) float64 {
+	guesses := math.Pow(bruteforceCardinality, float64(length))
+	minimum := float64(minSubmatchGuessesMultiChar + 1)
+	if length == 1 {
+		minimum = minSubmatchGuessesSingleChar + 1
+	}
+	return math.Max(guesses, minimum)
+}
+
+// strengthFeedback explains what made a weak password guessable, based on
+// the longest match in its cheapest sequence
+func strengthFeedback(score int, sequence []strengthMatch, length int) StrengthFeedback {
+	if length == 0 {
+		return StrengthFeedback{Suggestions: []string{
+			"Use a few words, avoid common phrases",
+			"No need for symbols, digits, or uppercase letters",
+		}}
+	}
+	if score > StrengthSomewhatGuessable {
+		return StrengthFeedback{Suggestions: []string{}}
+	}
+
+	var longest *strengthMatch
+	for k := range sequence {
+		if sequence[k].pattern == "bruteforce" {
+			continue
+		}
+		if longest == nil || len([]rune(sequence[k].token)) > len([]rune(longest.token)) {
+			longest = &sequence[k]
+		}
+	}
+
+	const extraWord = "Add another word or two. Uncommon words are better."
+	if longest == nil {
+		return StrengthFeedback{Suggestions: []string{extraWord}}
+	}
+	feedback := matchFeedback(*longest, len(sequence) == 1)
+	feedback.Suggestions = append([]string{extraWord}, feedback.Suggestions...)
+	return feedback
+}
+
+// matchFeedback explains why a match is easy to guess; sole is set when the
+// match is the whole password
+func matchFeedback(m strengthMatch, sole bool) StrengthFeedback {
+	switch m.pattern {
+	case "dictionary":
+		return dictionaryFeedback(m, sole)
+	case "spatial":
+		return StrengthFeedback{
+			Warning:     "Straight rows of keys are easy to guess",
+			Suggestions: []string{"Use a longer keyboard pattern with more turns"},
+		}
+	case "repeat":
+		warning := `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+		if len([]rune(m.repeatUnit)) == 1 {
+			warning = `Repeats like "aaa" are easy to guess`
+		}
+		return StrengthFeedback{Warning: warning, Suggestions: []string{"Avoid repeated words and characters"}}
+	case "sequence":
+		return StrengthFeedback{
+			Warning:     "Sequences like abc or 6543 are easy to guess",
+			Suggestions: []string{"Avoid sequences"},
+		}
+	case "date":
+		if m.yearOnly {
+			return StrengthFeedback{
+				Warning:     "Recent years are easy to guess",
+				Suggestions: []string{"Avoid recent years", "Avoid years that are associated with you"},
+			}
+		}
+		return StrengthFeedback{
+			Warning:     "Dates are often easy to guess",
+			Suggestions: []string{"Avoid dates and years that are associated with you"},
+		}
+	}
+	return StrengthFeedback{Suggestions: []string{}}
+}
+
+// dictionaryFeedback explains why a dictionary match is easy to guess
+func dictionaryFeedback(m strengthMatch, sole bool) StrengthFeedback {
+	var feedback StrengthFeedback
+	switch m.dictionary {
+	case dictionaryPasswords:
+		switch {
+		case sole && !m.l33t && !m.reversed && m.rank <= 10:
+			feedback.Warning = "This is a top-10 common password"
+		case sole && !m.l33t && !m.reversed && m.rank <= 100:
+			feedback.Warning = "This is a top-100 common password"
+		case sole:
+			feedback.Warning = "This is a very common password"
+		default:
+			feedback.Warning = "This is similar to a commonly used password"
+		}
+	case dictionaryWords:
+		if sole {
+			feedback.Warning = "A word by itself is easy to guess"
+		}
+	case dictionaryUserInputs:
+		feedback.Warning = "Avoid using your username or email in your password"
+	}
+
+	word := []rune(m.token)
+	switch {
+	case strings.ToUpper(m.token) == m.token && strings.ToLower(m.token) != m.token:
+		feedback.Suggestions = append(feedback.Suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	case unicode.IsUpper(word[0]):
+		feedback.Suggestions = append(feedback.Suggestions, "Capitalization doesn't help very much")
+	}
+	if m.reversed {
+		feedback.Suggestions = append(feedback.Suggestions, "Reversed words aren't much harder to guess")
+	}
+	if m.l33t {
+		feedback.Suggestions = append(feedback.Suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+	return feedback
+}
+
+// factorial returns n!
+func factorial(n int) float64 {
+	result := 1.0
+	for k := 2; k <= n; k++ {
+		result *= float64(k)
+	}
+	return result
+}
+
+// binomial returns n choose k
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := 1.0
+	for d := 1; d <= k; d++ {
+		result = result * float64(n-k+d) / float64(d)
+	}
+	return result
+}
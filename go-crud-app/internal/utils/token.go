@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+)
+
+// temporaryPasswordAlphabet leaves out characters that are easily misread
+// when a password is read out or copied by hand (0/O, 1/l/I)
+const temporaryPasswordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+
+// TemporaryPasswordLength is the length of generated temporary passwords,
+// unless the password policy demands more
+const TemporaryPasswordLength = 16
+
+// GenerateSecureToken generates a random 32-byte token encoded as hex
+func GenerateSecureToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hash of a token for storage
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateTemporaryPassword returns a random password that meets the
+// configured password policy, for an admin to hand to a user who must
+// replace it at their next login
+func GenerateTemporaryPassword() (string, error) {
+	length := max(TemporaryPasswordLength, PasswordRules.MinLength)
+	for {
+		password := make([]byte, length)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(temporaryPasswordAlphabet))))
+			if err != nil {
+				return "", err
+			}
+			password[i] = temporaryPasswordAlphabet[n.Int64()]
+		}
+		// Retry the rare draw that misses a required character class
+		if ValidatePassword(string(password)) == nil {
+			return string(password), nil
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package validation
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// EmailDomainMessage describes an email address whose domain may not register
+const EmailDomainMessage = "Registration is not allowed for this email domain"
+
+//go:embed disposable_domains.txt
+var disposableDomainList string
+
+// disposableDomains is the bundled list of disposable email providers
+var disposableDomains = parseDisposableDomains(disposableDomainList)
+
+// DomainPolicy restricts which email domains may register. An entry such as
+// "example.com" matches that domain only; "*.example.com" matches any of its
+// subdomains. Matching ignores case.
+type DomainPolicy struct {
+	Allow           []string // When non-empty, only matching domains may register
+	Deny            []string // Matching domains may never register, even if allowed
+	BlockDisposable bool     // Also deny the bundled disposable email providers and their subdomains
+}
+
+// EmailDomains is the domain policy applied to self-registration
+var EmailDomains = DomainPolicy{}
+
+// PermitsEmail reports whether an email address's domain may register
+func (p DomainPolicy) PermitsEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.TrimSuffix(strings.ToLower(email[at+1:]), ".")
+
+	if matchesAny(domain, p.Deny) {
+		return false
+	}
+	if p.BlockDisposable && isDisposable(domain) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchesAny(domain, p.Allow)
+}
+
+// ParseDomainList parses a comma-separated list of domain patterns
+func ParseDomainList(s string) ([]string, error) {
+	var domains []string
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		name := strings.TrimPrefix(entry, "*.")
+		if name == "" || strings.ContainsAny(name, "*@ ") || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+			return nil, fmt.Errorf("invalid email domain %q", entry)
+		}
+		domains = append(domains, entry)
+	}
+	return domains, nil
+}
+
+// matchesAny reports whether domain matches one of the patterns
+func matchesAny(domain string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if parent, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(domain, "."+parent) {
+				return true
+			}
+		} else if domain == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// isDisposable reports whether domain or one of its parents is a disposable provider
+func isDisposable(domain string) bool {
+	for {
+		if disposableDomains[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return false
+		}
+		domain = parent
+	}
+}
+
+// parseDisposableDomains reads the bundled list: one domain per line, with
+// blank lines and # comments ignored
+func parseDisposableDomains(list string) map[string]bool {
+	domains := make(map[string]bool)
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			domains[line] = true
+		}
+	}
+	return domains
+}
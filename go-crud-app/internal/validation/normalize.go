@@ -0,0 +1,70 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// NormalizationPolicy controls how user input is normalized before validation
+type NormalizationPolicy struct {
+	TrimSpace          bool // Trim leading and trailing whitespace from all fields
+	LowercaseEmail     bool // Lowercase email addresses
+	CollapseWhitespace bool // Collapse runs of internal whitespace in free-text fields such as organization and API key names
+}
+
+// DefaultPolicy is the normalization policy used when none is configured
+var DefaultPolicy = NormalizationPolicy{
+	TrimSpace:          true,
+	LowercaseEmail:     true,
+	CollapseWhitespace: true,
+}
+
+// Policy is the normalization policy applied across all write paths
+var Policy = DefaultPolicy
+
+// NormalizeUsername normalizes a username according to the policy
+func NormalizeUsername(username string) string {
+	if Policy.TrimSpace {
+		username = strings.TrimSpace(username)
+	}
+	return username
+}
+
+// NormalizeEmail normalizes an email address according to the policy and
+// puts it in canonical RFC 5322 form
+func NormalizeEmail(email string) string {
+	if Policy.TrimSpace {
+		email = strings.TrimSpace(email)
+	}
+	if Policy.LowercaseEmail {
+		// The IPv6 tag of an IP literal domain is case-sensitive to net/mail
+		email = strings.Replace(strings.ToLower(email), "@[ipv6:", "@[IPv6:", 1)
+	}
+	// Store the canonical form of a bare address, so "john"@example.com and
+	// john@example.com are the same account. Display names, angle brackets,
+	// comments and surrounding spaces are left for ValidEmail to reject.
+	if email != strings.TrimSpace(email) || strings.ContainsAny(email, "<>()") {
+		return email
+	}
+	if addr, err := mail.ParseAddress(email); err == nil && addr.Name == "" {
+		return addrSpec(addr)
+	}
+	return email
+}
+
+// addrSpec returns a parsed address as a bare addr-spec, quoting the local
+// part only when it needs to be
+func addrSpec(addr *mail.Address) string {
+	return strings.TrimSuffix(strings.TrimPrefix((&mail.Address{Address: addr.Address}).String(), "<"), ">")
+}
+
+// NormalizeText normalizes a free-text field (e.g. an organization name) according to the policy
+func NormalizeText(text string) string {
+	if Policy.CollapseWhitespace {
+		return strings.Join(strings.Fields(text), " ")
+	}
+	if Policy.TrimSpace {
+		text = strings.TrimSpace(text)
+	}
+	return text
+}
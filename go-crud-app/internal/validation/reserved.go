@@ -0,0 +1,108 @@
+package validation
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// ReservedUsernameMessage describes a username that may not be chosen
+const ReservedUsernameMessage = "This username is reserved"
+
+//go:embed reserved_usernames.txt
+var reservedUsernameList string
+
+// DefaultReservedUsernames is the bundled list of reserved usernames
+var DefaultReservedUsernames = parseReservedUsernames(reservedUsernameList)
+
+// UsernamePolicy lists the usernames users may not register or change to.
+// Matching ignores case, so reserving "admin" also reserves "Admin".
+type UsernamePolicy struct {
+	Reserved map[string]bool // Lowercased reserved usernames
+}
+
+// Usernames is the username policy applied to self-registration and
+// profile updates
+var Usernames = UsernamePolicy{Reserved: DefaultReservedUsernames}
+
+// NewUsernamePolicy returns a policy reserving the given usernames, plus
+// the bundled list when withDefaults is set
+func NewUsernamePolicy(withDefaults bool, usernames ...string) UsernamePolicy {
+	reserved := make(map[string]bool)
+	if withDefaults {
+		for username := range DefaultReservedUsernames {
+			reserved[username] = true
+		}
+	}
+	for _, username := range usernames {
+		reserved[strings.ToLower(username)] = true
+	}
+	return UsernamePolicy{Reserved: reserved}
+}
+
+// PermitsUsername reports whether username may be chosen by a user
+func (p UsernamePolicy) PermitsUsername(username string) bool {
+	return !p.Reserved[strings.ToLower(username)]
+}
+
+// ReserveRouteSegments returns a copy of the policy that also reserves the
+// route segments a username could be confused with: every literal segment of
+// paths that sits where another route takes a parameter, such as "inactive"
+// in /api/users/inactive beside /api/users/:id. Segments that aren't valid
+// usernames, such as "me", can't collide and are skipped.
+func (p UsernamePolicy) ReserveRouteSegments(paths ...string) UsernamePolicy {
+	reserved := make(map[string]bool, len(p.Reserved))
+	for username := range p.Reserved {
+		reserved[username] = true
+	}
+
+	// Prefixes followed by a parameter, e.g. "/api/users/" for /api/users/:id
+	parameterized := make(map[string]bool)
+	for _, path := range paths {
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+				parameterized[strings.Join(segments[:i], "/")] = true
+			}
+		}
+	}
+	for _, path := range paths {
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if parameterized[strings.Join(segments[:i], "/")] && ValidUsername(segment) {
+				reserved[strings.ToLower(segment)] = true
+			}
+		}
+	}
+	return UsernamePolicy{Reserved: reserved}
+}
+
+// ParseUsernameList parses a comma-separated list of usernames, rejecting
+// entries no user could register anyway
+func ParseUsernameList(s string) ([]string, error) {
+	var usernames []string
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !ValidUsername(entry) {
+			return nil, fmt.Errorf("invalid username %q", entry)
+		}
+		usernames = append(usernames, entry)
+	}
+	return usernames, nil
+}
+
+// parseReservedUsernames reads the bundled list: one username per line, with
+// blank lines and # comments ignored
+func parseReservedUsernames(list string) map[string]bool {
+	usernames := make(map[string]bool)
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			usernames[line] = true
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,78 @@
+package validation
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// UsernameMessage describes the username rules
+	UsernameMessage = "Username must be 3-50 characters and contain only letters, numbers, and underscores"
+	// EmailMessage describes an invalid email address
+	EmailMessage = "Invalid email format"
+	// MaxEmailLength is the longest email address accepted, in characters,
+	// matching the users.email column
+	MaxEmailLength = 100
+)
+
+var (
+	// Username validation regex (alphanumeric and underscore, 3-50 chars)
+	usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]{3,50}$`)
+)
+
+// ValidUsername reports whether a normalized username meets the username rules
+func ValidUsername(username string) bool {
+	return usernameRegex.MatchString(username)
+}
+
+// ValidEmail reports whether a normalized email address is well formed: a
+// bare RFC 5322 addr-spec, as parsed by net/mail, without a display name or
+// comments, whose domain is a dotted host name or an IP literal. Quoted local
+// parts such as "john doe"@example.com are accepted, in the canonical form
+// NormalizeEmail gives them.
+func ValidEmail(email string) bool {
+	// Cheap checks first, so obviously malformed input never reaches the parser
+	if email == "" || utf8.RuneCountInString(email) > MaxEmailLength || !strings.Contains(email, "@") ||
+		strings.IndexFunc(email, unicode.IsControl) >= 0 {
+		return false
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addrSpec(addr) != email {
+		return false
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	return validEmailDomain(addr.Address[at+1:])
+}
+
+// validEmailDomain reports whether domain is an IP literal such as
+// [192.0.2.1] or [IPv6:2001:db8::1], or a host name of at least two labels.
+// net/mail also allows single-label domains like "localhost", which can't
+// receive mail from the internet.
+func validEmailDomain(domain string) bool {
+	// net/mail has already checked the address in an IP literal
+	if strings.HasPrefix(domain, "[") {
+		return true
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if !domainLabelRegex.MatchString(label) {
+			return false
+		}
+	}
+	// The top-level domain has at least two characters and is never all
+	// digits, which would be an IP address
+	tld := labels[len(labels)-1]
+	return utf8.RuneCountInString(tld) >= 2 && strings.IndexFunc(tld, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0
+}
+
+// domainLabelRegex matches a host name label: letters (including
+// internationalized ones), digits and inner hyphens, up to 63 characters
+var domainLabelRegex = regexp.MustCompile(`^[\p{L}\p{N}](?:[\p{L}\p{N}\-]{0,61}[\p{L}\p{N}])?$`)
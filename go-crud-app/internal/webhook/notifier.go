@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// User lifecycle events delivered to webhook endpoints
+const (
+	EventUserRegistered  = "user.registered"
+	EventUserReactivated = "user.reactivated"
+	EventUserUpdated     = "user.updated"
+	EventUserDeleted     = "user.deleted"
+)
+
+const (
+	// DefaultMaxAttempts is how many times a delivery is tried before it is dead-lettered
+	DefaultMaxAttempts = 5
+	// DefaultInitialBackoff is the wait before the first retry; it doubles on each retry
+	DefaultInitialBackoff = 1 * time.Second
+	// DefaultMaxBackoff caps the wait between retries
+	DefaultMaxBackoff = 1 * time.Minute
+	// DefaultQueueSize is how many deliveries can wait in the queue
+	DefaultQueueSize = 1000
+	// DefaultTimeout bounds a single delivery attempt
+	DefaultTimeout = 10 * time.Second
+)
+
+// Event is the JSON payload delivered for a user lifecycle event. ID is the
+// same across retries so receivers can deduplicate.
+type Event struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	UserID    uint      `json:"user_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// DeadLetterFunc is called with a delivery that was given up on
+type DeadLetterFunc func(endpoint Endpoint, body []byte, attempts int, err error)
+
+// Config configures a Notifier. Zero values fall back to the defaults.
+type Config struct {
+	Endpoints      []Endpoint
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	QueueSize      int
+	Timeout        time.Duration
+	Client         *http.Client
+	// DeadLetter receives deliveries that failed every attempt, could not be
+	// retried or did not fit in the queue; it logs them by default
+	DeadLetter DeadLetterFunc
+}
+
+// errQueueFull is passed to the dead-letter handler when the queue has no room
+var errQueueFull = errors.New("webhook queue is full")
+
+// delivery is one event on its way to one endpoint
+type delivery struct {
+	endpoint Endpoint
+	body     []byte
+	attempts int
+	// spanContext is the span that raised the event, so delivery attempts
+	// join the originating request's trace
+	spanContext trace.SpanContext
+}
+
+// Notifier delivers signed lifecycle events to the configured endpoints from
+// a background queue, retrying failures with exponential backoff. Deliveries
+// still queued or waiting to retry at shutdown are lost.
+type Notifier struct {
+	config Config
+	queue  chan delivery
+}
+
+// NewNotifier creates a notifier. Start Run in the background (typically via
+// the supervisor) to deliver queued events.
+func NewNotifier(config Config) *Notifier {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = DefaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	}
+	if config.DeadLetter == nil {
+		config.DeadLetter = logDeadLetter
+	}
+
+	return &Notifier{
+		config: config,
+		queue:  make(chan delivery, config.QueueSize),
+	}
+}
+
+// Notify queues event for userID to every endpoint without waiting for
+// delivery. Deliveries are traced as part of the trace in ctx. It is safe to
+// call on a nil notifier, which does nothing.
+func (n *Notifier) Notify(ctx context.Context, event string, userID uint) {
+	if n == nil || len(n.config.Endpoints) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(Event{
+		ID:        newEventID(),
+		Event:     event,
+		UserID:    userID,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to encode webhook event %s: %v", event, err)
+		return
+	}
+
+	for _, endpoint := range n.config.Endpoints {
+		n.enqueue(delivery{endpoint: endpoint, body: body, spanContext: trace.SpanContextFromContext(ctx)})
+	}
+}
+
+// Run delivers queued events until ctx is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-n.queue:
+			n.deliver(ctx, d)
+		}
+	}
+}
+
+// deliver attempts d once and schedules a retry or dead-letters it on failure
+func (n *Notifier) deliver(ctx context.Context, d delivery) {
+	d.attempts++
+
+	retryable, err := n.send(ctx, d)
+	if err == nil {
+		return
+	}
+	if !retryable || d.attempts >= n.config.MaxAttempts {
+		n.config.DeadLetter(d.endpoint, d.body, d.attempts, err)
+		return
+	}
+
+	// Wait off the queue so a failing endpoint doesn't hold up other deliveries
+	time.AfterFunc(n.backoff(d.attempts), func() { n.enqueue(d) })
+}
+
+// send makes one delivery attempt. Network errors, timeouts, 5xx, 408 and 429
+// responses are retryable; other non-2xx responses are not.
+func (n *Notifier) send(ctx context.Context, d delivery) (bool, error) {
+	ctx, cancel := context.WithTimeout(trace.ContextWithSpanContext(ctx, d.spanContext), n.config.Timeout)
+	defer cancel()
+
+	req, err := NewRequest(ctx, d.endpoint, d.body, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := n.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("webhook endpoint responded with status %d", resp.StatusCode)
+	retryable := resp.StatusCode >= http.StatusInternalServerError ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retryable, err
+}
+
+// backoff returns the wait before retrying after the given number of attempts
+func (n *Notifier) backoff(attempts int) time.Duration {
+	wait := n.config.InitialBackoff
+	for i := 1; i < attempts && wait < n.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, n.config.MaxBackoff)
+}
+
+// enqueue adds d to the queue, dead-lettering it when the queue is full
+func (n *Notifier) enqueue(d delivery) {
+	select {
+	case n.queue <- d:
+	default:
+		n.config.DeadLetter(d.endpoint, d.body, d.attempts, errQueueFull)
+	}
+}
+
+// logDeadLetter records a failed delivery in the server log
+func logDeadLetter(endpoint Endpoint, body []byte, attempts int, err error) {
+	log.Printf("Webhook dead letter: url=%s attempts=%d error=%v payload=%s", endpoint.URL, attempts, err, body)
+}
+
+// newEventID returns a random identifier for an event
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
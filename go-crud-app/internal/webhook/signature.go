@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the payload signature(s), e.g. "v1=5257a869..."
+	SignatureHeader = "X-Signature"
+	// TimestampHeader carries the Unix time the payload was signed at
+	TimestampHeader = "X-Timestamp"
+	// DefaultTolerance is how far a signed timestamp may drift before receivers reject it
+	DefaultTolerance = 5 * time.Minute
+
+	// signatureScheme prefixes signatures so the algorithm can change without
+	// breaking receivers
+	signatureScheme = "v1"
+)
+
+var (
+	// ErrInvalidSignature is returned when no signature matches the payload
+	ErrInvalidSignature = errors.New("webhook signature does not match payload")
+	// ErrStaleTimestamp is returned when the signed timestamp is outside the tolerance
+	ErrStaleTimestamp = errors.New("webhook timestamp is outside the allowed tolerance")
+)
+
+// Endpoint is a webhook receiver with its own signing secret
+type Endpoint struct {
+	URL    string
+	Secret string
+}
+
+// EndpointsFromEnv parses WEBHOOK_ENDPOINTS, a comma-separated list of
+// "url|secret" pairs. Endpoints without their own secret fall back to
+// WEBHOOK_SECRET.
+func EndpointsFromEnv() []Endpoint {
+	defaultSecret := os.Getenv("WEBHOOK_SECRET")
+
+	var endpoints []Endpoint
+	for _, entry := range strings.Split(os.Getenv("WEBHOOK_ENDPOINTS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		url, secret, found := strings.Cut(entry, "|")
+		if !found || secret == "" {
+			secret = defaultSecret
+		}
+		endpoints = append(endpoints, Endpoint{URL: strings.TrimSpace(url), Secret: secret})
+	}
+	return endpoints
+}
+
+// Sign returns the signature of body at the given timestamp. The timestamp is
+// part of the signed content ("timestamp.body") so it cannot be altered to
+// replay an old delivery.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureScheme + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewRequest builds a signed POST request delivering body to the endpoint
+func NewRequest(ctx context.Context, endpoint Endpoint, body []byte, now time.Time) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, now, body))
+	return req, nil
+}
+
+// Verify checks the signature and timestamp headers of a delivery, as a
+// receiver would. The signature header may list several comma-separated
+// signatures (e.g. during secret rotation); any match is accepted. Deliveries
+// signed more than tolerance away from now are rejected to prevent replays.
+func Verify(secret, signatureHeader, timestampHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	timestamp := time.Unix(seconds, 0)
+
+	if tolerance > 0 {
+		if drift := now.Sub(timestamp); drift > tolerance || drift < -tolerance {
+			return ErrStaleTimestamp
+		}
+	}
+
+	expected := Sign(secret, timestamp, body)
+	for _, signature := range strings.Split(signatureHeader, ",") {
+		if hmac.Equal([]byte(strings.TrimSpace(signature)), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
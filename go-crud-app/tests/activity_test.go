@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newActivityRouter builds a router exposing login, user profiles and the
+// admin inactive users report
+func newActivityRouter() *gin.Engine {
+	router := newRefreshRouter()
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.GET("/inactive", middleware.RequireAdmin(), handlers.ListInactiveUsers)
+		users.GET("/:id", handlers.GetUserByID)
+	}
+	return router
+}
+
+// reloadUser reads user back from the database
+func reloadUser(t *testing.T, id uint) models.User {
+	t.Helper()
+
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		t.Fatalf("Failed to load user: %v", err)
+	}
+	return user
+}
+
+// setActivity backdates a user's creation and last-seen times
+func setActivity(t *testing.T, user models.User, createdAt time.Time, lastSeenAt *time.Time) {
+	t.Helper()
+
+	if err := database.DB.Model(&user).UpdateColumns(map[string]interface{}{
+		"created_at":   createdAt,
+		"last_seen_at": lastSeenAt,
+	}).Error; err != nil {
+		t.Fatalf("Failed to backdate user: %v", err)
+	}
+}
+
+func TestLoginRecordsActivity(t *testing.T) {
+	setupTestDB(t)
+	router := newActivityRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	other := createTestUser(t, "other", "other@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	before := time.Now().Add(-time.Second)
+	loginTestUser(t, router, user)
+
+	stored := reloadUser(t, user.ID)
+	if stored.LastLoginAt == nil || stored.LastLoginAt.Before(before) {
+		t.Fatalf("Expected the login time to be recorded, but got %v", stored.LastLoginAt)
+	}
+	if stored.LastSeenAt == nil || !stored.LastSeenAt.Equal(*stored.LastLoginAt) {
+		t.Errorf("Expected the user to be seen at login, but got %v", stored.LastSeenAt)
+	}
+	if stored.Version != user.Version {
+		t.Errorf("Expected login to leave the version at %d, but got %d", user.Version, stored.Version)
+	}
+
+	path := fmt.Sprintf("/users/%d", user.ID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, path, "", admin))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp models.UserResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.LastLoginAt == nil || !resp.LastLoginAt.Equal(*stored.LastLoginAt) {
+		t.Errorf("Expected admins to see the login time %v, but got %v", stored.LastLoginAt, resp.LastLoginAt)
+	}
+
+	// Neither other users nor the user themselves see it
+	for _, viewer := range []models.User{other, user} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, http.MethodGet, path, "", viewer))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+		}
+		if body := w.Body.String(); strings.Contains(body, "last_login_at") || strings.Contains(body, "last_seen_at") {
+			t.Errorf("Expected no activity times for %s, but got %s", viewer.Username, body)
+		}
+	}
+}
+
+func TestLastSeenIsThrottled(t *testing.T) {
+	setupTestDB(t)
+	router := newActivityRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	// Seen within the interval: requests don't write
+	recent := time.Now().Add(-middleware.LastSeenTouchInterval / 2).Truncate(time.Second)
+	setActivity(t, user, user.CreatedAt, &recent)
+	for range 3 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me", "", user))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if seen := reloadUser(t, user.ID).LastSeenAt; seen == nil || !seen.Equal(recent) {
+		t.Errorf("Expected last seen to stay at %v, but got %v", recent, seen)
+	}
+
+	// Seen longer ago: the next request refreshes it
+	stale := time.Now().Add(-2 * middleware.LastSeenTouchInterval)
+	setActivity(t, user, user.CreatedAt, &stale)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me", "", user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if seen := reloadUser(t, user.ID).LastSeenAt; seen == nil || time.Since(*seen) > time.Minute {
+		t.Errorf("Expected last seen to be refreshed, but got %v", seen)
+	}
+}
+
+func TestListInactiveUsers(t *testing.T) {
+	setupTestDB(t)
+	router := newActivityRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	now := time.Now()
+	longAgo := now.AddDate(0, 0, -90)
+
+	dormant := createTestUser(t, "dormant", "dormant@example.com")
+	seen := now.AddDate(0, 0, -40)
+	setActivity(t, dormant, longAgo, &seen)
+
+	neverSeen := createTestUser(t, "neverseen", "neverseen@example.com")
+	setActivity(t, neverSeen, now.AddDate(0, 0, -60), nil)
+
+	active := createTestUser(t, "active", "active@example.com")
+	seenRecently := now.AddDate(0, 0, -5)
+	setActivity(t, active, longAgo, &seenRecently)
+
+	newcomer := createTestUser(t, "newcomer", "newcomer@example.com")
+	setActivity(t, newcomer, now.AddDate(0, 0, -1), nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/inactive?days=30", "", admin))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Users []models.UserResponse `json:"users"`
+		Days  int                   `json:"days"`
+		Total int64                 `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Users) != 2 {
+		t.Fatalf("Expected 2 inactive users, but got %d: %s", resp.Total, w.Body.String())
+	}
+	// Least recently seen first; a user never seen counts from creation
+	if resp.Users[0].ID != neverSeen.ID || resp.Users[1].ID != dormant.ID {
+		t.Errorf("Expected users %d and %d, but got %d and %d", neverSeen.ID, dormant.ID, resp.Users[0].ID, resp.Users[1].ID)
+	}
+	if resp.Users[1].LastSeenAt == nil {
+		t.Errorf("Expected the admin response to include last seen, but got %s", w.Body.String())
+	}
+	if resp.Days != 30 {
+		t.Errorf("Expected days 30, but got %d", resp.Days)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/inactive?days=30&per_page=1&page=2", "", admin))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"username":"dormant"`) {
+		t.Errorf("Expected the second page to hold dormant, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestListInactiveUsersValidation(t *testing.T) {
+	setupTestDB(t)
+	router := newActivityRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	for _, path := range []string{"/users/inactive", "/users/inactive?days=0"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, http.MethodGet, path, "", admin))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, but got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/inactive?days=30", "", user))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a non-admin, but got %d: %s", w.Code, w.Body.String())
+	}
+}
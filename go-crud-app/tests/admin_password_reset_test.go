@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAdminPasswordResetRouter builds a router exposing login, the current
+// user and the admin password reset route
+func newAdminPasswordResetRouter() *gin.Engine {
+	router := newRefreshRouter()
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	users.Use(middleware.PasswordChangeGate("/users/me/password"))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.POST("/:id/reset-password", middleware.RequireAdmin(), handlers.AdminResetPassword)
+	}
+	return router
+}
+
+// adminResetPassword has caller reset the password of user, returning the response
+func adminResetPassword(t *testing.T, router *gin.Engine, caller models.User, userID uint, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, fmt.Sprintf("/users/%d/reset-password", userID), body, caller))
+	return w
+}
+
+// decodeAdminReset decodes a successful admin password reset response
+func decodeAdminReset(t *testing.T, w *httptest.ResponseRecorder) handlers.AdminResetPasswordResponse {
+	t.Helper()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp handlers.AdminResetPasswordResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+// login logs in with email and password, returning the tokens
+func login(t *testing.T, router *gin.Engine, email, password string) handlers.AuthResponse {
+	t.Helper()
+
+	w := postJSON(router, "/auth/login", fmt.Sprintf(`{"email":%q,"password":%q}`, email, password))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestAdminResetPasswordRequiresAdmin(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminPasswordResetRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+	other := createTestUser(t, "other", "other@example.com")
+
+	w := adminResetPassword(t, router, user, other.ID, `{"password":"NewPassword123"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+	if stored := reloadUser(t, other.ID); stored.PasswordHash != other.PasswordHash || stored.TokenVersion != other.TokenVersion {
+		t.Error("Expected the password to be unchanged")
+	}
+}
+
+func TestAdminResetPasswordForcesChange(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminPasswordResetRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	session := loginTestUser(t, router, user)
+
+	// A locked-out user gets back in with the new password
+	database.DB.Model(&user).Updates(map[string]interface{}{
+		"failed_login_attempts": 10,
+		"next_login_allowed_at": time.Now().Add(time.Hour),
+	})
+
+	w := adminResetPassword(t, router, admin, user.ID, `{"password":"NewPassword123"}`)
+	resp := decodeAdminReset(t, w)
+	if !resp.MustChangePassword || resp.TemporaryPassword != "" {
+		t.Errorf("Expected a forced change and no generated password, but got %+v", resp)
+	}
+	if strings.Contains(w.Body.String(), "password_hash") || strings.Contains(w.Body.String(), reloadUser(t, user.ID).PasswordHash) {
+		t.Errorf("Expected no password hash in the response, but got %s", w.Body.String())
+	}
+
+	// Existing sessions are revoked
+	if w := getMe(router, session.Token); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old token to be revoked, but got %d", w.Code)
+	}
+
+	// The next login only allows a password change
+	next := login(t, router, user.Email, "NewPassword123")
+	if !next.MustChangePassword {
+		t.Error("Expected the login to require a password change")
+	}
+	w = getMe(router, next.Token)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 until the password is changed, but got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeError(t, w); body.Code != apierror.CodePasswordChangeRequired {
+		t.Errorf("Expected code %s, but got %s", apierror.CodePasswordChangeRequired, body.Code)
+	}
+
+	var audit models.AuditLog
+	if err := database.DB.Where("action = ?", models.AuditAdminPasswordReset).First(&audit).Error; err != nil {
+		t.Fatalf("Expected the reset to be audited: %v", err)
+	}
+	if audit.ActorID == nil || *audit.ActorID != admin.ID || audit.Target != fmt.Sprintf("user:%d", user.ID) {
+		t.Errorf("Expected admin %d to be recorded resetting user %d, but got %+v", admin.ID, user.ID, audit)
+	}
+}
+
+func TestAdminResetPasswordGeneratesTemporaryPassword(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminPasswordResetRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	// A generated password is temporary even when asked otherwise
+	w := adminResetPassword(t, router, admin, user.ID, `{"must_change_password":false}`)
+	resp := decodeAdminReset(t, w)
+	if resp.TemporaryPassword == "" || !resp.MustChangePassword {
+		t.Fatalf("Expected a temporary password that must be changed, but got %+v", resp)
+	}
+	if err := utils.ValidatePassword(resp.TemporaryPassword); err != nil {
+		t.Errorf("Expected the temporary password to meet the policy, but got %v", err)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Errorf("Expected Cache-Control no-store, but got %q", cacheControl)
+	}
+	if next := login(t, router, user.Email, resp.TemporaryPassword); !next.MustChangePassword {
+		t.Error("Expected the login to require a password change")
+	}
+
+	// An empty body generates one too
+	w = adminResetPassword(t, router, admin, user.ID, "")
+	if again := decodeAdminReset(t, w); again.TemporaryPassword == "" || again.TemporaryPassword == resp.TemporaryPassword {
+		t.Errorf("Expected a new temporary password, but got %q", again.TemporaryPassword)
+	}
+
+	// So does an empty chunked body, which has no length
+	req := authRequest(t, http.MethodPost, fmt.Sprintf("/users/%d/reset-password", user.ID), "", admin)
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if chunked := decodeAdminReset(t, w); chunked.TemporaryPassword == "" {
+		t.Error("Expected a temporary password for an empty chunked body")
+	}
+}
+
+func TestAdminResetPasswordRejections(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminPasswordResetRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	superAdmin := createTestUser(t, "super", "super@example.com")
+	database.DB.Model(&superAdmin).Update("role", models.RoleSuperAdmin)
+
+	tests := []struct {
+		name     string
+		userID   uint
+		body     string
+		expected int
+	}{
+		{"weak password", user.ID, `{"password":"weak"}`, http.StatusUnprocessableEntity},
+		{"own account", admin.ID, `{"password":"NewPassword123"}`, http.StatusConflict},
+		{"super-admin", superAdmin.ID, `{"password":"NewPassword123"}`, http.StatusForbidden},
+		{"unknown user", 999, `{"password":"NewPassword123"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := adminResetPassword(t, router, admin, tt.userID, tt.body); w.Code != tt.expected {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
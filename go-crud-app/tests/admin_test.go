@@ -0,0 +1,326 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newAdminRouter builds a router exposing the admin-only user routes
+func newAdminRouter(maxBatch int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.POST("", middleware.RequireAdmin(), handlers.CreateUser)
+		users.POST("/bulk-delete", middleware.RequireAdmin(), handlers.BulkDeleteUsers(maxBatch))
+	}
+	return router
+}
+
+// createTestAdmin inserts a user with the admin role
+func createTestAdmin(t *testing.T, username, email string) models.User {
+	t.Helper()
+
+	user := createTestUser(t, username, email)
+	if err := database.DB.Model(&user).Update("role", models.RoleAdmin).Error; err != nil {
+		t.Fatalf("Failed to promote test user: %v", err)
+	}
+	return user
+}
+
+// userExists reports whether the user is present and not soft-deleted
+func userExists(t *testing.T, id uint) bool {
+	t.Helper()
+
+	var count int64
+	if err := database.DB.Model(&models.User{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	return count > 0
+}
+
+func TestBulkDeleteRequiresAdmin(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+
+	user := createTestUser(t, "testuser", "test@example.com")
+	other := createTestUser(t, "otheruser", "other@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", fmt.Sprintf(`{"ids":[%d]}`, other.ID), user))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, but got %d", w.Code)
+	}
+	if !userExists(t, other.ID) {
+		t.Error("Expected user to survive a non-admin bulk delete")
+	}
+}
+
+func TestBulkDeleteReportsPerIDResults(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	first := createTestUser(t, "first", "first@example.com")
+	second := createTestUser(t, "second", "second@example.com")
+
+	body := fmt.Sprintf(`{"ids":[%d,999,%d,%d]}`, first.ID, admin.ID, second.ID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", body, admin))
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status 207, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results []handlers.BulkDeleteResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	expected := map[uint]int{
+		first.ID:  http.StatusOK,
+		999:       http.StatusNotFound,
+		admin.ID:  http.StatusConflict,
+		second.ID: http.StatusOK,
+	}
+	if len(response.Results) != len(expected) {
+		t.Fatalf("Expected %d results, but got %d", len(expected), len(response.Results))
+	}
+	for _, result := range response.Results {
+		if result.Status != expected[result.ID] {
+			t.Errorf("Expected status %d for ID %d, but got %d", expected[result.ID], result.ID, result.Status)
+		}
+	}
+
+	if userExists(t, first.ID) || userExists(t, second.ID) {
+		t.Error("Expected listed users to be deleted")
+	}
+	if !userExists(t, admin.ID) {
+		t.Error("Expected admin's own account to survive without confirmation")
+	}
+
+	// Deletion is soft: the rows are still present when unscoped
+	var count int64
+	database.DB.Unscoped().Model(&models.User{}).Where("id IN ?", []uint{first.ID, second.ID}).Count(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 soft-deleted rows, but got %d", count)
+	}
+}
+
+func TestBulkDeleteProtectsSuperAdmins(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	superAdmin := createTestUser(t, "super", "super@example.com")
+	database.DB.Model(&superAdmin).Update("role", models.RoleSuperAdmin)
+
+	body := fmt.Sprintf(`{"ids":[%d,%d]}`, superAdmin.ID, user.ID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", body, admin))
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status 207, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Results []handlers.BulkDeleteResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Results) != 2 || response.Results[0].Status != http.StatusForbidden || response.Results[1].Status != http.StatusOK {
+		t.Errorf("Expected the super-admin to be refused and the user deleted, but got %+v", response.Results)
+	}
+	if !userExists(t, superAdmin.ID) || userExists(t, user.ID) {
+		t.Error("Expected only the user to be deleted")
+	}
+
+	// A super-admin may delete another
+	other := createTestUser(t, "other", "other@example.com")
+	database.DB.Model(&other).Update("role", models.RoleSuperAdmin)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", fmt.Sprintf(`{"ids":[%d]}`, superAdmin.ID), other))
+	if w.Code != http.StatusMultiStatus || userExists(t, superAdmin.ID) {
+		t.Errorf("Expected a super-admin to delete a super-admin, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBulkDeleteSelfWithConfirmation(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+
+	body := fmt.Sprintf(`{"ids":[%d],"confirm_self_delete":true}`, admin.ID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", body, admin))
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status 207, but got %d: %s", w.Code, w.Body.String())
+	}
+	if userExists(t, admin.ID) {
+		t.Error("Expected admin's own account to be deleted when confirmed")
+	}
+}
+
+func TestBulkDeleteRejectsOversizedBatch(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminRouter(2)
+
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", `{"ids":[1,2,3]}`, admin))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, but got %d", w.Code)
+	}
+}
+
+func TestBulkDeleteRollsBackOnError(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	first := createTestUser(t, "first", "first@example.com")
+	second := createTestUser(t, "second", "second@example.com")
+
+	// Fail the second delete in the batch
+	deletes := 0
+	err := database.DB.Callback().Delete().Before("gorm:delete").Register("test:fail_second_delete", func(db *gorm.DB) {
+		deletes++
+		if deletes == 2 {
+			db.AddError(errors.New("simulated failure"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"ids":[%d,%d]}`, first.ID, second.ID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", body, admin))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, but got %d", w.Code)
+	}
+	if !userExists(t, first.ID) {
+		t.Error("Expected the first deletion to be rolled back")
+	}
+}
+
+func TestAdminCreateUser(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+
+	admin := createTestAdmin(t, "adminuser", "admin@example.com")
+	user := createTestUser(t, "plainuser", "plain@example.com")
+
+	tests := []struct {
+		name               string
+		caller             models.User
+		body               string
+		expectedStatus     int
+		expectedRole       string
+		expectedMustChange bool
+	}{
+		{
+			name:           "Non-admin is forbidden",
+			caller:         user,
+			body:           `{"username":"newbie","email":"newbie@example.com","password":"TempPass123"}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:               "Defaults to a user with a temporary password",
+			caller:             admin,
+			body:               `{"username":"newbie","email":"newbie@example.com","password":"TempPass123"}`,
+			expectedStatus:     http.StatusCreated,
+			expectedRole:       models.RoleUser,
+			expectedMustChange: true,
+		},
+		{
+			name:               "Admin role with a permanent password",
+			caller:             admin,
+			body:               `{"username":"helper","email":"helper@example.com","password":"TempPass123","role":"admin","must_change_password":false}`,
+			expectedStatus:     http.StatusCreated,
+			expectedRole:       models.RoleAdmin,
+			expectedMustChange: false,
+		},
+		{
+			name:           "Unknown role",
+			caller:         admin,
+			body:           `{"username":"rooty","email":"rooty@example.com","password":"TempPass123","role":"root"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Weak password",
+			caller:         admin,
+			body:           `{"username":"weakling","email":"weak@example.com","password":"weak"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Duplicate email",
+			caller:         admin,
+			body:           `{"username":"another","email":"plain@example.com","password":"TempPass123"}`,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users", tt.body, tt.caller))
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			if strings.Contains(w.Body.String(), "token") {
+				t.Errorf("Expected no token for the new user, but got %s", w.Body.String())
+			}
+			var resp handlers.CreateUserResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.User.Role != tt.expectedRole || resp.MustChangePassword != tt.expectedMustChange {
+				t.Errorf("Expected role %s and must_change_password %v, but got %+v", tt.expectedRole, tt.expectedMustChange, resp)
+			}
+			if location := w.Header().Get("Location"); location != models.UserPath(resp.User.ID) {
+				t.Errorf("Expected Location %s, but got %s", models.UserPath(resp.User.ID), location)
+			}
+
+			var created models.User
+			if err := database.DB.First(&created, resp.User.ID).Error; err != nil {
+				t.Fatalf("Failed to load created user: %v", err)
+			}
+			if created.MustChangePassword != tt.expectedMustChange || !utils.CheckPassword("TempPass123", created.PasswordHash) {
+				t.Errorf("Expected the stored user to have the temporary password and must_change_password %v", tt.expectedMustChange)
+			}
+		})
+	}
+
+	var audit models.AuditLog
+	if err := database.DB.Where("action = ?", models.AuditAdminUserCreated).First(&audit).Error; err != nil {
+		t.Fatalf("Expected an %s audit entry: %v", models.AuditAdminUserCreated, err)
+	}
+	if audit.ActorID == nil || *audit.ActorID != admin.ID {
+		t.Errorf("Expected the audit entry to name admin %d, but got %v", admin.ID, audit.ActorID)
+	}
+}
@@ -0,0 +1,269 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAPIKeyRouter builds a router exposing the API key admin routes and a
+// few user routes to call with keys
+func newAPIKeyRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	apiKeys := router.Group("/api-keys")
+	apiKeys.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey), middleware.RequireAdmin())
+	{
+		apiKeys.GET("", handlers.ListAPIKeys)
+		apiKeys.POST("", handlers.CreateAPIKey)
+		apiKeys.DELETE("/:id", handlers.RevokeAPIKey)
+	}
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me", middleware.RequireScope(models.ScopeUsersRead), handlers.GetCurrentUser)
+		users.PATCH("/:id", middleware.RequireScope(models.ScopeUsersWrite), handlers.UpdateUser)
+	}
+	return router
+}
+
+// createAPIKey has admin mint an API key from body
+func createAPIKey(t *testing.T, router *gin.Engine, admin models.User, body string) handlers.CreateAPIKeyResponse {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/api-keys", body, admin))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp handlers.CreateAPIKeyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+// apiKeyRequest sends a request authenticated with an API key in the X-API-Key header
+func apiKeyRequest(router *gin.Engine, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.APIKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyAuthenticatesAsOwner(t *testing.T) {
+	setupTestDB(t)
+	router := newAPIKeyRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "service", "service@example.com")
+
+	created := createAPIKey(t, router, admin, fmt.Sprintf(`{"name":"nightly sync","scopes":["users:read"],"user_id":%d}`, user.ID))
+	if !strings.HasPrefix(created.Key, models.APIKeyPrefix) || !strings.HasPrefix(created.Key, created.APIKey.Hint) {
+		t.Fatalf("Expected a %s key starting with its hint, but got %+v", models.APIKeyPrefix, created)
+	}
+
+	var stored models.APIKey
+	if err := database.DB.First(&stored, created.APIKey.ID).Error; err != nil {
+		t.Fatalf("Failed to load API key: %v", err)
+	}
+	if stored.KeyHash == created.Key || strings.Contains(stored.KeyHash, created.Key) {
+		t.Error("Expected only a hash of the key to be stored")
+	}
+
+	// Both header forms are accepted
+	headers := map[string]string{
+		middleware.APIKeyHeader: created.Key,
+		"Authorization":         "ApiKey " + created.Key,
+	}
+	for header, value := range headers {
+		req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 with %s, but got %d: %s", header, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), `"username":"service"`) {
+			t.Errorf("Expected the key's owner, but got %s", w.Body.String())
+		}
+	}
+
+	if err := database.DB.First(&stored, created.APIKey.ID).Error; err != nil {
+		t.Fatalf("Failed to load API key: %v", err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("Expected the key's last use to be recorded")
+	}
+}
+
+func TestRevokedAndExpiredAPIKeysAreRejected(t *testing.T) {
+	setupTestDB(t)
+	router := newAPIKeyRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+
+	revoked := createAPIKey(t, router, admin, `{"name":"revoked","scopes":["users:read"]}`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodDelete, fmt.Sprintf("/api-keys/%d", revoked.APIKey.ID), "", admin))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	expired := createAPIKey(t, router, admin, fmt.Sprintf(`{"name":"expired","scopes":["users:read"],"expires_at":%q}`,
+		time.Now().Add(time.Hour).Format(time.RFC3339)))
+	database.DB.Model(&models.APIKey{}).Where("id = ?", expired.APIKey.ID).Update("expires_at", time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name    string
+		key     string
+		message string
+	}{
+		{"Revoked", revoked.Key, "API key has been revoked"},
+		{"Expired", expired.Key, "API key has expired"},
+		{"Unknown", models.APIKeyPrefix + "0123456789abcdef", "Invalid API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := apiKeyRequest(router, http.MethodGet, "/users/me", tt.key, "")
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401, but got %d: %s", w.Code, w.Body.String())
+			}
+			if body := decodeError(t, w); body.Code != apierror.CodeTokenInvalid || body.Message != tt.message {
+				t.Errorf("Expected %s with %q, but got %s with %q", apierror.CodeTokenInvalid, tt.message, body.Code, body.Message)
+			}
+		})
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	setupTestDB(t)
+	router := newAPIKeyRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+
+	readOnly := createAPIKey(t, router, admin, `{"name":"reader","scopes":["users:read"]}`)
+	readWrite := createAPIKey(t, router, admin, `{"name":"writer","scopes":["users:read","users:write"]}`)
+	adminKey := createAPIKey(t, router, admin, `{"name":"admin","scopes":["users:read","admin"]}`)
+	profilePath := fmt.Sprintf("/users/%d", admin.ID)
+
+	tests := []struct {
+		name           string
+		key            string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"Read scope allows reads", readOnly.Key, http.MethodGet, "/users/me", "", http.StatusOK},
+		{"Read scope denies writes", readOnly.Key, http.MethodPatch, profilePath, `{"username":"renamed"}`, http.StatusForbidden},
+		{"Write scope allows writes", readWrite.Key, http.MethodPatch, profilePath, `{"username":"renamed"}`, http.StatusOK},
+		{"Admin routes need the admin scope", readOnly.Key, http.MethodGet, "/api-keys", "", http.StatusForbidden},
+		{"Admin scope allows admin routes", adminKey.Key, http.MethodGet, "/api-keys", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := apiKeyRequest(router, tt.method, tt.path, tt.key, tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	setupTestDB(t)
+	router := newAPIKeyRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	superAdmin := setRole(t, createTestUser(t, "super", "super@example.com"), models.RoleSuperAdmin)
+
+	tests := []struct {
+		name           string
+		actor          models.User
+		body           string
+		expectedStatus int
+	}{
+		{"Non-admin", user, `{"name":"key","scopes":["users:read"]}`, http.StatusForbidden},
+		{"Unknown scope", admin, `{"name":"key","scopes":["users:delete"]}`, http.StatusUnprocessableEntity},
+		{"No scopes", admin, `{"name":"key","scopes":[]}`, http.StatusUnprocessableEntity},
+		{"Past expiry", admin, `{"name":"key","scopes":["users:read"],"expires_at":"2020-01-01T00:00:00Z"}`, http.StatusUnprocessableEntity},
+		{"Unknown owner", admin, `{"name":"key","scopes":["users:read"],"user_id":9999}`, http.StatusUnprocessableEntity},
+		{"Super-admin owner", admin, fmt.Sprintf(`{"name":"key","scopes":["users:read"],"user_id":%d}`, superAdmin.ID), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodPost, "/api-keys", tt.body, tt.actor))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	var count int64
+	database.DB.Model(&models.APIKey{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no API keys to be created, but got %d", count)
+	}
+}
+
+func TestCreateAPIKeyCannotExceedScopes(t *testing.T) {
+	setupTestDB(t)
+	router := newAPIKeyRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	adminOnly := createAPIKey(t, router, admin, `{"name":"minter","scopes":["admin"]}`)
+
+	narrowToken, err := utils.GenerateToken(utils.TokenOptions{UserID: admin.ID, Username: admin.Username, Email: admin.Email, OrgID: admin.OrgID, TokenVersion: admin.TokenVersion, Scopes: []string{models.ScopeUsersRead, models.ScopeAdmin}}, testJWTConfig)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		apiKey         string
+		body           string
+		expectedStatus int
+	}{
+		{"Narrowed token mints a wider key", narrowToken, "", `{"name":"key","scopes":["users:write"]}`, http.StatusUnprocessableEntity},
+		{"Narrowed token mints a key within its scopes", narrowToken, "", `{"name":"key","scopes":["users:read"]}`, http.StatusCreated},
+		{"Admin-only key mints a wider key", "", adminOnly.Key, `{"name":"key","scopes":["users:write"]}`, http.StatusUnprocessableEntity},
+		{"Admin scope for a plain user", narrowToken, "", fmt.Sprintf(`{"name":"key","scopes":["admin"],"user_id":%d}`, user.ID), http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w *httptest.ResponseRecorder
+			if tt.apiKey != "" {
+				w = apiKeyRequest(router, http.MethodPost, "/api-keys", tt.apiKey, tt.body)
+			} else {
+				w = bearerRequest(router, http.MethodPost, "/api-keys", tt.token, tt.body)
+			}
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusUnprocessableEntity {
+				if resp := decodeError(t, w); len(resp.Fields) != 1 || resp.Fields[0].Field != "scopes" {
+					t.Errorf("Expected one error on scopes, but got %+v", resp.Fields)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newAuditRouter exposes the audited routes and the admin audit log listing
+func newAuditRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", handlers.Register(testJWTConfig))
+	router.POST("/login", handlers.Login(testJWTConfig))
+
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.PUT("/me/password", handlers.ChangePassword(testJWTConfig))
+		users.DELETE("/:id", handlers.DeleteUser)
+	}
+	router.GET("/audit-logs", middleware.AuthMiddleware(testJWTConfig.SecretKey), middleware.RequireAdmin(), handlers.ListAuditLogs)
+	return router
+}
+
+// auditLogs returns all audit entries oldest first
+func auditLogs(t *testing.T) []models.AuditLog {
+	t.Helper()
+
+	var logs []models.AuditLog
+	if err := database.DB.Order("id").Find(&logs).Error; err != nil {
+		t.Fatalf("Failed to load audit logs: %v", err)
+	}
+	return logs
+}
+
+func TestAuditLogRecordsAuthEvents(t *testing.T) {
+	setupTestDB(t)
+	router := newAuditRouter()
+
+	postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"OldPassword123"}`)
+
+	if w := postJSON(router, "/login", `{"email":"nobody@example.com","password":"Secret123"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, but got %d", w.Code)
+	}
+	if w := postJSON(router, "/login", `{"email":"test@example.com","password":"WrongPass123"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, but got %d", w.Code)
+	}
+	token := loginToken(t, router, "test@example.com", "OldPassword123")
+
+	w := bearerRequest(router, http.MethodPut, "/users/me/password", token,
+		`{"current_password":"OldPassword123","new_password":"NewPassword123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var user models.User
+	database.DB.Where("email = ?", "test@example.com").First(&user)
+
+	logs := auditLogs(t)
+	expected := []struct {
+		action string
+		target string
+		actor  bool
+	}{
+		{models.AuditLoginFailed, "email:nobody@example.com", false},
+		{models.AuditLoginFailed, fmt.Sprintf("user:%d", user.ID), true},
+		{models.AuditLogin, fmt.Sprintf("user:%d", user.ID), true},
+		{models.AuditPasswordChanged, fmt.Sprintf("user:%d", user.ID), true},
+	}
+	if len(logs) != len(expected) {
+		t.Fatalf("Expected %d audit entries, but got %d: %+v", len(expected), len(logs), logs)
+	}
+	for i, e := range expected {
+		if logs[i].Action != e.action || logs[i].Target != e.target {
+			t.Errorf("Entry %d: expected %s on %s, but got %s on %s", i, e.action, e.target, logs[i].Action, logs[i].Target)
+		}
+		if (logs[i].ActorID != nil) != e.actor {
+			t.Errorf("Entry %d: expected actor present %v, but got %v", i, e.actor, logs[i].ActorID)
+		}
+		if logs[i].IP == "" {
+			t.Errorf("Entry %d: expected IP to be recorded", i)
+		}
+	}
+
+	// Nothing secret may end up in the audit table
+	raw, _ := json.Marshal(logs)
+	for _, secret := range []string{"OldPassword123", "NewPassword123", "WrongPass123", "Secret123", token} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("Expected audit log not to contain %q", secret)
+		}
+	}
+}
+
+func TestAuditLogRolledBackWithDeletion(t *testing.T) {
+	setupTestDB(t)
+	router := newAuditRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	// Fail every audit insert; the deletion must roll back with it
+	err := database.DB.Callback().Create().Before("gorm:create").Register("test:fail_audit", func(db *gorm.DB) {
+		if db.Statement.Table == "audit_logs" {
+			db.AddError(errors.New("audit unavailable"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodDelete, fmt.Sprintf("/users/%d", user.ID), "", user))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, but got %d: %s", w.Code, w.Body.String())
+	}
+	if !userExists(t, user.ID) {
+		t.Error("Expected deletion to be rolled back when the audit entry fails")
+	}
+}
+
+func TestListAuditLogs(t *testing.T) {
+	setupTestDB(t)
+	router := newAuditRouter()
+
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	for i := 0; i < 3; i++ {
+		database.DB.Create(&models.AuditLog{ActorID: &user.ID, Action: models.AuditLogin, Target: fmt.Sprintf("user:%d", user.ID)})
+	}
+	database.DB.Create(&models.AuditLog{ActorID: &admin.ID, Action: models.AuditAdminBulkDelete, Target: "user:99"})
+
+	tests := []struct {
+		name          string
+		query         string
+		viewer        models.User
+		expectedCode  int
+		expectedCount int
+		expectedTotal int
+	}{
+		{"non-admin forbidden", "", user, http.StatusForbidden, 0, 0},
+		{"all entries", "", admin, http.StatusOK, 4, 4},
+		{"paginated", "?page=2&per_page=3", admin, http.StatusOK, 1, 4},
+		{"filter by action", "?action=" + models.AuditAdminBulkDelete, admin, http.StatusOK, 1, 1},
+		{"filter by actor", fmt.Sprintf("?actor_id=%d", user.ID), admin, http.StatusOK, 3, 3},
+		{"per_page too large", "?per_page=1000", admin, http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodGet, "/audit-logs"+tt.query, "", tt.viewer))
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				AuditLogs []models.AuditLog `json:"audit_logs"`
+				Total     int               `json:"total"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(body.AuditLogs) != tt.expectedCount {
+				t.Errorf("Expected %d entries, but got %d", tt.expectedCount, len(body.AuditLogs))
+			}
+			if body.Total != tt.expectedTotal {
+				t.Errorf("Expected total %d, but got %d", tt.expectedTotal, body.Total)
+			}
+		})
+	}
+}
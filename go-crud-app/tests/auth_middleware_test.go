@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/utils"
+)
+
+func TestAuthMiddlewareErrorCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization func(t *testing.T, userID uint) string
+		expectedCode  string
+		challenge     string
+	}{
+		{
+			name:          "missing header",
+			authorization: func(t *testing.T, userID uint) string { return "" },
+			expectedCode:  apierror.CodeTokenInvalid,
+			challenge:     "Bearer",
+		},
+		{
+			name:          "malformed header",
+			authorization: func(t *testing.T, userID uint) string { return "Token abc" },
+			expectedCode:  apierror.CodeTokenInvalid,
+			challenge:     `Bearer error="invalid_token"`,
+		},
+		{
+			name: "wrong signature",
+			authorization: func(t *testing.T, userID uint) string {
+				return "Bearer " + mustToken(t, userID, 0, utils.JWTConfig{SecretKey: "other-secret", ExpirationHours: 1})
+			},
+			expectedCode: apierror.CodeTokenInvalid,
+			challenge:    `Bearer error="invalid_token"`,
+		},
+		{
+			name: "expired",
+			authorization: func(t *testing.T, userID uint) string {
+				var token string
+				issuedInPast(t, testJWTConfig, func() { token = mustToken(t, userID, 0, testJWTConfig) })
+				return "Bearer " + token
+			},
+			expectedCode: apierror.CodeTokenExpired,
+			challenge:    `Bearer error="invalid_token", error_description="Token has expired"`,
+		},
+		{
+			name: "refresh token",
+			authorization: func(t *testing.T, userID uint) string {
+				token, err := utils.GenerateRefreshToken(utils.TokenOptions{UserID: userID, Username: "testuser", Email: "test@example.com"}, testJWTConfig)
+				if err != nil {
+					t.Fatalf("Failed to generate refresh token: %v", err)
+				}
+				return "Bearer " + token
+			},
+			expectedCode: apierror.CodeTokenInvalid,
+			challenge:    `Bearer error="invalid_token"`,
+		},
+		{
+			name: "revoked",
+			authorization: func(t *testing.T, userID uint) string {
+				token := mustToken(t, userID, 0, testJWTConfig)
+				database.DB.Table("users").Where("id = ?", userID).UpdateColumn("token_version", 1)
+				return "Bearer " + token
+			},
+			expectedCode: apierror.CodeTokenInvalid,
+			challenge:    `Bearer error="invalid_token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			router := newLogoutRouter()
+			user := createTestUser(t, "testuser", "test@example.com")
+
+			req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+			if auth := tt.authorization(t, user.ID); auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401, but got %d", w.Code)
+			}
+
+			if body := decodeError(t, w); body.Code != tt.expectedCode {
+				t.Errorf("Expected code %q, but got %q", tt.expectedCode, body.Code)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, tt.challenge) {
+				t.Errorf("Expected WWW-Authenticate to start with %q, but got %q", tt.challenge, got)
+			}
+		})
+	}
+}
+
+// mustToken generates a token or fails the test
+func mustToken(t *testing.T, userID uint, tokenVersion int, config utils.JWTConfig) string {
+	t.Helper()
+
+	token, err := utils.GenerateToken(utils.TokenOptions{UserID: userID, Username: "testuser", Email: "test@example.com", TokenVersion: tokenVersion}, config)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	return token
+}
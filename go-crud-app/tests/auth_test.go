@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+
+	"gorm.io/gorm"
+)
+
+func TestRegisterDuplicateKeyReturnsConflict(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+
+	// Simulate a concurrent registration winning the race between the
+	// existence check and the insert
+	err := database.DB.Callback().Create().Before("gorm:create").Register("test:duplicate_key", func(db *gorm.DB) {
+		db.AddError(gorm.ErrDuplicatedKey)
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"StrongPass123"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateUserDuplicateUsernameReturnsConflict(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "testuser", "test@example.com")
+	createTestUser(t, "takenname", "taken@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPut, fmt.Sprintf("/users/%d", user.ID), `{"username":"takenname"}`, user))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterSetsLocationHeader(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+
+	w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"StrongPass123"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	expected := fmt.Sprintf("/api/users/%d", resp.User.ID)
+	if location := w.Header().Get("Location"); location != expected {
+		t.Errorf("Expected Location %s, but got %q", expected, location)
+	}
+	if resp.User.Links.Self != expected {
+		t.Errorf("Expected links.self %s, but got %q", expected, resp.User.Links.Self)
+	}
+}
@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/middleware"
+)
+
+const testAuthCookieName = "access_token"
+
+// enableAuthCookie turns on cookie auth for the rest of the test
+func enableAuthCookie(t *testing.T) {
+	t.Helper()
+
+	previous := middleware.AuthCookie
+	middleware.AuthCookie = middleware.AuthCookieConfig{
+		Name:     testAuthCookieName,
+		Path:     "/",
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+	t.Cleanup(func() { middleware.AuthCookie = previous })
+}
+
+func TestAuthMiddlewareTokenSources(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "cookieuser", "cookie@example.com")
+	token := mustToken(t, user.ID, user.TokenVersion, testJWTConfig)
+
+	tests := []struct {
+		name           string
+		cookieAuth     bool
+		header         string
+		cookie         string
+		expectedStatus int
+	}{
+		{"Header only", true, "Bearer " + token, "", http.StatusOK},
+		{"Cookie only", true, "", token, http.StatusOK},
+		{"Cookie ignored when cookie auth is disabled", false, "", token, http.StatusUnauthorized},
+		{"Header takes precedence over a valid cookie", true, "Bearer invalid", token, http.StatusUnauthorized},
+		{"Header takes precedence over an invalid cookie", true, "Bearer " + token, "invalid", http.StatusOK},
+		{"Invalid cookie", true, "", "invalid", http.StatusUnauthorized},
+		{"Neither", true, "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cookieAuth {
+				enableAuthCookie(t)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: testAuthCookieName, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRegisterSetsAuthCookie(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+
+	tests := []struct {
+		name         string
+		cookieAuth   bool
+		username     string
+		expectCookie bool
+	}{
+		{"Cookie auth enabled", true, "cookiejar", true},
+		{"Cookie auth disabled", false, "nocookies", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cookieAuth {
+				enableAuthCookie(t)
+			}
+
+			w := postJSON(router, "/register",
+				`{"username":"`+tt.username+`","email":"`+tt.username+`@example.com","password":"SecurePass123"}`)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status %d, but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+			}
+
+			var cookie *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == testAuthCookieName {
+					cookie = c
+				}
+			}
+			if !tt.expectCookie {
+				if cookie != nil {
+					t.Errorf("Expected no auth cookie, but got %v", cookie)
+				}
+				return
+			}
+
+			if cookie == nil {
+				t.Fatal("Expected an auth cookie, but got none")
+			}
+			if cookie.Value == "" || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode {
+				t.Errorf("Expected a non-empty HttpOnly, Secure, SameSite=Strict cookie, but got %v", cookie)
+			}
+			if expected := testJWTConfig.ExpirationHours * 3600; cookie.MaxAge != expected {
+				t.Errorf("Expected MaxAge %d, but got %d", expected, cookie.MaxAge)
+			}
+		})
+	}
+}
+
+func TestAuthCookieConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name             string
+		sameSite         string
+		secure           string
+		expectedSameSite http.SameSite
+		expectError      bool
+	}{
+		{"Defaults to strict", "", "", http.SameSiteStrictMode, false},
+		{"Lax", "Lax", "", http.SameSiteLaxMode, false},
+		{"None with Secure", "none", "true", http.SameSiteNoneMode, false},
+		{"None without Secure", "none", "false", 0, true},
+		{"Unknown value", "sometimes", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AUTH_COOKIE_NAME", testAuthCookieName)
+			t.Setenv("AUTH_COOKIE_SAMESITE", tt.sameSite)
+			t.Setenv("AUTH_COOKIE_SECURE", tt.secure)
+
+			config, err := middleware.AuthCookieConfigFromEnv()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, but got %+v", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			if config.SameSite != tt.expectedSameSite {
+				t.Errorf("Expected SameSite %v, but got %v", tt.expectedSameSite, config.SameSite)
+			}
+			if !config.HTTPOnly || config.Path != middleware.DefaultAuthCookiePath {
+				t.Errorf("Expected an HttpOnly cookie on %s, but got %+v", middleware.DefaultAuthCookiePath, config)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAvailabilityRouter(t *testing.T, detailed bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	previous := handlers.AvailabilityDetailed
+	handlers.AvailabilityDetailed = detailed
+	t.Cleanup(func() { handlers.AvailabilityDetailed = previous })
+
+	router := gin.New()
+	router.GET("/availability", handlers.CheckAvailability)
+	return router
+}
+
+func getAvailability(router *gin.Engine, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/availability?"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCheckAvailabilityDetailed(t *testing.T) {
+	setupTestDB(t)
+	router := newAvailabilityRouter(t, true)
+	createTestUser(t, "taken", "taken@example.com")
+	deleted := createTestUser(t, "gone", "gone@example.com")
+	if err := database.DB.Delete(&deleted).Error; err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected map[string]bool
+	}{
+		{"Free username", "username=newuser", map[string]bool{"username": true, "available": true}},
+		{"Taken username", "username=taken", map[string]bool{"username": false, "available": false}},
+		{"Free email", "email=new@example.com", map[string]bool{"email": true, "available": true}},
+		{"Taken email", "email=taken@example.com", map[string]bool{"email": false, "available": false}},
+		{"Taken email in another case", "email=Taken@Example.COM", map[string]bool{"email": false, "available": false}},
+		{"Taken email with spaces", "email=%20taken@example.com%20", map[string]bool{"email": false, "available": false}},
+		{"Deleted user's values", "username=gone&email=gone@example.com", map[string]bool{"username": true, "email": true, "available": true}},
+		{"One of two taken", "username=newuser&email=taken@example.com", map[string]bool{"username": true, "email": false, "available": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getAvailability(router, tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp map[string]bool
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(resp) != len(tt.expected) {
+				t.Errorf("Expected %v, but got %v", tt.expected, resp)
+			}
+			for field, available := range tt.expected {
+				if got, ok := resp[field]; !ok || got != available {
+					t.Errorf("Expected %s to be %v, but got %v", field, available, resp)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAvailabilityCombined(t *testing.T) {
+	setupTestDB(t)
+	router := newAvailabilityRouter(t, false)
+	createTestUser(t, "taken", "taken@example.com")
+
+	tests := []struct {
+		name      string
+		query     string
+		available bool
+	}{
+		{"Both free", "username=newuser&email=new@example.com", true},
+		{"Username taken", "username=taken&email=new@example.com", false},
+		{"Email taken", "username=newuser&email=taken@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getAvailability(router, tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+			}
+
+			// Only the combined flag, so the response doesn't say which value is taken
+			if keys := responseKeys(t, w.Body.Bytes()); !slices.Equal(keys, []string{"available"}) {
+				t.Errorf("Expected only the available field, but got %v", keys)
+			}
+			var resp struct {
+				Available bool `json:"available"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Available != tt.available {
+				t.Errorf("Expected available %v, but got %v", tt.available, resp.Available)
+			}
+		})
+	}
+}
+
+func TestCheckAvailabilityRejectsBadInput(t *testing.T) {
+	setupTestDB(t)
+	router := newAvailabilityRouter(t, true)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{"Nothing to check", "", http.StatusBadRequest},
+		{"Invalid username", "username=a!", http.StatusBadRequest},
+		{"Invalid email", "email=not-an-email", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := getAvailability(router, tt.query); w.Code != tt.expected {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("Registration closed", func(t *testing.T) {
+		handlers.RegistrationOpen = false
+		t.Cleanup(func() { handlers.RegistrationOpen = true })
+
+		if w := getAvailability(router, "username=newuser"); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, but got %d", http.StatusForbidden, w.Code)
+		}
+	})
+}
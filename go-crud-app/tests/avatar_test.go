@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/storage"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAvatarRouter builds a router with avatar routes backed by a temporary local store
+func newAvatarRouter(t *testing.T, maxBytes int64) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	config := handlers.AvatarConfig{
+		Store:    store,
+		MaxBytes: maxBytes,
+		Image:    utils.ImageConfig{MaxDimension: 4096, ResizeTo: 64, Format: utils.ImageFormatJPEG, JPEGQuality: 85},
+	}
+
+	router := gin.New()
+	router.Use(middleware.BodyLimitMiddleware(middleware.DefaultMaxBodyBytes, middleware.DefaultMaxJSONDepth))
+	router.GET("/users/:id/avatar", handlers.GetAvatar(store))
+	router.POST("/users/me/avatar",
+		middleware.AuthMiddleware(testJWTConfig.SecretKey),
+		middleware.BodyLimitMiddleware(config.MaxBytes+64<<10, 0),
+		handlers.UploadAvatar(config),
+	)
+	return router
+}
+
+// encodeTestPNG returns a solid PNG of the given size
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 10, G: 120, B: 200, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// avatarUploadRequest builds an authenticated multipart upload request
+func avatarUploadRequest(t *testing.T, user models.User, filename string, data []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("avatar", filename)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req := authRequest(t, http.MethodPost, "/users/me/avatar", body.String(), user)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestAvatarUploadAndServe(t *testing.T) {
+	setupTestDB(t)
+	router := newAvatarRouter(t, handlers.DefaultAvatarMaxBytes)
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, avatarUploadRequest(t, user, "me.png", encodeTestPNG(t, 200, 100)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.UserResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	expectedURL := fmt.Sprintf("/api/users/%d/avatar", user.ID)
+	if response.AvatarURL != expectedURL {
+		t.Errorf("Expected avatar_url %s, but got %q", expectedURL, response.AvatarURL)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/avatar", user.ID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "image/jpeg" {
+		t.Errorf("Expected content type image/jpeg, but got %s", contentType)
+	}
+	header, _, err := image.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to decode served avatar: %v", err)
+	}
+	if header.Width != 64 || header.Height != 32 {
+		t.Errorf("Expected avatar to be downscaled to 64x32, but got %dx%d", header.Width, header.Height)
+	}
+
+	// Unchanged avatars are revalidated with the ETag
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/avatar", user.ID), nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, but got %d", w.Code)
+	}
+}
+
+func TestAvatarUploadRejections(t *testing.T) {
+	setupTestDB(t)
+	router := newAvatarRouter(t, 4<<10)
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	oversized := append(encodeTestPNG(t, 8, 8), bytes.Repeat([]byte{0}, 8<<10)...)
+	truncatedPNG := encodeTestPNG(t, 32, 32)[:60]
+
+	tests := []struct {
+		name           string
+		filename       string
+		data           []byte
+		expectedStatus int
+	}{
+		{
+			name:           "Not an image despite extension",
+			filename:       "avatar.png",
+			data:           []byte("<html><script>alert(1)</script></html>"),
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "Oversized file",
+			filename:       "avatar.png",
+			data:           oversized,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "Image that fails to decode",
+			filename:       "avatar.png",
+			data:           truncatedPNG,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, avatarUploadRequest(t, user, tt.filename, tt.data))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAvatarNotFound(t *testing.T) {
+	setupTestDB(t)
+	router := newAvatarRouter(t, handlers.DefaultAvatarMaxBytes)
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/avatar", user.ID), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newBodyLimitRouter(maxBytes int64, maxDepth int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.BodyLimitMiddleware(maxBytes, maxDepth))
+	router.POST("/register", handlers.Register(testJWTConfig))
+	router.POST("/upload", middleware.BodyLimitMiddleware(4*maxBytes, 0), func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	})
+	return router
+}
+
+func TestOversizedPayloadRejected(t *testing.T) {
+	router := newBodyLimitRouter(1024, middleware.DefaultMaxJSONDepth)
+	payload := `{"username":"` + strings.Repeat("a", 4096) + `","email":"a@example.com","password":"StrongPass123"}`
+
+	w := postJSON(router, "/register", payload)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, but got %d", w.Code)
+	}
+}
+
+func TestOversizedChunkedPayloadRejected(t *testing.T) {
+	router := newBodyLimitRouter(1024, middleware.DefaultMaxJSONDepth)
+	payload := `{"username":"` + strings.Repeat("a", 4096) + `","email":"a@example.com","password":"StrongPass123"}`
+
+	// Unknown Content-Length forces the limit to be enforced while reading
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(payload))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDeeplyNestedPayloadRejected(t *testing.T) {
+	router := newBodyLimitRouter(middleware.DefaultMaxBodyBytes, 8)
+	payload := `{"username":` + strings.Repeat("[", 20) + strings.Repeat("]", 20) + `}`
+
+	w := postJSON(router, "/register", payload)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, but got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "nested too deeply") {
+		t.Errorf("Expected nesting error message, but got %s", w.Body.String())
+	}
+}
+
+func TestBracketsInStringsDoNotCountTowardsDepth(t *testing.T) {
+	router := newBodyLimitRouter(middleware.DefaultMaxBodyBytes, 2)
+	payload := `{"username":"[[[[{{{{\"","email":"bad","password":"x"}`
+
+	w := postJSON(router, "/register", payload)
+	if strings.Contains(w.Body.String(), "nested too deeply") {
+		t.Errorf("Expected brackets inside strings to be ignored, but got %s", w.Body.String())
+	}
+}
+
+func TestRouteLevelBodyLimitOverride(t *testing.T) {
+	router := newBodyLimitRouter(1024, 0)
+
+	w := postJSON(router, "/upload", strings.Repeat("a", 2048))
+	if w.Code != http.StatusOK || w.Body.String() != "2048" {
+		t.Errorf("Expected route override to allow 2048 bytes, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = postJSON(router, "/upload", strings.Repeat("a", 8192))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 above the route limit, but got %d", w.Code)
+	}
+}
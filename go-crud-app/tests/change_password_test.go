@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newSessionRouter exposes login, password changes and reset alongside an
+// authenticated route for checking which tokens are still accepted
+func newSessionRouter(mailer *fakeMailer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", handlers.Register(testJWTConfig))
+	router.POST("/login", handlers.Login(testJWTConfig))
+	router.POST("/forgot-password", handlers.ForgotPassword(handlers.PasswordResetConfig{
+		Mailer:   mailer,
+		TokenTTL: time.Hour,
+		ResetURL: "http://localhost/reset",
+	}))
+	router.POST("/reset-password", handlers.ResetPassword)
+
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.PUT("/me/password", handlers.ChangePassword(testJWTConfig))
+	}
+	return router
+}
+
+// loginToken logs in and returns the issued token
+func loginToken(t *testing.T, router *gin.Engine, email, password string) string {
+	t.Helper()
+
+	w := postJSON(router, "/login", `{"email":"`+email+`","password":"`+password+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+	return resp.Token
+}
+
+// bearerRequest sends a request with the given bearer token
+func bearerRequest(router *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	setupTestDB(t)
+	router := newSessionRouter(&fakeMailer{})
+
+	w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"OldPassword123"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Two sessions, e.g. the user's laptop and an attacker's copy of a token
+	current := loginToken(t, router, "test@example.com", "OldPassword123")
+	other := loginToken(t, router, "test@example.com", "OldPassword123")
+
+	w = bearerRequest(router, http.MethodPut, "/users/me/password", current,
+		`{"current_password":"OldPassword123","new_password":"NewPassword123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Token == "" {
+		t.Fatal("Expected a fresh token in the response")
+	}
+
+	for name, token := range map[string]string{"current": current, "other": other} {
+		if w := bearerRequest(router, http.MethodGet, "/users/me", token, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %s pre-change token to be rejected with 401, but got %d", name, w.Code)
+		}
+	}
+
+	if w := bearerRequest(router, http.MethodGet, "/users/me", resp.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected fresh token to be accepted, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	loginToken(t, router, "test@example.com", "NewPassword123")
+}
+
+func TestChangePasswordValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"wrong current password", `{"current_password":"WrongPassword123","new_password":"NewPassword123"}`, http.StatusUnprocessableEntity},
+		{"weak new password", `{"current_password":"OldPassword123","new_password":"weak"}`, http.StatusUnprocessableEntity},
+		{"missing fields", `{}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			router := newSessionRouter(&fakeMailer{})
+
+			postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"OldPassword123"}`)
+			token := loginToken(t, router, "test@example.com", "OldPassword123")
+
+			w := bearerRequest(router, http.MethodPut, "/users/me/password", token, tt.body)
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+
+			// A failed change leaves existing sessions intact
+			if w := bearerRequest(router, http.MethodGet, "/users/me", token, ""); w.Code != http.StatusOK {
+				t.Errorf("Expected token to remain valid, but got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	setupTestDB(t)
+	mailer := &fakeMailer{}
+	router := newSessionRouter(mailer)
+
+	postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"OldPassword123"}`)
+	session := loginToken(t, router, "test@example.com", "OldPassword123")
+
+	postJSON(router, "/forgot-password", `{"email":"test@example.com"}`)
+	if mailer.count() != 1 {
+		t.Fatalf("Expected 1 email to be sent, but got %d", mailer.count())
+	}
+	resetToken := mailer.sent[0][strings.LastIndex(mailer.sent[0], "token=")+len("token="):]
+
+	w := postJSON(router, "/reset-password", `{"token":"`+resetToken+`","password":"NewPassword123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := bearerRequest(router, http.MethodGet, "/users/me", session, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected pre-reset token to be rejected with 401, but got %d", w.Code)
+	}
+
+	var user models.User
+	database.DB.Where("email = ?", "test@example.com").First(&user)
+	if user.TokenVersion != 1 {
+		t.Errorf("Expected token version 1, but got %d", user.TokenVersion)
+	}
+}
+
+func TestForcedPasswordChangeGate(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/login", handlers.Login(testJWTConfig))
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	users.Use(middleware.PasswordChangeGate("/users/me/password"))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.GET("/:id", handlers.GetUserByID)
+		users.PUT("/me/password", handlers.ChangePassword(testJWTConfig))
+	}
+
+	temporaryHash, err := utils.HashPassword("TempPass123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	user := models.User{Username: "provisioned", Email: "provisioned@example.com", PasswordHash: temporaryHash, MustChangePassword: true}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	profilePath := fmt.Sprintf("/users/%d", user.ID)
+
+	w := postJSON(router, "/login", `{"email":"provisioned@example.com","password":"TempPass123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected login with the temporary password to succeed, but got %d: %s", w.Code, w.Body.String())
+	}
+	var login handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+	if !login.MustChangePassword {
+		t.Error("Expected the login response to flag must_change_password")
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"Own profile is blocked", http.MethodGet, "/users/me", "", http.StatusForbidden},
+		{"Other profiles are blocked", http.MethodGet, profilePath, "", http.StatusForbidden},
+		{"Password change is allowed", http.MethodPut, "/users/me/password",
+			`{"current_password":"TempPass123","new_password":"ChosenPass456"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := bearerRequest(router, tt.method, tt.path, login.Token, tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusForbidden {
+				if body := decodeError(t, w); body.Code != apierror.CodePasswordChangeRequired {
+					t.Errorf("Expected code %s, but got %s", apierror.CodePasswordChangeRequired, body.Code)
+				}
+			}
+		})
+	}
+
+	// The new password lifts the gate
+	token := loginToken(t, router, "provisioned@example.com", "ChosenPass456")
+	for _, path := range []string{"/users/me", profilePath} {
+		if w := bearerRequest(router, http.MethodGet, path, token, ""); w.Code != http.StatusOK {
+			t.Errorf("Expected status %d for %s after changing the password, but got %d: %s", http.StatusOK, path, w.Code, w.Body.String())
+		}
+	}
+	var stored models.User
+	if err := database.DB.First(&stored, user.ID).Error; err != nil {
+		t.Fatalf("Failed to reload user: %v", err)
+	}
+	if stored.MustChangePassword {
+		t.Error("Expected must_change_password to be cleared")
+	}
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// newCompressionRouter builds a user router behind the compression middleware
+func newCompressionRouter(minBytes int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.CompressionMiddleware(middleware.CompressionConfig{
+		Level:    middleware.DefaultCompressionLevel,
+		MinBytes: minBytes,
+	}))
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("", handlers.GetAllUsers(testUserListConfig))
+		users.GET("/me", handlers.GetCurrentUser)
+		users.GET("/:id", handlers.GetUserByID)
+		users.PUT("/:id", handlers.UpdateUser)
+	}
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", bytes.Repeat([]byte{0x89}, 4096))
+	})
+	return router
+}
+
+// encodedRequest sends an authenticated request accepting the given encodings
+func encodedRequest(t *testing.T, router *gin.Engine, method, path, acceptEncoding string, user models.User, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := authRequest(t, method, path, "", user)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// createManyUsers creates enough users for a list response to be large
+func createManyUsers(t *testing.T, n int) {
+	t.Helper()
+
+	for i := range n {
+		createTestUser(t, fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i))
+	}
+}
+
+func TestCompressionGzipsLargeListResponse(t *testing.T) {
+	setupTestDB(t)
+	router := newCompressionRouter(middleware.DefaultCompressionMinBytes)
+	user := createTestUser(t, "testuser", "test@example.com")
+	createManyUsers(t, 30)
+
+	plain := encodedRequest(t, router, http.MethodGet, "/users", "", user)
+	if plain.Code != http.StatusOK || plain.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("Expected an uncompressed 200, but got %d with %q", plain.Code, plain.Header().Get("Content-Encoding"))
+	}
+	if plain.Body.Len() < middleware.DefaultCompressionMinBytes {
+		t.Fatalf("Expected a response of at least %d bytes, but got %d", middleware.DefaultCompressionMinBytes, plain.Body.Len())
+	}
+
+	w := encodedRequest(t, router, http.MethodGet, "/users", "gzip, deflate", user)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Expected Content-Encoding gzip, but got %q", enc)
+	}
+	if vary := w.Header().Values("Vary"); !strings.Contains(strings.Join(vary, ","), "Accept-Encoding") {
+		t.Errorf("Expected Vary to include Accept-Encoding, but got %v", vary)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("Expected no Content-Length for the uncompressed body, but got %q", cl)
+	}
+	if w.Body.Len() >= plain.Body.Len() {
+		t.Errorf("Expected the gzipped body (%d bytes) to be smaller than the plain one (%d bytes)", w.Body.Len(), plain.Body.Len())
+	}
+
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Failed to read gzip body: %v", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress body: %v", err)
+	}
+	if !bytes.Equal(body, plain.Body.Bytes()) {
+		t.Errorf("Expected the decompressed body to match the plain response")
+	}
+}
+
+func TestCompressionPrefersBrotli(t *testing.T) {
+	setupTestDB(t)
+	router := newCompressionRouter(middleware.DefaultCompressionMinBytes)
+	user := createTestUser(t, "testuser", "test@example.com")
+	createManyUsers(t, 30)
+
+	w := encodedRequest(t, router, http.MethodGet, "/users", "gzip;q=0.8, br", user)
+	if enc := w.Header().Get("Content-Encoding"); enc != "br" {
+		t.Fatalf("Expected Content-Encoding br, but got %q", enc)
+	}
+	body, err := io.ReadAll(brotli.NewReader(w.Body))
+	if err != nil {
+		t.Fatalf("Failed to decompress body: %v", err)
+	}
+	if !strings.Contains(string(body), `"username":"user19"`) {
+		t.Errorf("Expected the user list, but got %s", body)
+	}
+
+	// Encodings refused with q=0 are never used
+	w = encodedRequest(t, router, http.MethodGet, "/users", "br;q=0, gzip", user)
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Errorf("Expected Content-Encoding gzip, but got %q", enc)
+	}
+}
+
+func TestCompressionSkipsSmallAndCompressedResponses(t *testing.T) {
+	setupTestDB(t)
+	router := newCompressionRouter(middleware.DefaultCompressionMinBytes)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	w := encodedRequest(t, router, http.MethodGet, "/users/me", "gzip", user)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected a small uncompressed 200, but got %d with %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(w.Body.String(), `"username":"testuser"`) {
+		t.Errorf("Expected the plain profile, but got %s", w.Body.String())
+	}
+
+	w = encodedRequest(t, router, http.MethodGet, "/image", "gzip, br", user)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 4096 {
+		t.Errorf("Expected the image unchanged, but got %d bytes with %q", w.Body.Len(), w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCompressionKeepsETagsUsable(t *testing.T) {
+	setupTestDB(t)
+	router := newCompressionRouter(1)
+	user := createTestUser(t, "testuser", "test@example.com")
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	w := encodedRequest(t, router, http.MethodGet, path, "gzip", user)
+	etag := w.Header().Get("ETag")
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.HasSuffix(etag, `-gzip"`) {
+		t.Fatalf("Expected a gzipped response with a gzip ETag, but got %q with %q", w.Header().Get("Content-Encoding"), etag)
+	}
+
+	// Revalidating the compressed representation answers 304 with its ETag
+	w = encodedRequest(t, router, http.MethodGet, path, "gzip", user, "If-None-Match", etag)
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != etag {
+		t.Errorf("Expected 304 with ETag %s, but got %d with %s", etag, w.Code, w.Header().Get("ETag"))
+	}
+
+	// The compressed ETag also works as a precondition for updates
+	req := authRequest(t, http.MethodPut, path, `{"username":"renamed"}`, user)
+	req.Header.Set("If-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newConcurrencyRouter serves /slow, which blocks until release is closed,
+// behind a limit of maxInFlight concurrent requests; started receives a
+// value as each /slow request begins
+func newConcurrencyRouter(maxInFlight int, started chan<- struct{}, release <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.ConcurrencyLimitMiddleware(maxInFlight, 2*time.Second, "/health"))
+	router.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestConcurrencyLimitShedsExcessRequests(t *testing.T) {
+	const limit = 3
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := newConcurrencyRouter(limit, started, release)
+
+	// Fill every slot with a request that waits for release
+	codes := make([]int, limit)
+	var wg sync.WaitGroup
+	for i := range limit {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+			codes[i] = w.Code
+		}()
+	}
+	for range limit {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for requests to start")
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, but got %d: %s", w.Code, w.Body.String())
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Errorf("Expected Retry-After 2, but got %q", retryAfter)
+	}
+	if resp := decodeError(t, w); resp.Code != apierror.CodeServiceUnavailable {
+		t.Errorf("Expected code %s, but got %s", apierror.CodeServiceUnavailable, resp.Code)
+	}
+
+	// Health checks are exempt
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the health check to return 200, but got %d", w.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected in-flight request %d to succeed, but got %d", i+1, code)
+		}
+	}
+
+	// Finished requests free their slots
+	go func() { <-started }()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 once the slots are free, but got %d", w.Code)
+	}
+}
+
+func TestConcurrencyLimitDisabled(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	close(release)
+	router := newConcurrencyRouter(0, started, release)
+
+	for range 10 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 without a limit, but got %d", w.Code)
+		}
+	}
+}
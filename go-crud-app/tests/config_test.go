@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getPublicConfig serves the public config endpoint and decodes the response
+func getPublicConfig(t *testing.T, options handlers.PublicConfigOptions) (*httptest.ResponseRecorder, handlers.PublicConfigResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/config", handlers.PublicConfig(options))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+
+	var resp handlers.PublicConfigResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w, resp
+}
+
+func TestPublicConfigReflectsSettings(t *testing.T) {
+	tests := []struct {
+		name             string
+		policy           utils.PasswordPolicy
+		registrationOpen bool
+	}{
+		{"defaults", utils.DefaultPasswordPolicy, true},
+		{"custom policy and closed registration", utils.PasswordPolicy{MinLength: 12, RequireDigit: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utils.PasswordRules = tt.policy
+			handlers.RegistrationOpen = tt.registrationOpen
+			defer func() {
+				utils.PasswordRules = utils.DefaultPasswordPolicy
+				handlers.RegistrationOpen = true
+			}()
+
+			w, resp := getPublicConfig(t, handlers.PublicConfigOptions{
+				JWT:           testJWTConfig,
+				PasswordReset: handlers.PasswordResetConfig{TokenTTL: time.Hour},
+				MaxAge:        handlers.DefaultPublicConfigMaxAge,
+			})
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, but got %d", w.Code)
+			}
+			if resp.PasswordPolicy != tt.policy {
+				t.Errorf("Expected password policy %+v, but got %+v", tt.policy, resp.PasswordPolicy)
+			}
+			if resp.RegistrationOpen != tt.registrationOpen {
+				t.Errorf("Expected registration_open %v, but got %v", tt.registrationOpen, resp.RegistrationOpen)
+			}
+			if resp.Token.Delivery != handlers.TokenDeliveryHeader {
+				t.Errorf("Expected token delivery %q, but got %q", handlers.TokenDeliveryHeader, resp.Token.Delivery)
+			}
+			if resp.Token.AccessTokenTTLSeconds != 24*3600 {
+				t.Errorf("Expected access token TTL 86400, but got %d", resp.Token.AccessTokenTTLSeconds)
+			}
+			if resp.Token.PasswordResetTokenTTLSeconds != 3600 {
+				t.Errorf("Expected reset token TTL 3600, but got %d", resp.Token.PasswordResetTokenTTLSeconds)
+			}
+			if resp.OAuthProviders == nil {
+				t.Error("Expected oauth_providers to be an empty list, but got null")
+			}
+		})
+	}
+}
+
+func TestPublicConfigCaching(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/config", handlers.PublicConfig(handlers.PublicConfigOptions{JWT: testJWTConfig, MaxAge: time.Minute}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("Expected Cache-Control %q, but got %q", "public, max-age=60", got)
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag header")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, but got %d", w.Code)
+	}
+}
+
+func TestRegisterClosed(t *testing.T) {
+	handlers.RegistrationOpen = false
+	defer func() { handlers.RegistrationOpen = true }()
+
+	w := postJSON(newAuthRouter(), "/register", `{"username":"testuser","email":"test@example.com","password":"StrongPass123"}`)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPasswordPolicyConfigurable(t *testing.T) {
+	utils.PasswordRules = utils.PasswordPolicy{MinLength: 12}
+	defer func() { utils.PasswordRules = utils.DefaultPasswordPolicy }()
+
+	if err := utils.ValidatePassword("alllowercase"); err != nil {
+		t.Errorf("Expected password to satisfy the relaxed policy, but got %v", err)
+	}
+
+	err := utils.ValidatePassword("Short1")
+	if !errors.Is(err, utils.ErrWeakPassword) {
+		t.Fatalf("Expected ErrWeakPassword, but got %v", err)
+	}
+	if expected := "password must be at least 12 characters"; err.Error() != expected {
+		t.Errorf("Expected message %q, but got %q", expected, err.Error())
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"testing"
+
+	"go-crud-app/internal/config"
+)
+
+// envFunc returns a getenv function backed by the map
+func envFunc(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestAppEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{"unset defaults to production", "", config.EnvProduction, false},
+		{"development", "development", config.EnvDevelopment, false},
+		{"case insensitive", " Production ", config.EnvProduction, false},
+		{"unknown", "staging", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := config.AppEnv(envFunc(map[string]string{"APP_ENV": tt.value}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, but got %v", tt.wantErr, err)
+			}
+			if env != tt.expected {
+				t.Errorf("Expected %q, but got %q", tt.expected, env)
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	strongSecret := "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name         string
+		env          string
+		values       map[string]string
+		wantErr      bool
+		wantWarnings bool
+	}{
+		{"production missing secret", config.EnvProduction, map[string]string{"DB_PASSWORD": "s3cret-db"}, true, false},
+		{"production placeholder secret", config.EnvProduction, map[string]string{"JWT_SECRET": config.DefaultJWTSecret, "DB_PASSWORD": "s3cret-db"}, true, false},
+		{"production example secret", config.EnvProduction, map[string]string{"JWT_SECRET": "your-secret-key-change-this-in-production-use-a-long-random-string", "DB_PASSWORD": "s3cret-db"}, true, false},
+		{"production strong config", config.EnvProduction, map[string]string{"JWT_SECRET": strongSecret, "DB_PASSWORD": "s3cret-db", "DB_SSLMODE": "require"}, false, false},
+		{"production short secret warns", config.EnvProduction, map[string]string{"JWT_SECRET": "short-secret", "DB_PASSWORD": "s3cret-db"}, false, true},
+		{"production weak defaults warn", config.EnvProduction, map[string]string{"JWT_SECRET": strongSecret, "DB_PASSWORD": "postgres", "DB_SSLMODE": "disable", "CORS_ORIGIN": "*"}, false, true},
+		{"production strict auth cookie", config.EnvProduction, map[string]string{"JWT_SECRET": strongSecret, "DB_PASSWORD": "s3cret-db", "DB_SSLMODE": "require", "AUTH_COOKIE_NAME": "access_token"}, false, false},
+		{"production strong pepper", config.EnvProduction, map[string]string{"JWT_SECRET": strongSecret, "DB_PASSWORD": "s3cret-db", "DB_SSLMODE": "require", "PEPPER": strongSecret}, false, false},
+		{"production short pepper warns", config.EnvProduction, map[string]string{"JWT_SECRET": strongSecret, "DB_PASSWORD": "s3cret-db", "DB_SSLMODE": "require", "PEPPER": "short-pepper"}, false, true},
+		{"production lax auth cookie warns", config.EnvProduction, map[string]string{"JWT_SECRET": strongSecret, "DB_PASSWORD": "s3cret-db", "DB_SSLMODE": "require", "AUTH_COOKIE_NAME": "access_token", "AUTH_COOKIE_SAMESITE": "lax"}, false, true},
+		{"production panic stack exposure", config.EnvProduction, map[string]string{"JWT_SECRET": strongSecret, "DB_PASSWORD": "s3cret-db", "DB_SSLMODE": "require", "PANIC_EXPOSE_STACK": "true"}, true, false},
+		{"development placeholder secret warns", config.EnvDevelopment, map[string]string{"DB_PASSWORD": "s3cret-db"}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := config.Validate(tt.env, envFunc(tt.values))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, but got %v", tt.wantErr, err)
+			}
+			if (len(warnings) > 0) != tt.wantWarnings {
+				t.Errorf("Expected warnings %v, but got %v", tt.wantWarnings, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// contextMarkerKey tags request contexts so queries can be traced back to them
+type contextMarkerKey struct{}
+
+func TestHandlersPassRequestContextToDatabase(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	var (
+		mu       sync.Mutex
+		queries  int
+		untraced []string
+	)
+	record := func(db *gorm.DB) {
+		mu.Lock()
+		defer mu.Unlock()
+		queries++
+		if db.Statement.Context.Value(contextMarkerKey{}) == nil {
+			untraced = append(untraced, db.Statement.Table)
+		}
+	}
+
+	callbacks := database.DB.Callback()
+	for name, err := range map[string]error{
+		"query":  callbacks.Query().Before("gorm:query").Register("test:trace_query", record),
+		"create": callbacks.Create().Before("gorm:create").Register("test:trace_create", record),
+		"update": callbacks.Update().Before("gorm:update").Register("test:trace_update", record),
+		"delete": callbacks.Delete().Before("gorm:delete").Register("test:trace_delete", record),
+	} {
+		if err != nil {
+			t.Fatalf("Failed to register %s callback: %v", name, err)
+		}
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextMarkerKey{}, true))
+	})
+	router.POST("/register", handlers.Register(testJWTConfig))
+	router.POST("/login", handlers.Login(testJWTConfig))
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("", handlers.GetAllUsers(testUserListConfig))
+		users.GET("/me", handlers.GetCurrentUser)
+		users.GET("/:id", handlers.GetUserByID)
+		users.PUT("/:id", handlers.UpdateUser)
+		users.DELETE("/:id", handlers.DeleteUser)
+	}
+
+	if w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"StrongPass123"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(router, "/login", `{"email":"test@example.com","password":"StrongPass123"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	user := createTestUser(t, "otheruser", "other@example.com")
+	path := fmt.Sprintf("/users/%d", user.ID)
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, "/users", ""},
+		{http.MethodGet, "/users/me", ""},
+		{http.MethodGet, path, ""},
+		{http.MethodPut, path, `{"username":"renamed"}`},
+		{http.MethodDelete, path, ""},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, req.method, req.path, req.body, user))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status 200, but got %d: %s", req.method, req.path, w.Code, w.Body.String())
+		}
+	}
+
+	// createTestUser writes outside a request, so it is the only untraced statement
+	if queries < 2 {
+		t.Fatalf("Expected database statements to be recorded, but got %d", queries)
+	}
+	if len(untraced) != 1 {
+		t.Errorf("Expected only the test fixture insert to run without the request context, but got untraced statements on %s",
+			strings.Join(untraced, ", "))
+	}
+}
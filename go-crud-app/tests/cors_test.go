@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSConfigFromEnv(t *testing.T) {
+	t.Setenv("CORS_ORIGIN", "https://app.example.com, https://*.example.org")
+	t.Setenv("CORS_ALLOW_METHODS", "GET,POST")
+	t.Setenv("CORS_ALLOW_HEADERS", "Authorization")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "")
+
+	config := middleware.CORSConfigFromEnv()
+
+	if config.AllowAllOrigins {
+		t.Error("Expected AllowAllOrigins to be false")
+	}
+	if !reflect.DeepEqual(config.AllowOrigins, []string{"https://app.example.com"}) {
+		t.Errorf("Expected exact origins [https://app.example.com], but got %v", config.AllowOrigins)
+	}
+	if !reflect.DeepEqual(config.AllowMethods, []string{"GET", "POST"}) {
+		t.Errorf("Expected methods [GET POST], but got %v", config.AllowMethods)
+	}
+	if !reflect.DeepEqual(config.AllowHeaders, []string{"Authorization"}) {
+		t.Errorf("Expected headers [Authorization], but got %v", config.AllowHeaders)
+	}
+	if !config.AllowCredentials {
+		t.Error("Expected credentials to be allowed by default")
+	}
+	if config.AllowOriginFunc == nil {
+		t.Fatal("Expected an origin matching function for wildcard origins")
+	}
+
+	origins := map[string]bool{
+		"https://api.example.org":        true,
+		"https://a.b.example.org":        true,
+		"https://example.org":            false,
+		"http://api.example.org":         false,
+		"https://evil.com/.example.org":  false,
+		"https://api.example.org.evil.c": false,
+	}
+	for origin, expected := range origins {
+		if got := config.AllowOriginFunc(origin); got != expected {
+			t.Errorf("Origin %s: expected %v, but got %v", origin, expected, got)
+		}
+	}
+}
+
+func TestCORSWildcardDropsCredentials(t *testing.T) {
+	t.Setenv("CORS_ORIGIN", "*")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+
+	config := middleware.CORSConfigFromEnv()
+
+	if !config.AllowAllOrigins {
+		t.Error("Expected AllowAllOrigins to be true")
+	}
+	if config.AllowCredentials {
+		t.Error("Expected credentials to be disabled with a wildcard origin")
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected a valid config, but got: %v", err)
+	}
+}
+
+func TestCORSMiddlewareAllowsSubdomain(t *testing.T) {
+	t.Setenv("CORS_ORIGIN", "https://*.example.com")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(cors.New(middleware.CORSConfigFromEnv()))
+	router.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected Access-Control-Allow-Origin 'https://app.example.com', but got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Expected Access-Control-Allow-Credentials 'true', but got %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// enableCSRFProtection turns on CSRF checks for the rest of the test
+func enableCSRFProtection(t *testing.T) {
+	t.Helper()
+
+	previous := middleware.CSRFProtection
+	middleware.CSRFProtection = true
+	t.Cleanup(func() { middleware.CSRFProtection = previous })
+}
+
+func newCSRFRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", handlers.Register(testJWTConfig))
+	router.GET("/csrf-token", middleware.AuthMiddleware(testJWTConfig.SecretKey), handlers.GetCSRFToken(testJWTConfig.SecretKey))
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	users.Use(middleware.CSRFMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.PATCH("/:id", handlers.UpdateUser)
+	}
+	return router
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	setupTestDB(t)
+	router := newCSRFRouter()
+	enableAuthCookie(t)
+
+	user := createTestUser(t, "csrfuser", "csrf@example.com")
+	token := mustToken(t, user.ID, user.TokenVersion, testJWTConfig)
+	validCSRF := middleware.CSRFToken(testJWTConfig.SecretKey, token)
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	tests := []struct {
+		name           string
+		csrfEnabled    bool
+		method         string
+		useCookie      bool
+		csrfToken      string
+		expectedStatus int
+	}{
+		{"Missing token", true, http.MethodPatch, true, "", http.StatusForbidden},
+		{"Mismatched token", true, http.MethodPatch, true, "not-the-token", http.StatusForbidden},
+		{"Token for another session", true, http.MethodPatch, true, middleware.CSRFToken(testJWTConfig.SecretKey, "other-token"), http.StatusForbidden},
+		{"Valid token", true, http.MethodPatch, true, validCSRF, http.StatusOK},
+		{"Safe methods are not checked", true, http.MethodGet, true, "", http.StatusOK},
+		{"Bearer header requests are not checked", true, http.MethodPatch, false, "", http.StatusOK},
+		{"Disabled", false, http.MethodPatch, true, "", http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.csrfEnabled {
+				enableCSRFProtection(t)
+			}
+
+			target, body := "/users/me", ""
+			if tt.method != http.MethodGet {
+				target, body = path, fmt.Sprintf(`{"username":"csrfuser%d"}`, i)
+			}
+			req := httptest.NewRequest(tt.method, target, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.useCookie {
+				req.AddCookie(&http.Cookie{Name: testAuthCookieName, Value: token})
+			} else {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			if tt.csrfToken != "" {
+				req.Header.Set(middleware.CSRFTokenHeader, tt.csrfToken)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusForbidden {
+				if body := decodeError(t, w); body.Code != apierror.CodeCSRFTokenInvalid {
+					t.Errorf("Expected code %s, but got %s", apierror.CodeCSRFTokenInvalid, body.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestCSRFTokenIssuance(t *testing.T) {
+	setupTestDB(t)
+	router := newCSRFRouter()
+	enableAuthCookie(t)
+	enableCSRFProtection(t)
+
+	w := postJSON(router, "/register", `{"username":"csrfnew","email":"csrfnew@example.com","password":"SecurePass123"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	expected := middleware.CSRFToken(testJWTConfig.SecretKey, resp.Token)
+	if got := w.Header().Get(middleware.CSRFTokenHeader); got != expected {
+		t.Errorf("Expected %s header %q on the auth response, but got %q", middleware.CSRFTokenHeader, expected, got)
+	}
+
+	// The token endpoint returns the same token for the cookie session
+	req := httptest.NewRequest(http.MethodGet, "/csrf-token", nil)
+	req.AddCookie(&http.Cookie{Name: testAuthCookieName, Value: resp.Token})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var body struct {
+		CSRFToken string `json:"csrf_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || body.CSRFToken != expected {
+		t.Errorf("Expected status 200 with token %q, but got %d with %q", expected, w.Code, body.CSRFToken)
+	}
+
+	// Header-token clients don't need one
+	req = httptest.NewRequest(http.MethodGet, "/csrf-token", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a bearer request, but got %d", http.StatusBadRequest, w.Code)
+	}
+}
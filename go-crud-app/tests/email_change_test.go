@@ -0,0 +1,249 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// useEmailChange switches the email change configuration for the rest of the test
+func useEmailChange(t *testing.T, config handlers.EmailChangeConfig) {
+	t.Helper()
+
+	previous := handlers.EmailChange
+	handlers.EmailChange = config
+	t.Cleanup(func() { handlers.EmailChange = previous })
+}
+
+// newEmailChangeRouter serves user updates and email confirmation, sending
+// confirmations through mailer
+func newEmailChangeRouter(t *testing.T, mailer *fakeMailer) *gin.Engine {
+	t.Helper()
+
+	useEmailChange(t, handlers.EmailChangeConfig{
+		Confirm:    true,
+		Mailer:     mailer,
+		TokenTTL:   time.Hour,
+		ConfirmURL: "https://api.example.com/api/auth/confirm-email",
+	})
+	router := newUserRouter()
+	router.GET("/confirm-email", handlers.ConfirmEmail)
+	return router
+}
+
+// requestEmailChange asks for user's email to be changed and returns the
+// emailed confirmation token
+func requestEmailChange(t *testing.T, router *gin.Engine, mailer *fakeMailer, user models.User, email string) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPatch, fmt.Sprintf("/users/%d", user.ID), `{"email":"`+email+`"}`, user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	for i, to := range mailer.recipients {
+		if to == strings.TrimSpace(strings.ToLower(email)) {
+			body := mailer.sent[i]
+			return body[strings.LastIndex(body, "token=")+len("token="):]
+		}
+	}
+	t.Fatalf("Expected a confirmation email to %s, but got emails to %v", email, mailer.recipients)
+	return ""
+}
+
+// confirmEmail requests the confirmation endpoint with token
+func confirmEmail(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/confirm-email?token="+url.QueryEscape(token), nil))
+	return w
+}
+
+func TestEmailChangeConfirmation(t *testing.T) {
+	setupTestDB(t)
+	mailer := &fakeMailer{}
+	router := newEmailChangeRouter(t, mailer)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	token := requestEmailChange(t, router, mailer, user, " New@Example.COM ")
+
+	// The email is held as pending and the current address is told about it
+	var pending models.User
+	database.DB.First(&pending, user.ID)
+	if pending.Email != "test@example.com" || pending.PendingEmail != "new@example.com" {
+		t.Errorf("Expected email test@example.com pending new@example.com, but got %s pending %s", pending.Email, pending.PendingEmail)
+	}
+	if len(mailer.recipients) != 2 || mailer.recipients[1] != "test@example.com" {
+		t.Errorf("Expected a notice to the current address, but got emails to %v", mailer.recipients)
+	}
+
+	w := confirmEmail(router, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var confirmed models.User
+	database.DB.First(&confirmed, user.ID)
+	if confirmed.Email != "new@example.com" || confirmed.PendingEmail != "" {
+		t.Errorf("Expected email new@example.com with nothing pending, but got %s pending %q", confirmed.Email, confirmed.PendingEmail)
+	}
+
+	var audits int64
+	database.DB.Model(&models.AuditLog{}).Where("action = ?", models.AuditEmailChanged).Count(&audits)
+	if audits != 1 {
+		t.Errorf("Expected 1 %s audit entry, but got %d", models.AuditEmailChanged, audits)
+	}
+
+	// Tokens are single-use
+	if w := confirmEmail(router, token); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a reused token to get status %d, but got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestEmailChangeTokenExpiry(t *testing.T) {
+	setupTestDB(t)
+	mailer := &fakeMailer{}
+	router := newEmailChangeRouter(t, mailer)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	token := requestEmailChange(t, router, mailer, user, "new@example.com")
+	if err := database.DB.Model(&models.EmailChangeToken{}).Where("user_id = ?", user.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("Failed to expire token: %v", err)
+	}
+
+	w := confirmEmail(router, token)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, but got %d", http.StatusBadRequest, w.Code)
+	}
+
+	var stored models.User
+	database.DB.First(&stored, user.ID)
+	if stored.Email != "test@example.com" {
+		t.Errorf("Expected email to stay test@example.com, but got %s", stored.Email)
+	}
+}
+
+func TestEmailChangeTokenUsedConcurrently(t *testing.T) {
+	setupTestDB(t)
+	mailer := &fakeMailer{}
+	router := newEmailChangeRouter(t, mailer)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	token := requestEmailChange(t, router, mailer, user, "new@example.com")
+
+	// Another confirmation uses the token right after this one has looked it up
+	used := false
+	err := database.DB.Callback().Query().After("gorm:query").Register("test:concurrent_confirm", func(db *gorm.DB) {
+		if used || db.Statement.Table != "email_change_tokens" {
+			return
+		}
+		used = true
+		database.DB.Exec("UPDATE email_change_tokens SET used_at = ?", time.Now())
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	w := confirmEmail(router, token)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for a token used concurrently, but got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	var audits int64
+	database.DB.Model(&models.AuditLog{}).Where("action = ?", models.AuditEmailChanged).Count(&audits)
+	if audits != 0 {
+		t.Errorf("Expected no %s audit entry, but got %d", models.AuditEmailChanged, audits)
+	}
+}
+
+func TestEmailChangeRejections(t *testing.T) {
+	t.Run("Email taken by another account", func(t *testing.T) {
+		setupTestDB(t)
+		mailer := &fakeMailer{}
+		router := newEmailChangeRouter(t, mailer)
+		user := createTestUser(t, "testuser", "test@example.com")
+		createTestUser(t, "other", "other@example.com")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, http.MethodPatch, fmt.Sprintf("/users/%d", user.ID), `{"email":"other@example.com"}`, user))
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, but got %d", http.StatusConflict, w.Code)
+		}
+		if mailer.count() != 0 {
+			t.Errorf("Expected no emails, but got %d", mailer.count())
+		}
+	})
+
+	t.Run("Email taken while the change was pending", func(t *testing.T) {
+		setupTestDB(t)
+		mailer := &fakeMailer{}
+		router := newEmailChangeRouter(t, mailer)
+		user := createTestUser(t, "testuser", "test@example.com")
+
+		token := requestEmailChange(t, router, mailer, user, "new@example.com")
+		createTestUser(t, "other", "new@example.com")
+
+		if w := confirmEmail(router, token); w.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, but got %d", http.StatusConflict, w.Code)
+		}
+	})
+
+	t.Run("Superseded by a newer request", func(t *testing.T) {
+		setupTestDB(t)
+		mailer := &fakeMailer{}
+		router := newEmailChangeRouter(t, mailer)
+		user := createTestUser(t, "testuser", "test@example.com")
+
+		stale := requestEmailChange(t, router, mailer, user, "first@example.com")
+		database.DB.First(&user, user.ID)
+		requestEmailChange(t, router, mailer, user, "second@example.com")
+
+		if w := confirmEmail(router, stale); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Cancelled by asking for the current email", func(t *testing.T) {
+		setupTestDB(t)
+		mailer := &fakeMailer{}
+		router := newEmailChangeRouter(t, mailer)
+		user := createTestUser(t, "testuser", "test@example.com")
+
+		token := requestEmailChange(t, router, mailer, user, "new@example.com")
+		database.DB.First(&user, user.ID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, http.MethodPatch, fmt.Sprintf("/users/%d", user.ID), `{"email":"test@example.com"}`, user))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		if w := confirmEmail(router, token); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		setupTestDB(t)
+		router := newEmailChangeRouter(t, &fakeMailer{})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/confirm-email", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/grpcapi/userv1"
+	"go-crud-app/internal/validation"
+
+	"google.golang.org/grpc/codes"
+)
+
+// useEmailDomains sets the registration domain policy for the duration of a test
+func useEmailDomains(t *testing.T, policy validation.DomainPolicy) {
+	t.Helper()
+
+	previous := validation.EmailDomains
+	validation.EmailDomains = policy
+	t.Cleanup(func() { validation.EmailDomains = previous })
+}
+
+func TestDomainPolicyPermitsEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   validation.DomainPolicy
+		email    string
+		expected bool
+	}{
+		{name: "No lists", email: "user@anything.com", expected: true},
+		{name: "Allowed domain", policy: validation.DomainPolicy{Allow: []string{"corp.com"}}, email: "user@corp.com", expected: true},
+		{name: "Allowed domain ignores case", policy: validation.DomainPolicy{Allow: []string{"corp.com"}}, email: "user@CORP.Com", expected: true},
+		{name: "Domain outside allowlist", policy: validation.DomainPolicy{Allow: []string{"corp.com"}}, email: "user@gmail.com", expected: false},
+		{name: "Exact entry skips subdomains", policy: validation.DomainPolicy{Allow: []string{"corp.com"}}, email: "user@eu.corp.com", expected: false},
+		{name: "Wildcard matches subdomain", policy: validation.DomainPolicy{Allow: []string{"*.corp.com"}}, email: "user@eu.corp.com", expected: true},
+		{name: "Wildcard matches nested subdomain", policy: validation.DomainPolicy{Allow: []string{"*.corp.com"}}, email: "user@a.eu.corp.com", expected: true},
+		{name: "Wildcard skips apex", policy: validation.DomainPolicy{Allow: []string{"*.corp.com"}}, email: "user@corp.com", expected: false},
+		{name: "Wildcard skips lookalike", policy: validation.DomainPolicy{Allow: []string{"*.corp.com"}}, email: "user@evilcorp.com", expected: false},
+		{name: "Denied domain", policy: validation.DomainPolicy{Deny: []string{"spam.com"}}, email: "user@spam.com", expected: false},
+		{name: "Denied wildcard", policy: validation.DomainPolicy{Deny: []string{"*.spam.com"}}, email: "user@x.spam.com", expected: false},
+		{name: "Deny wins over allow", policy: validation.DomainPolicy{Allow: []string{"*.corp.com"}, Deny: []string{"contractors.corp.com"}}, email: "user@contractors.corp.com", expected: false},
+		{name: "Disposable allowed when not blocked", email: "user@mailinator.com", expected: true},
+		{name: "Disposable blocked", policy: validation.DomainPolicy{BlockDisposable: true}, email: "user@mailinator.com", expected: false},
+		{name: "Disposable subdomain blocked", policy: validation.DomainPolicy{BlockDisposable: true}, email: "user@inbox.Mailinator.com", expected: false},
+		{name: "Regular domain with disposable blocking", policy: validation.DomainPolicy{BlockDisposable: true}, email: "user@example.com", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.PermitsEmail(tt.email); got != tt.expected {
+				t.Errorf("Expected %v, but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseDomainList(t *testing.T) {
+	domains, err := validation.ParseDomainList(" Corp.com, *.Example.org ,,")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(domains) != 2 || domains[0] != "corp.com" || domains[1] != "*.example.org" {
+		t.Errorf("Expected [corp.com *.example.org], but got %v", domains)
+	}
+
+	for _, invalid := range []string{"*", "*.", "a*.com", "user@corp.com", ".corp.com"} {
+		if _, err := validation.ParseDomainList(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected, but it was accepted", invalid)
+		}
+	}
+}
+
+func TestRegisterChecksEmailDomain(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+	useEmailDomains(t, validation.DomainPolicy{
+		Allow:           []string{"corp.com", "*.corp.com"},
+		BlockDisposable: true,
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "Allowed domain",
+			body:           `{"username":"alloweduser","email":"user@corp.com","password":"StrongPass123"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Allowed subdomain",
+			body:           `{"username":"subuser","email":"user@EU.corp.com","password":"StrongPass123"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Denied domain",
+			body:           `{"username":"outsider","email":"user@gmail.com","password":"StrongPass123"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   apierror.CodeEmailDomainNotAllowed,
+		},
+		{
+			name:           "Malformed email is checked first",
+			body:           `{"username":"malformed","email":"not-an-email","password":"StrongPass123"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   apierror.CodeValidationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(router, "/register", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode == "" {
+				return
+			}
+
+			body := decodeError(t, w)
+			if body.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, but got %s", tt.expectedCode, body.Code)
+			}
+			if len(body.Fields) != 1 || body.Fields[0].Field != "email" {
+				t.Errorf("Expected an email field error, but got %+v", body.Fields)
+			}
+		})
+	}
+}
+
+func TestGRPCRegisterChecksEmailDomain(t *testing.T) {
+	setupTestDB(t)
+	client := newGRPCClient(t)
+	useEmailDomains(t, validation.DomainPolicy{Deny: []string{"*.example.com"}})
+
+	_, err := client.Register(context.Background(), &userv1.RegisterRequest{
+		Username: "grpcuser",
+		Email:    "grpc@mail.example.com",
+		Password: "Password123",
+	})
+	assertCode(t, err, codes.InvalidArgument)
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorEnvelopeShape(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	createTestUser(t, "testuser", "test@example.com")
+
+	router := gin.New()
+	router.POST("/login", middleware.RateLimitMiddleware(middleware.NewRateLimiter(2, time.Minute)), handlers.Login(testJWTConfig))
+	router.POST("/register", handlers.Register(testJWTConfig))
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	users.GET("/me", handlers.GetCurrentUser)
+	router.NoRoute(handlers.NoRoute)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"invalid credentials", http.MethodPost, "/login", `{"email":"test@example.com","password":"WrongPass123"}`, http.StatusUnauthorized, apierror.CodeInvalidCredentials},
+		{"rate limited", http.MethodPost, "/login", `{"email":"test@example.com","password":"WrongPass123"}`, http.StatusTooManyRequests, apierror.CodeRateLimited},
+		{"validation failed", http.MethodPost, "/register", `{"username":"a!","email":"bad","password":"weak"}`, http.StatusUnprocessableEntity, apierror.CodeValidationFailed},
+		{"malformed payload", http.MethodPost, "/register", `{not json`, http.StatusBadRequest, apierror.CodeBadRequest},
+		{"missing token", http.MethodGet, "/users/me", "", http.StatusUnauthorized, apierror.CodeTokenInvalid},
+		{"unknown route", http.MethodGet, "/nonsense", "", http.StatusNotFound, apierror.CodeRouteNotFound},
+	}
+
+	// The limiter allows two login attempts, so the second case is the third request
+	postJSON(router, "/login", `{"email":"test@example.com","password":"WrongPass123"}`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assertErrorEnvelope(t, w, tt.expectedStatus, tt.expectedCode)
+		})
+	}
+}
+
+// assertErrorEnvelope checks the response is exactly {"error": {"code", "message", ...}}
+func assertErrorEnvelope(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Errorf("Expected status %d, but got %d: %s", status, w.Code, w.Body.String())
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(raw) != 1 || raw["error"] == nil {
+		t.Fatalf("Expected a single top-level \"error\" key, but got %s", w.Body.String())
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(raw["error"], &body); err != nil {
+		t.Fatalf("Expected \"error\" to be an object, but got %s", raw["error"])
+	}
+	if body["code"] != code {
+		t.Errorf("Expected code %q, but got %v", code, body["code"])
+	}
+	if message, ok := body["message"].(string); !ok || message == "" {
+		t.Errorf("Expected a non-empty message, but got %v", body["message"])
+	}
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conditionalRequest sends an authenticated request with one conditional header
+func conditionalRequest(t *testing.T, router *gin.Engine, method, path, body string, user models.User, header, etag string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := authRequest(t, method, path, body, user)
+	if header != "" {
+		req.Header.Set(header, etag)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUserGetHonorsIfNoneMatch(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "etaguser", "etag@example.com")
+	other := createTestUser(t, "otheruser", "other@example.com")
+
+	for _, path := range []string{"/users/me", fmt.Sprintf("/users/%d", other.ID)} {
+		t.Run(path, func(t *testing.T) {
+			w := conditionalRequest(t, router, http.MethodGet, path, "", user, "", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, but got %d", w.Code)
+			}
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				t.Fatal("Expected an ETag header, but got none")
+			}
+
+			tests := []struct {
+				name           string
+				ifNoneMatch    string
+				expectedStatus int
+			}{
+				{name: "Current ETag", ifNoneMatch: etag, expectedStatus: http.StatusNotModified},
+				{name: "Weak form of current ETag", ifNoneMatch: "W/" + etag, expectedStatus: http.StatusNotModified},
+				{name: "Listed among others", ifNoneMatch: `"stale", ` + etag, expectedStatus: http.StatusNotModified},
+				{name: "Stale ETag", ifNoneMatch: `"stale"`, expectedStatus: http.StatusOK},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					w := conditionalRequest(t, router, http.MethodGet, path, "", user, "If-None-Match", tt.ifNoneMatch)
+					if w.Code != tt.expectedStatus {
+						t.Errorf("Expected status %d, but got %d", tt.expectedStatus, w.Code)
+					}
+					if tt.expectedStatus == http.StatusNotModified && w.Body.Len() != 0 {
+						t.Errorf("Expected an empty 304 body, but got %s", w.Body.String())
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestUserETagChangesOnUpdate(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "etaguser", "etag@example.com")
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	before := conditionalRequest(t, router, http.MethodGet, "/users/me", "", user, "", "").Header().Get("ETag")
+
+	time.Sleep(time.Millisecond)
+	w := conditionalRequest(t, router, http.MethodPatch, path, `{"username":"renamed"}`, user, "If-Match", before)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	after := w.Header().Get("ETag")
+	if after == "" || after == before {
+		t.Fatalf("Expected a new ETag after the update, but got %q (was %q)", after, before)
+	}
+
+	// The ETag returned by the update is the one GET now serves
+	w = conditionalRequest(t, router, http.MethodGet, "/users/me", "", user, "If-None-Match", after)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304 for the post-update ETag, but got %d", w.Code)
+	}
+}
+
+func TestUpdateUserHonorsIfMatch(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "etaguser", "etag@example.com")
+	path := fmt.Sprintf("/users/%d", user.ID)
+	etag := conditionalRequest(t, router, http.MethodGet, "/users/me", "", user, "", "").Header().Get("ETag")
+
+	// A first client updates with the current ETag
+	time.Sleep(time.Millisecond)
+	w := conditionalRequest(t, router, http.MethodPatch, path, `{"username":"firstedit"}`, user, "If-Match", etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A second client still holding the old ETag is refused
+	w = conditionalRequest(t, router, http.MethodPatch, path, `{"username":"secondedit"}`, user, "If-Match", etag)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status 412, but got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeError(t, w); body.Code != apierror.CodePreconditionFailed {
+		t.Errorf("Expected code %s, but got %s", apierror.CodePreconditionFailed, body.Code)
+	}
+
+	w = conditionalRequest(t, router, http.MethodGet, "/users/me", "", user, "", "")
+	if !containsUsername(w, "firstedit") {
+		t.Errorf("Expected the first edit to survive, but got %s", w.Body.String())
+	}
+
+	// If-Match: * and no If-Match both update unconditionally
+	for _, ifMatch := range []string{"*", ""} {
+		w = conditionalRequest(t, router, http.MethodPatch, path, `{"username":"thirdedit"}`, user, "If-Match", ifMatch)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with If-Match %q, but got %d", ifMatch, w.Code)
+		}
+	}
+}
+
+// containsUsername reports whether a user response has the given username
+func containsUsername(w *httptest.ResponseRecorder, username string) bool {
+	var resp models.UserResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		return false
+	}
+	return resp.Username == username
+}
+
+func TestUserETagVariesByRepresentation(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "etaguser", "etag@example.com")
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	otherPath := fmt.Sprintf("/users/%d", user.ID)
+
+	etags := map[string]string{}
+	for name, request := range map[string]struct {
+		path   string
+		caller models.User
+	}{
+		"full":     {"/users/me", user},
+		"selected": {"/users/me?fields=username", user},
+		"admin":    {otherPath, admin},
+	} {
+		w := conditionalRequest(t, router, http.MethodGet, request.path, "", request.caller, "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, but got %d", name, w.Code)
+		}
+		etags[name] = w.Header().Get("ETag")
+	}
+	if etags["full"] == etags["selected"] {
+		t.Error("Expected a field selection to have its own ETag")
+	}
+	if w := conditionalRequest(t, router, http.MethodGet, otherPath, "", user, "If-None-Match", etags["admin"]); w.Code != http.StatusOK {
+		t.Errorf("Expected the admin view's ETag not to revalidate the user's own view, but got %d", w.Code)
+	}
+
+	// Any representation of the current version satisfies If-Match
+	w := conditionalRequest(t, router, http.MethodPatch, otherPath, `{"username":"renamed"}`, user, "If-Match", etags["selected"])
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for the field selection's ETag, but got %d: %s", w.Code, w.Body.String())
+	}
+	// Weak tags never do
+	w = conditionalRequest(t, router, http.MethodPatch, otherPath, `{"username":"again"}`, user, "If-Match", "W/"+w.Header().Get("ETag"))
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 for a weak ETag, but got %d", w.Code)
+	}
+}
+
+func TestUserETagChangesOnPasswordChange(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+	router.PUT("/password", middleware.AuthMiddleware(testJWTConfig.SecretKey), handlers.ChangePassword(testJWTConfig))
+
+	user := createTestUser(t, "etaguser", "etag@example.com")
+	hash, err := utils.HashPassword("SecurePass123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	database.DB.Model(&user).UpdateColumn("password_hash", hash)
+	before := conditionalRequest(t, router, http.MethodGet, "/users/me", "", user, "", "").Header().Get("ETag")
+
+	time.Sleep(time.Millisecond)
+	w := conditionalRequest(t, router, http.MethodPut, "/password", `{"current_password":"SecurePass123","new_password":"NewPassword123"}`, user, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// updated_at moved, so the cached copy is stale
+	w = conditionalRequest(t, router, http.MethodGet, "/users/me", "", reloadUser(t, user.ID), "If-None-Match", before)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after the password change, but got %d", w.Code)
+	}
+}
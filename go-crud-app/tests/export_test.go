@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/retention"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newExportRouter exposes the export endpoint behind auth and the given limiter
+func newExportRouter(limiter middleware.Limiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/users/me/export", middleware.AuthMiddleware(testJWTConfig.SecretKey),
+		middleware.RateLimitMiddleware(limiter), handlers.ExportCurrentUser(retention.Policy{MinDays: 30, MaxDays: 365}))
+	return router
+}
+
+func TestExportCurrentUser(t *testing.T) {
+	setupTestDB(t)
+	router := newExportRouter(middleware.NewRateLimiter(10, time.Hour))
+
+	user := createTestUser(t, "exporter", "exporter@example.com")
+	other := createTestUser(t, "other", "other@example.com")
+	records := []any{
+		&models.AuthIdentity{UserID: user.ID, Provider: "google", Subject: "google-subject-1"},
+		&models.PasswordResetToken{UserID: user.ID, TokenHash: "secret-token-hash", ExpiresAt: time.Now().Add(time.Hour)},
+		&models.AuditLog{ActorID: &user.ID, Action: models.AuditLogin, Target: fmt.Sprintf("user:%d", user.ID)},
+		&models.AuditLog{ActorID: &other.ID, Action: models.AuditAdminBulkDelete, Target: fmt.Sprintf("user:%d", user.ID)},
+		&models.AuditLog{ActorID: &other.ID, Action: models.AuditLogin, Target: fmt.Sprintf("user:%d", other.ID)},
+	}
+	for _, record := range records {
+		if err := database.DB.Create(record).Error; err != nil {
+			t.Fatalf("Failed to create %T: %v", record, err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me/export", "", user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	expectedDisposition := fmt.Sprintf(`attachment; filename="user-%d-export.json"`, user.ID)
+	if disposition := w.Header().Get("Content-Disposition"); disposition != expectedDisposition {
+		t.Errorf("Expected Content-Disposition %s, but got %q", expectedDisposition, disposition)
+	}
+
+	// Secrets never appear, under any key
+	for _, secret := range []string{"not-a-real-hash", "secret-token-hash", "password_hash", "token_hash", "token_version"} {
+		if strings.Contains(w.Body.String(), secret) {
+			t.Errorf("Expected the export not to contain %q", secret)
+		}
+	}
+
+	var export handlers.UserExport
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	if export.User.ID != user.ID || export.User.Email != user.Email || export.User.Role != models.RoleUser {
+		t.Errorf("Expected the full record of user %d, but got %+v", user.ID, export.User)
+	}
+	if export.Retention.MaxDays != 365 {
+		t.Errorf("Expected retention settings in the export, but got %+v", export.Retention)
+	}
+	if len(export.Providers) != 2 || export.Providers[1].Provider != "google" {
+		t.Errorf("Expected the local and google providers, but got %+v", export.Providers)
+	}
+	if len(export.PasswordResets) != 1 || export.PasswordResets[0].ExpiresAt.IsZero() {
+		t.Errorf("Expected 1 password reset, but got %+v", export.PasswordResets)
+	}
+	if len(export.AuditLogs) != 2 {
+		t.Errorf("Expected the 2 audit entries involving the user, but got %d", len(export.AuditLogs))
+	}
+
+	// The export itself is audited
+	var exported int64
+	database.DB.Model(&models.AuditLog{}).Where("action = ? AND actor_id = ?", models.AuditUserExported, user.ID).Count(&exported)
+	if exported != 1 {
+		t.Errorf("Expected 1 %s audit entry, but got %d", models.AuditUserExported, exported)
+	}
+}
+
+func TestExportCurrentUserIsRateLimited(t *testing.T) {
+	setupTestDB(t)
+	router := newExportRouter(middleware.NewRateLimiter(1, time.Hour))
+	user := createTestUser(t, "exporter", "exporter@example.com")
+
+	expected := []int{http.StatusOK, http.StatusTooManyRequests}
+	for i, status := range expected {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me/export", "", user))
+		if w.Code != status {
+			t.Errorf("Expected export %d to return %d, but got %d", i+1, status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newFieldNamingRouter builds a user router that writes JSON field names in
+// defaultNaming unless the client asks otherwise
+func newFieldNamingRouter(defaultNaming string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.CompressionMiddleware(middleware.CompressionConfig{
+		Level:    middleware.DefaultCompressionLevel,
+		MinBytes: 1,
+	}))
+	router.Use(middleware.FieldNamingMiddleware(defaultNaming))
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.GET("/:id", handlers.GetUserByID)
+	}
+	return router
+}
+
+// namedRequest sends an authenticated GET with the given Accept header
+func namedRequest(t *testing.T, router *gin.Engine, path, accept string, user models.User) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := authRequest(t, http.MethodGet, path, "", user)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestFieldNamingForSameEndpoint(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	tests := []struct {
+		name          string
+		defaultNaming string
+		accept        string
+		want          []string
+		unwanted      []string
+	}{
+		{"Snake by default", middleware.FieldNamingSnake, "",
+			[]string{`"created_at":`, `"updated_at":`, `"username":"testuser"`}, []string{`"createdAt"`}},
+		{"Camel by default", middleware.FieldNamingCamel, "",
+			[]string{`"createdAt":`, `"updatedAt":`, `"username":"testuser"`}, []string{`"created_at"`}},
+		{"Camel profile", middleware.FieldNamingSnake, `application/json; profile="camelCase"`,
+			[]string{`"createdAt":`, `"updatedAt":`}, []string{`"created_at"`}},
+		{"Snake profile", middleware.FieldNamingCamel, `application/json; profile="snake_case"`,
+			[]string{`"created_at":`, `"updated_at":`}, []string{`"createdAt"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newFieldNamingRouter(tt.defaultNaming)
+			w := namedRequest(t, router, "/users/me", tt.accept, user)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+			}
+			body := w.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected %s in %s", want, body)
+				}
+			}
+			for _, unwanted := range tt.unwanted {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("Expected no %s in %s", unwanted, body)
+				}
+			}
+			if vary := strings.Join(w.Header().Values("Vary"), ","); !strings.Contains(vary, "Accept") {
+				t.Errorf("Expected Vary to include Accept, but got %q", vary)
+			}
+		})
+	}
+}
+
+func TestFieldNamingAppliesToErrorsAndCompression(t *testing.T) {
+	setupTestDB(t)
+	router := newFieldNamingRouter(middleware.FieldNamingCamel)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	w := namedRequest(t, router, "/users/9999", "", user)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"requestId":"`) || strings.Contains(body, "request_id") {
+		t.Errorf("Expected a camelCase error envelope, but got %s", body)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, `profile="camelCase"`) {
+		t.Errorf("Expected the Content-Type to name the camelCase profile, but got %q", ct)
+	}
+
+	// The rewritten body is what gets compressed
+	req := authRequest(t, http.MethodGet, fmt.Sprintf("/users/%d", user.ID), "", user)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a gzipped response, but got %q", w.Header().Get("Content-Encoding"))
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Failed to read gzip body: %v", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress body: %v", err)
+	}
+	if !strings.Contains(string(body), `"createdAt":`) {
+		t.Errorf("Expected camelCase fields, but got %s", body)
+	}
+}
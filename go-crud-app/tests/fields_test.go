@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+)
+
+// responseKeys returns the sorted top-level keys of a JSON object
+func responseKeys(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestFieldSelection(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "selector", "selector@example.com")
+	other := createTestUser(t, "other", "other@example.com")
+
+	// avatar_url is omitted when unset
+	fullFields := []string{"created_at", "email", "id", "links", "role", "status", "updated_at", "username", "version"}
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedKeys []string
+	}{
+		{
+			name:         "Absent parameter returns the full object",
+			path:         "/users/me",
+			expectedKeys: fullFields,
+		},
+		{
+			name:         "Empty parameter returns the full object",
+			path:         "/users/me?fields=",
+			expectedKeys: fullFields,
+		},
+		{
+			name:         "Subset of fields",
+			path:         "/users/me?fields=id,username",
+			expectedKeys: []string{"id", "username"},
+		},
+		{
+			name:         "Whitespace and empty entries are ignored",
+			path:         fmt.Sprintf("/users/%d?fields=%%20email,,role", user.ID),
+			expectedKeys: []string{"email", "role"},
+		},
+		{
+			name:         "Private fields stay hidden on public profiles",
+			path:         fmt.Sprintf("/users/%d?fields=id,email", other.ID),
+			expectedKeys: []string{"id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodGet, tt.path, "", user))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if keys := responseKeys(t, w.Body.Bytes()); !slices.Equal(keys, tt.expectedKeys) {
+				t.Errorf("Expected fields %v, but got %v", tt.expectedKeys, keys)
+			}
+		})
+	}
+}
+
+func TestFieldSelectionOnUserList(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "selector", "selector@example.com")
+	createTestUser(t, "other", "other@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users?fields=username", "", user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Users []json.RawMessage `json:"users"`
+		Count int               `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Count != 1 || len(resp.Users) != 1 {
+		t.Fatalf("Expected 1 user, but got %d", len(resp.Users))
+	}
+	if keys := responseKeys(t, resp.Users[0]); !slices.Equal(keys, []string{"username"}) {
+		t.Errorf("Expected fields [username], but got %v", keys)
+	}
+}
+
+func TestFieldSelectionRejectsUnknownFields(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "selector", "selector@example.com")
+
+	for _, path := range []string{"/users/me?fields=id,password_hash", "/users?fields=nope"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodGet, path, "", user))
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, but got %d", http.StatusBadRequest, w.Code)
+			}
+			body := decodeError(t, w)
+			if body.Code != apierror.CodeValidationFailed || len(body.Fields) != 1 || body.Fields[0].Field != "fields" {
+				t.Errorf("Expected a validation error for fields, but got %+v", body)
+			}
+		})
+	}
+}
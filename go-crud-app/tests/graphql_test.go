@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"go-crud-app/internal/graphqlapi"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// graphQLResponse is a GraphQL response with the error extensions this API sets
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newGraphQLRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/graphql", middleware.AuthMiddleware(testJWTConfig.SecretKey), graphqlapi.Handler(graphqlapi.NewSchema()))
+	return router
+}
+
+// graphQLRequest runs query with variables as user and decodes the response
+func graphQLRequest(t *testing.T, router *gin.Engine, user models.User, query string, variables map[string]any) graphQLResponse {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/graphql", string(body), user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp graphQLResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestGraphQLMeQuery(t *testing.T) {
+	setupTestDB(t)
+	router := newGraphQLRouter()
+
+	user := createTestUser(t, "graphuser", "graph@example.com")
+
+	resp := graphQLRequest(t, router, user, `{ me { id username email role } }`, nil)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("Expected no errors, but got %+v", resp.Errors)
+	}
+
+	var data struct {
+		Me struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+			Email    string `json:"email"`
+			Role     string `json:"role"`
+		} `json:"me"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if data.Me.ID != strconv.Itoa(int(user.ID)) || data.Me.Username != "graphuser" ||
+		data.Me.Email != "graph@example.com" || data.Me.Role != models.RoleUser {
+		t.Errorf("Expected the current user, but got %+v", data.Me)
+	}
+}
+
+func TestGraphQLRequiresAuthentication(t *testing.T) {
+	router := newGraphQLRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, but got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestGraphQLEnforcesUserRules(t *testing.T) {
+	setupTestDB(t)
+	router := newGraphQLRouter()
+
+	user := createTestUser(t, "owner", "owner@example.com")
+	other := createTestUser(t, "other", "other@example.com")
+	otherID := strconv.Itoa(int(other.ID))
+
+	tests := []struct {
+		name         string
+		query        string
+		variables    map[string]any
+		expectedCode string
+		expectedData string
+	}{
+		{
+			name:         "Other users are reduced to public fields",
+			query:        `query($id: ID!) { user(id: $id) { username email } }`,
+			variables:    map[string]any{"id": otherID},
+			expectedData: `{"user":{"username":"other","email":null}}`,
+		},
+		{
+			name:         "Users list excludes the caller",
+			query:        `{ users(pageSize: 10) { total users { username } } }`,
+			expectedData: `{"users":{"total":1,"users":[{"username":"other"}]}}`,
+		},
+		{
+			name:         "Invalid page size",
+			query:        `{ users(pageSize: 1000) { total } }`,
+			expectedCode: "validation_failed",
+		},
+		{
+			name:         "Unknown user",
+			query:        `{ user(id: "999") { username } }`,
+			expectedCode: "not_found",
+		},
+		{
+			name:         "Updating another user is forbidden",
+			query:        `mutation($id: ID!) { updateUser(id: $id, input: {username: "taken"}) { username } }`,
+			variables:    map[string]any{"id": otherID},
+			expectedCode: "forbidden",
+		},
+		{
+			name:         "Deleting another user is forbidden",
+			query:        `mutation($id: ID!) { deleteUser(id: $id) }`,
+			variables:    map[string]any{"id": otherID},
+			expectedCode: "forbidden",
+		},
+		{
+			name:         "Invalid update is rejected",
+			query:        `mutation($id: ID!) { updateUser(id: $id, input: {email: "not-an-email"}) { email } }`,
+			variables:    map[string]any{"id": strconv.Itoa(int(user.ID))},
+			expectedCode: "validation_failed",
+		},
+		{
+			name:         "Own profile is updated",
+			query:        `mutation($id: ID!) { updateUser(id: $id, input: {username: "renamed"}) { username } }`,
+			variables:    map[string]any{"id": strconv.Itoa(int(user.ID))},
+			expectedData: `{"updateUser":{"username":"renamed"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := graphQLRequest(t, router, user, tt.query, tt.variables)
+
+			if tt.expectedCode != "" {
+				if len(resp.Errors) != 1 || resp.Errors[0].Extensions.Code != tt.expectedCode {
+					t.Errorf("Expected a %s error, but got %+v", tt.expectedCode, resp.Errors)
+				}
+				return
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("Expected no errors, but got %+v", resp.Errors)
+			}
+			if string(resp.Data) != tt.expectedData {
+				t.Errorf("Expected data %s, but got %s", tt.expectedData, resp.Data)
+			}
+		})
+	}
+}
+
+func TestGraphQLDeleteUser(t *testing.T) {
+	setupTestDB(t)
+	router := newGraphQLRouter()
+
+	user := createTestUser(t, "leaving", "leaving@example.com")
+
+	resp := graphQLRequest(t, router, user, `mutation($id: ID!) { deleteUser(id: $id) }`,
+		map[string]any{"id": strconv.Itoa(int(user.ID))})
+	if len(resp.Errors) > 0 || string(resp.Data) != `{"deleteUser":true}` {
+		t.Fatalf("Expected deleteUser to succeed, but got data %s and errors %+v", resp.Data, resp.Errors)
+	}
+
+	if count := countUsers(t); count != 0 {
+		t.Errorf("Expected 0 users after deletion, but got %d", count)
+	}
+}
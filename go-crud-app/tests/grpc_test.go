@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/grpcapi"
+	"go-crud-app/internal/grpcapi/userv1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCClient serves the gRPC API over an in-memory listener and returns a client for it
+func newGRPCClient(t *testing.T) userv1.UserServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	srv := grpcapi.NewGRPCServer(testJWTConfig)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create gRPC client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return userv1.NewUserServiceClient(conn)
+}
+
+// withBearer attaches the token as authorization metadata
+func withBearer(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// assertCode checks that err is a gRPC status with the expected code
+func assertCode(t *testing.T, err error, expected codes.Code) {
+	t.Helper()
+
+	if got := status.Code(err); got != expected {
+		t.Errorf("Expected code %s, but got %s (%v)", expected, got, err)
+	}
+}
+
+func TestGRPCUserLifecycle(t *testing.T) {
+	setupTestDB(t)
+	client := newGRPCClient(t)
+	ctx := context.Background()
+
+	registered, err := client.Register(ctx, &userv1.RegisterRequest{
+		Username: "grpcuser",
+		Email:    "GRPC@example.com",
+		Password: "Password123",
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if registered.GetUser().GetEmail() != "grpc@example.com" {
+		t.Errorf("Expected normalized email grpc@example.com, but got %s", registered.GetUser().GetEmail())
+	}
+
+	_, err = client.Register(ctx, &userv1.RegisterRequest{
+		Username: "grpcuser",
+		Email:    "grpc@example.com",
+		Password: "Password123",
+	})
+	assertCode(t, err, codes.AlreadyExists)
+
+	_, err = client.Login(ctx, &userv1.LoginRequest{Email: "grpc@example.com", Password: "WrongPassword1"})
+	assertCode(t, err, codes.Unauthenticated)
+
+	login, err := client.Login(ctx, &userv1.LoginRequest{Email: "grpc@example.com", Password: "Password123"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	authed := withBearer(login.GetToken())
+	id := login.GetUser().GetId()
+
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{Id: id})
+	assertCode(t, err, codes.Unauthenticated)
+
+	_, err = client.GetUser(withBearer(login.GetRefreshToken()), &userv1.GetUserRequest{Id: id})
+	assertCode(t, err, codes.Unauthenticated)
+
+	user, err := client.GetUser(authed, &userv1.GetUserRequest{Id: id})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.GetUsername() != "grpcuser" {
+		t.Errorf("Expected username grpcuser, but got %s", user.GetUsername())
+	}
+
+	_, err = client.GetUser(authed, &userv1.GetUserRequest{Id: id + 100})
+	assertCode(t, err, codes.NotFound)
+
+	newName := "renamed"
+	updated, err := client.UpdateUser(authed, &userv1.UpdateUserRequest{Id: id, Username: &newName})
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.GetUsername() != newName || updated.GetEmail() != "grpc@example.com" {
+		t.Errorf("Expected only the username to change, but got %+v", updated)
+	}
+
+	badEmail := "not-an-email"
+	_, err = client.UpdateUser(authed, &userv1.UpdateUserRequest{Id: id, Email: &badEmail})
+	assertCode(t, err, codes.InvalidArgument)
+
+	other := createTestUser(t, "otheruser", "other@example.com")
+	_, err = client.DeleteUser(authed, &userv1.DeleteUserRequest{Id: uint64(other.ID)})
+	assertCode(t, err, codes.PermissionDenied)
+
+	if _, err := client.DeleteUser(authed, &userv1.DeleteUserRequest{Id: id}); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	// Tokens for a deleted user are rejected
+	_, err = client.GetUser(authed, &userv1.GetUserRequest{Id: id})
+	assertCode(t, err, codes.Unauthenticated)
+}
+
+func TestGRPCPublicProfile(t *testing.T) {
+	setupTestDB(t)
+	client := newGRPCClient(t)
+
+	viewer := createTestUser(t, "viewer", "viewer@example.com")
+	target := createTestUser(t, "target", "target@example.com")
+
+	user, err := client.GetUser(withBearer(mustToken(t, viewer.ID, viewer.TokenVersion, testJWTConfig)),
+		&userv1.GetUserRequest{Id: uint64(target.ID)})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.GetEmail() != "" {
+		t.Errorf("Expected the email to be hidden, but got %s", user.GetEmail())
+	}
+}
+
+func TestGRPCPasswordChangeRequired(t *testing.T) {
+	setupTestDB(t)
+	client := newGRPCClient(t)
+
+	user := createTestUser(t, "provisioned", "provisioned@example.com")
+	if err := database.DB.Model(&user).Update("must_change_password", true).Error; err != nil {
+		t.Fatalf("Failed to flag user: %v", err)
+	}
+
+	// The gRPC API can't change passwords, so every authenticated call is refused
+	_, err := client.GetUser(withBearer(mustToken(t, user.ID, user.TokenVersion, testJWTConfig)),
+		&userv1.GetUserRequest{Id: uint64(user.ID)})
+	assertCode(t, err, codes.PermissionDenied)
+}
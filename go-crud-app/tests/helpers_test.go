@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testJWTConfig is the JWT configuration shared by handler tests
+var testJWTConfig = utils.JWTConfig{
+	SecretKey:              "test-secret-key",
+	ExpirationHours:        24,
+	RefreshExpirationHours: 168,
+}
+
+// testUserListConfig is the user list pagination configuration shared by handler tests
+var testUserListConfig = handlers.UserListConfig{
+	DefaultPageSize: handlers.DefaultUserPageSize,
+	MaxPageSize:     handlers.DefaultMaxUserPageSize,
+	CursorSecret:    testJWTConfig.SecretKey,
+}
+
+// issuedInPast runs issue with the token clock set far enough back that tokens
+// issued under config's lifetimes have already expired
+func issuedInPast(t *testing.T, config utils.JWTConfig, issue func()) {
+	t.Helper()
+
+	hours := max(config.ExpirationHours, config.RefreshExpirationHours) + 1
+	utils.TokenClock = func() time.Time { return time.Now().Add(-time.Duration(hours) * time.Hour) }
+	defer func() { utils.TokenClock = time.Now }()
+
+	issue()
+}
+
+// setupTestDB points database.DB at a fresh, migrated in-memory SQLite database
+func setupTestDB(t *testing.T) {
+	t.Helper()
+
+	openTestDB(t, t.Name())
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+}
+
+// openTestDB points database.DB at a fresh, empty in-memory SQLite database
+// named name for the rest of the test
+func openTestDB(t *testing.T, name string) {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(name, "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+
+	t.Cleanup(func() {
+		database.Close()
+		database.DB = previous
+	})
+}
+
+// createTestUser inserts a user directly, skipping the slow bcrypt hash
+func createTestUser(t *testing.T, username, email string) models.User {
+	t.Helper()
+
+	user := models.User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: "not-a-real-hash",
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return user
+}
+
+// decodeError decodes the standard error envelope from a response
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) apierror.Body {
+	t.Helper()
+
+	var resp apierror.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	return resp.Error
+}
+
+// authRequest builds a request carrying a valid bearer token for the user
+func authRequest(t *testing.T, method, path, body string, user models.User) *http.Request {
+	t.Helper()
+
+	token, err := utils.GenerateToken(utils.TokenOptions{UserID: user.ID, Username: user.Username, Email: user.Email, OrgID: user.OrgID, TokenVersion: user.TokenVersion}, testJWTConfig)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/i18n"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// localizedPost sends a JSON POST with the given Accept-Language header
+func localizedPost(router *gin.Engine, path, acceptLanguage, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", acceptLanguage)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		lang           string
+		acceptLanguage string
+		expected       language.Tag
+	}{
+		{name: "No preference", expected: language.English},
+		{name: "Header", acceptLanguage: "es", expected: language.Spanish},
+		{name: "Regional variant", acceptLanguage: "fr-CA", expected: language.French},
+		{name: "Weighted header", acceptLanguage: "de;q=0.9, es;q=0.8, en;q=0.1", expected: language.Spanish},
+		{name: "Query parameter wins", lang: "fr", acceptLanguage: "es", expected: language.French},
+		{name: "Invalid query parameter", lang: "!!", acceptLanguage: "es", expected: language.Spanish},
+		{name: "Unsupported locale", acceptLanguage: "ja", expected: language.English},
+		{name: "Malformed header", acceptLanguage: ";;;", expected: language.English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := i18n.Match(tt.lang, tt.acceptLanguage)
+			base, _ := got.Base()
+			expectedBase, _ := tt.expected.Base()
+			if base != expectedBase {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTranslateCarriesArguments(t *testing.T) {
+	got := i18n.Translate(language.Spanish, "Too many failed login attempts. Try again in 42 seconds")
+	expected := "Demasiados intentos de inicio de sesión fallidos. Inténtelo de nuevo en 42 segundos"
+	if got != expected {
+		t.Errorf("Expected %q, but got %q", expected, got)
+	}
+
+	untranslated := "Something no catalog knows about"
+	if got := i18n.Translate(language.French, untranslated); got != untranslated {
+		t.Errorf("Expected untranslated messages to fall back to English, but got %q", got)
+	}
+}
+
+func TestLocalizedErrorMessages(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+	createTestUser(t, "testuser", "test@example.com")
+
+	tests := []struct {
+		name             string
+		path             string
+		acceptLanguage   string
+		body             string
+		expectedCode     string
+		expectedMessage  string
+		expectedLanguage string
+	}{
+		{
+			name:             "Spanish auth error",
+			path:             "/login",
+			acceptLanguage:   "es-ES,es;q=0.9",
+			body:             `{"email":"test@example.com","password":"WrongPass123"}`,
+			expectedCode:     apierror.CodeInvalidCredentials,
+			expectedMessage:  "Correo electrónico o contraseña incorrectos",
+			expectedLanguage: "es",
+		},
+		{
+			name:             "French via query parameter",
+			path:             "/login?lang=fr",
+			acceptLanguage:   "es",
+			body:             `{"email":"test@example.com","password":"WrongPass123"}`,
+			expectedCode:     apierror.CodeInvalidCredentials,
+			expectedMessage:  "Adresse e-mail ou mot de passe incorrect",
+			expectedLanguage: "fr",
+		},
+		{
+			name:             "Unsupported locale falls back to English",
+			path:             "/login",
+			acceptLanguage:   "ja",
+			body:             `{"email":"test@example.com","password":"WrongPass123"}`,
+			expectedCode:     apierror.CodeInvalidCredentials,
+			expectedMessage:  "Invalid email or password",
+			expectedLanguage: "en",
+		},
+		{
+			name:             "Spanish validation error",
+			path:             "/register",
+			acceptLanguage:   "es",
+			body:             `{"username":"newuser","email":"new@example.com"}`,
+			expectedCode:     apierror.CodeValidationFailed,
+			expectedMessage:  "La validación ha fallado",
+			expectedLanguage: "es",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := localizedPost(router, tt.path, tt.acceptLanguage, tt.body)
+			body := decodeError(t, w)
+			if body.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, but got %s", tt.expectedCode, body.Code)
+			}
+			if body.Message != tt.expectedMessage {
+				t.Errorf("Expected message %q, but got %q", tt.expectedMessage, body.Message)
+			}
+			if got := w.Header().Get("Content-Language"); got != tt.expectedLanguage {
+				t.Errorf("Expected Content-Language %s, but got %s", tt.expectedLanguage, got)
+			}
+		})
+	}
+}
+
+func TestLocalizedFieldErrors(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+
+	w := localizedPost(router, "/register", "fr", `{"username":"newuser","email":"new@example.com","password":"weak"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := decodeError(t, w)
+	expected := "le mot de passe doit comporter au moins 8 caractères et contenir une majuscule, une minuscule et un chiffre"
+	if len(body.Fields) != 1 || body.Fields[0].Field != "password" || body.Fields[0].Message != expected {
+		t.Errorf("Expected a translated password field error, but got %+v", body.Fields)
+	}
+}
+
+func TestLocalizedRateLimitError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", middleware.RateLimitMiddleware(middleware.NewRateLimiter(1, time.Minute)), handlers.Login(testJWTConfig))
+	setupTestDB(t)
+
+	localizedPost(router, "/login", "es", `{}`)
+	w := localizedPost(router, "/login", "es", `{}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, but got %d", w.Code)
+	}
+
+	body := decodeError(t, w)
+	expected := "Se ha superado el límite de solicitudes. Inténtelo de nuevo más tarde."
+	if body.Code != apierror.CodeRateLimited || body.Message != expected {
+		t.Errorf("Expected %s with %q, but got %s with %q", apierror.CodeRateLimited, expected, body.Code, body.Message)
+	}
+}
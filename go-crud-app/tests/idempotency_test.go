@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newIdempotentRegisterRouter builds a router exposing registration behind the idempotency store
+func newIdempotentRegisterRouter(store *middleware.IdempotencyStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", middleware.IdempotencyMiddleware(store), handlers.Register(testJWTConfig))
+	return router
+}
+
+// postWithKey posts a JSON body with an Idempotency-Key header
+func postWithKey(router *gin.Engine, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// countUsers returns the number of users in the test database
+func countUsers(t *testing.T) int64 {
+	t.Helper()
+
+	var count int64
+	if err := database.DB.Model(&models.User{}).Count(&count).Error; err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	return count
+}
+
+const registerBody = `{"username":"retryuser","email":"retry@example.com","password":"Password123"}`
+
+func TestIdempotentRegistrationReplay(t *testing.T) {
+	setupTestDB(t)
+	router := newIdempotentRegisterRouter(middleware.NewIdempotencyStore(time.Minute))
+
+	first := postWithKey(router, "/register", "key-1", registerBody)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", first.Code, first.Body.String())
+	}
+	if first.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Errorf("Expected the first response not to be marked as replayed")
+	}
+
+	retry := postWithKey(router, "/register", "key-1", registerBody)
+	if retry.Code != http.StatusCreated {
+		t.Fatalf("Expected replayed status 201, but got %d: %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+		t.Errorf("Expected %s: true on the replayed response", middleware.IdempotentReplayedHeader)
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected the replayed body to match the original\nfirst: %s\nretry: %s", first.Body.String(), retry.Body.String())
+	}
+	if location := retry.Header().Get("Location"); location == "" || location != first.Header().Get("Location") {
+		t.Errorf("Expected the replayed Location %q, but got %q", first.Header().Get("Location"), location)
+	}
+	if count := countUsers(t); count != 1 {
+		t.Errorf("Expected 1 user after a retried registration, but got %d", count)
+	}
+}
+
+func TestIdempotentReplayHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.POST("/sessions", middleware.IdempotencyMiddleware(middleware.NewIdempotencyStore(time.Minute)), func(c *gin.Context) {
+		c.Header("Location", "/sessions/1")
+		c.Header(middleware.CSRFTokenHeader, "csrf-token")
+		c.SetCookie("session", "secret", 3600, "/", "", true, true)
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	first := postWithKey(router, "/sessions", "key-1", `{}`)
+	retry := postWithKey(router, "/sessions", "key-1", `{}`)
+	if retry.Code != http.StatusCreated || retry.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+		t.Fatalf("Expected a replayed 201, but got %d: %s", retry.Code, retry.Body.String())
+	}
+
+	// Headers the handler set are replayed...
+	for _, name := range []string{"Location", "Set-Cookie", middleware.CSRFTokenHeader, "Content-Type"} {
+		if got, want := retry.Header().Values(name), first.Header().Values(name); len(want) == 0 || !slices.Equal(got, want) {
+			t.Errorf("Expected the replayed %s %q, but got %q", name, want, got)
+		}
+	}
+	// ...while those set before it belong to the retry
+	if retry.Header().Get(middleware.RequestIDHeader) == first.Header().Get(middleware.RequestIDHeader) {
+		t.Errorf("Expected the retry to get its own request ID, but got %q", retry.Header().Get(middleware.RequestIDHeader))
+	}
+}
+
+func TestIdempotencyDistinguishesGenuineConflicts(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "new key for an existing email is a real conflict",
+			key:            "key-2",
+			body:           registerBody,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   apierror.CodeConflict,
+		},
+		{
+			name:           "no key for an existing email is a real conflict",
+			key:            "",
+			body:           registerBody,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   apierror.CodeConflict,
+		},
+		{
+			name:           "same key with a different body is rejected",
+			key:            "key-1",
+			body:           `{"username":"otheruser","email":"other@example.com","password":"Password123"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   apierror.CodeIdempotencyMismatch,
+		},
+		{
+			name:           "malformed key is rejected",
+			key:            "bad key!",
+			body:           registerBody,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   apierror.CodeBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			router := newIdempotentRegisterRouter(middleware.NewIdempotencyStore(time.Minute))
+
+			if w := postWithKey(router, "/register", "key-1", registerBody); w.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+			}
+
+			w := postWithKey(router, "/register", tt.key, tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if body := decodeError(t, w); body.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, but got %s", tt.expectedCode, body.Code)
+			}
+			if w.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+				t.Errorf("Expected the response not to be marked as replayed")
+			}
+		})
+	}
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	router := gin.New()
+	router.POST("/flaky", middleware.IdempotencyMiddleware(middleware.NewIdempotencyStore(time.Minute)), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	if w := postWithKey(router, "/flaky", "key-1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, but got %d", w.Code)
+	}
+	w := postWithKey(router, "/flaky", "key-1", `{}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected the retry to be processed with status 201, but got %d", w.Code)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the handler to run twice, but it ran %d times", calls)
+	}
+}
+
+func TestIdempotencyKeysExpire(t *testing.T) {
+	setupTestDB(t)
+	router := newIdempotentRegisterRouter(middleware.NewIdempotencyStore(time.Nanosecond))
+
+	if w := postWithKey(router, "/register", "key-1", registerBody); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	time.Sleep(time.Millisecond)
+
+	// Once the stored response has expired the retry is processed again
+	w := postWithKey(router, "/register", "key-1", registerBody)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 after expiry, but got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"go-crud-app/internal/utils"
+)
+
+// exifMarker identifies an EXIF APP1 segment in a JPEG file
+var exifMarker = []byte("Exif\x00\x00")
+
+// encodeTestJPEG encodes a solid image and injects an EXIF segment after the SOI marker
+func encodeTestJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	payload := append(append([]byte{}, exifMarker...), []byte("GPS 51.5074 N 0.1278 W")...)
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	data := buf.Bytes()
+	withExif := append([]byte{}, data[:2]...)
+	withExif = append(withExif, segment...)
+	return append(withExif, data[2:]...)
+}
+
+func TestProcessImageDownscalesAndStripsExif(t *testing.T) {
+	data := encodeTestJPEG(t, 1200, 600)
+	if !bytes.Contains(data, exifMarker) {
+		t.Fatal("Expected test image to contain EXIF data")
+	}
+
+	config := utils.DefaultImageConfig()
+	config.ResizeTo = 300
+
+	out, contentType, err := utils.ProcessImage(data, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if contentType != "image/jpeg" {
+		t.Errorf("Expected content type image/jpeg, but got %s", contentType)
+	}
+	if bytes.Contains(out, exifMarker) {
+		t.Error("Expected EXIF data to be stripped, but it is still present")
+	}
+
+	header, format, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Failed to decode processed image: %v", err)
+	}
+	if format != "jpeg" {
+		t.Errorf("Expected format jpeg, but got %s", format)
+	}
+	if header.Width != 300 || header.Height != 150 {
+		t.Errorf("Expected 300x150, but got %dx%d", header.Width, header.Height)
+	}
+}
+
+func TestProcessImageRejections(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		config   utils.ImageConfig
+		expected error
+	}{
+		{
+			name:     "Not an image",
+			data:     []byte("definitely not an image"),
+			config:   utils.DefaultImageConfig(),
+			expected: utils.ErrInvalidImage,
+		},
+		{
+			name:     "Truncated image",
+			data:     encodeTestJPEG(t, 64, 64)[:200],
+			config:   utils.DefaultImageConfig(),
+			expected: utils.ErrInvalidImage,
+		},
+		{
+			name:     "Exceeds max dimension",
+			data:     encodeTestJPEG(t, 300, 100),
+			config:   utils.ImageConfig{MaxDimension: 200, Format: utils.ImageFormatPNG},
+			expected: utils.ErrImageTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := utils.ProcessImage(tt.data, tt.config)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error %v, but got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestProcessImageKeepsSmallImagesAndConvertsFormat(t *testing.T) {
+	data := encodeTestJPEG(t, 40, 20)
+
+	out, contentType, err := utils.ProcessImage(data, utils.ImageConfig{ResizeTo: 512, Format: utils.ImageFormatPNG})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if contentType != "image/png" {
+		t.Errorf("Expected content type image/png, but got %s", contentType)
+	}
+
+	header, format, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Failed to decode processed image: %v", err)
+	}
+	if format != "png" {
+		t.Errorf("Expected format png, but got %s", format)
+	}
+	if header.Width != 40 || header.Height != 20 {
+		t.Errorf("Expected 40x20, but got %dx%d", header.Width, header.Height)
+	}
+}
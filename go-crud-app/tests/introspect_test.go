@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testServiceKey is the service key accepted by the introspection router
+const testServiceKey = "test-service-key"
+
+// newIntrospectRouter builds a router exposing the introspection endpoint
+func newIntrospectRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/introspect", middleware.RequireServiceKey([]string{testServiceKey}), handlers.IntrospectToken(testJWTConfig.SecretKey))
+	return router
+}
+
+// introspect posts a form-encoded introspection request with the given service key
+func introspect(router *gin.Engine, serviceKey, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if serviceKey != "" {
+		req.Header.Set(middleware.APIKeyHeader, serviceKey)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeIntrospection decodes a successful introspection response
+func decodeIntrospection(t *testing.T, w *httptest.ResponseRecorder) handlers.IntrospectResponse {
+	t.Helper()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp handlers.IntrospectResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestIntrospectActiveToken(t *testing.T) {
+	setupTestDB(t)
+	router := newIntrospectRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	w := introspect(router, testServiceKey, mustToken(t, user.ID, 0, testJWTConfig))
+	resp := decodeIntrospection(t, w)
+
+	if !resp.Active || resp.Sub != strconv.FormatUint(uint64(user.ID), 10) {
+		t.Fatalf("Expected an active token for user %d, but got %+v", user.ID, resp)
+	}
+	if resp.Username != "testuser" || resp.Email != "test@example.com" || resp.Exp == 0 || resp.Iat == 0 {
+		t.Errorf("Expected the token's claims, but got %+v", resp)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("Expected Cache-Control no-store, but got %q", cc)
+	}
+}
+
+func TestIntrospectInactiveTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		token func(t *testing.T, user models.User) string
+	}{
+		{
+			name: "Expired",
+			token: func(t *testing.T, user models.User) string {
+				var token string
+				issuedInPast(t, testJWTConfig, func() { token = mustToken(t, user.ID, 0, testJWTConfig) })
+				return token
+			},
+		},
+		{
+			name: "Revoked",
+			token: func(t *testing.T, user models.User) string {
+				token := mustToken(t, user.ID, 0, testJWTConfig)
+				database.DB.Model(&user).Update("token_version", 1)
+				return token
+			},
+		},
+		{
+			name: "Suspended user",
+			token: func(t *testing.T, user models.User) string {
+				database.DB.Model(&user).Update("status", models.StatusSuspended)
+				return mustToken(t, user.ID, 0, testJWTConfig)
+			},
+		},
+		{
+			name:  "Refresh token",
+			token: func(t *testing.T, user models.User) string { return mustRefreshToken(t, user) },
+		},
+		{
+			name:  "Malformed",
+			token: func(t *testing.T, user models.User) string { return "not-a-token" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			router := newIntrospectRouter()
+			user := createTestUser(t, "testuser", "test@example.com")
+
+			w := introspect(router, testServiceKey, tt.token(t, user))
+			if resp := decodeIntrospection(t, w); resp.Active || resp.Sub != "" {
+				t.Errorf("Expected an inactive token, but got %+v", resp)
+			}
+			if body := w.Body.String(); body != `{"active":false}` {
+				t.Errorf("Expected only the active flag, but got %s", body)
+			}
+		})
+	}
+}
+
+func TestIntrospectRequiresServiceKey(t *testing.T) {
+	setupTestDB(t)
+	router := newIntrospectRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+	token := mustToken(t, user.ID, 0, testJWTConfig)
+
+	for _, key := range []string{"", "wrong-key", token} {
+		if w := introspect(router, key, token); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for key %q, but got %d: %s", key, w.Code, w.Body.String())
+		}
+	}
+
+	w := introspect(router, testServiceKey, "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a token, but got %d: %s", w.Code, w.Body.String())
+	}
+}
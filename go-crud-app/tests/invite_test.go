@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newInviteRouter builds a router exposing invite creation and registration
+func newInviteRouter() *gin.Engine {
+	router := newAuthRouter()
+	router.POST("/invites",
+		middleware.AuthMiddleware(testJWTConfig.SecretKey),
+		middleware.RequireAdmin(),
+		handlers.CreateInvite(testJWTConfig.SecretKey))
+	return router
+}
+
+// createInvite has admin create an invite from body, returning the token
+func createInvite(t *testing.T, router *gin.Engine, admin models.User, body string) handlers.CreateInviteResponse {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/invites", body, admin))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp handlers.CreateInviteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+// registerWithInvite registers newbie with the given email and invite token
+func registerWithInvite(router *gin.Engine, email, token string) *httptest.ResponseRecorder {
+	return postJSON(router, "/register", fmt.Sprintf(
+		`{"username":"newbie","email":%q,"password":"StrongPass123","invite_token":%q}`, email, token))
+}
+
+func TestRegisterWithInvite(t *testing.T) {
+	setupTestDB(t)
+	handlers.Invites.Required = true
+	defer func() { handlers.Invites.Required = false }()
+
+	router := newInviteRouter()
+	admin := setRole(t, createTestOrgUser(t, "orgadmin", "orgadmin@example.com", 1), models.RoleAdmin)
+	invite := createInvite(t, router, admin, `{"email":"Newbie@Example.com","role":"admin"}`)
+
+	w := registerWithInvite(router, "newbie@example.com", invite.InviteToken)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The new user gets the invite's role and the admin's organization
+	var user models.User
+	if err := database.DB.Where("username = ?", "newbie").First(&user).Error; err != nil {
+		t.Fatalf("Failed to load registered user: %v", err)
+	}
+	if user.Role != models.RoleAdmin || !models.SameOrg(user.OrgID, admin.OrgID) {
+		t.Errorf("Expected an admin of organization %d, but got org %v role %q", *admin.OrgID, user.OrgID, user.Role)
+	}
+
+	var stored models.Invite
+	if err := database.DB.First(&stored, invite.Invite.ID).Error; err != nil {
+		t.Fatalf("Failed to load invite: %v", err)
+	}
+	if stored.UsedAt == nil || stored.UsedBy == nil || *stored.UsedBy != user.ID {
+		t.Errorf("Expected invite used by %d, but got %+v", user.ID, stored)
+	}
+
+	var count int64
+	database.DB.Model(&models.AuditLog{}).Where("action = ?", models.AuditAdminInviteCreated).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 invite audit entry, but got %d", count)
+	}
+}
+
+func TestRegisterRejectsInvalidInvites(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string // Invite request, if any
+		token    func(token string) string
+		prepare  func(t *testing.T, inviteID uint)
+		expected string
+	}{
+		{
+			name:     "Missing",
+			token:    func(string) string { return "" },
+			expected: "invite_token is required",
+		},
+		{
+			name: "Forged signature",
+			body: `{}`,
+			token: func(token string) string {
+				// Unlike the last one, the first signature character has no padding bits
+				i := strings.Index(token, ".") + 1
+				replacement := "A"
+				if token[i] == 'A' {
+					replacement = "B"
+				}
+				return token[:i] + replacement + token[i+1:]
+			},
+			expected: "invite_token is not valid",
+		},
+		{
+			name: "Unknown",
+			token: func(string) string {
+				signed, _, _ := utils.GenerateInviteToken(testJWTConfig.SecretKey)
+				return signed
+			},
+			expected: "invite_token is not valid",
+		},
+		{
+			name:  "Expired",
+			body:  `{}`,
+			token: func(token string) string { return token },
+			prepare: func(t *testing.T, inviteID uint) {
+				database.DB.Model(&models.Invite{}).Where("id = ?", inviteID).Update("expires_at", time.Now().Add(-time.Minute))
+			},
+			expected: "invite_token has expired",
+		},
+		{
+			name:  "Already used",
+			body:  `{}`,
+			token: func(token string) string { return token },
+			prepare: func(t *testing.T, inviteID uint) {
+				database.DB.Model(&models.Invite{}).Where("id = ?", inviteID).Update("used_at", time.Now())
+			},
+			expected: "invite_token has already been used",
+		},
+		{
+			name:     "Different email",
+			body:     `{"email":"someone@example.com"}`,
+			token:    func(token string) string { return token },
+			expected: "invite_token was issued for a different email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			handlers.Invites.Required = true
+			defer func() { handlers.Invites.Required = false }()
+
+			router := newInviteRouter()
+			admin := createTestAdmin(t, "admin", "admin@example.com")
+
+			var token string
+			if tt.body != "" {
+				invite := createInvite(t, router, admin, tt.body)
+				token = invite.InviteToken
+				if tt.prepare != nil {
+					tt.prepare(t, invite.Invite.ID)
+				}
+			}
+
+			w := registerWithInvite(router, "newbie@example.com", tt.token(token))
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("Expected status 422, but got %d: %s", w.Code, w.Body.String())
+			}
+			resp := decodeError(t, w)
+			if len(resp.Fields) != 1 || resp.Fields[0].Field != "invite_token" || resp.Fields[0].Message != tt.expected {
+				t.Errorf("Expected invite_token error %q, but got %+v", tt.expected, resp.Fields)
+			}
+
+			var count int64
+			database.DB.Model(&models.User{}).Where("username = ?", "newbie").Count(&count)
+			if count != 0 {
+				t.Error("Expected no user to be registered")
+			}
+		})
+	}
+}
+
+func TestInviteSingleUse(t *testing.T) {
+	setupTestDB(t)
+	router := newInviteRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	invite := createInvite(t, router, admin, `{}`)
+
+	// Invites are optional, but one that is presented is still consumed
+	if w := registerWithInvite(router, "newbie@example.com", invite.InviteToken); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := postJSON(router, "/register", fmt.Sprintf(
+		`{"username":"second","email":"second@example.com","password":"StrongPass123","invite_token":%q}`, invite.InviteToken))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateInviteRequiresAdmin(t *testing.T) {
+	setupTestDB(t)
+	router := newInviteRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/invites", `{}`, user))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Plain admins cannot invite into other organizations or invite super-admins
+	for _, body := range []string{`{"org_id":2}`, `{"role":"superadmin"}`} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, http.MethodPost, "/invites", body, admin))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for %s, but got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/invites", `{"email":"not-an-email"}`, admin))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, but got %d: %s", w.Code, w.Body.String())
+	}
+}
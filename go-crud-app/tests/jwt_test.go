@@ -1,10 +1,13 @@
 package tests
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"go-crud-app/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestGenerateToken(t *testing.T) {
@@ -13,7 +16,7 @@ func TestGenerateToken(t *testing.T) {
 		ExpirationHours: 24,
 	}
 
-	token, err := utils.GenerateToken(1, "testuser", "test@example.com", config)
+	token, err := utils.GenerateToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com"}, config)
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
@@ -30,7 +33,7 @@ func TestValidateToken(t *testing.T) {
 	}
 
 	// Generate a valid token
-	token, err := utils.GenerateToken(1, "testuser", "test@example.com", config)
+	token, err := utils.GenerateToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com"}, config)
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
@@ -99,19 +102,117 @@ func TestValidateToken(t *testing.T) {
 func TestExpiredToken(t *testing.T) {
 	config := utils.JWTConfig{
 		SecretKey:       "test-secret-key",
-		ExpirationHours: -1, // Expired token
+		ExpirationHours: 24,
+	}
+
+	// Issue the token more than ExpirationHours ago
+	var token string
+	issuedInPast(t, config, func() {
+		var err error
+		token, err = utils.GenerateToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com"}, config)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+	})
+
+	_, err := utils.ValidateToken(token, config.SecretKey)
+	if !errors.Is(err, utils.ErrExpiredToken) {
+		t.Errorf("Expected ErrExpiredToken, but got %v", err)
+	}
+}
+
+func TestGenerateTokenRejectsNonPositiveExpiration(t *testing.T) {
+	for _, hours := range []int{0, -1} {
+		config := utils.JWTConfig{SecretKey: "test-secret-key", ExpirationHours: hours, RefreshExpirationHours: hours}
+
+		if _, err := utils.GenerateToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com"}, config); !errors.Is(err, utils.ErrInvalidExpiration) {
+			t.Errorf("Expected ErrInvalidExpiration for %d hours, but got %v", hours, err)
+		}
+		if _, err := utils.GenerateRefreshToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com"}, config); !errors.Is(err, utils.ErrInvalidExpiration) {
+			t.Errorf("Expected ErrInvalidExpiration for %d refresh hours, but got %v", hours, err)
+		}
+	}
+}
+
+func TestJWTConfigValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		access        int
+		refresh       int
+		expectedError bool
+	}{
+		{name: "valid", access: 24, refresh: 168, expectedError: false},
+		{name: "equal lifetimes", access: 24, refresh: 24, expectedError: false},
+		{name: "zero access", access: 0, refresh: 168, expectedError: true},
+		{name: "negative access", access: -1, refresh: 168, expectedError: true},
+		{name: "zero refresh", access: 24, refresh: 0, expectedError: true},
+		{name: "refresh shorter than access", access: 24, refresh: 12, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := utils.JWTConfig{SecretKey: "test-secret-key", ExpirationHours: tt.access, RefreshExpirationHours: tt.refresh}
+			err := config.Validate()
+			if (err != nil) != tt.expectedError {
+				t.Errorf("Expected error %v, but got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateTokenWrapsCause(t *testing.T) {
+	config := utils.JWTConfig{
+		SecretKey:       "test-secret-key",
+		ExpirationHours: 24,
+	}
+	generate := func() string {
+		token, err := utils.GenerateToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com"}, config)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
 	}
 
-	token, err := utils.GenerateToken(1, "testuser", "test@example.com", config)
+	var expired string
+	issuedInPast(t, config, func() { expired = generate() })
+
+	utils.TokenClock = func() time.Time { return time.Now().Add(time.Hour) }
+	notYetValid := generate()
+	utils.TokenClock = time.Now
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": 1}).SignedString(jwt.UnsafeAllowNoneSignatureType)
 	if err != nil {
-		t.Fatalf("Failed to generate token: %v", err)
+		t.Fatalf("Failed to build unsigned token: %v", err)
 	}
 
-	// Wait a moment to ensure token is expired
-	time.Sleep(100 * time.Millisecond)
+	tests := []struct {
+		name             string
+		token            string
+		secret           string
+		expectedSentinel error
+		expectedCause    error
+	}{
+		{"Expired", expired, config.SecretKey, utils.ErrExpiredToken, jwt.ErrTokenExpired},
+		{"Not valid yet", notYetValid, config.SecretKey, utils.ErrInvalidToken, jwt.ErrTokenNotValidYet},
+		{"Malformed", "not.a.jwt", config.SecretKey, utils.ErrInvalidToken, jwt.ErrTokenMalformed},
+		{"Wrong signature", generate(), "another-secret", utils.ErrInvalidToken, jwt.ErrTokenSignatureInvalid},
+		{"Unexpected signing method", unsigned, config.SecretKey, utils.ErrInvalidToken, jwt.ErrTokenUnverifiable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := utils.ValidateToken(tt.token, tt.secret)
+			if !errors.Is(err, tt.expectedSentinel) {
+				t.Errorf("Expected %v, but got %v", tt.expectedSentinel, err)
+			}
+			if !errors.Is(err, tt.expectedCause) {
+				t.Errorf("Expected the cause %v to be wrapped, but got %v", tt.expectedCause, err)
+			}
+		})
+	}
 
-	_, err = utils.ValidateToken(token, config.SecretKey)
-	if err == nil {
-		t.Error("Expected error for expired token, but got none")
+	// Expired tokens don't also match ErrInvalidToken, so callers can tell them apart
+	if _, err := utils.ValidateToken(expired, config.SecretKey); errors.Is(err, utils.ErrInvalidToken) {
+		t.Errorf("Expected an expired token not to match ErrInvalidToken, but got %v", err)
 	}
 }
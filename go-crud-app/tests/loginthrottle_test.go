@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/loginthrottle"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+)
+
+// useLoginThrottle switches the login throttling policy for the rest of the test
+func useLoginThrottle(t *testing.T, policy loginthrottle.Policy) {
+	t.Helper()
+
+	previous := loginthrottle.Current
+	loginthrottle.Current = policy
+	t.Cleanup(func() { loginthrottle.Current = previous })
+}
+
+func TestLoginThrottleDelaySchedule(t *testing.T) {
+	exponential := loginthrottle.Policy{
+		Strategy:    loginthrottle.StrategyExponential,
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    10 * time.Second,
+	}
+	fixed := loginthrottle.Policy{
+		Strategy:     loginthrottle.StrategyFixed,
+		MaxAttempts:  3,
+		LockDuration: 15 * time.Minute,
+	}
+	off := loginthrottle.DefaultPolicy()
+
+	tests := []struct {
+		name     string
+		policy   loginthrottle.Policy
+		failures int
+		expected time.Duration
+	}{
+		{"Exponential below the threshold", exponential, 2, 0},
+		{"Exponential first wait", exponential, 3, time.Second},
+		{"Exponential doubles", exponential, 4, 2 * time.Second},
+		{"Exponential doubles again", exponential, 6, 8 * time.Second},
+		{"Exponential is capped", exponential, 7, 10 * time.Second},
+		{"Exponential stays capped", exponential, 100, 10 * time.Second},
+		{"Fixed below the threshold", fixed, 2, 0},
+		{"Fixed locks at the threshold", fixed, 3, 15 * time.Minute},
+		{"Fixed lock doesn't grow", fixed, 10, 15 * time.Minute},
+		{"Off never waits", off, 100, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Delay(tt.failures); got != tt.expected {
+				t.Errorf("Expected %s after %d failures, but got %s", tt.expected, tt.failures, got)
+			}
+		})
+	}
+}
+
+func TestLoginThrottlePolicyValidate(t *testing.T) {
+	valid := loginthrottle.DefaultPolicy()
+
+	tests := []struct {
+		name        string
+		modify      func(p *loginthrottle.Policy)
+		shouldError bool
+	}{
+		{"Default", func(p *loginthrottle.Policy) {}, false},
+		{"Fixed", func(p *loginthrottle.Policy) { p.Strategy = loginthrottle.StrategyFixed }, false},
+		{"Exponential", func(p *loginthrottle.Policy) { p.Strategy = loginthrottle.StrategyExponential }, false},
+		{"Unknown strategy", func(p *loginthrottle.Policy) { p.Strategy = "linear" }, true},
+		{"Zero attempts", func(p *loginthrottle.Policy) {
+			p.Strategy = loginthrottle.StrategyFixed
+			p.MaxAttempts = 0
+		}, true},
+		{"Zero lock", func(p *loginthrottle.Policy) {
+			p.Strategy = loginthrottle.StrategyFixed
+			p.LockDuration = 0
+		}, true},
+		{"Cap below base", func(p *loginthrottle.Policy) {
+			p.Strategy = loginthrottle.StrategyExponential
+			p.MaxDelay = p.BaseDelay / 2
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := valid
+			tt.modify(&policy)
+			err := policy.Validate()
+			if tt.shouldError && err == nil {
+				t.Errorf("Expected an error for %+v, but got none", policy)
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("Expected no error, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+	useLoginThrottle(t, loginthrottle.Policy{
+		Strategy:    loginthrottle.StrategyExponential,
+		MaxAttempts: 2,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+	})
+
+	hash, err := utils.HashPassword("SecurePass123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	user := models.User{Username: "throttled", Email: "throttled@example.com", PasswordHash: hash}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	correct := `{"email":"throttled@example.com","password":"SecurePass123"}`
+	wrong := `{"email":"throttled@example.com","password":"WrongPass123"}`
+
+	// A success resets the count, so failures must be consecutive
+	postJSON(router, "/login", wrong)
+	if w := postJSON(router, "/login", correct); w.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, but got %d: %s", w.Code, w.Body.String())
+	}
+	assertFailedLogins(t, user.ID, 0, false)
+
+	for i := 1; i <= 2; i++ {
+		if w := postJSON(router, "/login", wrong); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected failed attempt %d to return %d, but got %d", i, http.StatusUnauthorized, w.Code)
+		}
+	}
+	assertFailedLogins(t, user.ID, 2, true)
+
+	// Even the correct password is refused while waiting
+	w := postJSON(router, "/login", correct)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d while throttled, but got %d: %s", http.StatusTooManyRequests, w.Code, w.Body.String())
+	}
+	if body := decodeError(t, w); body.Code != apierror.CodeLoginThrottled {
+		t.Errorf("Expected code %s, but got %s", apierror.CodeLoginThrottled, body.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Expected Retry-After 60, but got %q", retryAfter)
+	}
+
+	// Once the wait has passed, a correct login succeeds and resets the count
+	if err := database.DB.Model(&user).UpdateColumn("next_login_allowed_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("Failed to expire the wait: %v", err)
+	}
+	if w := postJSON(router, "/login", correct); w.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed after the wait, but got %d: %s", w.Code, w.Body.String())
+	}
+	assertFailedLogins(t, user.ID, 0, false)
+}
+
+// assertFailedLogins checks the stored failure count and whether a wait is set
+func assertFailedLogins(t *testing.T, userID uint, expectedCount int, expectWait bool) {
+	t.Helper()
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		t.Fatalf("Failed to reload user: %v", err)
+	}
+	if user.FailedLoginAttempts != expectedCount {
+		t.Errorf("Expected %d failed logins, but got %d", expectedCount, user.FailedLoginAttempts)
+	}
+	if (user.NextLoginAllowedAt != nil) != expectWait {
+		t.Errorf("Expected a pending wait to be %v, but got %v", expectWait, user.NextLoginAllowedAt)
+	}
+}
+
+func TestLoginThrottleTreatsUnknownEmailsLikeAccounts(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+	useLoginThrottle(t, loginthrottle.Policy{
+		Strategy:    loginthrottle.StrategyExponential,
+		MaxAttempts: 2,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+	})
+	createTestUser(t, "testuser", "test@example.com")
+
+	// Wrong passwords for an account and for an unknown email get the same
+	// responses, so throttling doesn't reveal which emails are registered
+	for _, email := range []string{"test@example.com", "nobody@example.com"} {
+		body := fmt.Sprintf(`{"email":%q,"password":"WrongPass123"}`, email)
+		for i, expected := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+			w := postJSON(router, "/login", body)
+			if w.Code != expected {
+				t.Fatalf("Expected attempt %d for %s to return %d, but got %d: %s", i+1, email, expected, w.Code, w.Body.String())
+			}
+			if expected == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+				t.Errorf("Expected Retry-After 60 for %s, but got %q", email, w.Header().Get("Retry-After"))
+			}
+		}
+	}
+
+	// Unknown emails are stored hashed
+	var failure models.LoginFailure
+	if err := database.DB.First(&failure).Error; err != nil {
+		t.Fatalf("Expected the unknown email's failures to be stored: %v", err)
+	}
+	if failure.EmailHash == "nobody@example.com" || failure.FailedLoginAttempts != 2 {
+		t.Errorf("Expected 2 failures under a hashed email, but got %+v", failure)
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newLogoutRouter builds a router exposing the session management routes
+func newLogoutRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.POST("/me/logout-all", handlers.LogoutAll)
+	}
+	return router
+}
+
+func TestLogoutAllRevokesExistingTokens(t *testing.T) {
+	setupTestDB(t)
+	router := newLogoutRouter()
+
+	user := createTestUser(t, "testuser", "test@example.com")
+	oldToken := authRequest(t, http.MethodGet, "/users/me", "", user).Header.Get("Authorization")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/me/logout-all", "", user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var updated models.User
+	database.DB.First(&updated, user.ID)
+	if updated.TokenVersion != user.TokenVersion+1 {
+		t.Errorf("Expected token version %d, but got %d", user.TokenVersion+1, updated.TokenVersion)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	req.Header.Set("Authorization", oldToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected old token to be rejected with 401, but got %d", w.Code)
+	}
+
+	// Tokens issued at the new version are accepted
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me", "", updated))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected new token to be accepted, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthMiddlewareTokenVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		tokenVersion int
+		expected     int
+	}{
+		{"older version", 1, http.StatusUnauthorized},
+		{"current version", 2, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			router := newLogoutRouter()
+
+			user := createTestUser(t, "testuser", "test@example.com")
+			database.DB.Model(&user).UpdateColumn("token_version", 2)
+
+			token, err := utils.GenerateToken(utils.TokenOptions{UserID: user.ID, Username: user.Username, Email: user.Email, OrgID: user.OrgID, TokenVersion: tt.tokenVersion}, testJWTConfig)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareRejectsDeletedUser(t *testing.T) {
+	setupTestDB(t)
+	router := newLogoutRouter()
+
+	user := createTestUser(t, "testuser", "test@example.com")
+	req := authRequest(t, http.MethodGet, "/users/me", "", user)
+	database.DB.Delete(&user)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, but got %d", w.Code)
+	}
+}
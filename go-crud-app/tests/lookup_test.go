@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// failQueriesKey marks a request context whose database queries should fail
+type failQueriesKey struct{}
+
+// failQueriesHeader asks failQueries to simulate a database outage
+const failQueriesHeader = "X-Test-Fail-Queries"
+
+// failQueries marks the request context when failQueriesHeader is set, so
+// queries made after authentication fail like a lost connection would
+func failQueries(c *gin.Context) {
+	if c.GetHeader(failQueriesHeader) != "" {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), failQueriesKey{}, true))
+	}
+}
+
+// newLookupRouter builds a router whose handlers can be made to see database errors
+func newLookupRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	err := database.DB.Callback().Query().Before("gorm:query").Register("test:fail_queries", func(db *gorm.DB) {
+		if db.Statement.Context.Value(failQueriesKey{}) != nil {
+			db.AddError(errors.New("simulated connection failure"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", failQueries, handlers.Login(testJWTConfig))
+	router.POST("/forgot-password", failQueries, handlers.ForgotPassword(handlers.PasswordResetConfig{Mailer: &fakeMailer{}, TokenTTL: time.Hour}))
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey), failQueries)
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.GET("/:id", handlers.GetUserByID)
+		users.DELETE("/:id", handlers.DeleteUser)
+	}
+	return router
+}
+
+func TestLookupDistinguishesMissingRowsFromDatabaseErrors(t *testing.T) {
+	setupTestDB(t)
+	router := newLookupRouter(t)
+
+	user := createTestUser(t, "lookupuser", "lookup@example.com")
+	other := createTestUser(t, "otheruser", "other@example.com")
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		failQueries    bool
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "Current user found", method: http.MethodGet, path: "/users/me", expectedStatus: http.StatusOK},
+		{name: "Current user with database error", method: http.MethodGet, path: "/users/me", failQueries: true, expectedStatus: http.StatusInternalServerError, expectedCode: apierror.CodeInternal},
+		{name: "User by ID found", method: http.MethodGet, path: fmt.Sprintf("/users/%d", other.ID), expectedStatus: http.StatusOK},
+		{name: "User by ID missing", method: http.MethodGet, path: "/users/9999", expectedStatus: http.StatusNotFound, expectedCode: apierror.CodeNotFound},
+		{name: "User by ID with database error", method: http.MethodGet, path: fmt.Sprintf("/users/%d", other.ID), failQueries: true, expectedStatus: http.StatusInternalServerError, expectedCode: apierror.CodeInternal},
+		{name: "Delete with database error", method: http.MethodDelete, path: fmt.Sprintf("/users/%d", user.ID), failQueries: true, expectedStatus: http.StatusInternalServerError, expectedCode: apierror.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := authRequest(t, tt.method, tt.path, "", user)
+			if tt.failQueries {
+				req.Header.Set(failQueriesHeader, "true")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				if body := decodeError(t, w); body.Code != tt.expectedCode {
+					t.Errorf("Expected code %s, but got %s", tt.expectedCode, body.Code)
+				}
+			}
+		})
+	}
+
+	if !userExists(t, user.ID) {
+		t.Error("Expected the user to survive a delete that failed its lookup")
+	}
+}
+
+func TestUnauthenticatedLookupsReportDatabaseErrors(t *testing.T) {
+	setupTestDB(t)
+	router := newLookupRouter(t)
+	createTestUser(t, "lookupuser", "lookup@example.com")
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		failQueries    bool
+		expectedStatus int
+	}{
+		{name: "Login with unknown email", path: "/login", body: `{"email":"nobody@example.com","password":"Password123"}`, expectedStatus: http.StatusUnauthorized},
+		{name: "Login with database error", path: "/login", body: `{"email":"lookup@example.com","password":"Password123"}`, failQueries: true, expectedStatus: http.StatusInternalServerError},
+		{name: "Forgot password with unknown email", path: "/forgot-password", body: `{"email":"nobody@example.com"}`, expectedStatus: http.StatusOK},
+		{name: "Forgot password with database error", path: "/forgot-password", body: `{"email":"lookup@example.com"}`, failQueries: true, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.failQueries {
+				req.Header.Set(failQueriesHeader, "true")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/metrics"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrapeMetrics serves one request to /ping, then scrapes /metrics
+func scrapeMetrics(t *testing.T, format, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.MetricsMiddleware())
+	router.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	router.GET("/metrics", handlers.Metrics(format))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+	return w
+}
+
+func TestOpenMetricsExposition(t *testing.T) {
+	w := scrapeMetrics(t, metrics.FormatOpenMetrics, "")
+	body := w.Body.String()
+
+	if contentType := w.Header().Get("Content-Type"); contentType != metrics.OpenMetricsContentType {
+		t.Errorf("Expected content type %q, but got %q", metrics.OpenMetricsContentType, contentType)
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("Expected exposition to end with # EOF")
+	}
+
+	lines := []*regexp.Regexp{
+		regexp.MustCompile(`(?m)^# TYPE http_requests counter$`),
+		regexp.MustCompile(`(?m)^# UNIT http_request_duration_seconds seconds$`),
+		regexp.MustCompile(`(?m)^http_requests_total\{method="GET",route="/ping",status="200"\} [1-9][0-9]*$`),
+		regexp.MustCompile(`(?m)^http_request_duration_seconds_bucket\{method="GET",route="/ping",le="1\.0"\} [1-9][0-9]*( # \{request_id="[0-9a-f]+"\} [0-9.e-]+ [0-9]+\.[0-9]{3})?$`),
+		regexp.MustCompile(`(?m)^http_request_duration_seconds_bucket\{method="GET",route="/ping",le="[0-9.]+"\} [0-9]+ # \{request_id="[0-9a-f]+"\} [0-9.e-]+ [0-9]+\.[0-9]{3}$`),
+	}
+	for _, line := range lines {
+		if !line.MatchString(body) {
+			t.Errorf("Expected a line matching %s, but got:\n%s", line, body)
+		}
+	}
+}
+
+func TestPrometheusExposition(t *testing.T) {
+	w := scrapeMetrics(t, metrics.FormatPrometheus, "")
+	body := w.Body.String()
+
+	if contentType := w.Header().Get("Content-Type"); contentType != metrics.PrometheusContentType {
+		t.Errorf("Expected content type %q, but got %q", metrics.PrometheusContentType, contentType)
+	}
+	if strings.Contains(body, "# EOF") || strings.Contains(body, "# UNIT") || strings.Contains(body, " # {") {
+		t.Errorf("Expected no OpenMetrics-only syntax, but got:\n%s", body)
+	}
+	if !regexp.MustCompile(`(?m)^# TYPE http_requests_total counter$`).MatchString(body) {
+		t.Errorf("Expected counter family to be named http_requests_total, but got:\n%s", body)
+	}
+}
+
+func TestMetricsFormatNegotiation(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+	}{
+		{
+			name:        "OpenMetrics scraper",
+			accept:      "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5",
+			contentType: metrics.OpenMetricsContentType,
+		},
+		{
+			name:        "Classic scraper",
+			accept:      "text/plain",
+			contentType: metrics.PrometheusContentType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := scrapeMetrics(t, handlers.MetricsFormatAuto, tt.accept)
+			if contentType := w.Header().Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("Expected content type %q, but got %q", tt.contentType, contentType)
+			}
+		})
+	}
+}
+
+func TestMetricsCountRecoveredPanics(t *testing.T) {
+	captureLog(t)
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RecoveryMiddleware(false))
+	router.Use(middleware.MetricsMiddleware())
+	router.GET("/panic-counted", func(c *gin.Context) {
+		panic("something went wrong")
+	})
+	router.GET("/metrics", handlers.Metrics(metrics.FormatPrometheus))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic-counted", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, but got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	line := regexp.MustCompile(`(?m)^http_requests_total\{method="GET",route="/panic-counted",status="500"\} 1$`)
+	if !line.MatchString(w.Body.String()) {
+		t.Errorf("Expected the panic to be counted as a 500, but got:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/migrations"
+)
+
+// schemaTables are the tables created by the versioned migrations
+var schemaTables = []string{"users", "password_reset_tokens", "auth_identities", "audit_logs", "sessions", "organizations", "invites"}
+
+// appliedMigrations returns the IDs recorded in the migrations table
+func appliedMigrations(t *testing.T) []string {
+	t.Helper()
+
+	var ids []string
+	if err := database.DB.Table(migrations.TableName).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatalf("Failed to read applied migrations: %v", err)
+	}
+	return ids
+}
+
+// columnNames returns the sorted column names of a table in the current database
+func columnNames(t *testing.T, table string) []string {
+	t.Helper()
+
+	columns, err := database.DB.Migrator().ColumnTypes(table)
+	if err != nil {
+		t.Fatalf("Failed to read columns of %s: %v", table, err)
+	}
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name()
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestMigrateUpThenDownLeavesCleanSchema(t *testing.T) {
+	openTestDB(t, t.Name())
+	migrator := database.DB.Migrator()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	for _, table := range schemaTables {
+		if !migrator.HasTable(table) {
+			t.Errorf("Expected table %s after migrating up", table)
+		}
+	}
+	if ids := appliedMigrations(t); len(ids) != len(migrations.All()) {
+		t.Errorf("Expected %d applied migrations, but got %v", len(migrations.All()), ids)
+	}
+
+	// Migrating again is a no-op
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Second Migrate failed: %v", err)
+	}
+
+	for range migrations.All() {
+		if err := database.Rollback(); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+	}
+	for _, table := range schemaTables {
+		if migrator.HasTable(table) {
+			t.Errorf("Expected table %s to be dropped after rolling back", table)
+		}
+	}
+	if ids := appliedMigrations(t); len(ids) != 0 {
+		t.Errorf("Expected no applied migrations after rolling back, but got %v", ids)
+	}
+
+	if err := database.Rollback(); err == nil {
+		t.Error("Expected an error rolling back with nothing applied, but got none")
+	}
+
+	// The schema can be rebuilt from scratch
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Migrate after rollback failed: %v", err)
+	}
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	openTestDB(t, t.Name()+"_auto")
+	if err := database.AutoMigrate(); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	expected := make(map[string][]string)
+	for _, table := range schemaTables {
+		expected[table] = columnNames(t, table)
+	}
+
+	openTestDB(t, t.Name()+"_versioned")
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// A mismatch means a model changed without a matching migration
+	for _, table := range schemaTables {
+		if got := columnNames(t, table); !slices.Equal(got, expected[table]) {
+			t.Errorf("Expected %s columns %v, but got %v", table, expected[table], got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minimalJWTConfig issues tokens without the username and email
+var minimalJWTConfig = func() utils.JWTConfig {
+	config := testJWTConfig
+	config.MinimalClaims = true
+	return config
+}()
+
+// newIdentityRouter serves login with config, and /whoami, which echoes the
+// username and email the auth middleware put in the context
+func newIdentityRouter(config utils.JWTConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/auth/login", handlers.Login(config))
+	router.GET("/whoami", middleware.AuthMiddleware(config.SecretKey), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"username": c.GetString("username"), "email": c.GetString("email")})
+	})
+	return router
+}
+
+// whoami returns the username and email the middleware resolved for token
+func whoami(t *testing.T, router *gin.Engine, token string) (string, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Username, resp.Email
+}
+
+// rawClaims decodes a token's payload without verifying it
+func rawClaims(t *testing.T, token string) jwt.MapClaims {
+	t.Helper()
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	return claims
+}
+
+func TestTokenClaimModes(t *testing.T) {
+	orgID := uint(7)
+	rich, err := utils.GenerateToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com", OrgID: &orgID, SessionID: "jti", Scopes: []string{models.ScopeUsersRead}}, testJWTConfig)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	minimal, err := utils.GenerateToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com", OrgID: &orgID, SessionID: "jti", Scopes: []string{models.ScopeUsersRead}}, minimalJWTConfig)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	richClaims := rawClaims(t, rich)
+	if richClaims["username"] != "testuser" || richClaims["email"] != "test@example.com" {
+		t.Errorf("Expected rich claims to embed the username and email, but got %v", richClaims)
+	}
+
+	minimalClaims := rawClaims(t, minimal)
+	for _, claim := range []string{"username", "email"} {
+		if _, ok := minimalClaims[claim]; ok {
+			t.Errorf("Expected no %s claim in a minimal token, but got %v", claim, minimalClaims)
+		}
+	}
+	for _, claim := range []string{"user_id", "org_id", "scopes", "jti", "exp"} {
+		if _, ok := minimalClaims[claim]; !ok {
+			t.Errorf("Expected a %s claim in a minimal token, but got %v", claim, minimalClaims)
+		}
+	}
+	if len(minimal) >= len(rich) {
+		t.Errorf("Expected the minimal token to be shorter, but got %d vs %d bytes", len(minimal), len(rich))
+	}
+
+	refresh, err := utils.GenerateRefreshToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com", SessionID: "jti"}, minimalJWTConfig)
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	if claims := rawClaims(t, refresh); claims["email"] != nil {
+		t.Errorf("Expected no email in a minimal refresh token, but got %v", claims)
+	}
+}
+
+func TestMinimalTokenIdentityLookup(t *testing.T) {
+	setupTestDB(t)
+	router := newIdentityRouter(minimalJWTConfig)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	resp := loginTestUser(t, router, user)
+	if claims := rawClaims(t, resp.Token); claims["email"] != nil {
+		t.Fatalf("Expected login to issue a minimal token, but got %v", claims)
+	}
+	if username, email := whoami(t, router, resp.Token); username != "testuser" || email != "test@example.com" {
+		t.Errorf("Expected testuser and test@example.com, but got %s and %s", username, email)
+	}
+
+	// The same token reflects an email change
+	database.DB.Model(&user).Update("email", "new@example.com")
+	if _, email := whoami(t, router, resp.Token); email != "new@example.com" {
+		t.Errorf("Expected the new email, but got %s", email)
+	}
+
+	introspection := decodeIntrospection(t, introspect(newIntrospectRouter(), testServiceKey, resp.Token))
+	if !introspection.Active || introspection.Username != "testuser" || introspection.Email != "new@example.com" {
+		t.Errorf("Expected introspection to report the current identity, but got %+v", introspection)
+	}
+}
+
+func TestRichTokenIdentity(t *testing.T) {
+	setupTestDB(t)
+	router := newIdentityRouter(testJWTConfig)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	resp := loginTestUser(t, router, user)
+	if claims := rawClaims(t, resp.Token); claims["email"] != "test@example.com" {
+		t.Fatalf("Expected login to embed the email by default, but got %v", claims)
+	}
+
+	// Rich tokens keep the identity they were issued with
+	database.DB.Model(&user).Update("email", "new@example.com")
+	if username, email := whoami(t, router, resp.Token); username != "testuser" || email != "test@example.com" {
+		t.Errorf("Expected testuser and test@example.com, but got %s and %s", username, email)
+	}
+
+	// Both kinds of token are accepted whatever the setting
+	minimal, err := utils.GenerateToken(utils.TokenOptions{UserID: user.ID, Username: user.Username, Email: user.Email, TokenVersion: user.TokenVersion}, minimalJWTConfig)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if _, email := whoami(t, router, minimal); email != "new@example.com" {
+		t.Errorf("Expected the current email for a minimal token, but got %s", email)
+	}
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/validation"
+)
+
+func TestNormalizationPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   validation.NormalizationPolicy
+		username string
+		email    string
+		text     string
+		expected [3]string
+	}{
+		{
+			name:     "Default policy",
+			policy:   validation.DefaultPolicy,
+			username: "  johndoe ",
+			email:    " John@Example.COM ",
+			text:     "  John   Q.  Doe ",
+			expected: [3]string{"johndoe", "john@example.com", "John Q. Doe"},
+		},
+		{
+			name:     "Lowercasing disabled",
+			policy:   validation.NormalizationPolicy{TrimSpace: true},
+			username: " johndoe",
+			email:    " John@Example.COM",
+			text:     " John   Doe ",
+			expected: [3]string{"johndoe", "John@Example.COM", "John   Doe"},
+		},
+		{
+			name:     "Everything disabled",
+			policy:   validation.NormalizationPolicy{},
+			username: " johndoe",
+			email:    " John@Example.COM",
+			text:     " John  Doe",
+			expected: [3]string{" johndoe", " John@Example.COM", " John  Doe"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation.Policy = tt.policy
+			defer func() { validation.Policy = validation.DefaultPolicy }()
+
+			got := [3]string{
+				validation.NormalizeUsername(tt.username),
+				validation.NormalizeEmail(tt.email),
+				validation.NormalizeText(tt.text),
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, but got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalizationAcrossWritePaths(t *testing.T) {
+	setupTestDB(t)
+
+	// Register
+	w := postJSON(newAuthRouter(), "/register", `{"username":"  newuser ","email":" New@Example.COM ","password":"StrongPass123"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var registered models.User
+	if err := database.DB.Where("username = ?", "newuser").First(&registered).Error; err != nil {
+		t.Fatalf("Expected trimmed username to be stored: %v", err)
+	}
+	if registered.Email != "new@example.com" {
+		t.Errorf("Expected email 'new@example.com', but got %q", registered.Email)
+	}
+
+	// Update, applying the email immediately
+	useEmailChange(t, handlers.EmailChangeConfig{Confirm: false})
+	w = httptest.NewRecorder()
+	newUserRouter().ServeHTTP(w, authRequest(t, http.MethodPut, fmt.Sprintf("/users/%d", registered.ID),
+		`{"username":"  renamed ","email":" Renamed@Example.COM "}`, registered))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var updated models.User
+	database.DB.First(&updated, registered.ID)
+	if updated.Username != "renamed" {
+		t.Errorf("Expected username 'renamed', but got %q", updated.Username)
+	}
+	if updated.Email != "renamed@example.com" {
+		t.Errorf("Expected email 'renamed@example.com', but got %q", updated.Email)
+	}
+
+	// Login with differently formatted email
+	w = postJSON(newAuthRouter(), "/login", `{"email":" RENAMED@example.com ","password":"StrongPass123"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestNormalizationOfFreeTextFields(t *testing.T) {
+	setupTestDB(t)
+
+	// An organization named at registration
+	handlers.OrgRegistration = handlers.OrgRegistrationOpen
+	defer func() { handlers.OrgRegistration = handlers.OrgRegistrationNone }()
+	w := postJSON(newAuthRouter(), "/register", `{"username":"founder","email":"founder@example.com","password":"StrongPass123","organization":"  Acme \t  Corp "}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var org models.Organization
+	if err := database.DB.First(&org).Error; err != nil {
+		t.Fatalf("Failed to load organization: %v", err)
+	}
+	if org.Name != "Acme Corp" {
+		t.Errorf("Expected organization name 'Acme Corp', but got %q", org.Name)
+	}
+
+	// An API key's name
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	created := createAPIKey(t, newAPIKeyRouter(), admin, `{"name":" Nightly   sync  job ","scopes":["users:read"]}`)
+	if created.APIKey.Name != "Nightly sync job" {
+		t.Errorf("Expected API key name 'Nightly sync job', but got %q", created.APIKey.Name)
+	}
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/handlers"
+)
+
+func TestNotFoundCodesDistinguishRouteFromResource(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+	router.NoRoute(handlers.NoRoute)
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode string
+	}{
+		{
+			name:         "Missing user",
+			path:         "/users/999",
+			expectedCode: apierror.CodeNotFound,
+		},
+		{
+			name:         "Unknown route",
+			path:         "/nonsense",
+			expectedCode: apierror.CodeRouteNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodGet, tt.path, "", user))
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("Expected status 404, but got %d", w.Code)
+			}
+
+			body := decodeError(t, w)
+			if body.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, but got %q", tt.expectedCode, body.Code)
+			}
+		})
+	}
+}
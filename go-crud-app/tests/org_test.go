@@ -0,0 +1,440 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createTestOrgUser inserts a user belonging to organization orgID
+func createTestOrgUser(t *testing.T, username, email string, orgID uint) models.User {
+	t.Helper()
+
+	user := createTestUser(t, username, email)
+	if err := database.DB.Model(&user).UpdateColumn("org_id", orgID).Error; err != nil {
+		t.Fatalf("Failed to set test user organization: %v", err)
+	}
+	user.OrgID = &orgID
+	return user
+}
+
+// createTestOrg inserts an organization with the given invite code
+func createTestOrg(t *testing.T, name, inviteCode string) models.Organization {
+	t.Helper()
+
+	org := models.Organization{Name: name, InviteCode: inviteCode}
+	if err := database.DB.Create(&org).Error; err != nil {
+		t.Fatalf("Failed to create test organization: %v", err)
+	}
+	return org
+}
+
+// setRole changes a test user's role
+func setRole(t *testing.T, user models.User, role string) models.User {
+	t.Helper()
+
+	if err := database.DB.Model(&user).Update("role", role).Error; err != nil {
+		t.Fatalf("Failed to set test user role: %v", err)
+	}
+	user.Role = role
+	return user
+}
+
+func TestGetAllUsersScopedToOrg(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	viewer := createTestOrgUser(t, "viewer", "viewer@example.com", 1)
+	createTestOrgUser(t, "colleague", "colleague@example.com", 1)
+	createTestOrgUser(t, "outsider", "outsider@example.com", 2)
+	createTestUser(t, "unaffiliated", "unaffiliated@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users", "", viewer))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Users []models.UserResponse `json:"users"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Users) != 1 || body.Users[0].Username != "colleague" {
+		t.Errorf("Expected only colleague, but got %+v", body.Users)
+	}
+}
+
+func TestGetAllUsersWithoutOrgsUnaffected(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	viewer := createTestUser(t, "viewer", "viewer@example.com")
+	createTestUser(t, "other", "other@example.com")
+	createTestOrgUser(t, "tenant", "tenant@example.com", 1)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users", "", viewer))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Users []models.UserResponse `json:"users"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Users) != 1 || body.Users[0].Username != "other" {
+		t.Errorf("Expected only other, but got %+v", body.Users)
+	}
+}
+
+func TestUserByIDHidesOtherOrgs(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPatch, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			setupTestDB(t)
+			router := newUserRouter()
+
+			viewer := createTestOrgUser(t, "viewer", "viewer@example.com", 1)
+			outsider := createTestOrgUser(t, "outsider", "outsider@example.com", 2)
+
+			body := ""
+			if method == http.MethodPatch {
+				body = `{"username":"renamed"}`
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, method, fmt.Sprintf("/users/%d", outsider.ID), body, viewer))
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("Expected status 404, but got %d: %s", w.Code, w.Body.String())
+			}
+
+			var stored models.User
+			if err := database.DB.First(&stored, outsider.ID).Error; err != nil {
+				t.Fatalf("Expected outsider to remain, but got %v", err)
+			}
+			if stored.Username != "outsider" {
+				t.Errorf("Expected outsider unchanged, but got username %q", stored.Username)
+			}
+		})
+	}
+}
+
+func TestTokenCarriesOrgID(t *testing.T) {
+	orgID := uint(7)
+	token, err := utils.GenerateToken(utils.TokenOptions{UserID: 1, Username: "testuser", Email: "test@example.com", OrgID: &orgID}, testJWTConfig)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	claims, err := utils.ValidateToken(token, testJWTConfig.SecretKey)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.OrgID == nil || *claims.OrgID != orgID {
+		t.Errorf("Expected org ID %d, but got %v", orgID, claims.OrgID)
+	}
+}
+
+func TestTokenRevokedAfterOrgChange(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestOrgUser(t, "testuser", "test@example.com", 1)
+	req := authRequest(t, http.MethodGet, "/users/me", "", user)
+	database.DB.Model(&user).UpdateColumn("org_id", 2)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterWithOrganization(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           string
+		body           string
+		expectedStatus int
+		expectedField  string
+		expectedOrg    bool // Joined the existing organization
+		expectedNewOrg bool
+	}{
+		{
+			name:           "Organizations disabled ignore the invite code",
+			mode:           handlers.OrgRegistrationNone,
+			body:           `{"username":"newbie","email":"newbie@example.com","password":"StrongPass123","invite_code":"acme-code"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Invite code joins its organization",
+			mode:           handlers.OrgRegistrationInvite,
+			body:           `{"username":"newbie","email":"newbie@example.com","password":"StrongPass123","invite_code":"acme-code"}`,
+			expectedStatus: http.StatusCreated,
+			expectedOrg:    true,
+		},
+		{
+			name:           "Invite mode requires a code",
+			mode:           handlers.OrgRegistrationInvite,
+			body:           `{"username":"newbie","email":"newbie@example.com","password":"StrongPass123","organization":"Mine"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedField:  "invite_code",
+		},
+		{
+			name:           "Unknown invite code",
+			mode:           handlers.OrgRegistrationOpen,
+			body:           `{"username":"newbie","email":"newbie@example.com","password":"StrongPass123","invite_code":"wrong"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedField:  "invite_code",
+		},
+		{
+			name:           "Open mode creates an organization",
+			mode:           handlers.OrgRegistrationOpen,
+			body:           `{"username":"newbie","email":"newbie@example.com","password":"StrongPass123","organization":"Newco"}`,
+			expectedStatus: http.StatusCreated,
+			expectedNewOrg: true,
+		},
+		{
+			name:           "Open mode requires a code or name",
+			mode:           handlers.OrgRegistrationOpen,
+			body:           `{"username":"newbie","email":"newbie@example.com","password":"StrongPass123"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedField:  "organization",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			handlers.OrgRegistration = tt.mode
+			defer func() { handlers.OrgRegistration = handlers.OrgRegistrationNone }()
+
+			acme := createTestOrg(t, "Acme", "acme-code")
+			router := newAuthRouter()
+
+			w := postJSON(router, "/register", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedField != "" {
+				resp := decodeError(t, w)
+				if len(resp.Fields) != 1 || resp.Fields[0].Field != tt.expectedField {
+					t.Errorf("Expected a %s field error, but got %+v", tt.expectedField, resp.Fields)
+				}
+				return
+			}
+
+			var user models.User
+			if err := database.DB.Where("username = ?", "newbie").First(&user).Error; err != nil {
+				t.Fatalf("Failed to load registered user: %v", err)
+			}
+			switch {
+			case tt.expectedOrg:
+				if user.OrgID == nil || *user.OrgID != acme.ID || user.Role != models.RoleUser {
+					t.Errorf("Expected a user of organization %d, but got org %v role %q", acme.ID, user.OrgID, user.Role)
+				}
+			case tt.expectedNewOrg:
+				if user.OrgID == nil || *user.OrgID == acme.ID || user.Role != models.RoleAdmin {
+					t.Fatalf("Expected the admin of a new organization, but got org %v role %q", user.OrgID, user.Role)
+				}
+				var org models.Organization
+				if err := database.DB.First(&org, *user.OrgID).Error; err != nil {
+					t.Fatalf("Failed to load new organization: %v", err)
+				}
+				if org.Name != "Newco" || org.InviteCode == "" {
+					t.Errorf("Expected organization Newco with an invite code, but got %+v", org)
+				}
+			default:
+				if user.OrgID != nil {
+					t.Errorf("Expected no organization, but got %d", *user.OrgID)
+				}
+			}
+
+			// The token carries the organization
+			var resp handlers.AuthResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			claims, err := utils.ValidateToken(resp.Token, testJWTConfig.SecretKey)
+			if err != nil {
+				t.Fatalf("Failed to validate token: %v", err)
+			}
+			if !models.SameOrg(claims.OrgID, user.OrgID) {
+				t.Errorf("Expected token org %v, but got %v", user.OrgID, claims.OrgID)
+			}
+		})
+	}
+}
+
+func TestAdminsScopedToOrg(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+
+	admin := setRole(t, createTestOrgUser(t, "orgadmin", "orgadmin@example.com", 1), models.RoleAdmin)
+	member := createTestOrgUser(t, "member", "member@example.com", 1)
+	outsider := createTestOrgUser(t, "outsider", "outsider@example.com", 2)
+
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"ids":[%d,%d]}`, member.ID, outsider.ID)
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", body, admin))
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status 207, but got %d: %s", w.Code, w.Body.String())
+	}
+	if userExists(t, member.ID) {
+		t.Error("Expected member of the admin's organization to be deleted")
+	}
+	if !userExists(t, outsider.ID) {
+		t.Error("Expected user of another organization to remain")
+	}
+
+	// Users created by an org admin join the admin's organization
+	w = httptest.NewRecorder()
+	body = `{"username":"newbie","email":"newbie@example.com","password":"TempPass123"}`
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users", body, admin))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.User
+	if err := database.DB.Where("username = ?", "newbie").First(&created).Error; err != nil {
+		t.Fatalf("Failed to load created user: %v", err)
+	}
+	if !models.SameOrg(created.OrgID, admin.OrgID) {
+		t.Errorf("Expected created user in organization %d, but got %v", *admin.OrgID, created.OrgID)
+	}
+
+	// Only super-admins choose the organization or create super-admins
+	for _, body := range []string{
+		`{"username":"other","email":"other@example.com","password":"TempPass123","org_id":2}`,
+		`{"username":"other","email":"other@example.com","password":"TempPass123","role":"superadmin"}`,
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users", body, admin))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for %s, but got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestSuperAdminSeesEveryOrg(t *testing.T) {
+	setupTestDB(t)
+	acme := createTestOrg(t, "Acme", "acme-code")
+	superAdmin := setRole(t, createTestOrgUser(t, "root", "root@example.com", 1), models.RoleSuperAdmin)
+	outsider := createTestOrgUser(t, "outsider", "outsider@example.com", 2)
+
+	w := httptest.NewRecorder()
+	newUserRouter().ServeHTTP(w, authRequest(t, http.MethodGet, fmt.Sprintf("/users/%d", outsider.ID), "", superAdmin))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+	w = httptest.NewRecorder()
+	body := fmt.Sprintf(`{"username":"newbie","email":"newbie@example.com","password":"TempPass123","org_id":%d}`, acme.ID)
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users", body, superAdmin))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	body = `{"username":"lost","email":"lost@example.com","password":"TempPass123","org_id":999}`
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users", body, superAdmin))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an unknown organization, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", fmt.Sprintf(`{"ids":[%d]}`, outsider.ID), superAdmin))
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status 207, but got %d: %s", w.Code, w.Body.String())
+	}
+	if userExists(t, outsider.ID) {
+		t.Error("Expected super-admin to delete a user of another organization")
+	}
+}
+
+func TestGetCurrentOrg(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/me/org", middleware.AuthMiddleware(testJWTConfig.SecretKey), handlers.GetCurrentOrg)
+
+	acme := createTestOrg(t, "Acme", "acme-code")
+	admin := setRole(t, createTestOrgUser(t, "orgadmin", "orgadmin@example.com", acme.ID), models.RoleAdmin)
+	member := createTestOrgUser(t, "member", "member@example.com", acme.ID)
+	loner := createTestUser(t, "loner", "loner@example.com")
+
+	tests := []struct {
+		name               string
+		user               models.User
+		expectedStatus     int
+		expectedInviteCode string
+	}{
+		{"admin sees the invite code", admin, http.StatusOK, "acme-code"},
+		{"member doesn't", member, http.StatusOK, ""},
+		{"no organization", loner, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me/org", "", tt.user))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var org models.OrganizationResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &org); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if org.Name != "Acme" || org.InviteCode != tt.expectedInviteCode {
+				t.Errorf("Expected Acme with invite code %q, but got %+v", tt.expectedInviteCode, org)
+			}
+		})
+	}
+}
+
+func TestListAuditLogsScopedToOrg(t *testing.T) {
+	setupTestDB(t)
+	router := newAuditRouter()
+
+	admin := setRole(t, createTestOrgUser(t, "orgadmin", "orgadmin@example.com", 1), models.RoleAdmin)
+	member := createTestOrgUser(t, "member", "member@example.com", 1)
+	outsider := createTestOrgUser(t, "outsider", "outsider@example.com", 2)
+	for _, actor := range []models.User{member, outsider} {
+		entry := models.AuditLog{Action: models.AuditLogin, ActorID: &actor.ID}
+		if err := database.DB.Create(&entry).Error; err != nil {
+			t.Fatalf("Failed to create audit entry: %v", err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/audit-logs", "", admin))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		AuditLogs []models.AuditLog `json:"audit_logs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.AuditLogs) != 1 || *body.AuditLogs[0].ActorID != member.ID {
+		t.Errorf("Expected only the member's entry, but got %+v", body.AuditLogs)
+	}
+}
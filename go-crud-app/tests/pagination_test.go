@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userListPage is the decoded body of a user list response
+type userListPage struct {
+	Users      []models.PublicUserResponse `json:"users"`
+	Count      int                         `json:"count"`
+	NextCursor *string                     `json:"next_cursor"`
+	Page       int                         `json:"page"`
+	PerPage    int                         `json:"per_page"`
+	Total      int64                       `json:"total"`
+}
+
+// newUserListRouter builds a router exposing the user list with the given page sizes
+func newUserListRouter(defaultPageSize, maxPageSize int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	users.GET("", handlers.GetAllUsers(handlers.UserListConfig{
+		DefaultPageSize: defaultPageSize,
+		MaxPageSize:     maxPageSize,
+		CursorSecret:    testJWTConfig.SecretKey,
+	}))
+	return router
+}
+
+// listUsers requests the user list as viewer and decodes the page
+func listUsers(t *testing.T, router *gin.Engine, viewer models.User, query string) userListPage {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users?"+query, "", viewer))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var page userListPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return page
+}
+
+func TestCursorPaginationVisitsEveryUserOnce(t *testing.T) {
+	setupTestDB(t)
+	router := newUserListRouter(3, 10)
+
+	viewer := createTestUser(t, "viewer", "viewer@example.com")
+	expected := map[uint]bool{}
+	for i := 0; i < 7; i++ {
+		user := createTestUser(t, fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i))
+		expected[user.ID] = true
+	}
+
+	seen := map[uint]int{}
+	query := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("Expected pagination to finish, but it kept returning cursors")
+		}
+
+		page := listUsers(t, router, viewer, query)
+		if page.Count != len(page.Users) || page.Count > 3 {
+			t.Fatalf("Expected at most 3 users matching count, but got %d users and count %d", len(page.Users), page.Count)
+		}
+		for _, user := range page.Users {
+			seen[user.ID]++
+		}
+		if page.NextCursor == nil {
+			break
+		}
+
+		// Users registering between requests must neither shift nor repeat
+		// rows on later pages
+		user := createTestUser(t, fmt.Sprintf("late%d", pages), fmt.Sprintf("late%d@example.com", pages))
+		expected[user.ID] = true
+
+		query = "cursor=" + url.QueryEscape(*page.NextCursor)
+	}
+
+	for id := range expected {
+		if seen[id] != 1 {
+			t.Errorf("Expected user %d to be listed once, but it was listed %d times", id, seen[id])
+		}
+	}
+	if len(seen) != len(expected) {
+		t.Errorf("Expected %d users, but got %d", len(expected), len(seen))
+	}
+	if seen[viewer.ID] != 0 {
+		t.Error("Expected the current user to be excluded from the list")
+	}
+}
+
+func TestCursorPaginationRejectsForgedCursors(t *testing.T) {
+	setupTestDB(t)
+	router := newUserListRouter(1, 10)
+
+	viewer := createTestUser(t, "viewer", "viewer@example.com")
+	createTestUser(t, "first", "first@example.com")
+	createTestUser(t, "second", "second@example.com")
+
+	page := listUsers(t, router, viewer, "")
+	if page.NextCursor == nil {
+		t.Fatal("Expected a next_cursor, but got none")
+	}
+	issued := *page.NextCursor
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Garbage", cursor: "not-a-cursor"},
+		{name: "Signed with another secret", cursor: utils.EncodeCursor(1, "other-secret")},
+		{name: "Payload swapped", cursor: utils.EncodeCursor(0, "other-secret")[:2] + issued[2:]},
+		{name: "Signature truncated", cursor: issued[:len(issued)-2]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users?cursor="+url.QueryEscape(tt.cursor), "", viewer))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, but got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestUserListPageSizeLimits(t *testing.T) {
+	setupTestDB(t)
+	router := newUserListRouter(2, 5)
+
+	viewer := createTestUser(t, "viewer", "viewer@example.com")
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{name: "Default limit", query: "", expectedStatus: http.StatusOK},
+		{name: "Limit at maximum", query: "limit=5", expectedStatus: http.StatusOK},
+		{name: "Limit above maximum", query: "limit=6", expectedStatus: http.StatusBadRequest},
+		{name: "Negative limit", query: "limit=-1", expectedStatus: http.StatusBadRequest},
+		{name: "per_page above maximum", query: "page=1&per_page=6", expectedStatus: http.StatusBadRequest},
+		{name: "Cursor and page together", query: "limit=2&page=1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users?"+tt.query, "", viewer))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUserListOffsetPagination(t *testing.T) {
+	setupTestDB(t)
+	router := newUserListRouter(2, 5)
+
+	viewer := createTestUser(t, "viewer", "viewer@example.com")
+	var users []models.User
+	for i := 0; i < 5; i++ {
+		users = append(users, createTestUser(t, fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i)))
+	}
+
+	page := listUsers(t, router, viewer, "page=2&per_page=2")
+	if page.Page != 2 || page.PerPage != 2 || page.Total != 5 {
+		t.Errorf("Expected page 2, per_page 2 and total 5, but got %d, %d and %d", page.Page, page.PerPage, page.Total)
+	}
+	if len(page.Users) != 2 || page.Users[0].ID != users[2].ID || page.Users[1].ID != users[3].ID {
+		t.Errorf("Expected users %d and %d, but got %+v", users[2].ID, users[3].ID, page.Users)
+	}
+	if page.NextCursor != nil {
+		t.Errorf("Expected no next_cursor in offset mode, but got %s", *page.NextCursor)
+	}
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newPasswordExpiryRouter builds a router with login, the current user and
+// the password change route behind the forced password change gate
+func newPasswordExpiryRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", handlers.Register(testJWTConfig))
+	router.POST("/login", handlers.Login(testJWTConfig))
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	users.Use(middleware.PasswordChangeGate("/users/me/password"))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.PUT("/me/password", handlers.ChangePassword(testJWTConfig))
+	}
+	return router
+}
+
+// setPasswordMaxAge enables password expiry for the rest of the test
+func setPasswordMaxAge(t *testing.T, maxAge time.Duration) {
+	t.Helper()
+
+	previous := utils.PasswordMaxAge
+	utils.PasswordMaxAge = maxAge
+	t.Cleanup(func() { utils.PasswordMaxAge = previous })
+}
+
+// registerForExpiry registers testuser and returns the stored user
+func registerForExpiry(t *testing.T, router *gin.Engine) models.User {
+	t.Helper()
+
+	w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"OldPassword123"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	var user models.User
+	if err := database.DB.Where("username = ?", "testuser").First(&user).Error; err != nil {
+		t.Fatalf("Failed to load registered user: %v", err)
+	}
+	return user
+}
+
+// agePassword backdates when the user's password was last changed
+func agePassword(t *testing.T, user models.User, age time.Duration) {
+	t.Helper()
+
+	if err := database.DB.Model(&user).Update("password_changed_at", time.Now().Add(-age)).Error; err != nil {
+		t.Fatalf("Failed to age password: %v", err)
+	}
+}
+
+func TestFreshPasswordIsNotExpired(t *testing.T) {
+	setupTestDB(t)
+	setPasswordMaxAge(t, 90*24*time.Hour)
+	router := newPasswordExpiryRouter()
+	user := registerForExpiry(t, router)
+
+	if user.PasswordChangedAt == nil {
+		t.Fatal("Expected registration to record when the password was set")
+	}
+
+	token := loginToken(t, router, "test@example.com", "OldPassword123")
+	if w := bearerRequest(router, http.MethodGet, "/users/me", token, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestExpiredPasswordTriggersGate(t *testing.T) {
+	setupTestDB(t)
+	setPasswordMaxAge(t, 90*24*time.Hour)
+	router := newPasswordExpiryRouter()
+	user := registerForExpiry(t, router)
+	agePassword(t, user, 91*24*time.Hour)
+
+	w := postJSON(router, "/login", `{"email":"test@example.com","password":"OldPassword123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected login with an expired password to succeed, but got %d: %s", w.Code, w.Body.String())
+	}
+	var login handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+	if !login.MustChangePassword || !login.PasswordExpired {
+		t.Errorf("Expected the login response to flag an expired password, but got %+v", login)
+	}
+
+	w = bearerRequest(router, http.MethodGet, "/users/me", login.Token, "")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+	body := decodeError(t, w)
+	if body.Code != apierror.CodePasswordChangeRequired || body.Message != middleware.PasswordExpiredMessage {
+		t.Errorf("Expected %s with %q, but got %s with %q", apierror.CodePasswordChangeRequired, middleware.PasswordExpiredMessage, body.Code, body.Message)
+	}
+
+	// The policy is off by default
+	utils.PasswordMaxAge = 0
+	if w := bearerRequest(router, http.MethodGet, "/users/me", login.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 without a max age, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPasswordChangeResetsExpiry(t *testing.T) {
+	setupTestDB(t)
+	setPasswordMaxAge(t, 90*24*time.Hour)
+	router := newPasswordExpiryRouter()
+	user := registerForExpiry(t, router)
+	agePassword(t, user, 91*24*time.Hour)
+
+	token := loginToken(t, router, "test@example.com", "OldPassword123")
+	w := bearerRequest(router, http.MethodPut, "/users/me/password", token,
+		`{"current_password":"OldPassword123","new_password":"NewPassword456"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var changed handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &changed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if changed.PasswordExpired {
+		t.Error("Expected the new password not to be expired")
+	}
+
+	if w := bearerRequest(router, http.MethodGet, "/users/me", changed.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after changing the password, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored models.User
+	if err := database.DB.First(&stored, user.ID).Error; err != nil {
+		t.Fatalf("Failed to reload user: %v", err)
+	}
+	if stored.PasswordChangedAt == nil || time.Since(*stored.PasswordChangedAt) > time.Minute {
+		t.Errorf("Expected password_changed_at to be reset, but got %v", stored.PasswordChangedAt)
+	}
+}
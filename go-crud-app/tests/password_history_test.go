@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setPasswordHistorySize enables password history for the rest of the test
+func setPasswordHistorySize(t *testing.T, size int) {
+	t.Helper()
+
+	previous := handlers.PasswordHistorySize
+	handlers.PasswordHistorySize = size
+	t.Cleanup(func() { handlers.PasswordHistorySize = previous })
+}
+
+// changePassword logs in with current and changes the password to next
+func changePassword(t *testing.T, router *gin.Engine, current, next string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	token := loginToken(t, router, "test@example.com", current)
+	return bearerRequest(router, http.MethodPut, "/users/me/password", token,
+		fmt.Sprintf(`{"current_password":%q,"new_password":%q}`, current, next))
+}
+
+// expectPasswordReused checks that a password change was refused as reuse
+func expectPasswordReused(t *testing.T, w *httptest.ResponseRecorder, field string) {
+	t.Helper()
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeError(t, w)
+	if len(resp.Fields) != 1 || resp.Fields[0].Field != field || !strings.Contains(resp.Fields[0].Message, "must differ") {
+		t.Errorf("Expected a reuse error for %s, but got %+v", field, resp.Fields)
+	}
+}
+
+func TestPasswordHistoryRejectsRecentPasswords(t *testing.T) {
+	setupTestDB(t)
+	setPasswordHistorySize(t, 3)
+	router := newSessionRouter(&fakeMailer{})
+
+	if w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"FirstPass123"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	for _, step := range [][2]string{{"FirstPass123", "SecondPass123"}, {"SecondPass123", "ThirdPass123"}} {
+		if w := changePassword(t, router, step[0], step[1]); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// The current password and the two before it are remembered
+	for _, reused := range []string{"ThirdPass123", "SecondPass123", "FirstPass123"} {
+		expectPasswordReused(t, changePassword(t, router, "ThirdPass123", reused), "new_password")
+	}
+
+	// One more change pushes the first password out of the history
+	if w := changePassword(t, router, "ThirdPass123", "FourthPass123"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if w := changePassword(t, router, "FourthPass123", "FirstPass123"); w.Code != http.StatusOK {
+		t.Fatalf("Expected a password older than the history to be allowed, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Only the replaced passwords still in the window are stored
+	var count int64
+	database.DB.Model(&models.PasswordHistory{}).Count(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 remembered passwords, but got %d", count)
+	}
+}
+
+func TestPasswordResetRejectsRecentPasswords(t *testing.T) {
+	setupTestDB(t)
+	setPasswordHistorySize(t, 2)
+	mailer := &fakeMailer{}
+	router := newSessionRouter(mailer)
+
+	if w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"FirstPass123"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	if w := changePassword(t, router, "FirstPass123", "SecondPass123"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := postJSON(router, "/forgot-password", `{"email":"test@example.com"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	resetToken := mailer.sent[0][strings.LastIndex(mailer.sent[0], "token=")+len("token="):]
+
+	for _, reused := range []string{"SecondPass123", "FirstPass123"} {
+		w := postJSON(router, "/reset-password", fmt.Sprintf(`{"token":%q,"password":%q}`, resetToken, reused))
+		expectPasswordReused(t, w, "password")
+	}
+
+	// A refused password leaves the reset token usable
+	w := postJSON(router, "/reset-password", fmt.Sprintf(`{"token":%q,"password":"ThirdPass123"}`, resetToken))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPasswordHistoryOffAllowsReuse(t *testing.T) {
+	setupTestDB(t)
+	router := newSessionRouter(&fakeMailer{})
+
+	if w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"FirstPass123"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	if w := changePassword(t, router, "FirstPass123", "FirstPass123"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var count int64
+	database.DB.Model(&models.PasswordHistory{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no remembered passwords, but got %d", count)
+	}
+}
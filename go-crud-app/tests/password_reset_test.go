@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// fakeMailer records sent emails instead of delivering them
+type fakeMailer struct {
+	mu         sync.Mutex
+	sent       []string
+	recipients []string
+}
+
+func (m *fakeMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, body)
+	m.recipients = append(m.recipients, to)
+	return nil
+}
+
+func (m *fakeMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+func newPasswordResetRouter(config handlers.PasswordResetConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/forgot-password", handlers.ForgotPassword(config))
+	router.POST("/reset-password", handlers.ResetPassword)
+	return router
+}
+
+func TestForgotPasswordHourlyCap(t *testing.T) {
+	setupTestDB(t)
+	createTestUser(t, "testuser", "test@example.com")
+
+	mailer := &fakeMailer{}
+	router := newPasswordResetRouter(handlers.PasswordResetConfig{
+		Mailer:             mailer,
+		TokenTTL:           time.Hour,
+		MaxRequestsPerHour: 2,
+		ResetURL:           "http://localhost/reset",
+	})
+
+	for i := 0; i < 4; i++ {
+		w := postJSON(router, "/forgot-password", `{"email":"test@example.com"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, but got %d", i+1, w.Code)
+		}
+	}
+
+	if got := mailer.count(); got != 2 {
+		t.Errorf("Expected 2 emails to be sent, but got %d", got)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	setupTestDB(t)
+
+	mailer := &fakeMailer{}
+	router := newPasswordResetRouter(handlers.PasswordResetConfig{
+		Mailer:   mailer,
+		TokenTTL: time.Hour,
+	})
+
+	w := postJSON(router, "/forgot-password", `{"email":"nobody@example.com"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+	if got := mailer.count(); got != 0 {
+		t.Errorf("Expected no emails to be sent, but got %d", got)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	mailer := &fakeMailer{}
+	router := newPasswordResetRouter(handlers.PasswordResetConfig{
+		Mailer:   mailer,
+		TokenTTL: time.Hour,
+		ResetURL: "http://localhost/reset",
+	})
+
+	// Issue two tokens; only the latest one may be used
+	postJSON(router, "/forgot-password", `{"email":"test@example.com"}`)
+	postJSON(router, "/forgot-password", `{"email":"test@example.com"}`)
+	if mailer.count() != 2 {
+		t.Fatalf("Expected 2 emails to be sent, but got %d", mailer.count())
+	}
+	staleToken := mailer.sent[0][strings.LastIndex(mailer.sent[0], "token=")+len("token="):]
+	token := mailer.sent[1][strings.LastIndex(mailer.sent[1], "token=")+len("token="):]
+
+	w := postJSON(router, "/reset-password", `{"token":"`+staleToken+`","password":"NewPassword123"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for superseded token, but got %d", w.Code)
+	}
+
+	w = postJSON(router, "/reset-password", `{"token":"`+token+`","password":"NewPassword123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored models.User
+	database.DB.First(&stored, user.ID)
+	if !utils.CheckPassword("NewPassword123", stored.PasswordHash) {
+		t.Error("Expected password to be updated")
+	}
+
+	// The token is single-use
+	w = postJSON(router, "/reset-password", `{"token":"`+token+`","password":"OtherPassword123"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for reused token, but got %d", w.Code)
+	}
+}
+
+func TestResetPasswordTokenUsedConcurrently(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	mailer := &fakeMailer{}
+	router := newPasswordResetRouter(handlers.PasswordResetConfig{
+		Mailer:   mailer,
+		TokenTTL: time.Hour,
+		ResetURL: "http://localhost/reset",
+	})
+	postJSON(router, "/forgot-password", `{"email":"test@example.com"}`)
+	token := mailer.sent[0][strings.LastIndex(mailer.sent[0], "token=")+len("token="):]
+
+	// Another reset uses the token right after this one has looked it up
+	used := false
+	err := database.DB.Callback().Query().After("gorm:query").Register("test:concurrent_reset", func(db *gorm.DB) {
+		if used || db.Statement.Table != "password_reset_tokens" {
+			return
+		}
+		used = true
+		database.DB.Exec("UPDATE password_reset_tokens SET used_at = ?", time.Now())
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	w := postJSON(router, "/reset-password", `{"token":"`+token+`","password":"NewPassword123"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a token used concurrently, but got %d: %s", w.Code, w.Body.String())
+	}
+	if stored := reloadUser(t, user.ID); stored.PasswordHash != user.PasswordHash || stored.TokenVersion != user.TokenVersion {
+		t.Error("Expected the password to be unchanged")
+	}
+}
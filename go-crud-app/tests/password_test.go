@@ -1,11 +1,25 @@
 package tests
 
 import (
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
 
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/models"
 	"go-crud-app/internal/utils"
 )
 
+// useHasher switches the password hasher for the rest of the test
+func useHasher(t *testing.T, hasher utils.PasswordHasher) {
+	t.Helper()
+
+	previous := utils.Hasher
+	utils.Hasher = hasher
+	t.Cleanup(func() { utils.Hasher = previous })
+}
+
 func TestHashPassword(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -138,6 +152,21 @@ func TestValidatePassword(t *testing.T) {
 			password:    "C0mpl3xP@ssw0rd!",
 			shouldError: false,
 		},
+		{
+			name:        "Exactly 72 bytes",
+			password:    "Aa1" + strings.Repeat("x", 69),
+			shouldError: false,
+		},
+		{
+			name:        "73 bytes",
+			password:    "Aa1" + strings.Repeat("x", 70),
+			shouldError: true,
+		},
+		{
+			name:        "Under 72 characters but over 72 bytes",
+			password:    "Aa1" + strings.Repeat("é", 35),
+			shouldError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -153,3 +182,298 @@ func TestValidatePassword(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePasswordUnicode(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		shouldError bool
+	}{
+		{
+			name:        "Cyrillic letters",
+			password:    "Пароль2024",
+			shouldError: false,
+		},
+		{
+			name:        "Greek letters",
+			password:    "Κωδικός9ος",
+			shouldError: false,
+		},
+		{
+			name:        "Non-ASCII digit",
+			password:    "Passwort٣xyz",
+			shouldError: false,
+		},
+		{
+			name:        "Counted by characters, not bytes",
+			password:    "Äöü1ßéàç",
+			shouldError: false,
+		},
+		{
+			name:        "Cyrillic without uppercase",
+			password:    "пароль2024",
+			shouldError: true,
+		},
+		{
+			name:        "Cyrillic without lowercase",
+			password:    "ПАРОЛЬ2024",
+			shouldError: true,
+		},
+		{
+			name:        "Cyrillic without a number",
+			password:    "ПарольПароль",
+			shouldError: true,
+		},
+		{
+			name:        "Uncased letters count as neither case",
+			password:    "密码密码密码1a",
+			shouldError: true,
+		},
+		{
+			name:        "Emoji count once each",
+			password:    "Ab1😀😀😀😀😀",
+			shouldError: false,
+		},
+		{
+			name:        "Too few characters despite many bytes",
+			password:    "Ab1😀😀😀",
+			shouldError: true,
+		},
+		{
+			name:        "Combining accents count as separate characters",
+			password:    "Ae\u0301e\u0301e\u03011",
+			shouldError: false,
+		},
+		{
+			name:        "Precomposed accents count once",
+			password:    "Aéé1xyz",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidatePassword(tt.password)
+
+			if tt.shouldError && err == nil {
+				t.Errorf("Expected error for password %s, but got none", tt.password)
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("Unexpected error for password %s: %v", tt.password, err)
+			}
+		})
+	}
+}
+
+func TestPasswordHashAlgorithms(t *testing.T) {
+	password := "TestPassword123"
+	bcryptHasher := utils.BcryptHasher{Cost: utils.BcryptCost}
+	argon2Hasher := utils.DefaultArgon2idHasher
+
+	tests := []struct {
+		name           string
+		hasher         utils.PasswordHasher
+		expectedPrefix string
+	}{
+		{"bcrypt", bcryptHasher, "$2a$"},
+		{"argon2id", argon2Hasher, "$argon2id$v=19$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useHasher(t, tt.hasher)
+			hash, err := utils.HashPassword(password)
+			if err != nil {
+				t.Fatalf("Failed to hash password: %v", err)
+			}
+			if !strings.HasPrefix(hash, tt.expectedPrefix) {
+				t.Errorf("Expected hash with prefix %s, but got %s", tt.expectedPrefix, hash)
+			}
+
+			// Hashes keep verifying whichever algorithm is configured afterwards
+			for _, configured := range []utils.PasswordHasher{bcryptHasher, argon2Hasher} {
+				useHasher(t, configured)
+				if !utils.CheckPassword(password, hash) {
+					t.Errorf("Expected %T to verify the correct password", configured)
+				}
+				if utils.CheckPassword("WrongPassword123", hash) {
+					t.Errorf("Expected %T to reject a wrong password", configured)
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	bcryptHash, err := utils.BcryptHasher{Cost: utils.BcryptCost}.Hash("TestPassword123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	argon2Hash, err := utils.DefaultArgon2idHasher.Hash("TestPassword123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	stronger := utils.DefaultArgon2idHasher
+	stronger.Time++
+
+	tests := []struct {
+		name     string
+		hasher   utils.PasswordHasher
+		hash     string
+		expected bool
+	}{
+		{"bcrypt hash under bcrypt", utils.BcryptHasher{Cost: utils.BcryptCost}, bcryptHash, false},
+		{"bcrypt hash under higher cost", utils.BcryptHasher{Cost: utils.BcryptCost + 1}, bcryptHash, true},
+		{"bcrypt hash under argon2id", utils.DefaultArgon2idHasher, bcryptHash, true},
+		{"argon2id hash under argon2id", utils.DefaultArgon2idHasher, argon2Hash, false},
+		{"argon2id hash under stronger parameters", stronger, argon2Hash, true},
+		{"argon2id hash under bcrypt", utils.BcryptHasher{Cost: utils.BcryptCost}, argon2Hash, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useHasher(t, tt.hasher)
+			if result := utils.PasswordNeedsRehash(tt.hash); result != tt.expected {
+				t.Errorf("Expected %v, but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	tests := []struct {
+		algorithm   string
+		expected    utils.PasswordHasher
+		shouldError bool
+	}{
+		{"", utils.BcryptHasher{Cost: utils.BcryptCost}, false},
+		{"bcrypt", utils.BcryptHasher{Cost: utils.BcryptCost}, false},
+		{"Argon2id", utils.DefaultArgon2idHasher, false},
+		{"scrypt", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			hasher, err := utils.NewPasswordHasher(tt.algorithm)
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("Expected error for %q, but got %T", tt.algorithm, hasher)
+				}
+				return
+			}
+			if err != nil || hasher != tt.expected {
+				t.Errorf("Expected %+v, but got %+v (%v)", tt.expected, hasher, err)
+			}
+		})
+	}
+}
+
+func TestLoginUpgradesLegacyHash(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+
+	password := "SecurePass123"
+	legacyHash, err := utils.BcryptHasher{Cost: utils.BcryptCost}.Hash(password)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	user := models.User{Username: "legacy", Email: "legacy@example.com", PasswordHash: legacyHash}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	useHasher(t, utils.DefaultArgon2idHasher)
+	body := `{"email":"legacy@example.com","password":"` + password + `"}`
+
+	for _, attempt := range []string{"legacy hash", "upgraded hash"} {
+		w := postJSON(router, "/login", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected login with the %s to succeed, but got %d: %s", attempt, w.Code, w.Body.String())
+		}
+
+		var stored models.User
+		if err := database.DB.First(&stored, user.ID).Error; err != nil {
+			t.Fatalf("Failed to reload user: %v", err)
+		}
+		if !strings.HasPrefix(stored.PasswordHash, "$argon2id$") {
+			t.Errorf("Expected an argon2id hash after login with the %s, but got %s", attempt, stored.PasswordHash)
+		}
+		if stored.TokenVersion != user.TokenVersion {
+			t.Errorf("Expected token version %d to be unchanged, but got %d", user.TokenVersion, stored.TokenVersion)
+		}
+	}
+}
+
+func TestLongPasswordsSharingPrefix(t *testing.T) {
+	// bcrypt would hash both to the same value, since it ignores bytes past 72
+	prefix := "Aa1" + strings.Repeat("x", utils.MaxPasswordBytes-3)
+	passwords := []string{prefix + "first", prefix + "second"}
+
+	for _, hasher := range []utils.PasswordHasher{utils.BcryptHasher{Cost: utils.BcryptCost}, utils.DefaultArgon2idHasher} {
+		useHasher(t, hasher)
+		for _, password := range passwords {
+			if _, err := utils.HashPassword(password); !errors.Is(err, utils.ErrPasswordTooLong) {
+				t.Errorf("Expected %v from %T for a %d-byte password, but got %v", utils.ErrPasswordTooLong, hasher, len(password), err)
+			}
+		}
+	}
+
+	// The longest accepted password is hashed in full, so a one-byte change is detected
+	hash, err := utils.HashPassword(prefix)
+	if err != nil {
+		t.Fatalf("Failed to hash a %d-byte password: %v", len(prefix), err)
+	}
+	if utils.CheckPassword(prefix[:len(prefix)-1]+"y", hash) {
+		t.Error("Expected a password differing in the last byte to be rejected")
+	}
+}
+
+func TestLoginComparesPasswordForUnknownEmails(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+
+	createTestUser(t, "known", "known@example.com")
+	oauthOnly := models.User{Username: "oauthonly", Email: "oauth@example.com"}
+	if err := database.DB.Create(&oauthOnly).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{name: "Unknown email", email: "nobody@example.com"},
+		{name: "Wrong password", email: "known@example.com"},
+		{name: "Account without a password", email: "oauth@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := utils.PasswordComparisons()
+			w := postJSON(router, "/login", `{"email":"`+tt.email+`","password":"WrongPassword1"}`)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status %d, but got %d", http.StatusUnauthorized, w.Code)
+			}
+			if body := decodeError(t, w); body.Message != "Invalid email or password" {
+				t.Errorf("Expected the generic login error, but got %q", body.Message)
+			}
+			if compared := utils.PasswordComparisons() - before; compared != 1 {
+				t.Errorf("Expected 1 password comparison, but got %d", compared)
+			}
+		})
+	}
+}
+
+func TestDummyCheckPassword(t *testing.T) {
+	for _, hasher := range []utils.PasswordHasher{utils.BcryptHasher{Cost: utils.BcryptCost}, utils.DefaultArgon2idHasher} {
+		useHasher(t, hasher)
+
+		if utils.DummyCheckPassword("SecurePass123") {
+			t.Errorf("Expected the dummy check with %T to fail, but it passed", hasher)
+		}
+		if utils.CheckPassword("SecurePass123", "") {
+			t.Errorf("Expected an empty hash with %T to fail, but it passed", hasher)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// usePeppers switches the current and previous peppers for the rest of the test
+func usePeppers(t *testing.T, current *utils.Pepper, previous ...utils.Pepper) {
+	t.Helper()
+
+	prevCurrent, prevPrevious := utils.CurrentPepper, utils.PreviousPeppers
+	utils.CurrentPepper, utils.PreviousPeppers = current, previous
+	t.Cleanup(func() { utils.CurrentPepper, utils.PreviousPeppers = prevCurrent, prevPrevious })
+}
+
+func TestPepperedHashes(t *testing.T) {
+	oldPepper := utils.Pepper{ID: "2023", Secret: "old-pepper-secret"}
+	newPepper := utils.Pepper{ID: "2024", Secret: "new-pepper-secret"}
+	password := "TestPassword123"
+
+	for _, hasher := range []utils.PasswordHasher{utils.BcryptHasher{Cost: bcrypt.MinCost}, utils.DefaultArgon2idHasher} {
+		useHasher(t, hasher)
+
+		usePeppers(t, nil)
+		plain, err := utils.HashPassword(password)
+		if err != nil {
+			t.Fatalf("Failed to hash password: %v", err)
+		}
+		usePeppers(t, &oldPepper)
+		peppered, err := utils.HashPassword(password)
+		if err != nil {
+			t.Fatalf("Failed to hash password: %v", err)
+		}
+		if strings.HasPrefix(plain, "$pepper$") {
+			t.Errorf("Expected no pepper marker without a pepper, but got %s", plain)
+		}
+		if !strings.HasPrefix(peppered, "$pepper$2023$") {
+			t.Errorf("Expected the pepper ID in the hash, but got %s", peppered)
+		}
+
+		tests := []struct {
+			name        string
+			current     *utils.Pepper
+			previous    []utils.Pepper
+			hash        string
+			verifies    bool
+			needsRehash bool
+		}{
+			{"Unpeppered hash without a pepper", nil, nil, plain, true, false},
+			{"Unpeppered hash once a pepper is added", &oldPepper, nil, plain, true, true},
+			{"Peppered hash with its pepper", &oldPepper, nil, peppered, true, false},
+			{"Peppered hash after rotation", &newPepper, []utils.Pepper{oldPepper}, peppered, true, true},
+			{"Peppered hash with its pepper retired", &newPepper, nil, peppered, false, true},
+			{"Peppered hash with the pepper removed", nil, nil, peppered, false, true},
+			{"Peppered hash with the pepper only retired", nil, []utils.Pepper{oldPepper}, peppered, true, true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				usePeppers(t, tt.current, tt.previous...)
+
+				if got := utils.CheckPassword(password, tt.hash); got != tt.verifies {
+					t.Errorf("Expected %T to verify the correct password: %v, but got %v", hasher, tt.verifies, got)
+				}
+				if utils.CheckPassword("WrongPassword123", tt.hash) {
+					t.Errorf("Expected %T to reject a wrong password", hasher)
+				}
+				if got := utils.PasswordNeedsRehash(tt.hash); got != tt.needsRehash {
+					t.Errorf("Expected needs rehash %v, but got %v", tt.needsRehash, got)
+				}
+			})
+		}
+	}
+
+	t.Run("Same ID with another secret", func(t *testing.T) {
+		useHasher(t, utils.BcryptHasher{Cost: bcrypt.MinCost})
+		usePeppers(t, &oldPepper)
+		hash, err := utils.HashPassword(password)
+		if err != nil {
+			t.Fatalf("Failed to hash password: %v", err)
+		}
+
+		usePeppers(t, &utils.Pepper{ID: oldPepper.ID, Secret: "a-different-secret"})
+		if utils.CheckPassword(password, hash) {
+			t.Error("Expected a hash to need the pepper it was made with")
+		}
+	})
+}
+
+func TestLoginRotatesPepper(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+	useHasher(t, utils.BcryptHasher{Cost: bcrypt.MinCost})
+
+	oldPepper := utils.Pepper{ID: "old", Secret: "old-pepper-secret"}
+	newPepper := utils.Pepper{ID: "new", Secret: "new-pepper-secret"}
+
+	password := "SecurePass123"
+	usePeppers(t, &oldPepper)
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	user := models.User{Username: "peppered", Email: "peppered@example.com", PasswordHash: hash}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// Rotate: the old pepper is only kept to verify hashes made with it
+	usePeppers(t, &newPepper, oldPepper)
+	body := `{"email":"peppered@example.com","password":"` + password + `"}`
+	if w := postJSON(router, "/login", body); w.Code != http.StatusOK {
+		t.Fatalf("Expected login with the old pepper to succeed, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored models.User
+	if err := database.DB.First(&stored, user.ID).Error; err != nil {
+		t.Fatalf("Failed to reload user: %v", err)
+	}
+	if !strings.HasPrefix(stored.PasswordHash, "$pepper$new$") {
+		t.Errorf("Expected the hash to move to the new pepper, but got %s", stored.PasswordHash)
+	}
+
+	// Once upgraded, the old pepper can be dropped
+	usePeppers(t, &newPepper)
+	if w := postJSON(router, "/login", body); w.Code != http.StatusOK {
+		t.Errorf("Expected login after retiring the old pepper to succeed, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestParsePeppers(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    []string
+		shouldError bool
+	}{
+		{"Empty", "", nil, false},
+		{"One", "2023:secret", []string{"2023"}, false},
+		{"Several with spaces", " 2022:a , 2023:b:c ", []string{"2022", "2023"}, false},
+		{"Missing secret", "2023", nil, true},
+		{"Empty secret", "2023:", nil, true},
+		{"Invalid ID", "20$23:secret", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peppers, err := utils.ParsePeppers(tt.value)
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("Expected an error for %q, but got none", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			var ids []string
+			for _, pepper := range peppers {
+				ids = append(ids, pepper.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected pepper IDs %v, but got %v", tt.expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newProvidersRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me/providers", handlers.GetLinkedProviders)
+		users.DELETE("/me/providers/:provider", handlers.UnlinkProvider)
+	}
+	return router
+}
+
+func TestListLinkedProviders(t *testing.T) {
+	setupTestDB(t)
+	router := newProvidersRouter()
+
+	user := createTestUser(t, "testuser", "test@example.com")
+	database.DB.Create(&models.AuthIdentity{UserID: user.ID, Provider: "google", Subject: "google-123"})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me/providers", "", user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var resp struct {
+		Providers []handlers.LinkedProvider `json:"providers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(resp.Providers) != 2 || resp.Providers[0].Provider != "local" || resp.Providers[1].Provider != "google" {
+		t.Errorf("Expected providers [local google], but got %+v", resp.Providers)
+	}
+}
+
+func TestUnlinkLastProviderIsBlocked(t *testing.T) {
+	setupTestDB(t)
+	router := newProvidersRouter()
+
+	user := createTestUser(t, "testuser", "test@example.com")
+	database.DB.Create(&models.AuthIdentity{UserID: user.ID, Provider: "google", Subject: "google-123"})
+
+	tests := []struct {
+		name           string
+		provider       string
+		expectedStatus int
+	}{
+		{
+			name:           "Unlink local while google remains",
+			provider:       "local",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unlink provider that isn't linked",
+			provider:       "github",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Unlink last remaining provider",
+			provider:       "google",
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodDelete, "/users/me/providers/"+tt.provider, "", user))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	var count int64
+	database.DB.Model(&models.AuthIdentity{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected google identity to remain linked, but found %d identities", count)
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newProxyRouter builds a rate-limited router (one request per client IP)
+// that trusts the given proxies
+func newProxyRouter(t *testing.T, proxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	if err := middleware.ConfigureTrustedProxies(router, proxies); err != nil {
+		t.Fatalf("Failed to configure trusted proxies: %v", err)
+	}
+	router.GET("/ip", middleware.RateLimitMiddleware(middleware.NewRateLimiter(1, time.Minute)), func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+	return router
+}
+
+// getFrom sends a request from remoteAddr with a forwarded-for header
+func getFrom(router *gin.Engine, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestForgedForwardedForIgnoredWithoutTrustedProxies(t *testing.T) {
+	router := newProxyRouter(t, nil)
+
+	first := getFrom(router, "203.0.113.7:4000", "198.51.100.1")
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", first.Code)
+	}
+	if first.Body.String() != "203.0.113.7" {
+		t.Errorf("Expected client IP 203.0.113.7, but got %s", first.Body.String())
+	}
+
+	// A different forged address must not earn a fresh rate-limit bucket
+	second := getFrom(router, "203.0.113.7:4001", "198.51.100.2")
+	if second.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 despite a forged X-Forwarded-For, but got %d", second.Code)
+	}
+}
+
+func TestForwardedForHonoredFromTrustedProxy(t *testing.T) {
+	router := newProxyRouter(t, []string{"10.0.0.0/8"})
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expectedIP   string
+	}{
+		{name: "trusted proxy", remoteAddr: "10.0.0.5:4000", forwardedFor: "198.51.100.1", expectedIP: "198.51.100.1"},
+		{name: "second client via trusted proxy", remoteAddr: "10.0.0.5:4001", forwardedFor: "198.51.100.2", expectedIP: "198.51.100.2"},
+		{name: "untrusted peer", remoteAddr: "203.0.113.7:4000", forwardedFor: "198.51.100.3", expectedIP: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getFrom(router, tt.remoteAddr, tt.forwardedFor)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, but got %d", w.Code)
+			}
+			if w.Body.String() != tt.expectedIP {
+				t.Errorf("Expected client IP %s, but got %s", tt.expectedIP, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestConfigureTrustedProxiesRejectsInvalidEntries(t *testing.T) {
+	if err := middleware.ConfigureTrustedProxies(gin.New(), []string{"not-a-cidr"}); err == nil {
+		t.Error("Expected an error for an invalid proxy entry, but got none")
+	}
+}
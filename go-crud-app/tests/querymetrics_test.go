@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/metrics"
+	"go-crud-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// slowQueries makes queries on the users table take at least delay
+func slowQueries(t *testing.T, delay time.Duration) {
+	t.Helper()
+
+	err := database.DB.Callback().Query().Before("gorm:query").Register("test:slow_users", func(db *gorm.DB) {
+		if db.Statement.Table == "users" {
+			time.Sleep(delay)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register slow query callback: %v", err)
+	}
+}
+
+func TestQueryMetricsLogSlowQueries(t *testing.T) {
+	setupTestDB(t)
+	if err := database.UseQueryMetrics(database.DB, 20*time.Millisecond); err != nil {
+		t.Fatalf("Failed to enable query metrics: %v", err)
+	}
+	slowQueries(t, 30*time.Millisecond)
+	createTestUser(t, "secretuser", "secret@example.com")
+	logs := captureLog(t)
+
+	// Fast queries aren't logged
+	var count int64
+	database.DB.Model(&models.Session{}).Where("user_id = ?", 1).Count(&count)
+	if logs.Len() != 0 {
+		t.Fatalf("Expected no slow query log, but got %s", logs.String())
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", "secret@example.com").First(&user).Error; err != nil {
+		t.Fatalf("Failed to load user: %v", err)
+	}
+	logged := logs.String()
+	if !strings.Contains(logged, "Slow query warning: operation=query") || !strings.Contains(logged, "email = ?") {
+		t.Errorf("Expected the slow query and its SQL to be logged, but got %s", logged)
+	}
+	if strings.Contains(logged, "secret@example.com") {
+		t.Errorf("Expected the query arguments to be left out, but got %s", logged)
+	}
+
+	var exposition bytes.Buffer
+	if err := metrics.DefaultRegistry.Write(&exposition, metrics.FormatPrometheus); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	for _, operation := range []string{"create", "query"} {
+		pattern := regexp.MustCompile(`(?m)^db_query_duration_seconds_count\{operation="` + operation + `"\} [1-9][0-9]*$`)
+		if !pattern.MatchString(exposition.String()) {
+			t.Errorf("Expected %s queries to be counted, but got %s", operation, exposition.String())
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeRateLimitClock freezes the rate limiter clock for the rest of the test
+// and returns a function that advances it
+func fakeRateLimitClock(t *testing.T) func(time.Duration) {
+	t.Helper()
+
+	now := time.Now()
+	middleware.RateLimitClock = func() time.Time { return now }
+	t.Cleanup(func() { middleware.RateLimitClock = time.Now })
+
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+// allowN counts how many of n requests for key the limiter allows
+func allowN(limiter middleware.Limiter, key string, n int) int {
+	allowed := 0
+	for range n {
+		if ok, _ := limiter.AllowWithRetry(key); ok {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestTokenBucketBurstThenThrottle(t *testing.T) {
+	fakeRateLimitClock(t)
+	limiter := middleware.NewTokenBucketLimiter(5, time.Second)
+
+	if allowed := allowN(limiter, "client", 10); allowed != 5 {
+		t.Errorf("Expected a burst of 5 requests to be allowed, but got %d", allowed)
+	}
+
+	allowed, retryAfter := limiter.AllowWithRetry("client")
+	if allowed {
+		t.Fatal("Expected the request after the burst to be throttled")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Expected a retry delay within one refill interval, but got %v", retryAfter)
+	}
+
+	// Other clients have their own bucket
+	if allowed := allowN(limiter, "other", 1); allowed != 1 {
+		t.Errorf("Expected another client to be allowed, but got %d", allowed)
+	}
+}
+
+func TestTokenBucketSteadyStateRefill(t *testing.T) {
+	advance := fakeRateLimitClock(t)
+	limiter := middleware.NewTokenBucketLimiter(3, time.Second)
+
+	allowN(limiter, "client", 3)
+
+	tests := []struct {
+		name     string
+		wait     time.Duration
+		requests int
+		expected int
+	}{
+		{name: "half an interval earns nothing", wait: 500 * time.Millisecond, requests: 1, expected: 0},
+		{name: "a full interval earns one request", wait: 500 * time.Millisecond, requests: 2, expected: 1},
+		{name: "one request per interval is sustained", wait: time.Second, requests: 1, expected: 1},
+		{name: "a long idle period refills only up to the burst", wait: time.Hour, requests: 5, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advance(tt.wait)
+			if allowed := allowN(limiter, "client", tt.requests); allowed != tt.expected {
+				t.Errorf("Expected %d allowed requests, but got %d", tt.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestSlidingWindowSlidesPastOldRequests(t *testing.T) {
+	advance := fakeRateLimitClock(t)
+	limiter := middleware.NewRateLimiter(2, time.Minute)
+
+	allowN(limiter, "client", 1)
+	advance(30 * time.Second)
+	allowN(limiter, "client", 1)
+
+	// The first request has left the window, the second hasn't
+	advance(31 * time.Second)
+	if allowed := allowN(limiter, "client", 2); allowed != 1 {
+		t.Fatalf("Expected 1 allowed request, but got %d", allowed)
+	}
+	allowed, retryAfter := limiter.AllowWithRetry("client")
+	if allowed {
+		t.Fatal("Expected the request to be throttled")
+	}
+	if retryAfter != 29*time.Second {
+		t.Errorf("Expected to retry once the second request leaves the window in 29s, but got %v", retryAfter)
+	}
+}
+
+func TestLimitersEvictLeastRecentlyUsedKeys(t *testing.T) {
+	fakeRateLimitClock(t)
+
+	tests := []struct {
+		name       string
+		newLimiter func() middleware.Limiter
+	}{
+		{
+			name: "sliding window",
+			newLimiter: func() middleware.Limiter {
+				limiter := middleware.NewRateLimiter(1, time.Minute)
+				limiter.MaxKeys = 2
+				return limiter
+			},
+		},
+		{
+			name: "token bucket",
+			newLimiter: func() middleware.Limiter {
+				limiter := middleware.NewTokenBucketLimiter(1, time.Minute)
+				limiter.MaxKeys = 2
+				return limiter
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := tt.newLimiter()
+			allowN(limiter, "first", 1)
+			allowN(limiter, "second", 1)
+			// Using first again makes second the least recently used
+			allowN(limiter, "first", 1)
+			allowN(limiter, "third", 1)
+
+			if allowed := allowN(limiter, "first", 1); allowed != 0 {
+				t.Error("Expected the recently used client to still be limited")
+			}
+			if allowed := allowN(limiter, "second", 1); allowed != 1 {
+				t.Error("Expected the least recently used client to be forgotten")
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddlewareAcceptsEitherLimiter(t *testing.T) {
+	fakeRateLimitClock(t)
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		limiter middleware.Limiter
+	}{
+		{name: "sliding window", limiter: middleware.NewRateLimiter(2, time.Minute)},
+		{name: "token bucket", limiter: middleware.NewTokenBucketLimiter(2, time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/ping", middleware.RateLimitMiddleware(tt.limiter), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			codes := make([]int, 3)
+			for i := range codes {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+				codes[i] = w.Code
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+					t.Error("Expected a Retry-After header on the throttled response")
+				}
+			}
+
+			expected := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+			for i := range expected {
+				if codes[i] != expected[i] {
+					t.Errorf("Expected statuses %v, but got %v", expected, codes)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterCleanupExitsWhenCancelled(t *testing.T) {
+	tests := []struct {
+		name       string
+		newLimiter func() middleware.Limiter
+	}{
+		{
+			name: "sliding window",
+			newLimiter: func() middleware.Limiter {
+				limiter := middleware.NewRateLimiter(1, time.Millisecond)
+				limiter.CleanupInterval = time.Millisecond
+				return limiter
+			},
+		},
+		{
+			name: "token bucket",
+			newLimiter: func() middleware.Limiter {
+				limiter := middleware.NewTokenBucketLimiter(1, time.Millisecond)
+				limiter.CleanupInterval = time.Millisecond
+				return limiter
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := tt.newLimiter()
+			allowN(limiter, "client", 2)
+
+			baseline := runtime.NumGoroutine()
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				limiter.Cleanup(ctx)
+				close(done)
+			}()
+
+			// Let a few cleanup passes run, then stop them
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("Expected Cleanup to return after cancellation, but it is still running")
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := runtime.NumGoroutine(); n > baseline {
+				t.Errorf("Expected at most %d goroutines after Cleanup returned, but got %d", baseline, n)
+			}
+		})
+	}
+}
+
+func TestRateLimitWarningThreshold(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		percent  int
+		limiter  func() middleware.Limiter
+		expected []string // Warning header per request; the 6th is throttled
+	}{
+		{
+			name:     "Disabled by default",
+			percent:  0,
+			limiter:  func() middleware.Limiter { return middleware.NewRateLimiter(5, time.Minute) },
+			expected: []string{"", "", "", "", ""},
+		},
+		{
+			name:     "Sliding window warns from the threshold",
+			percent:  80,
+			limiter:  func() middleware.Limiter { return middleware.NewRateLimiter(5, time.Minute) },
+			expected: []string{"", "", "", "80% of the rate limit used", "100% of the rate limit used"},
+		},
+		{
+			name:     "Just below the boundary",
+			percent:  81,
+			limiter:  func() middleware.Limiter { return middleware.NewRateLimiter(5, time.Minute) },
+			expected: []string{"", "", "", "", "100% of the rate limit used"},
+		},
+		{
+			name:     "Token bucket warns from the threshold",
+			percent:  80,
+			limiter:  func() middleware.Limiter { return middleware.NewTokenBucketLimiter(5, time.Minute) },
+			expected: []string{"", "", "", "80% of the rate limit used", "100% of the rate limit used"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeRateLimitClock(t)
+			middleware.RateLimitWarningPercent = tt.percent
+			defer func() { middleware.RateLimitWarningPercent = 0 }()
+
+			router := gin.New()
+			router.GET("/ping", middleware.RateLimitMiddleware(tt.limiter()), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			for i, expected := range tt.expected {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected request %d to be allowed, but got %d", i+1, w.Code)
+				}
+				if got := w.Header().Get(middleware.RateLimitWarningHeader); got != expected {
+					t.Errorf("Expected request %d warning %q, but got %q", i+1, expected, got)
+				}
+			}
+
+			// The blocked request gets a 429, not a warning
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+			if w.Code != http.StatusTooManyRequests {
+				t.Fatalf("Expected status 429, but got %d", w.Code)
+			}
+			if got := w.Header().Get(middleware.RateLimitWarningHeader); got != "" {
+				t.Errorf("Expected no warning on the blocked request, but got %q", got)
+			}
+		})
+	}
+}
+
+// BenchmarkRateLimiterDistinctKeys sends every request from a new client, as
+// a flood of spoofed IPs would; the tracked keys and heap stay bounded by
+// MaxKeys however large b.N grows
+func BenchmarkRateLimiterDistinctKeys(b *testing.B) {
+	const maxKeys = 10000
+	limiter := middleware.NewRateLimiter(5, time.Minute)
+	limiter.MaxKeys = maxKeys
+
+	b.ReportAllocs()
+	for i := range b.N {
+		limiter.Allow(fmt.Sprintf("client-%d", i))
+	}
+	b.StopTimer()
+
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	b.ReportMetric(float64(limiter.Len()), "keys")
+	b.ReportMetric(float64(stats.HeapAlloc)/(1<<20), "heap-MiB")
+	if limiter.Len() > maxKeys {
+		b.Fatalf("Expected at most %d keys, but got %d", maxKeys, limiter.Len())
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRecoveryRouter builds a router with handlers that panic, one after
+// starting a response that is still buffered by the compression middleware.
+// Like the server, it recovers panics before any other middleware runs.
+func newRecoveryRouter(exposeStack bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RecoveryMiddleware(exposeStack))
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.CompressionMiddleware(middleware.CompressionConfig{
+		Level:    middleware.DefaultCompressionLevel,
+		MinBytes: middleware.DefaultCompressionMinBytes,
+	}))
+	router.Use(middleware.FieldNamingMiddleware(middleware.FieldNamingSnake))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("something went wrong")
+	})
+	router.GET("/panic-buffered", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("something went wrong")
+	})
+	return router
+}
+
+// captureLog redirects the standard logger for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(previous) })
+	return &buf
+}
+
+func TestRecoveryReturnsStructuredError(t *testing.T) {
+	logs := captureLog(t)
+	router := newRecoveryRouter(false)
+
+	// The compression middleware holds back the partial response, so it
+	// can still be replaced
+	req := httptest.NewRequest(http.MethodGet, "/panic-buffered", nil)
+	req.Header.Set(middleware.RequestIDHeader, "panic-request")
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, but got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Expected a JSON response, but got %q", ct)
+	}
+	var resp apierror.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected the error envelope, but got %s", w.Body.String())
+	}
+	if resp.Error.Code != apierror.CodeInternal || resp.Error.Message != middleware.InternalErrorMessage {
+		t.Errorf("Expected %s with %q, but got %+v", apierror.CodeInternal, middleware.InternalErrorMessage, resp.Error)
+	}
+	if resp.Error.RequestID != "panic-request" {
+		t.Errorf("Expected request ID panic-request, but got %q", resp.Error.RequestID)
+	}
+	if resp.Error.Stack != "" || strings.Contains(w.Body.String(), "something went wrong") || strings.Contains(w.Body.String(), "partial") {
+		t.Errorf("Expected nothing about the panic in the response, but got %s", w.Body.String())
+	}
+
+	logged := logs.String()
+	for _, want := range []string{"request_id=panic-request", "something went wrong", "recovery_test.go"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("Expected the log to contain %q, but got %s", want, logged)
+		}
+	}
+}
+
+func TestRecoveryExposesStackWhenEnabled(t *testing.T) {
+	captureLog(t)
+	router := newRecoveryRouter(true)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, but got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeError(t, w)
+	if resp.Code != apierror.CodeInternal || !strings.Contains(resp.Stack, "recovery_test.go") {
+		t.Errorf("Expected %s with the stack trace, but got %+v", apierror.CodeInternal, resp)
+	}
+}
+
+func TestRecoveryKeepsFieldNaming(t *testing.T) {
+	captureLog(t)
+	router := newRecoveryRouter(false)
+
+	// The panic unwinds the field naming middleware before it is recovered
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(middleware.RequestIDHeader, "panic-request")
+	req.Header.Set("Accept", `application/json; profile="camelCase"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, but got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, `profile="camelCase"`) {
+		t.Errorf("Expected a camelCase profile, but got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `"requestId":"panic-request"`) {
+		t.Errorf("Expected camelCase field names, but got %s", w.Body.String())
+	}
+}
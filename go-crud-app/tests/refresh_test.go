@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRefreshRouter builds a router exposing the login and refresh routes
+func newRefreshRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/auth/login", handlers.Login(testJWTConfig))
+	router.POST("/auth/refresh", handlers.Refresh(testJWTConfig))
+	return router
+}
+
+// refreshRequest posts a refresh token to the refresh endpoint
+func refreshRequest(router *gin.Engine, refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(handlers.RefreshRequest{RefreshToken: refreshToken})
+	return postJSON(router, "/auth/refresh", string(body))
+}
+
+func TestLoginReturnsRefreshToken(t *testing.T) {
+	setupTestDB(t)
+	router := newRefreshRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+	hash, err := utils.HashPassword("Password123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	database.DB.Model(&user).Update("password_hash", hash)
+
+	w := postJSON(router, "/auth/login", `{"email":"test@example.com","password":"Password123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	claims, err := utils.ValidateToken(resp.RefreshToken, testJWTConfig.SecretKey)
+	if err != nil {
+		t.Fatalf("Expected a valid refresh token, but got %v", err)
+	}
+	if !claims.IsRefresh() {
+		t.Errorf("Expected token type %q, but got %q", utils.TokenTypeRefresh, claims.TokenType)
+	}
+
+	w = refreshRequest(router, resp.RefreshToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var refreshed handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if refreshed.Token == "" || refreshed.RefreshToken == "" {
+		t.Errorf("Expected a new token pair, but got %+v", refreshed)
+	}
+	if refreshed.User.Email != "test@example.com" {
+		t.Errorf("Expected user test@example.com, but got %s", refreshed.User.Email)
+	}
+}
+
+func TestRefreshRejectsBadTokens(t *testing.T) {
+	setupTestDB(t)
+	router := newRefreshRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	tests := []struct {
+		name         string
+		token        func(t *testing.T) string
+		expectedCode string
+	}{
+		{
+			name:         "malformed",
+			token:        func(t *testing.T) string { return "not-a-token" },
+			expectedCode: apierror.CodeTokenInvalid,
+		},
+		{
+			name:         "access token",
+			token:        func(t *testing.T) string { return mustToken(t, user.ID, user.TokenVersion, testJWTConfig) },
+			expectedCode: apierror.CodeTokenInvalid,
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				var token string
+				issuedInPast(t, testJWTConfig, func() { token = mustRefreshToken(t, user) })
+				return token
+			},
+			expectedCode: apierror.CodeTokenExpired,
+		},
+		{
+			name: "revoked",
+			token: func(t *testing.T) string {
+				token := mustRefreshToken(t, user)
+				database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+					UpdateColumn("token_version", user.TokenVersion+1)
+				return token
+			},
+			expectedCode: apierror.CodeTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := refreshRequest(router, tt.token(t))
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401, but got %d: %s", w.Code, w.Body.String())
+			}
+			if body := decodeError(t, w); body.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, but got %s", tt.expectedCode, body.Code)
+			}
+		})
+	}
+}
+
+// mustRefreshToken generates a refresh token for the user or fails the test
+func mustRefreshToken(t *testing.T, user models.User) string {
+	t.Helper()
+
+	token, err := utils.GenerateRefreshToken(utils.TokenOptions{UserID: user.ID, Username: user.Username, Email: user.Email, OrgID: user.OrgID, TokenVersion: user.TokenVersion}, testJWTConfig)
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	return token
+}
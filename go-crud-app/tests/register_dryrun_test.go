@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newDryRunRouter serves registration behind a rate limit of limit requests per minute
+func newDryRunRouter(limit int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", middleware.RateLimitMiddleware(middleware.NewRateLimiter(limit, time.Minute)),
+		handlers.Register(testJWTConfig))
+	return router
+}
+
+func TestRegisterValidateOnly(t *testing.T) {
+	setupTestDB(t)
+	router := newDryRunRouter(10)
+
+	w := postJSON(router, "/register?validate_only=true", `{"username":"newuser","email":"new@example.com","password":"Password123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); body != `{"valid":true}` {
+		t.Errorf("Expected {\"valid\":true}, but got %s", body)
+	}
+	if w.Header().Get("Location") != "" {
+		t.Errorf("Expected no Location header, but got %q", w.Header().Get("Location"))
+	}
+	if count := countUsers(t); count != 0 {
+		t.Errorf("Expected no user to be created, but got %d", count)
+	}
+
+	// The same details still register for real afterwards
+	if w := postJSON(router, "/register", `{"username":"newuser","email":"new@example.com","password":"Password123"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A dry run sees the same conflict as a real registration
+	w = postJSON(router, "/register?validate_only=true", `{"username":"newuser","email":"other@example.com","password":"Password123"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterValidateOnlyReportsFieldErrors(t *testing.T) {
+	setupTestDB(t)
+	router := newDryRunRouter(10)
+
+	w := postJSON(router, "/register?validate_only=true", `{"username":"a!","email":"not-an-email","password":"short"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeError(t, w)
+	if resp.Code != apierror.CodeValidationFailed {
+		t.Errorf("Expected code %s, but got %s", apierror.CodeValidationFailed, resp.Code)
+	}
+	fields := make(map[string]bool)
+	for _, field := range resp.Fields {
+		fields[field.Field] = true
+	}
+	for _, field := range []string{"username", "email", "password"} {
+		if !fields[field] {
+			t.Errorf("Expected a %s field error, but got %+v", field, resp.Fields)
+		}
+	}
+
+	if w := postJSON(router, "/register?validate_only=maybe", `{"username":"newuser","email":"new@example.com","password":"Password123"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid validate_only, but got %d: %s", w.Code, w.Body.String())
+	}
+	if count := countUsers(t); count != 0 {
+		t.Errorf("Expected no user to be created, but got %d", count)
+	}
+}
+
+func TestRegisterValidateOnlyIsRateLimited(t *testing.T) {
+	setupTestDB(t)
+	router := newDryRunRouter(2)
+
+	body := `{"username":"newuser","email":"new@example.com","password":"Password123"}`
+	for i := range 2 {
+		if w := postJSON(router, "/register?validate_only=true", body); w.Code != http.StatusOK {
+			t.Fatalf("Expected dry run %d to succeed, but got %d: %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	if w := postJSON(router, "/register?validate_only=true", body); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterValidateOnlyStrictSendsNoEmail(t *testing.T) {
+	setupTestDB(t)
+	mailer := &fakeMailer{}
+	useDuplicateResponse(t, handlers.DuplicateResponseStrict, mailer)
+	router := newDryRunRouter(10)
+	createTestUser(t, "existing", "existing@example.com")
+
+	w := postJSON(router, "/register?validate_only=true", `{"username":"another","email":"existing@example.com","password":"Password123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if mailer.count() != 0 {
+		t.Errorf("Expected no email, but got %v", mailer.sent)
+	}
+}
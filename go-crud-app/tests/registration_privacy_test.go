@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useDuplicateResponse switches registration's duplicate response for the
+// rest of the test, sending registration emails through mailer
+func useDuplicateResponse(t *testing.T, response string, mailer *fakeMailer) {
+	t.Helper()
+
+	previous := handlers.RegistrationPrivacy
+	handlers.RegistrationPrivacy = handlers.RegistrationPrivacyConfig{
+		DuplicateResponse: response,
+		Mailer:            mailer,
+		TokenTTL:          time.Hour,
+		ConfirmURL:        "https://api.example.com/api/auth/confirm-registration",
+	}
+	t.Cleanup(func() { handlers.RegistrationPrivacy = previous })
+}
+
+// newRegistrationPrivacyRouter serves registration, login and registration confirmation
+func newRegistrationPrivacyRouter() *gin.Engine {
+	router := newSessionRouter(&fakeMailer{})
+	router.GET("/confirm-registration", handlers.ConfirmRegistration(testJWTConfig))
+	return router
+}
+
+// confirmRegistration requests the confirmation endpoint with token
+func confirmRegistration(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/confirm-registration?token="+url.QueryEscape(token), nil))
+	return w
+}
+
+func TestRegisterDuplicateResponseVerboseAndGeneric(t *testing.T) {
+	tests := []struct {
+		response string
+		message  string
+	}{
+		{handlers.DuplicateResponseVerbose, "User with this email or username already exists"},
+		{handlers.DuplicateResponseGeneric, "Registration could not be completed with these details"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.response, func(t *testing.T) {
+			setupTestDB(t)
+			useDuplicateResponse(t, tt.response, &fakeMailer{})
+			router := newRegistrationPrivacyRouter()
+			createTestUser(t, "existing", "existing@example.com")
+
+			for _, body := range []string{
+				`{"username":"newuser","email":"existing@example.com","password":"Password123"}`,
+				`{"username":"existing","email":"new@example.com","password":"Password123"}`,
+			} {
+				w := postJSON(router, "/register", body)
+				if w.Code != http.StatusConflict {
+					t.Fatalf("Expected status 409, but got %d: %s", w.Code, w.Body.String())
+				}
+				if resp := decodeError(t, w); resp.Code != apierror.CodeConflict || resp.Message != tt.message {
+					t.Errorf("Expected %s with %q, but got %s with %q", apierror.CodeConflict, tt.message, resp.Code, resp.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterStrictHidesExistingAccounts(t *testing.T) {
+	setupTestDB(t)
+	mailer := &fakeMailer{}
+	useDuplicateResponse(t, handlers.DuplicateResponseStrict, mailer)
+	router := newRegistrationPrivacyRouter()
+	createTestUser(t, "existing", "existing@example.com")
+
+	tests := []struct {
+		name     string
+		body     string
+		to       string
+		contains string
+	}{
+		{"New account", `{"username":"newuser","email":"new@example.com","password":"Password123"}`,
+			"new@example.com", "Use the link below to confirm your email address"},
+		{"Taken email", `{"username":"another","email":"existing@example.com","password":"Password123"}`,
+			"existing@example.com", "already has one"},
+		{"Taken username", `{"username":"existing","email":"other@example.com","password":"Password123"}`,
+			"other@example.com", "The username existing is already taken"},
+	}
+
+	var responses []string
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(router, "/register", tt.body)
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("Expected status 202, but got %d: %s", w.Code, w.Body.String())
+			}
+			responses = append(responses, w.Body.String())
+
+			if mailer.count() != i+1 {
+				t.Fatalf("Expected %d emails, but got %d", i+1, mailer.count())
+			}
+			if mailer.recipients[i] != tt.to || !strings.Contains(mailer.sent[i], tt.contains) {
+				t.Errorf("Expected an email to %s containing %q, but got one to %s: %s", tt.to, tt.contains, mailer.recipients[i], mailer.sent[i])
+			}
+		})
+	}
+	for _, resp := range responses[1:] {
+		if resp != responses[0] {
+			t.Errorf("Expected identical responses, but got %s and %s", responses[0], resp)
+		}
+	}
+
+	var count int64
+	database.DB.Model(&models.User{}).Count(&count)
+	if count != 2 {
+		t.Errorf("Expected only the new account to be created, but got %d users", count)
+	}
+}
+
+func TestRegisterStrictRequiresConfirmation(t *testing.T) {
+	setupTestDB(t)
+	mailer := &fakeMailer{}
+	useDuplicateResponse(t, handlers.DuplicateResponseStrict, mailer)
+	router := newRegistrationPrivacyRouter()
+
+	if w := postJSON(router, "/register", `{"username":"newuser","email":"new@example.com","password":"Password123"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := postJSON(router, "/login", `{"email":"new@example.com","password":"Password123"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 before confirmation, but got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decodeError(t, w); resp.Code != apierror.CodeConfirmationRequired {
+		t.Errorf("Expected code %s, but got %s", apierror.CodeConfirmationRequired, resp.Code)
+	}
+
+	body := mailer.sent[0]
+	token := body[strings.LastIndex(body, "token=")+len("token="):]
+	w = confirmRegistration(router, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"token"`) {
+		t.Errorf("Expected confirmation to log the user in, but got %s", w.Body.String())
+	}
+
+	var user models.User
+	database.DB.Where("email = ?", "new@example.com").First(&user)
+	if user.Status != models.StatusActive {
+		t.Errorf("Expected status %s, but got %s", models.StatusActive, user.Status)
+	}
+	loginToken(t, router, "new@example.com", "Password123")
+
+	if w := confirmRegistration(router, token); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a used token to be rejected, but got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupReplicaDB migrates a second in-memory database standing in for a
+// read replica, routes database.DB's reads to it and returns a direct
+// handle to it
+func setupReplicaDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dialector := sqlite.Open(fmt.Sprintf("file:%s_replica?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_")))
+	replica, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open replica database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := replica.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// Migrate the replica with the same migrations as the primary
+	primary := database.DB
+	database.DB = replica
+	err = database.Migrate()
+	database.DB = primary
+	if err != nil {
+		t.Fatalf("Failed to migrate replica database: %v", err)
+	}
+
+	if err := database.UseReplicas(database.DB, dialector); err != nil {
+		t.Fatalf("Failed to configure replica: %v", err)
+	}
+	return replica
+}
+
+// usernames lists the usernames visible through db
+func usernames(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var names []string
+	if err := db.Model(&models.User{}).Order("username").Pluck("username", &names).Error; err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	return names
+}
+
+func TestReadsGoToReplica(t *testing.T) {
+	setupTestDB(t)
+	replica := setupReplicaDB(t)
+
+	// Writes go to the primary only, as if replication hadn't caught up
+	createTestUser(t, "onprimary", "primary@example.com")
+	if err := replica.Create(&models.User{Username: "onreplica", Email: "replica@example.com", PasswordHash: "x"}).Error; err != nil {
+		t.Fatalf("Failed to seed replica: %v", err)
+	}
+
+	if names := usernames(t, database.DB); !slices.Equal(names, []string{"onreplica"}) {
+		t.Errorf("Expected reads to see the replica's users, but got %v", names)
+	}
+
+	// Transactions always run on the primary
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if names := usernames(t, tx); !slices.Equal(names, []string{"onprimary"}) {
+			t.Errorf("Expected reads in a transaction to see the primary's users, but got %v", names)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+}
+
+func TestReadsAfterWriteGoToPrimary(t *testing.T) {
+	setupTestDB(t)
+	setupReplicaDB(t)
+
+	ctx := database.TrackWrites(context.Background())
+	if names := usernames(t, database.WithContext(ctx)); len(names) != 0 {
+		t.Errorf("Expected no users on the replica, but got %v", names)
+	}
+
+	if err := database.WithContext(ctx).Create(&models.User{Username: "writer", Email: "writer@example.com", PasswordHash: "x"}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if names := usernames(t, database.WithContext(ctx)); !slices.Equal(names, []string{"writer"}) {
+		t.Errorf("Expected a read after a write to see the primary's users, but got %v", names)
+	}
+
+	// Contexts that haven't written keep reading from the replica
+	other := database.TrackWrites(context.Background())
+	if names := usernames(t, database.WithContext(other)); len(names) != 0 {
+		t.Errorf("Expected another context to read from the replica, but got %v", names)
+	}
+}
+
+func TestParseReplicas(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"", nil},
+		{"replica1", []string{"replica1:5432"}},
+		{"replica1:6432, replica2 ,", []string{"replica1:6432", "replica2:5432"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := database.ParseReplicas(tt.value, "5432"); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %v, but got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRequestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.POST("/register", handlers.Register(testJWTConfig))
+	router.GET("/protected", middleware.AuthMiddleware(testJWTConfig.SecretKey), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestErrorBodyContainsRequestID(t *testing.T) {
+	router := newRequestIDRouter()
+
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{
+			name: "Handler validation error",
+			req:  httptest.NewRequest(http.MethodPost, "/register", nil),
+		},
+		{
+			name: "Middleware error",
+			req:  httptest.NewRequest(http.MethodGet, "/protected", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, tt.req)
+
+			headerID := w.Header().Get(middleware.RequestIDHeader)
+			if headerID == "" {
+				t.Fatal("Expected X-Request-ID header, but got none")
+			}
+
+			body := decodeError(t, w)
+			if body.RequestID != headerID {
+				t.Errorf("Expected request_id %q, but got %q", headerID, body.RequestID)
+			}
+		})
+	}
+}
+
+func TestClientRequestIDIsPropagated(t *testing.T) {
+	router := newRequestIDRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set(middleware.RequestIDHeader, "client-id-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get(middleware.RequestIDHeader); got != "client-id-123" {
+		t.Errorf("Expected X-Request-ID 'client-id-123', but got %q", got)
+	}
+}
+
+func TestRequestIDOmittedWhenDisabled(t *testing.T) {
+	middleware.IncludeRequestIDInErrors = false
+	defer func() { middleware.IncludeRequestIDInErrors = true }()
+
+	router := newRequestIDRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/protected", nil))
+
+	var body map[string]map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if _, ok := body["error"]["request_id"]; ok {
+		t.Error("Expected request_id to be omitted, but it was present")
+	}
+}
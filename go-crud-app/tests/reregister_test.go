@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// softDeleteUser soft-deletes the user with the given email
+func softDeleteUser(t *testing.T, email string) models.User {
+	t.Helper()
+
+	var user models.User
+	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		t.Fatalf("Failed to find user %s: %v", email, err)
+	}
+	if err := database.DB.Delete(&user).Error; err != nil {
+		t.Fatalf("Failed to soft-delete user: %v", err)
+	}
+	return user
+}
+
+func TestRegisterAfterSoftDelete(t *testing.T) {
+	body := `{"username":"testuser","email":"test@example.com","password":"StrongPass123"}`
+
+	tests := []struct {
+		name           string
+		deleteFirst    bool
+		expectedStatus int
+	}{
+		{
+			name:           "deleted account frees its username and email",
+			deleteFirst:    true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "active account still blocks its username and email",
+			deleteFirst:    false,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			router := newAuthRouter()
+
+			if w := postJSON(router, "/register", body); w.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+			}
+			if tt.deleteFirst {
+				softDeleteUser(t, "test@example.com")
+			}
+
+			w := postJSON(router, "/register", body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUniqueIndexesIgnoreSoftDeletedRows(t *testing.T) {
+	setupTestDB(t)
+
+	deleted := createTestUser(t, "testuser", "test@example.com")
+	if err := database.DB.Delete(&deleted).Error; err != nil {
+		t.Fatalf("Failed to soft-delete user: %v", err)
+	}
+
+	// A live row may reuse the deleted row's username and email...
+	createTestUser(t, "testuser", "test@example.com")
+
+	// ...but two live rows may not share them
+	duplicate := models.User{Username: "testuser", Email: "other@example.com", PasswordHash: "hash"}
+	if err := database.DB.Create(&duplicate).Error; !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("Expected a duplicated key error for a live username, but got %v", err)
+	}
+}
+
+// enableReactivation turns on account reactivation for the rest of the test
+func enableReactivation(t *testing.T) {
+	t.Helper()
+
+	previous := handlers.ReactivateDeletedAccounts
+	handlers.ReactivateDeletedAccounts = true
+	t.Cleanup(func() { handlers.ReactivateDeletedAccounts = previous })
+}
+
+func TestRegisterReactivatesDeletedAccount(t *testing.T) {
+	setupTestDB(t)
+	enableReactivation(t)
+	router := newAuthRouter()
+
+	if w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"StrongPass123"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	deleted := softDeleteUser(t, "test@example.com")
+	if err := database.DB.Unscoped().Model(&deleted).Update("role", models.RoleAdmin).Error; err != nil {
+		t.Fatalf("Failed to promote user: %v", err)
+	}
+	oldToken := mustToken(t, deleted.ID, deleted.TokenVersion, testJWTConfig)
+
+	w := postJSON(router, "/register", `{"username":"newname","email":"test@example.com","password":"NewStrongPass456"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.User.ID != deleted.ID {
+		t.Errorf("Expected the deleted account %d to be reactivated, but got %d", deleted.ID, resp.User.ID)
+	}
+	if resp.User.Username != "newname" {
+		t.Errorf("Expected username newname, but got %s", resp.User.Username)
+	}
+	if resp.User.Role != models.RoleUser {
+		t.Errorf("Expected role %s after reactivation, but got %s", models.RoleUser, resp.User.Role)
+	}
+	if count := countUsers(t); count != 1 {
+		t.Errorf("Expected 1 user after reactivation, but got %d", count)
+	}
+
+	// The new token works, tokens issued before the deletion stay revoked
+	if _, err := middleware.VerifyToken(context.Background(), resp.Token, testJWTConfig.SecretKey); err != nil {
+		t.Errorf("Expected the new token to be valid, but got %v", err)
+	}
+	if _, err := middleware.VerifyToken(context.Background(), oldToken, testJWTConfig.SecretKey); !errors.Is(err, utils.ErrRevokedToken) {
+		t.Errorf("Expected the old token to be revoked, but got %v", err)
+	}
+
+	// The new password replaces the old one
+	if w := postJSON(router, "/login", `{"email":"test@example.com","password":"StrongPass123"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old password to be rejected with 401, but got %d", w.Code)
+	}
+	if w := postJSON(router, "/login", `{"email":"test@example.com","password":"NewStrongPass456"}`); w.Code != http.StatusOK {
+		t.Errorf("Expected the new password to log in with 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterReactivationGuards(t *testing.T) {
+	tests := []struct {
+		name           string
+		deleteFirst    bool
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "active account is not reactivated",
+			deleteFirst:    false,
+			body:           `{"username":"newname","email":"test@example.com","password":"NewStrongPass456"}`,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   apierror.CodeConflict,
+		},
+		{
+			name:           "weak password is rejected",
+			deleteFirst:    true,
+			body:           `{"username":"newname","email":"test@example.com","password":"weak"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   apierror.CodeValidationFailed,
+		},
+		{
+			name:           "username taken by an active account is rejected",
+			deleteFirst:    true,
+			body:           `{"username":"takenname","email":"test@example.com","password":"NewStrongPass456"}`,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   apierror.CodeConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			enableReactivation(t)
+			router := newAuthRouter()
+
+			user := createTestUser(t, "testuser", "test@example.com")
+			createTestUser(t, "takenname", "taken@example.com")
+			if tt.deleteFirst {
+				softDeleteUser(t, "test@example.com")
+			}
+
+			w := postJSON(router, "/register", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if body := decodeError(t, w); body.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, but got %s", tt.expectedCode, body.Code)
+			}
+
+			// The account is left as it was
+			var stored models.User
+			if err := database.DB.Unscoped().First(&stored, user.ID).Error; err != nil {
+				t.Fatalf("Failed to load user: %v", err)
+			}
+			if stored.DeletedAt.Valid != tt.deleteFirst || stored.Username != "testuser" {
+				t.Errorf("Expected the account to be unchanged, but got username %s deleted %v", stored.Username, stored.DeletedAt.Valid)
+			}
+		})
+	}
+}
+
+func TestReactivationJoinsRegistrationOrg(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         string
+		body         string
+		expectedOrg  string
+		expectedRole string
+	}{
+		{
+			name:         "invite code joins its organization",
+			mode:         handlers.OrgRegistrationInvite,
+			body:         `{"username":"newname","email":"test@example.com","password":"NewStrongPass456","invite_code":"code-b"}`,
+			expectedOrg:  "Org B",
+			expectedRole: models.RoleUser,
+		},
+		{
+			name:         "organization name creates a new organization",
+			mode:         handlers.OrgRegistrationOpen,
+			body:         `{"username":"newname","email":"test@example.com","password":"NewStrongPass456","organization":"Org C"}`,
+			expectedOrg:  "Org C",
+			expectedRole: models.RoleAdmin,
+		},
+		{
+			name:         "no organization registration leaves the organization",
+			mode:         handlers.OrgRegistrationNone,
+			body:         `{"username":"newname","email":"test@example.com","password":"NewStrongPass456"}`,
+			expectedRole: models.RoleUser,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			enableReactivation(t)
+			handlers.OrgRegistration = tt.mode
+			defer func() { handlers.OrgRegistration = handlers.OrgRegistrationNone }()
+			router := newAuthRouter()
+
+			orgA := createTestOrg(t, "Org A", "code-a")
+			createTestOrg(t, "Org B", "code-b")
+			user := createTestOrgUser(t, "testuser", "test@example.com", orgA.ID)
+			if err := database.DB.Model(&user).Update("status", models.StatusSuspended).Error; err != nil {
+				t.Fatalf("Failed to suspend user: %v", err)
+			}
+			softDeleteUser(t, "test@example.com")
+
+			w := postJSON(router, "/register", tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+			}
+
+			var stored models.User
+			if err := database.DB.First(&stored, user.ID).Error; err != nil {
+				t.Fatalf("Failed to load reactivated user: %v", err)
+			}
+			if stored.Status != models.StatusActive {
+				t.Errorf("Expected status %s after reactivation, but got %s", models.StatusActive, stored.Status)
+			}
+			if stored.Role != tt.expectedRole {
+				t.Errorf("Expected role %s, but got %s", tt.expectedRole, stored.Role)
+			}
+
+			// The account never returns to the organization it was deleted from
+			if tt.expectedOrg == "" {
+				if stored.OrgID != nil {
+					t.Errorf("Expected no organization, but got %d", *stored.OrgID)
+				}
+				return
+			}
+			if stored.OrgID == nil {
+				t.Fatalf("Expected organization %s, but got none", tt.expectedOrg)
+			}
+			var org models.Organization
+			if err := database.DB.First(&org, *stored.OrgID).Error; err != nil {
+				t.Fatalf("Failed to load organization: %v", err)
+			}
+			if org.Name != tt.expectedOrg {
+				t.Errorf("Expected organization %s, but got %s", tt.expectedOrg, org.Name)
+			}
+		})
+	}
+}
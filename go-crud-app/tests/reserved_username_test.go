@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/validation"
+)
+
+// useUsernames sets the username policy for the duration of a test
+func useUsernames(t *testing.T, policy validation.UsernamePolicy) {
+	t.Helper()
+
+	previous := validation.Usernames
+	validation.Usernames = policy
+	t.Cleanup(func() { validation.Usernames = previous })
+}
+
+// expectReservedUsername checks that w rejected the username as reserved
+func expectReservedUsername(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d: %s", w.Code, w.Body.String())
+	}
+	body := decodeError(t, w)
+	if body.Code != apierror.CodeUsernameReserved {
+		t.Errorf("Expected code %s, but got %s", apierror.CodeUsernameReserved, body.Code)
+	}
+	if len(body.Fields) != 1 || body.Fields[0].Field != "username" {
+		t.Errorf("Expected a username field error, but got %+v", body.Fields)
+	}
+}
+
+func TestUsernamePolicy(t *testing.T) {
+	policy := validation.NewUsernamePolicy(true, "Acme_Staff")
+
+	tests := []struct {
+		username string
+		expected bool
+	}{
+		{"admin", false},
+		{"ROOT", false},
+		{"api", false},
+		{"me", false},
+		{"acme_staff", false},
+		{"Acme_Staff", false},
+		{"administrators", true},
+		{"johndoe", true},
+	}
+	for _, tt := range tests {
+		if got := policy.PermitsUsername(tt.username); got != tt.expected {
+			t.Errorf("PermitsUsername(%q): expected %v, but got %v", tt.username, tt.expected, got)
+		}
+	}
+
+	if !validation.NewUsernamePolicy(false, "acme_staff").PermitsUsername("admin") {
+		t.Error("Expected admin to be allowed without the bundled list")
+	}
+}
+
+func TestParseUsernameList(t *testing.T) {
+	usernames, err := validation.ParseUsernameList(" ceo, Acme_Staff ,,")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(usernames) != 2 || usernames[0] != "ceo" || usernames[1] != "Acme_Staff" {
+		t.Errorf("Expected [ceo Acme_Staff], but got %v", usernames)
+	}
+
+	if _, err := validation.ParseUsernameList("ok_name,not-valid"); err == nil {
+		t.Error("Expected an invalid username to be rejected")
+	}
+}
+
+func TestReserveRouteSegments(t *testing.T) {
+	policy := validation.NewUsernamePolicy(false).ReserveRouteSegments(
+		"/api/users",
+		"/api/users/me",
+		"/api/users/inactive",
+		"/api/users/:id",
+		"/api/users/:id/status",
+		"/api/users/bulk-delete",
+		"/api/auth/login",
+	)
+
+	// Segments beside /api/users/:id could be mistaken for a user
+	for _, username := range []string{"inactive", "Inactive"} {
+		if policy.PermitsUsername(username) {
+			t.Errorf("Expected %q to be reserved", username)
+		}
+	}
+	// "me" is too short to be a username at all
+	if validation.ValidUsername("me") {
+		t.Error("Expected me to fail the username rules")
+	}
+	// Segments that never take a user's place stay free
+	for _, username := range []string{"api", "users", "status", "login"} {
+		if !policy.PermitsUsername(username) {
+			t.Errorf("Expected %q to be allowed", username)
+		}
+	}
+}
+
+func TestRegisterRejectsReservedUsername(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+	useUsernames(t, validation.NewUsernamePolicy(true, "ceo"))
+
+	for _, username := range []string{"admin", "Root", "ceo"} {
+		t.Run(username, func(t *testing.T) {
+			body := fmt.Sprintf(`{"username":%q,"email":"%s@example.com","password":"StrongPass123"}`, username, username)
+			expectReservedUsername(t, postJSON(router, "/register", body))
+		})
+	}
+
+	if count := countUsers(t); count != 0 {
+		t.Errorf("Expected no user to be created, but got %d", count)
+	}
+	if w := postJSON(router, "/register", `{"username":"johndoe","email":"john@example.com","password":"StrongPass123"}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterRejectsRouteSegmentUsername(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+	useUsernames(t, validation.NewUsernamePolicy(false).ReserveRouteSegments("/users/me", "/users/inactive", "/users/:id"))
+
+	expectReservedUsername(t, postJSON(router, "/register", `{"username":"inactive","email":"inactive@example.com","password":"StrongPass123"}`))
+	expectReservedUsername(t, postJSON(router, "/register", `{"username":"INACTIVE","email":"inactive@example.com","password":"StrongPass123"}`))
+
+	// The username rules already keep out "me"
+	w := postJSON(router, "/register", `{"username":"me","email":"me@example.com","password":"StrongPass123"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeError(t, w); body.Code != apierror.CodeValidationFailed {
+		t.Errorf("Expected code %s, but got %s", apierror.CodeValidationFailed, body.Code)
+	}
+}
+
+func TestUpdateUserRejectsReservedUsername(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+	useUsernames(t, validation.NewUsernamePolicy(true))
+	user := createTestUser(t, "testuser", "test@example.com")
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPatch, path, `{"username":"support"}`, user))
+	expectReservedUsername(t, w)
+
+	// Someone who already holds a reserved name (e.g. a seeded admin) can keep it
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPut, fmt.Sprintf("/users/%d", admin.ID), `{"username":"admin","email":"root@example.com"}`, admin))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAvailabilityReportsReservedUsername(t *testing.T) {
+	setupTestDB(t)
+	router := newAvailabilityRouter(t, true)
+	useUsernames(t, validation.NewUsernamePolicy(true))
+
+	w := getAvailability(router, "username=root")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); body != `{"available":false,"username":false}` {
+		t.Errorf("Expected the reserved username to be unavailable, but got %s", body)
+	}
+}
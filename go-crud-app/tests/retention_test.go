@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/retention"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createInactiveUser inserts a user whose last login was the given number of days ago
+func createInactiveUser(t *testing.T, username string, inactiveDays int, retentionDays *int) models.User {
+	t.Helper()
+
+	user := createTestUser(t, username, username+"@example.com")
+	lastLogin := time.Now().AddDate(0, 0, -inactiveDays)
+	if err := database.DB.Model(&user).Updates(map[string]interface{}{
+		"last_login_at":  lastLogin,
+		"retention_days": retentionDays,
+	}).Error; err != nil {
+		t.Fatalf("Failed to update test user: %v", err)
+	}
+	return user
+}
+
+func TestSweeperRespectsShorterRetentionPreference(t *testing.T) {
+	setupTestDB(t)
+
+	thirty, sixty := 30, 60
+	shortRetention := createInactiveUser(t, "shortretention", 40, &thirty)
+	longRetention := createInactiveUser(t, "longretention", 40, &sixty)
+	noPreference := createInactiveUser(t, "nopreference", 400, nil)
+	recentlyActive := createInactiveUser(t, "recentlyactive", 5, &thirty)
+
+	sweeper := &retention.Sweeper{Policy: retention.DefaultPolicy()}
+	purged, err := sweeper.Sweep(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 user to be purged, but got %d", purged)
+	}
+
+	for _, user := range []models.User{longRetention, noPreference, recentlyActive} {
+		if !userExists(t, user.ID) {
+			t.Errorf("Expected user %s to be kept", user.Username)
+		}
+	}
+	if userExists(t, shortRetention.ID) {
+		t.Fatal("Expected user with a shorter retention preference to be purged")
+	}
+
+	var purgedUser models.User
+	if err := database.DB.Unscoped().First(&purgedUser, shortRetention.ID).Error; err != nil {
+		t.Fatalf("Failed to load purged user: %v", err)
+	}
+	if strings.Contains(purgedUser.Email, "shortretention") || strings.Contains(purgedUser.Username, "shortretention") {
+		t.Errorf("Expected purged user to be anonymized, but got %s / %s", purgedUser.Username, purgedUser.Email)
+	}
+}
+
+func TestSweeperAppliesDefaultAndMaximumRetention(t *testing.T) {
+	setupTestDB(t)
+
+	tooLong := 500
+	noPreference := createInactiveUser(t, "nopreference", 100, nil)
+	abovePolicyMax := createInactiveUser(t, "abovemax", 100, &tooLong)
+	recentlyActive := createInactiveUser(t, "recentlyactive", 10, nil)
+
+	// Default applies to users without a preference; the maximum caps preferences
+	// chosen before an admin lowered it
+	sweeper := &retention.Sweeper{Policy: retention.Policy{MinDays: 30, MaxDays: 90, DefaultDays: 60}}
+	if _, err := sweeper.Sweep(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if userExists(t, noPreference.ID) {
+		t.Error("Expected user without a preference to be purged after the default window")
+	}
+	if userExists(t, abovePolicyMax.ID) {
+		t.Error("Expected preference above the maximum to be capped")
+	}
+	if !userExists(t, recentlyActive.ID) {
+		t.Error("Expected recently active user to be kept")
+	}
+}
+
+func TestUpdateRetentionValidatesBounds(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	policy := retention.Policy{MinDays: 30, MaxDays: 365}
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	router.PUT("/users/me/retention", handlers.UpdateRetention(policy))
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "Below minimum",
+			body:           `{"retention_days":7}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Above maximum",
+			body:           `{"retention_days":1000}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Within bounds",
+			body:           `{"retention_days":90}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Clear preference",
+			body:           `{"retention_days":null}`,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodPut, "/users/me/retention", tt.body, user))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// deleteUserDaysAgo marks user as deleted the given number of days ago
+func deleteUserDaysAgo(t *testing.T, user models.User, deletedDays int) {
+	t.Helper()
+
+	if err := database.DB.Model(&user).Update("deleted_at", time.Now().AddDate(0, 0, -deletedDays)).Error; err != nil {
+		t.Fatalf("Failed to soft-delete test user: %v", err)
+	}
+}
+
+// rowExists reports whether the user row is still stored, deleted or not
+func rowExists(t *testing.T, userID uint) bool {
+	t.Helper()
+
+	var count int64
+	if err := database.DB.Unscoped().Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	return count > 0
+}
+
+func TestPurgerRemovesUsersDeletedBeforeRetentionWindow(t *testing.T) {
+	setupTestDB(t)
+
+	expired := createTestUser(t, "expired", "expired@example.com")
+	recent := createTestUser(t, "recent", "recent@example.com")
+	active := createTestUser(t, "active", "active@example.com")
+	now := time.Now()
+	for _, user := range []models.User{expired, recent} {
+		session := models.Session{UserID: user.ID, JTI: user.Username, IssuedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+		if err := database.DB.Create(&session).Error; err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	deleteUserDaysAgo(t, expired, 40)
+	deleteUserDaysAgo(t, recent, 5)
+
+	purger := &retention.Purger{After: 30 * 24 * time.Hour}
+	purged, err := purger.Purge(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 user to be purged, but got %d", purged)
+	}
+
+	if rowExists(t, expired.ID) {
+		t.Error("Expected the user deleted before the retention window to be purged")
+	}
+	if !rowExists(t, recent.ID) {
+		t.Error("Expected the recently deleted user to be kept")
+	}
+	if !userExists(t, active.ID) {
+		t.Error("Expected the active user to be kept")
+	}
+
+	var sessions int64
+	database.DB.Model(&models.Session{}).Where("user_id = ?", expired.ID).Count(&sessions)
+	if sessions != 0 {
+		t.Errorf("Expected the purged user's sessions to be removed, but got %d", sessions)
+	}
+	database.DB.Model(&models.Session{}).Where("user_id = ?", recent.ID).Count(&sessions)
+	if sessions != 1 {
+		t.Errorf("Expected the kept user's session to remain, but got %d", sessions)
+	}
+}
+
+func TestPurgerStopsWhenCancelled(t *testing.T) {
+	setupTestDB(t)
+
+	expired := createTestUser(t, "expired", "expired@example.com")
+	deleteUserDaysAgo(t, expired, 40)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	purger := &retention.Purger{After: 30 * 24 * time.Hour}
+	if _, err := purger.Purge(ctx, time.Now()); err == nil {
+		t.Error("Expected a cancelled purge to fail")
+	}
+	if !rowExists(t, expired.ID) {
+		t.Error("Expected nothing to be purged after cancellation")
+	}
+
+	// Run returns once its context is cancelled
+	done := make(chan struct{})
+	go func() {
+		(&retention.Purger{After: time.Hour, Interval: time.Hour}).Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return after cancellation")
+	}
+}
@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// sqlStateError mimics a driver error carrying a Postgres SQLSTATE
+type sqlStateError string
+
+func (e sqlStateError) Error() string {
+	return fmt.Sprintf("ERROR: transaction conflict (SQLSTATE %s)", string(e))
+}
+
+func (e sqlStateError) SQLState() string { return string(e) }
+
+// serializationFailure is the error Postgres returns for a transaction that
+// lost a serializable conflict
+const serializationFailure = sqlStateError("40001")
+
+// useFastRetries shortens the retry backoff for the duration of a test
+func useFastRetries(t *testing.T) {
+	t.Helper()
+
+	previous := database.TransactionRetryBackoff
+	database.TransactionRetryBackoff = time.Millisecond
+	t.Cleanup(func() { database.TransactionRetryBackoff = previous })
+}
+
+// failDeletes makes the next failures deletes on the test database fail with
+// a serialization failure; a negative count fails every delete
+func failDeletes(t *testing.T, failures int) {
+	t.Helper()
+
+	err := database.DB.Callback().Delete().After("gorm:delete").Register("test:serialization_failure", func(db *gorm.DB) {
+		if failures == 0 {
+			return
+		}
+		failures--
+		db.AddError(serializationFailure)
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+}
+
+func TestRetryTransactionRetriesSerializationFailures(t *testing.T) {
+	setupTestDB(t)
+	useFastRetries(t)
+
+	attempts := 0
+	err := database.RetryTransaction(database.DB, func(tx *gorm.DB) error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("update failed: %w", serializationFailure)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the transaction to succeed, but got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, but got %d", attempts)
+	}
+
+	// Deadlocks are retried too
+	attempts = 0
+	err = database.RetryTransaction(database.DB, func(tx *gorm.DB) error {
+		attempts++
+		if attempts == 1 {
+			return sqlStateError("40P01")
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("Expected a deadlock to be retried once, but got %d attempts and %v", attempts, err)
+	}
+}
+
+func TestRetryTransactionGivesUp(t *testing.T) {
+	setupTestDB(t)
+	useFastRetries(t)
+
+	attempts := 0
+	err := database.RetryTransaction(database.DB, func(tx *gorm.DB) error {
+		attempts++
+		return serializationFailure
+	})
+	if !errors.Is(err, database.ErrWriteConflict) || !errors.Is(err, serializationFailure) {
+		t.Errorf("Expected a write conflict wrapping the last failure, but got %v", err)
+	}
+	if attempts != database.TransactionRetries+1 {
+		t.Errorf("Expected %d attempts, but got %d", database.TransactionRetries+1, attempts)
+	}
+
+	// Other errors, including other SQLSTATEs, fail at once
+	for _, failure := range []error{errors.New("boom"), sqlStateError("23505")} {
+		attempts = 0
+		err = database.RetryTransaction(database.DB, func(tx *gorm.DB) error {
+			attempts++
+			return failure
+		})
+		if !errors.Is(err, failure) || errors.Is(err, database.ErrWriteConflict) || attempts != 1 {
+			t.Errorf("Expected %v to fail after 1 attempt, but got %d attempts and %v", failure, attempts, err)
+		}
+	}
+}
+
+func TestRetryTransactionRollsBackFailedAttempts(t *testing.T) {
+	setupTestDB(t)
+	useFastRetries(t)
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	attempts := 0
+	err := database.RetryTransaction(database.DB, func(tx *gorm.DB) error {
+		attempts++
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return serializationFailure
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the transaction to succeed, but got %v", err)
+	}
+	if version := reloadUser(t, user.ID).TokenVersion; version != user.TokenVersion+1 {
+		t.Errorf("Expected only the successful attempt to apply, but got token version %d", version)
+	}
+}
+
+func TestBulkDeleteRetriesSerializationFailure(t *testing.T) {
+	setupTestDB(t)
+	useFastRetries(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	first := createTestUser(t, "first", "first@example.com")
+	second := createTestUser(t, "second", "second@example.com")
+	failDeletes(t, 2)
+
+	body := fmt.Sprintf(`{"ids":[%d,%d]}`, first.ID, second.ID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", body, admin))
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status 207, but got %d: %s", w.Code, w.Body.String())
+	}
+	// Results from the failed attempts are discarded
+	if count := strings.Count(w.Body.String(), `"status":200`); count != 2 {
+		t.Errorf("Expected 2 results, but got %s", w.Body.String())
+	}
+	if userExists(t, first.ID) || userExists(t, second.ID) {
+		t.Error("Expected both users to be deleted")
+	}
+}
+
+func TestBulkDeleteReportsPersistentConflict(t *testing.T) {
+	setupTestDB(t)
+	useFastRetries(t)
+	router := newAdminRouter(handlers.DefaultBulkDeleteMaxBatch)
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	failDeletes(t, -1)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPost, "/users/bulk-delete", fmt.Sprintf(`{"ids":[%d]}`, user.ID), admin))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, but got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, but got %q", w.Header().Get("Retry-After"))
+	}
+	if !userExists(t, user.ID) {
+		t.Error("Expected the user to survive the failed transaction")
+	}
+}
+
+func TestResetPasswordRetriesSerializationFailure(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int
+		expectedStatus int
+	}{
+		{
+			name:           "a transient conflict is retried",
+			failures:       2,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "a persistent conflict is reported",
+			failures:       -1,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			useFastRetries(t)
+			user := createTestUser(t, "testuser", "test@example.com")
+
+			mailer := &fakeMailer{}
+			router := newPasswordResetRouter(handlers.PasswordResetConfig{
+				Mailer:   mailer,
+				TokenTTL: time.Hour,
+				ResetURL: "http://localhost/reset",
+			})
+			postJSON(router, "/forgot-password", `{"email":"test@example.com"}`)
+			token := mailer.sent[0][strings.LastIndex(mailer.sent[0], "token=")+len("token="):]
+
+			// Fail the password update inside the reset's transaction
+			failures := tt.failures
+			err := database.DB.Callback().Update().After("gorm:update").Register("test:serialization_failure", func(db *gorm.DB) {
+				if failures == 0 || db.Statement.Table != "users" {
+					return
+				}
+				failures--
+				db.AddError(serializationFailure)
+			})
+			if err != nil {
+				t.Fatalf("Failed to register callback: %v", err)
+			}
+
+			w := postJSON(router, "/reset-password", `{"token":"`+token+`","password":"NewPassword123"}`)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			changed := reloadUser(t, user.ID).PasswordHash != user.PasswordHash
+			if changed != (tt.expectedStatus == http.StatusOK) {
+				t.Errorf("Expected the password to change only on success, but got changed %v", changed)
+			}
+		})
+	}
+}
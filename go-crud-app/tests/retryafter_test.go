@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFormatRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 21, 12, 0, 0, 0, time.UTC)
+	delay := 90*time.Second + 500*time.Millisecond
+
+	tests := []struct {
+		name     string
+		format   middleware.RetryAfterFormat
+		expected string
+	}{
+		{
+			name:     "Delta seconds rounds up",
+			format:   middleware.RetryAfterSeconds,
+			expected: "91",
+		},
+		{
+			name:     "HTTP date",
+			format:   middleware.RetryAfterHTTPDate,
+			expected: "Wed, 21 Jan 2026 12:01:30 GMT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := middleware.FormatRetryAfter(tt.format, now, delay); got != tt.expected {
+				t.Errorf("Expected %q, but got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRateLimitRetryAfterHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RateLimitMiddleware(middleware.NewRateLimiter(1, time.Minute)))
+	router.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, but got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected Retry-After '60', but got %q", got)
+	}
+}
+
+func TestMaintenanceRetryAfterHeader(t *testing.T) {
+	middleware.RetryAfterMode = middleware.RetryAfterHTTPDate
+	defer func() { middleware.RetryAfterMode = middleware.RetryAfterSeconds }()
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.MaintenanceMiddleware(true, 5*time.Minute, "/health"))
+	router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, but got %d", w.Code)
+	}
+
+	retryAt, err := http.ParseTime(w.Header().Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("Expected Retry-After to be an HTTP date: %v", err)
+	}
+	if until := time.Until(retryAt); until < 4*time.Minute || until > 5*time.Minute {
+		t.Errorf("Expected Retry-After about 5 minutes from now, but got %v", until)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected exempt path to return 200, but got %d", w.Code)
+	}
+}
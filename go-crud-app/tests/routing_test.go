@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/handlers"
+)
+
+func TestRoutingFallbacks(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+	handlers.ConfigureRouting(router)
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedCode   string
+		expectedAllow  string
+	}{
+		{
+			name:           "Unsupported method on a collection",
+			method:         http.MethodDelete,
+			path:           "/users",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   apierror.CodeMethodNotAllowed,
+			expectedAllow:  "GET",
+		},
+		{
+			name:           "Unsupported method on a resource",
+			method:         http.MethodPost,
+			path:           "/users/1",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   apierror.CodeMethodNotAllowed,
+			expectedAllow:  "GET, PUT, PATCH, DELETE",
+		},
+		{
+			name:           "Unknown path",
+			method:         http.MethodGet,
+			path:           "/nonsense",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   apierror.CodeRouteNotFound,
+		},
+		{
+			name:           "Trailing slash is not redirected",
+			method:         http.MethodGet,
+			path:           "/users/",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   apierror.CodeRouteNotFound,
+		},
+		{
+			name:           "Wrong case is not redirected",
+			method:         http.MethodGet,
+			path:           "/USERS/me",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   apierror.CodeRouteNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, tt.method, tt.path, "", user))
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if body := decodeError(t, w); body.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, but got %q", tt.expectedCode, body.Code)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("Expected Allow %q, but got %q", tt.expectedAllow, allow)
+			}
+		})
+	}
+}
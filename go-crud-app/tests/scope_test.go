@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+)
+
+func TestLoginTokensCarryRoleScopes(t *testing.T) {
+	setupTestDB(t)
+	router := newSessionRouter(&fakeMailer{})
+
+	if w := postJSON(router, "/register", `{"username":"testuser","email":"test@example.com","password":"Password123"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	claims, err := utils.ValidateToken(loginToken(t, router, "test@example.com", "Password123"), testJWTConfig.SecretKey)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if want := []string{models.ScopeUsersRead, models.ScopeUsersWrite}; !slices.Equal(claims.Scopes, want) {
+		t.Errorf("Expected scopes %v, but got %v", want, claims.Scopes)
+	}
+
+	for _, role := range []string{models.RoleAdmin, models.RoleSuperAdmin} {
+		if !slices.Contains(models.RoleScopes(role), models.ScopeAdmin) {
+			t.Errorf("Expected the %s role to be granted the admin scope", role)
+		}
+	}
+	if slices.Contains(models.RoleScopes(models.RoleUser), models.ScopeAdmin) {
+		t.Error("Expected the user role not to be granted the admin scope")
+	}
+}
+
+func TestLoginNarrowsScopes(t *testing.T) {
+	setupTestDB(t)
+	router := newRefreshRouter()
+	user := createTestUser(t, "testuser", "test@example.com")
+	hash, err := utils.HashPassword("Password123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	database.DB.Model(&user).Update("password_hash", hash)
+
+	tokenScopes := func(token string) []string {
+		t.Helper()
+		claims, err := utils.ValidateToken(token, testJWTConfig.SecretKey)
+		if err != nil {
+			t.Fatalf("Failed to validate token: %v", err)
+		}
+		return claims.Scopes
+	}
+
+	w := postJSON(router, "/auth/login", `{"email":"test@example.com","password":"Password123","scopes":["users:read"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	want := []string{models.ScopeUsersRead}
+	if got := tokenScopes(resp.Token); !slices.Equal(got, want) {
+		t.Errorf("Expected scopes %v, but got %v", want, got)
+	}
+
+	// Refreshing must not widen the token back to the role's scopes
+	w = refreshRequest(router, resp.RefreshToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var refreshed handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got := tokenScopes(refreshed.Token); !slices.Equal(got, want) {
+		t.Errorf("Expected refreshed scopes %v, but got %v", want, got)
+	}
+
+	for _, scopes := range []string{`[]`, `["bogus"]`, `["admin"]`} {
+		w := postJSON(router, "/auth/login", `{"email":"test@example.com","password":"Password123","scopes":`+scopes+`}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422 for scopes %s, but got %d: %s", scopes, w.Code, w.Body.String())
+			continue
+		}
+		if resp := decodeError(t, w); len(resp.Fields) == 0 || resp.Fields[0].Field != "scopes" {
+			t.Errorf("Expected an error on scopes for %s, but got %+v", scopes, resp.Fields)
+		}
+	}
+}
+
+func TestNarrowedAdminTokenLosesAdminAccess(t *testing.T) {
+	setupTestDB(t)
+	router := newAdminPasswordResetRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	hash, err := utils.HashPassword("Password123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	database.DB.Model(&admin).Update("password_hash", hash)
+	resetPath := fmt.Sprintf("/users/%d/reset-password", user.ID)
+
+	w := postJSON(router, "/auth/login", `{"email":"admin@example.com","password":"Password123","scopes":["users:read"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var narrowed handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &narrowed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	w = bearerRequest(router, http.MethodPost, resetPath, narrowed.Token, "")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 without the admin scope, but got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decodeError(t, w); resp.Code != apierror.CodeForbidden {
+		t.Errorf("Expected code %s, but got %s", apierror.CodeForbidden, resp.Code)
+	}
+
+	// The same admin with every scope may use the route
+	full := login(t, router, admin.Email, "Password123")
+	if w := bearerRequest(router, http.MethodPost, resetPath, full.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with the admin scope, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	setupTestDB(t)
+	router := newAPIKeyRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	profilePath := fmt.Sprintf("/users/%d", admin.ID)
+
+	scopedToken := func(scopes ...string) string {
+		token, err := utils.GenerateToken(utils.TokenOptions{UserID: admin.ID, Username: admin.Username, Email: admin.Email, OrgID: admin.OrgID, TokenVersion: admin.TokenVersion, Scopes: scopes}, testJWTConfig)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
+	}
+	writeOnlyKey := createAPIKey(t, router, admin, `{"name":"writer","scopes":["users:write"]}`).Key
+
+	tests := []struct {
+		name          string
+		token         string
+		apiKey        string
+		method        string
+		path          string
+		missingScope  string
+		missingScheme string
+	}{
+		{name: "Read token reads", token: scopedToken(models.ScopeUsersRead), method: http.MethodGet, path: "/users/me"},
+		{name: "Read token writes", token: scopedToken(models.ScopeUsersRead), method: http.MethodPatch, path: profilePath,
+			missingScope: models.ScopeUsersWrite, missingScheme: "Bearer"},
+		{name: "Write token reads", token: scopedToken(models.ScopeUsersWrite), method: http.MethodGet, path: "/users/me",
+			missingScope: models.ScopeUsersRead, missingScheme: "Bearer"},
+		{name: "Write token writes", token: scopedToken(models.ScopeUsersWrite), method: http.MethodPatch, path: profilePath},
+		{name: "Token without scopes reads", token: scopedToken(), method: http.MethodGet, path: "/users/me"},
+		{name: "Token without scopes writes", token: scopedToken(), method: http.MethodPatch, path: profilePath},
+		{name: "Token with empty scopes reads", token: scopedToken([]string{}...), method: http.MethodGet, path: "/users/me",
+			missingScope: models.ScopeUsersRead, missingScheme: "Bearer"},
+		{name: "Token with empty scopes writes", token: scopedToken([]string{}...), method: http.MethodPatch, path: profilePath,
+			missingScope: models.ScopeUsersWrite, missingScheme: "Bearer"},
+		{name: "Write key reads", apiKey: writeOnlyKey, method: http.MethodGet, path: "/users/me",
+			missingScope: models.ScopeUsersRead, missingScheme: middleware.APIKeyScheme},
+		{name: "Write key writes", apiKey: writeOnlyKey, method: http.MethodPatch, path: profilePath},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"username":"renamed%d"}`, i)
+			var w *httptest.ResponseRecorder
+			if tt.apiKey != "" {
+				w = apiKeyRequest(router, tt.method, tt.path, tt.apiKey, body)
+			} else {
+				w = bearerRequest(router, tt.method, tt.path, tt.token, body)
+			}
+
+			if tt.missingScope == "" {
+				if w.Code != http.StatusOK {
+					t.Errorf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+				}
+				return
+			}
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+			}
+			if resp := decodeError(t, w); resp.Code != apierror.CodeInsufficientScope {
+				t.Errorf("Expected code %s, but got %s", apierror.CodeInsufficientScope, resp.Code)
+			}
+			want := fmt.Sprintf(`%s error="insufficient_scope", scope="%s"`, tt.missingScheme, tt.missingScope)
+			if got := w.Header().Get("WWW-Authenticate"); got != want {
+				t.Errorf("Expected WWW-Authenticate %q, but got %q", want, got)
+			}
+		})
+	}
+}
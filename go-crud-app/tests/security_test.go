@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newSecurityRouter(config middleware.SecurityHeadersConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.SecurityHeadersMiddleware(config))
+	router.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestSecurityHeadersPresent(t *testing.T) {
+	router := newSecurityRouter(middleware.DefaultSecurityHeadersConfig())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	expected := map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "no-referrer",
+		"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+	}
+	for header, value := range expected {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("Expected %s %q, but got %q", header, value, got)
+		}
+	}
+
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Expected no HSTS header over plain HTTP, but got %q", got)
+	}
+}
+
+func TestSecurityHeadersHSTSOverTLS(t *testing.T) {
+	router := newSecurityRouter(middleware.DefaultSecurityHeadersConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.TLS = &tls.ConnectionState{}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+		t.Errorf("Expected HSTS header over TLS, but got %q", got)
+	}
+}
+
+func TestSecurityHeadersDisabled(t *testing.T) {
+	config := middleware.DefaultSecurityHeadersConfig()
+	config.ContentSecurityPolicy = ""
+	config.HSTSMaxAge = 0
+	router := newSecurityRouter(config)
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.TLS = &tls.ConnectionState{}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Security-Policy"); got != "" {
+		t.Errorf("Expected no CSP header, but got %q", got)
+	}
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Expected no HSTS header, but got %q", got)
+	}
+	if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("Expected other headers to remain, but got X-Content-Type-Options %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+)
+
+func TestSeedAdminCreatesAdmin(t *testing.T) {
+	setupTestDB(t)
+
+	seed := database.AdminSeed{Username: "admin", Email: " Admin@Example.com ", Password: "SecurePass123!"}
+	if err := database.SeedAdmin(seed); err != nil {
+		t.Fatalf("Expected seeding to succeed, but got %v", err)
+	}
+
+	var admin models.User
+	if err := database.DB.Where("email = ?", "admin@example.com").First(&admin).Error; err != nil {
+		t.Fatalf("Expected seeded admin to exist, but got %v", err)
+	}
+	if admin.Role != models.RoleAdmin {
+		t.Errorf("Expected role %q, but got %q", models.RoleAdmin, admin.Role)
+	}
+	if admin.PasswordHash == seed.Password || !utils.CheckPassword(seed.Password, admin.PasswordHash) {
+		t.Error("Expected password to be stored as a valid hash")
+	}
+}
+
+func TestSeedAdminIsIdempotent(t *testing.T) {
+	setupTestDB(t)
+
+	seed := database.AdminSeed{Username: "admin", Email: "admin@example.com", Password: "SecurePass123!"}
+	for i := 0; i < 2; i++ {
+		if err := database.SeedAdmin(seed); err != nil {
+			t.Fatalf("Expected seeding run %d to succeed, but got %v", i+1, err)
+		}
+	}
+
+	var count int64
+	database.DB.Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 user, but got %d", count)
+	}
+}
+
+func TestSeedAdminSkipsWhenUsersExist(t *testing.T) {
+	setupTestDB(t)
+	existing := createTestUser(t, "testuser", "test@example.com")
+	database.DB.Delete(&existing)
+
+	seed := database.AdminSeed{Username: "admin", Email: "admin@example.com", Password: "SecurePass123!"}
+	if err := database.SeedAdmin(seed); err != nil {
+		t.Fatalf("Expected seeding to succeed, but got %v", err)
+	}
+
+	var count int64
+	database.DB.Unscoped().Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no admin to be seeded, but got %d", count)
+	}
+}
+
+func TestSeedAdminRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		seed database.AdminSeed
+	}{
+		{"missing email", database.AdminSeed{Username: "admin", Password: "SecurePass123!"}},
+		{"missing password", database.AdminSeed{Username: "admin", Email: "admin@example.com"}},
+		{"weak password", database.AdminSeed{Username: "admin", Email: "admin@example.com", Password: "short"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+
+			if err := database.SeedAdmin(tt.seed); err == nil {
+				t.Error("Expected seeding to fail, but got nil")
+			}
+		})
+	}
+}
+
+func TestCreateAdmin(t *testing.T) {
+	tests := []struct {
+		name          string
+		seed          database.AdminSeed
+		expectedError bool
+	}{
+		{name: "valid", seed: database.AdminSeed{Username: "admin2", Email: "Admin2@Example.com", Password: "SecurePass123!"}},
+		{name: "duplicate email", seed: database.AdminSeed{Username: "admin3", Email: "test@example.com", Password: "SecurePass123!"}, expectedError: true},
+		{name: "invalid username", seed: database.AdminSeed{Username: "a!", Email: "admin4@example.com", Password: "SecurePass123!"}, expectedError: true},
+		{name: "invalid email", seed: database.AdminSeed{Username: "admin5", Email: "nope", Password: "SecurePass123!"}, expectedError: true},
+		{name: "weak password", seed: database.AdminSeed{Username: "admin6", Email: "admin6@example.com", Password: "weak"}, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			createTestUser(t, "testuser", "test@example.com")
+
+			admin, err := database.CreateAdmin(tt.seed)
+			if tt.expectedError {
+				if err == nil {
+					t.Error("Expected an error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected admin creation to succeed, but got %v", err)
+			}
+			if admin.Role != models.RoleAdmin || admin.Email != "admin2@example.com" {
+				t.Errorf("Expected normalized admin admin2@example.com, but got %s with role %s", admin.Email, admin.Role)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newSessionListRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/login", handlers.Login(testJWTConfig))
+	router.POST("/refresh", handlers.Refresh(testJWTConfig))
+
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.GET("/me/sessions", handlers.ListSessions)
+		users.DELETE("/me/sessions/:id", handlers.RevokeSession)
+	}
+	return router
+}
+
+// createLoginUser inserts a user with a real password hash so it can log in
+func createLoginUser(t *testing.T, username, email, password string) models.User {
+	t.Helper()
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	user := models.User{Username: username, Email: email, PasswordHash: hash}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user
+}
+
+// loginFrom logs in with the given user agent and returns the token pair
+func loginFrom(t *testing.T, router *gin.Engine, userAgent, email, password string) handlers.AuthResponse {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/login",
+		bytes.NewBufferString(`{"email":"`+email+`","password":"`+password+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+	return resp
+}
+
+// listSessions fetches the sessions visible to the token
+func listSessions(t *testing.T, router *gin.Engine, token string) []models.SessionResponse {
+	t.Helper()
+
+	w := bearerRequest(router, http.MethodGet, "/users/me/sessions", token, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 listing sessions, but got %d: %s", w.Code, w.Body.String())
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("jti")) {
+		t.Errorf("Expected sessions to omit their jti, but got %s", w.Body.String())
+	}
+
+	var resp struct {
+		Sessions []models.SessionResponse `json:"sessions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode sessions: %v", err)
+	}
+	return resp.Sessions
+}
+
+func TestListSessions(t *testing.T) {
+	setupTestDB(t)
+	router := newSessionListRouter()
+	createLoginUser(t, "testuser", "test@example.com", "SecurePass123")
+	other := createLoginUser(t, "otheruser", "other@example.com", "SecurePass123")
+
+	loginFrom(t, router, "Laptop", "test@example.com", "SecurePass123")
+	phone := loginFrom(t, router, "Phone", "test@example.com", "SecurePass123")
+	loginFrom(t, router, "Other", other.Email, "SecurePass123")
+
+	sessions := listSessions(t, router, phone.Token)
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, but got %d: %+v", len(sessions), sessions)
+	}
+	agents := map[string]bool{}
+	for _, session := range sessions {
+		agents[session.UserAgent] = session.Current
+		if session.IssuedAt.IsZero() || session.LastSeenAt.IsZero() {
+			t.Errorf("Expected issued and last seen times, but got %+v", session)
+		}
+	}
+	if current, ok := agents["Phone"]; !ok || !current {
+		t.Errorf("Expected the Phone session to be listed as current, but got %v", agents)
+	}
+	if current, ok := agents["Laptop"]; !ok || current {
+		t.Errorf("Expected the Laptop session to be listed but not current, but got %v", agents)
+	}
+
+	// Refreshing continues the session rather than starting another
+	w := postJSON(router, "/refresh", `{"refresh_token":"`+phone.RefreshToken+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected refresh to succeed, but got %d: %s", w.Code, w.Body.String())
+	}
+	var refreshed handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("Failed to decode refresh response: %v", err)
+	}
+	sessions = listSessions(t, router, refreshed.Token)
+	if len(sessions) != 2 {
+		t.Errorf("Expected 2 sessions after refreshing, but got %d", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.UserAgent == "Phone" && !session.Current {
+			t.Error("Expected the refreshed token to belong to the Phone session")
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	setupTestDB(t)
+	router := newSessionListRouter()
+	createLoginUser(t, "testuser", "test@example.com", "SecurePass123")
+	other := createLoginUser(t, "otheruser", "other@example.com", "SecurePass123")
+
+	laptop := loginFrom(t, router, "Laptop", "test@example.com", "SecurePass123")
+	phone := loginFrom(t, router, "Phone", "test@example.com", "SecurePass123")
+	otherLogin := loginFrom(t, router, "Other", other.Email, "SecurePass123")
+
+	var laptopID uint
+	for _, session := range listSessions(t, router, phone.Token) {
+		if session.UserAgent == "Laptop" {
+			laptopID = session.ID
+		}
+	}
+	otherID := listSessions(t, router, otherLogin.Token)[0].ID
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{"Invalid ID", "/users/me/sessions/abc", http.StatusBadRequest},
+		{"Another user's session", fmt.Sprintf("/users/me/sessions/%d", otherID), http.StatusNotFound},
+		{"Unknown session", "/users/me/sessions/999", http.StatusNotFound},
+		{"Own session", fmt.Sprintf("/users/me/sessions/%d", laptopID), http.StatusOK},
+		{"Already revoked", fmt.Sprintf("/users/me/sessions/%d", laptopID), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := bearerRequest(router, http.MethodDelete, tt.path, phone.Token, "")
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// The revoked session's tokens stop working, refresh token included
+	if w := bearerRequest(router, http.MethodGet, "/users/me", laptop.Token, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked access token to be rejected, but got %d", w.Code)
+	}
+	if w := postJSON(router, "/refresh", `{"refresh_token":"`+laptop.RefreshToken+`"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked refresh token to be rejected, but got %d", w.Code)
+	}
+
+	// The other sessions are untouched
+	if w := bearerRequest(router, http.MethodGet, "/users/me", phone.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the current session to keep working, but got %d", w.Code)
+	}
+	if w := bearerRequest(router, http.MethodGet, "/users/me", otherLogin.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the other user's session to keep working, but got %d", w.Code)
+	}
+	if sessions := listSessions(t, router, phone.Token); len(sessions) != 1 || sessions[0].UserAgent != "Phone" {
+		t.Errorf("Expected only the Phone session to remain, but got %+v", sessions)
+	}
+
+	var audits int64
+	database.DB.Model(&models.AuditLog{}).Where("action = ?", models.AuditSessionRevoked).Count(&audits)
+	if audits != 1 {
+		t.Errorf("Expected 1 %s audit entry, but got %d", models.AuditSessionRevoked, audits)
+	}
+}
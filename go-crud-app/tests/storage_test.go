@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-crud-app/internal/storage"
+)
+
+// newFakeS3 serves an in-memory bucket that rejects unsigned or tampered requests
+func newFakeS3(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") ||
+			r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStoresRoundTrip(t *testing.T) {
+	server := newFakeS3(t)
+
+	s3Store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "avatars",
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create S3 store: %v", err)
+	}
+	localStore, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create local store: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		store storage.Store
+	}{
+		{name: "S3", store: s3Store},
+		{name: "Local", store: localStore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if err := tt.store.Put(ctx, "avatars/1-abc.jpg", []byte("image-data"), "image/jpeg"); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+
+			body, err := tt.store.Get(ctx, "avatars/1-abc.jpg")
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			data, _ := io.ReadAll(body)
+			body.Close()
+			if string(data) != "image-data" {
+				t.Errorf("Expected stored data, but got %q", data)
+			}
+
+			if err := tt.store.Delete(ctx, "avatars/1-abc.jpg"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if _, err := tt.store.Get(ctx, "avatars/1-abc.jpg"); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("Expected ErrNotFound after delete, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestLocalStoreRejectsPathTraversal(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create local store: %v", err)
+	}
+
+	if err := store.Put(context.Background(), "../escape.jpg", []byte("x"), "image/jpeg"); err == nil {
+		t.Error("Expected an error for a key escaping the store directory, but got none")
+	}
+}
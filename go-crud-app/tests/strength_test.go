@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPasswordStrengthScores(t *testing.T) {
+	tests := []struct {
+		password string
+		minScore int
+		maxScore int
+	}{
+		{"password", 0, 0},
+		{"123456", 0, 0},
+		{"qwerty", 0, 0},
+		{"Password1", 0, 1},
+		{"P@ssw0rd", 0, 1},
+		{"drowssap", 0, 1},
+		{"aaaaaaaaaa", 0, 1},
+		{"abcdefgh", 0, 1},
+		{"asdfghjkl", 0, 1},
+		{"monkey1990", 0, 2},
+		{"kX8#qp2!Lm9z", 4, 4},
+		{"correcthorsebatterystaple", 4, 4},
+		{"glacier-tuba-wander-71", 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			strength := utils.EstimatePasswordStrength(tt.password)
+			if strength.Score < tt.minScore || strength.Score > tt.maxScore {
+				t.Errorf("Expected score %d-%d, but got %d (%+v)", tt.minScore, tt.maxScore, strength.Score, strength)
+			}
+			if strength.Score <= utils.StrengthSomewhatGuessable && len(strength.Feedback.Suggestions) == 0 {
+				t.Error("Expected suggestions for a weak password")
+			}
+		})
+	}
+}
+
+func TestPasswordStrengthPenalizesUserInputs(t *testing.T) {
+	without := utils.EstimatePasswordStrength("zanzibar.quokka")
+	with := utils.EstimatePasswordStrength("zanzibar.quokka", "zanzibar", "quokka@example.com")
+	if with.GuessesLog10 >= without.GuessesLog10 || with.Score >= without.Score {
+		t.Errorf("Expected user inputs to lower the estimate, but got %+v without and %+v with", without, with)
+	}
+
+	weak := utils.EstimatePasswordStrength("quokka99", "zanzibar", "quokka@example.com")
+	if weak.Feedback.Warning != "Avoid using your username or email in your password" {
+		t.Errorf("Expected a user input warning, but got %q", weak.Feedback.Warning)
+	}
+}
+
+func TestPasswordStrengthLongPassword(t *testing.T) {
+	start := time.Now()
+	utils.EstimatePasswordStrength(strings.Repeat("aB3$", utils.MaxPasswordBytes/4))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected a maximum-length password to be estimated quickly, but took %v", elapsed)
+	}
+}
+
+func TestCheckPasswordStrengthEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/password-strength", handlers.CheckPasswordStrength)
+
+	w := postJSON(router, "/password-strength", `{"password":"johndoe2024","username":"johndoe","email":"john@example.com"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Errorf("Expected Cache-Control no-store, but got %q", cacheControl)
+	}
+	var strength utils.PasswordStrength
+	if err := json.Unmarshal(w.Body.Bytes(), &strength); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if strength.Score > utils.StrengthVeryGuessable || strength.Feedback.Warning == "" {
+		t.Errorf("Expected a low score with a warning, but got %+v", strength)
+	}
+
+	// The score is advisory, so a password failing the policy is still scored
+	w = postJSON(router, "/password-strength", `{"password":"glacier tuba wander"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = postJSON(router, "/password-strength", `{}`)
+	if w.Code != http.StatusBadRequest && w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a missing password to be rejected, but got %d", w.Code)
+	}
+
+	w = postJSON(router, "/password-strength", `{"password":"`+strings.Repeat("a", utils.MaxPasswordBytes+1)+`"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an overlong password, but got %d", w.Code)
+	}
+}
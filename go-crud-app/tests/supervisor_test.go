@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/supervisor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// waitFor polls cond until it returns true or the timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestSupervisorRestartsPanickingWorker(t *testing.T) {
+	workers := supervisor.New(100 * time.Millisecond)
+	defer workers.Stop()
+
+	var runs int32
+	workers.Go("flaky", func(ctx context.Context) {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			panic("boom")
+		}
+		<-ctx.Done()
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/health/details", handlers.HealthDetails(workers))
+
+	healthStatus := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/details", nil))
+		return w.Code
+	}
+
+	// Unhealthy while waiting to restart
+	if !waitFor(t, time.Second, func() bool { return !workers.Healthy() }) {
+		t.Fatal("Expected worker to be reported unhealthy after panicking")
+	}
+	if code := healthStatus(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while unhealthy, but got %d", code)
+	}
+
+	// Healthy again once restarted
+	if !waitFor(t, time.Second, workers.Healthy) {
+		t.Fatal("Expected worker to be reported healthy after restarting")
+	}
+	if code := healthStatus(); code != http.StatusOK {
+		t.Errorf("Expected status 200 once healthy, but got %d", code)
+	}
+
+	status := workers.Status()
+	if len(status) != 1 || status[0].Restarts != 1 || status[0].LastPanic != "boom" {
+		t.Errorf("Expected one restart after panic 'boom', but got %+v", status)
+	}
+	if atomic.LoadInt32(&runs) != 2 {
+		t.Errorf("Expected worker to run twice, but ran %d times", runs)
+	}
+}
+
+func TestSupervisorStopWaitsForWorkers(t *testing.T) {
+	workers := supervisor.New(time.Millisecond)
+
+	var stopped int32
+	workers.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		atomic.StoreInt32(&stopped, 1)
+	})
+
+	workers.Stop()
+
+	if atomic.LoadInt32(&stopped) != 1 {
+		t.Error("Expected worker to have exited after Stop")
+	}
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+	"go-crud-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newSuspendRouter builds a router exposing login, refresh, the current
+// user and the admin user status route
+func newSuspendRouter() *gin.Engine {
+	router := newRefreshRouter()
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("/me", handlers.GetCurrentUser)
+		users.PUT("/:id/status", middleware.RequireAdmin(), handlers.UpdateUserStatus)
+	}
+	return router
+}
+
+// setUserStatus has admin set the status of user, returning the response
+func setUserStatus(t *testing.T, router *gin.Engine, admin models.User, userID uint, status string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPut, fmt.Sprintf("/users/%d/status", userID), fmt.Sprintf(`{"status":%q}`, status), admin))
+	return w
+}
+
+// loginTestUser gives user a known password and logs in, returning the tokens
+func loginTestUser(t *testing.T, router *gin.Engine, user models.User) handlers.AuthResponse {
+	t.Helper()
+
+	hash, err := utils.HashPassword("Password123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	database.DB.Model(&user).Update("password_hash", hash)
+
+	w := postJSON(router, "/auth/login", fmt.Sprintf(`{"email":%q,"password":"Password123"}`, user.Email))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+// getMe fetches the current user with a bearer token
+func getMe(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSuspendedUserIsLockedOut(t *testing.T) {
+	setupTestDB(t)
+	router := newSuspendRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	session := loginTestUser(t, router, user)
+
+	w := setUserStatus(t, router, admin, user.ID, models.StatusSuspended)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Existing sessions are cut off
+	w = getMe(router, session.Token)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decodeError(t, w); resp.Code != apierror.CodeAccountSuspended {
+		t.Errorf("Expected code %q, but got %q", apierror.CodeAccountSuspended, resp.Code)
+	}
+	if w := refreshRequest(router, session.RefreshToken); w.Code != http.StatusForbidden {
+		t.Errorf("Expected refresh status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	// And new logins are refused, even with the right password
+	w = postJSON(router, "/auth/login", `{"email":"test@example.com","password":"Password123"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decodeError(t, w); resp.Code != apierror.CodeAccountSuspended {
+		t.Errorf("Expected code %q, but got %q", apierror.CodeAccountSuspended, resp.Code)
+	}
+
+	var count int64
+	database.DB.Model(&models.AuditLog{}).Where("action = ?", models.AuditAdminUserSuspended).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 suspension audit entry, but got %d", count)
+	}
+}
+
+func TestReactivatedUserCanLogIn(t *testing.T) {
+	setupTestDB(t)
+	router := newSuspendRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	session := loginTestUser(t, router, user)
+
+	if w := setUserStatus(t, router, admin, user.ID, models.StatusSuspended); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	w := setUserStatus(t, router, admin, user.ID, models.StatusActive)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		User models.UserResponse `json:"user"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.User.Status != models.StatusActive {
+		t.Errorf("Expected status %q, but got %q", models.StatusActive, resp.User.Status)
+	}
+
+	// Tokens issued before the suspension stay revoked
+	if w := getMe(router, session.Token); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an old token, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	session = loginTestUser(t, router, user)
+	if w := getMe(router, session.Token); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateUserStatusRejections(t *testing.T) {
+	setupTestDB(t)
+	router := newSuspendRouter()
+	admin := createTestAdmin(t, "admin", "admin@example.com")
+	user := createTestUser(t, "testuser", "test@example.com")
+	superAdmin := setRole(t, createTestUser(t, "super", "super@example.com"), models.RoleSuperAdmin)
+
+	tests := []struct {
+		name           string
+		actor          models.User
+		target         uint
+		status         string
+		expectedStatus int
+	}{
+		{"Non-admin", user, admin.ID, models.StatusSuspended, http.StatusForbidden},
+		{"Own account", admin, admin.ID, models.StatusSuspended, http.StatusConflict},
+		{"Super-admin target", admin, superAdmin.ID, models.StatusSuspended, http.StatusForbidden},
+		{"Unknown status", admin, user.ID, "banned", http.StatusUnprocessableEntity},
+		{"Unknown user", admin, 9999, models.StatusSuspended, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := setUserStatus(t, router, tt.actor, tt.target, tt.status)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	var stored models.User
+	database.DB.First(&stored, user.ID)
+	if stored.Status != models.StatusActive {
+		t.Errorf("Expected user to stay %q, but got %q", models.StatusActive, stored.Status)
+	}
+}
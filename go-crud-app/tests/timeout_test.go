@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestSlowHandlerReturnsGatewayTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.TimeoutMiddleware(20 * time.Millisecond))
+	router.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, but got %d", w.Code)
+	}
+}
+
+func TestSlowQueryIsCancelledAndReturnsGatewayTimeout(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	// Simulate a query that only finishes when its context is cancelled
+	err := database.DB.Callback().Query().Before("gorm:query").Register("test:slow_query", func(db *gorm.DB) {
+		select {
+		case <-db.Statement.Context.Done():
+			db.AddError(db.Statement.Context.Err())
+		case <-time.After(5 * time.Second):
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.TimeoutMiddleware(50 * time.Millisecond))
+	router.GET("/users/me", middleware.AuthMiddleware(testJWTConfig.SecretKey), handlers.GetCurrentUser)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me", "", user))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, but got %d: %s", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected query to be cancelled at the deadline, but request took %s", elapsed)
+	}
+}
+
+func TestFastRequestUnaffectedByTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.TimeoutMiddleware(time.Second))
+	router.GET("/fast", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, but got %d", w.Code)
+	}
+}
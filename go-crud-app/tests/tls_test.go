@@ -0,0 +1,307 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/tlsserver"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 and its
+// key to certFile and keyFile, stamped with modTime, and returns the certificate
+func writeSelfSignedCert(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	files := map[string][]byte{
+		certFile: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyFile:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(name, data, 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set time on %s: %v", name, err)
+		}
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+// tlsClient returns an HTTP/2-capable client that trusts only the given certificates
+func tlsClient(certs ...*x509.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool},
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		},
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Minute)
+	first := writeSelfSignedCert(t, certFile, keyFile, 1, start)
+
+	reloader, err := tlsserver.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "healthy"}) })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := &http.Server{Handler: router, TLSConfig: tlsserver.Config(reloader, tlsserver.Settings{})}
+	go func() {
+		if err := srv.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("Failed to serve TLS: %v", err)
+		}
+	}()
+	t.Cleanup(func() { srv.Close() })
+	url := "https://" + listener.Addr().String() + "/health"
+
+	// get requests the health endpoint and returns the serial of the served certificate
+	get := func(t *testing.T, client *http.Client) int64 {
+		t.Helper()
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("Failed to request %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status %d, but got %d", http.StatusOK, resp.StatusCode)
+		}
+		if resp.ProtoMajor != 2 {
+			t.Errorf("Expected HTTP/2, but got %s", resp.Proto)
+		}
+		return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	t.Run("Serves the configured certificate over HTTP/2", func(t *testing.T) {
+		if serial := get(t, tlsClient(first)); serial != 1 {
+			t.Errorf("Expected certificate serial 1, but got %d", serial)
+		}
+	})
+
+	var second *x509.Certificate
+	t.Run("Reloads a rotated certificate", func(t *testing.T) {
+		second = writeSelfSignedCert(t, certFile, keyFile, 2, start.Add(30*time.Second))
+		if serial := get(t, tlsClient(second)); serial != 2 {
+			t.Errorf("Expected certificate serial 2, but got %d", serial)
+		}
+	})
+
+	t.Run("Keeps the previous certificate when the new one is unreadable", func(t *testing.T) {
+		if err := os.WriteFile(certFile, []byte("not a certificate"), 0o600); err != nil {
+			t.Fatalf("Failed to write certificate: %v", err)
+		}
+		if serial := get(t, tlsClient(second)); serial != 2 {
+			t.Errorf("Expected certificate serial 2, but got %d", serial)
+		}
+	})
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort string
+		host      string
+		target    string
+		expected  string
+	}{
+		{
+			name:      "Standard port is omitted",
+			httpsPort: "443",
+			host:      "api.example.com",
+			target:    "/api/users?page=2",
+			expected:  "https://api.example.com/api/users?page=2",
+		},
+		{
+			name:      "Custom port replaces the request's port",
+			httpsPort: "8443",
+			host:      "api.example.com:8080",
+			target:    "/health",
+			expected:  "https://api.example.com:8443/health",
+		},
+		{
+			name:      "IPv6 host",
+			httpsPort: "8443",
+			host:      "[::1]",
+			target:    "/",
+			expected:  "https://[::1]:8443/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			tlsserver.RedirectHandler(tt.httpsPort).ServeHTTP(w, req)
+
+			if w.Code != http.StatusPermanentRedirect {
+				t.Errorf("Expected status %d, but got %d", http.StatusPermanentRedirect, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != tt.expected {
+				t.Errorf("Expected redirect to %s, but got %s", tt.expected, location)
+			}
+		})
+	}
+}
+
+func TestTLSSettings(t *testing.T) {
+	settings, err := tlsserver.ParseSettings("1.3", "", "x25519, P256")
+	if err != nil {
+		t.Fatalf("Expected valid settings, but got %v", err)
+	}
+	config := tlsserver.Config(&tlsserver.CertReloader{}, settings)
+	if config.MinVersion != tls.VersionTLS13 {
+		t.Errorf("Expected minimum version TLS 1.3, but got %s", tls.VersionName(config.MinVersion))
+	}
+	if len(config.CurvePreferences) != 2 || config.CurvePreferences[0] != tls.X25519 || config.CurvePreferences[1] != tls.CurveP256 {
+		t.Errorf("Expected curves [X25519 CurveP256], but got %v", config.CurvePreferences)
+	}
+
+	// Defaults: TLS 1.2 with Go's cipher suites and curves
+	settings, err = tlsserver.ParseSettings("", "", "")
+	if err != nil {
+		t.Fatalf("Expected valid settings, but got %v", err)
+	}
+	config = tlsserver.Config(&tlsserver.CertReloader{}, settings)
+	if config.MinVersion != tls.VersionTLS12 || config.CipherSuites != nil || config.CurvePreferences != nil {
+		t.Errorf("Expected TLS 1.2 and Go's defaults, but got %s, %v and %v", tls.VersionName(config.MinVersion), config.CipherSuites, config.CurvePreferences)
+	}
+
+	settings, err = tlsserver.ParseSettings("1.2", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,tls_ecdhe_ecdsa_with_chacha20_poly1305_sha256", "")
+	if err != nil {
+		t.Fatalf("Expected valid settings, but got %v", err)
+	}
+	expected := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256}
+	if config := tlsserver.Config(&tlsserver.CertReloader{}, settings); !slices.Equal(config.CipherSuites, expected) {
+		t.Errorf("Expected cipher suites %v, but got %v", expected, config.CipherSuites)
+	}
+}
+
+func TestTLSSettingsRejectInsecure(t *testing.T) {
+	tests := []struct {
+		name         string
+		minVersion   string
+		cipherSuites string
+		curves       string
+	}{
+		{"TLS 1.0", "1.0", "", ""},
+		{"TLS 1.1", "1.1", "", ""},
+		{"unknown version", "2.0", "", ""},
+		{"insecure cipher suite", "", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_RSA_WITH_RC4_128_SHA", ""},
+		{"unknown cipher suite", "", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_MADE_UP", ""},
+		{"TLS 1.3 cipher suite", "", "TLS_AES_128_GCM_SHA256", ""},
+		{"cipher suites with TLS 1.3", "1.3", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", ""},
+		{"no HTTP/2 cipher suite", "", "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", ""},
+		{"unknown curve", "", "", "X25519,P192"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tlsserver.ParseSettings(tt.minVersion, tt.cipherSuites, tt.curves); err == nil {
+				t.Error("Expected the settings to be rejected")
+			}
+		})
+	}
+}
+
+func TestServeTLSEnforcesMinVersion(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	cert := writeSelfSignedCert(t, certFile, keyFile, 1, time.Now().Add(-time.Minute))
+	reloader, err := tlsserver.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := &http.Server{
+		Handler:   http.NotFoundHandler(),
+		TLSConfig: tlsserver.Config(reloader, tlsserver.Settings{MinVersion: tls.VersionTLS13}),
+	}
+	go func() {
+		if err := srv.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("Failed to serve TLS: %v", err)
+		}
+	}()
+	t.Cleanup(func() { srv.Close() })
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	for _, tt := range []struct {
+		maxVersion uint16
+		ok         bool
+	}{
+		{tls.VersionTLS12, false},
+		{tls.VersionTLS13, true},
+	} {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{RootCAs: pool, MaxVersion: tt.maxVersion})
+		if err == nil {
+			conn.Close()
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("Expected a %s client to connect: %v, but got error %v", tls.VersionName(tt.maxVersion), tt.ok, err)
+		}
+	}
+}
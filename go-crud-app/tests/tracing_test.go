@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/tracing"
+	"go-crud-app/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	spanExporterOnce sync.Once
+	spanExporter     *tracetest.InMemoryExporter
+)
+
+// recordSpans installs a tracer provider that records spans in memory. The
+// provider is global, so it is installed once and shared by every test; tests
+// pick out their own spans by trace ID.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	spanExporterOnce.Do(func() {
+		if _, err := tracing.Setup(context.Background(), tracing.Config{}); err != nil {
+			t.Fatalf("Failed to set up tracing: %v", err)
+		}
+		spanExporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+	})
+	return spanExporter
+}
+
+// spansInTrace returns the names and kinds of the recorded spans belonging to traceID
+func spansInTrace(exporter *tracetest.InMemoryExporter, traceID string) map[string]trace.SpanKind {
+	spans := make(map[string]trace.SpanKind)
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID().String() == traceID {
+			spans[span.Name] = span.SpanKind
+		}
+	}
+	return spans
+}
+
+func newTracingRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.TracingMiddleware("test-service", "/health"))
+	router.Use(middleware.RequestIDMiddleware())
+	router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	users.GET("/:id", handlers.GetUserByID)
+	return router
+}
+
+func TestTracingRecordsRequestAndQuerySpans(t *testing.T) {
+	exporter := recordSpans(t)
+	setupTestDB(t)
+	if err := database.UseTracing(database.DB); err != nil {
+		t.Fatalf("Failed to enable query tracing: %v", err)
+	}
+	router := newTracingRouter()
+
+	user := createTestUser(t, "traced", "traced@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/1", "", user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Without a client-supplied ID, the request ID is the trace ID
+	traceID := w.Header().Get(middleware.RequestIDHeader)
+	spans := spansInTrace(exporter, traceID)
+	if len(spans) == 0 {
+		t.Fatalf("Expected spans in trace %s (the request ID), but got none", traceID)
+	}
+
+	if kind, ok := spans["GET /users/:id"]; !ok || kind != trace.SpanKindServer {
+		t.Errorf("Expected a server span for GET /users/:id, but got %v", spans)
+	}
+	queries := 0
+	for _, kind := range spans {
+		if kind == trace.SpanKindClient {
+			queries++
+		}
+	}
+	if queries == 0 {
+		t.Errorf("Expected query spans in the request's trace, but got %v", spans)
+	}
+}
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	exporter := recordSpans(t)
+	router := newTracingRouter()
+
+	const traceID = "4bf92f3577b34a4e8b4e1b2b3c5d7e9f"
+	tests := []struct {
+		name              string
+		path              string
+		requestID         string
+		expectedRequestID string
+		expectedSpans     int
+	}{
+		{
+			name:              "Traced route takes the incoming trace ID",
+			path:              "/users/1",
+			expectedRequestID: traceID,
+			expectedSpans:     1,
+		},
+		{
+			name:              "Client request ID is kept",
+			path:              "/users/1",
+			requestID:         "client-id",
+			expectedRequestID: "client-id",
+			expectedSpans:     1,
+		},
+		{
+			name:          "Skipped path is not traced",
+			path:          "/health",
+			expectedSpans: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+			if tt.requestID != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if tt.expectedRequestID != "" {
+				if got := w.Header().Get(middleware.RequestIDHeader); got != tt.expectedRequestID {
+					t.Errorf("Expected request ID %q, but got %q", tt.expectedRequestID, got)
+				}
+			}
+			if spans := spansInTrace(exporter, traceID); len(spans) != tt.expectedSpans {
+				t.Errorf("Expected %d spans in the incoming trace, but got %v", tt.expectedSpans, spans)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveryPropagatesTraceContext(t *testing.T) {
+	recordSpans(t)
+
+	traceparents := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+	}))
+	t.Cleanup(server.Close)
+
+	notifier := startNotifier(t, webhook.Config{Endpoints: []webhook.Endpoint{{URL: server.URL, Secret: "secret"}}})
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "request")
+	notifier.Notify(ctx, webhook.EventUserUpdated, 42)
+	span.End()
+
+	select {
+	case traceparent := <-traceparents:
+		traceID := span.SpanContext().TraceID().String()
+		if len(traceparent) < 35 || traceparent[3:35] != traceID {
+			t.Errorf("Expected a traceparent in trace %s, but got %q", traceID, traceparent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a webhook delivery")
+	}
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/middleware"
+	"go-crud-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newUserRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	users := router.Group("/users")
+	users.Use(middleware.AuthMiddleware(testJWTConfig.SecretKey))
+	{
+		users.GET("", handlers.GetAllUsers(testUserListConfig))
+		users.GET("/me", handlers.GetCurrentUser)
+		users.GET("/:id", handlers.GetUserByID)
+		users.PUT("/:id", handlers.UpdateUser)
+		users.PATCH("/:id", handlers.UpdateUser)
+		users.DELETE("/:id", handlers.DeleteUser)
+	}
+	return router
+}
+
+func TestUpdateUserReturnsFreshUpdatedAt(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	// Backdate the row so the update is guaranteed to move updated_at forward
+	past := time.Now().Add(-1 * time.Hour)
+	if err := database.DB.Model(&user).UpdateColumn("updated_at", past).Error; err != nil {
+		t.Fatalf("Failed to backdate user: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodPut, fmt.Sprintf("/users/%d", user.ID), `{"username":"renamed"}`, user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp models.UserResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if resp.Username != "renamed" {
+		t.Errorf("Expected username 'renamed', but got %s", resp.Username)
+	}
+	if !resp.UpdatedAt.After(past) {
+		t.Errorf("Expected updated_at after %v, but got %v", past, resp.UpdatedAt)
+	}
+
+	var stored models.User
+	database.DB.First(&stored, user.ID)
+	if !resp.UpdatedAt.Equal(stored.UpdatedAt) {
+		t.Errorf("Expected updated_at %v to match stored %v", resp.UpdatedAt, stored.UpdatedAt)
+	}
+}
+
+func TestUpdateUserIDParsing(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "testuser", "test@example.com")
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{
+			name:           "Non-numeric ID",
+			path:           "/users/abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Negative ID",
+			path:           "/users/-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Valid numeric ID",
+			path:           fmt.Sprintf("/users/%d", user.ID),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodPut, tt.path, `{"username":"renamed"}`, user))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateUserPartialSemantics(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		body             string
+		expectedStatus   int
+		expectedUsername string
+		expectedEmail    string
+		errorField       string
+	}{
+		{"omitted email is left unchanged", http.MethodPatch, `{"username":"renamed"}`, http.StatusOK, "renamed", "test@example.com", ""},
+		{"PUT behaves like PATCH", http.MethodPut, `{"email":"new@example.com"}`, http.StatusOK, "testuser", "new@example.com", ""},
+		{"empty email is rejected", http.MethodPatch, `{"email":""}`, http.StatusUnprocessableEntity, "testuser", "test@example.com", "email"},
+		{"empty username is rejected", http.MethodPatch, `{"username":"  "}`, http.StatusUnprocessableEntity, "testuser", "test@example.com", "username"},
+		{"null is treated as omitted", http.MethodPatch, `{"username":"renamed","email":null}`, http.StatusOK, "renamed", "test@example.com", ""},
+		{"no fields", http.MethodPatch, `{}`, http.StatusUnprocessableEntity, "testuser", "test@example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			useEmailChange(t, handlers.EmailChangeConfig{Confirm: false})
+			router := newUserRouter()
+			user := createTestUser(t, "testuser", "test@example.com")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, tt.method, fmt.Sprintf("/users/%d", user.ID), tt.body, user))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			if tt.errorField != "" {
+				fields := decodeError(t, w).Fields
+				if len(fields) != 1 || fields[0].Field != tt.errorField {
+					t.Errorf("Expected a single error for field %s, but got %+v", tt.errorField, fields)
+				}
+			}
+
+			var stored models.User
+			database.DB.First(&stored, user.ID)
+			if stored.Username != tt.expectedUsername {
+				t.Errorf("Expected username %q, but got %q", tt.expectedUsername, stored.Username)
+			}
+			if stored.Email != tt.expectedEmail {
+				t.Errorf("Expected email %q, but got %q", tt.expectedEmail, stored.Email)
+			}
+		})
+	}
+}
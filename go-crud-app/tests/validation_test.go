@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAuthRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", handlers.Register(testJWTConfig))
+	router.POST("/login", handlers.Login(testJWTConfig))
+	return router
+}
+
+func postJSON(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterFieldErrors(t *testing.T) {
+	router := newAuthRouter()
+
+	w := postJSON(router, "/register", `{"username":"a!","email":"not-an-email","password":"weak"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d", w.Code)
+	}
+
+	resp := decodeError(t, w)
+
+	if resp.Code != apierror.CodeValidationFailed {
+		t.Errorf("Expected code %q, but got %q", apierror.CodeValidationFailed, resp.Code)
+	}
+	if resp.Message == "" {
+		t.Error("Expected top-level message, but got empty string")
+	}
+
+	fields := make(map[string]bool)
+	for _, fe := range resp.Fields {
+		fields[fe.Field] = true
+		if fe.Message == "" {
+			t.Errorf("Expected message for field %s, but got empty string", fe.Field)
+		}
+	}
+	for _, field := range []string{"username", "email", "password"} {
+		if !fields[field] {
+			t.Errorf("Expected error for field %s, but got none", field)
+		}
+	}
+}
+
+func TestLoginBindingFieldErrors(t *testing.T) {
+	router := newAuthRouter()
+
+	w := postJSON(router, "/login", `{"email":"test@example.com"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+
+	resp := decodeError(t, w)
+
+	if len(resp.Fields) != 1 || resp.Fields[0].Field != "password" {
+		t.Errorf("Expected a single error for field password, but got %+v", resp.Fields)
+	}
+}
+
+func TestMalformedPayloadMessage(t *testing.T) {
+	router := newAuthRouter()
+
+	w := postJSON(router, "/login", `{not json`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+
+	resp := decodeError(t, w)
+
+	if resp.Code != apierror.CodeBadRequest {
+		t.Errorf("Expected code %q, but got %q", apierror.CodeBadRequest, resp.Code)
+	}
+	if resp.Message != "Invalid request payload" {
+		t.Errorf("Expected message 'Invalid request payload', but got %q", resp.Message)
+	}
+	if len(resp.Fields) != 0 {
+		t.Errorf("Expected no field errors, but got %+v", resp.Fields)
+	}
+}
+
+func TestMalformedVersusInvalidStatus(t *testing.T) {
+	setupTestDB(t)
+	router := newAuthRouter()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"Not JSON", `{not json`, http.StatusBadRequest, apierror.CodeBadRequest},
+		{"Wrong field type", `{"username":42,"email":"test@example.com","password":"ValidPass123"}`, http.StatusBadRequest, apierror.CodeBadRequest},
+		{"Missing required field", `{"username":"testuser","email":"test@example.com"}`, http.StatusBadRequest, apierror.CodeValidationFailed},
+		{"Weak password", `{"username":"testuser","email":"test@example.com","password":"weak"}`, http.StatusUnprocessableEntity, apierror.CodeValidationFailed},
+		{"Bad email format", `{"username":"testuser","email":"not-an-email","password":"ValidPass123"}`, http.StatusUnprocessableEntity, apierror.CodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(router, "/register", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if resp := decodeError(t, w); resp.Code != tt.expectedCode {
+				t.Errorf("Expected code %q, but got %q", tt.expectedCode, resp.Code)
+			}
+		})
+	}
+}
+
+func TestValidEmail(t *testing.T) {
+	valid := []string{
+		"user@example.com",
+		"user+tag@sub.example.co.uk",
+		"first.last@example.com",
+		"o'brien@example.ie",
+		`"john doe"@example.com`,
+		"user@[192.0.2.1]",
+		"user@[IPv6:2001:db8::1]",
+		"user@example.xn--p1ai",
+		"user@xn--bcher-kva.example",
+		"jöhn@bücher.de",
+	}
+	for _, email := range valid {
+		if !validation.ValidEmail(validation.NormalizeEmail(email)) {
+			t.Errorf("Expected %q to be valid", email)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"plainaddress",
+		"user@@example.com",
+		"@example.com",
+		"user@",
+		"user@localhost",
+		"user@example..com",
+		"user@-example.com",
+		"user@exa_mple.com",
+		"user@example.123",
+		"user@example.c",
+		".user@example.com",
+		"user.@example.com",
+		"us..er@example.com",
+		"a b@example.com",
+		"user@[300.1.1.1]",
+		"John <john@example.com>",
+		"<john@example.com>",
+		"john@example.com (work)",
+		"john@example.com, jane@example.com",
+		"user\n@example.com",
+		strings.Repeat("a", validation.MaxEmailLength) + "@example.com",
+	}
+	for _, email := range invalid {
+		if validation.ValidEmail(validation.NormalizeEmail(email)) {
+			t.Errorf("Expected %q to be invalid", email)
+		}
+	}
+}
+
+func TestNormalizeEmailCanonicalForm(t *testing.T) {
+	tests := map[string]string{
+		` "John"@Example.COM `:       "john@example.com",
+		`"john doe"@example.com`:     `"john doe"@example.com`,
+		"user@[IPv6:2001:DB8::1]":    "user@[IPv6:2001:db8::1]",
+		"User+Tag@Sub.Example.Co.UK": "user+tag@sub.example.co.uk",
+		"John <john@example.com>":    "john <john@example.com>",
+	}
+	for input, expected := range tests {
+		if got := validation.NormalizeEmail(input); got != expected {
+			t.Errorf("Expected %q to normalize to %q, but got %q", input, expected, got)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/apierror"
+	"go-crud-app/internal/database"
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/models"
+)
+
+func TestUpdateProfileRejectsConcurrentUpdate(t *testing.T) {
+	setupTestDB(t)
+	created := createTestUser(t, "versioned", "versioned@example.com")
+
+	// Two requests read the same version of the user
+	var first, second models.User
+	if err := database.DB.First(&first, created.ID).Error; err != nil {
+		t.Fatalf("Failed to load user: %v", err)
+	}
+	if err := database.DB.First(&second, created.ID).Error; err != nil {
+		t.Fatalf("Failed to load user: %v", err)
+	}
+
+	if err := handlers.UpdateProfile(database.DB, &first, map[string]interface{}{"username": "firstwriter"}); err != nil {
+		t.Fatalf("Expected the first update to succeed, but got %v", err)
+	}
+	if first.Version != created.Version+1 {
+		t.Errorf("Expected version %d after the update, but got %d", created.Version+1, first.Version)
+	}
+
+	err := handlers.UpdateProfile(database.DB, &second, map[string]interface{}{"username": "secondwriter"})
+	if !errors.Is(err, handlers.ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict for the second update, but got %v", err)
+	}
+
+	var stored models.User
+	if err := database.DB.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("Failed to load user: %v", err)
+	}
+	if stored.Username != "firstwriter" || stored.Version != first.Version {
+		t.Errorf("Expected the first update to survive at version %d, but got %s at version %d", first.Version, stored.Username, stored.Version)
+	}
+}
+
+func TestUpdateUserChecksVersion(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	user := createTestUser(t, "versioned", "versioned@example.com")
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	// Both clients fetch the user first
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users/me", "", user))
+	var fetched models.UserResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &fetched); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if fetched.Version != 1 {
+		t.Fatalf("Expected a new user to be at version 1, but got %d", fetched.Version)
+	}
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedVersion int
+	}{
+		{
+			name:            "First client with the current version",
+			body:            fmt.Sprintf(`{"username":"firstclient","version":%d}`, fetched.Version),
+			expectedStatus:  http.StatusOK,
+			expectedVersion: 2,
+		},
+		{
+			name:           "Second client with the stale version",
+			body:           fmt.Sprintf(`{"username":"secondclient","version":%d}`, fetched.Version),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:            "Update without a version",
+			body:            `{"username":"thirdclient"}`,
+			expectedStatus:  http.StatusOK,
+			expectedVersion: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodPatch, path, tt.body, user))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				if body := decodeError(t, w); body.Code != apierror.CodeVersionConflict {
+					t.Errorf("Expected code %s, but got %s", apierror.CodeVersionConflict, body.Code)
+				}
+				return
+			}
+			var resp models.UserResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Version != tt.expectedVersion {
+				t.Errorf("Expected version %d, but got %d", tt.expectedVersion, resp.Version)
+			}
+		})
+	}
+}
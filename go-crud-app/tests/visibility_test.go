@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-crud-app/internal/handlers"
+)
+
+func TestGetUserByIDVisibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		visibility  string
+		viewerAdmin bool
+		viewSelf    bool
+		expectEmail bool
+	}{
+		{"self", handlers.ProfileVisibilityRestricted, false, true, true},
+		{"other user", handlers.ProfileVisibilityRestricted, false, false, false},
+		{"admin", handlers.ProfileVisibilityRestricted, true, false, true},
+		{"other user with full visibility", handlers.ProfileVisibilityFull, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			handlers.ProfileVisibility = tt.visibility
+			defer func() { handlers.ProfileVisibility = handlers.ProfileVisibilityRestricted }()
+
+			router := newUserRouter()
+			viewer := createTestUser(t, "viewer", "viewer@example.com")
+			if tt.viewerAdmin {
+				viewer = createTestAdmin(t, "admin", "admin@example.com")
+			}
+			target := createTestUser(t, "target", "target@example.com")
+			if tt.viewSelf {
+				target = viewer
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequest(t, http.MethodGet, fmt.Sprintf("/users/%d", target.ID), "", viewer))
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body["username"] != target.Username {
+				t.Errorf("Expected username %q, but got %v", target.Username, body["username"])
+			}
+			if _, hasEmail := body["email"]; hasEmail != tt.expectEmail {
+				t.Errorf("Expected email present %v, but got %v", tt.expectEmail, hasEmail)
+			}
+		})
+	}
+}
+
+func TestGetAllUsersHidesEmailsFromNonAdmins(t *testing.T) {
+	setupTestDB(t)
+	router := newUserRouter()
+
+	viewer := createTestUser(t, "viewer", "viewer@example.com")
+	createTestUser(t, "other", "other@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(t, http.MethodGet, "/users", "", viewer))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var body struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Users) != 1 {
+		t.Fatalf("Expected 1 user, but got %d", len(body.Users))
+	}
+	if _, hasEmail := body.Users[0]["email"]; hasEmail {
+		t.Error("Expected email to be omitted from the list, but it was present")
+	}
+}
@@ -0,0 +1,303 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-crud-app/internal/handlers"
+	"go-crud-app/internal/webhook"
+)
+
+func TestWebhookSignatureCoversTimestampAndBody(t *testing.T) {
+	endpoint := webhook.Endpoint{URL: "https://example.com/hooks", Secret: "endpoint-secret"}
+	body := []byte(`{"event":"user.created","user_id":1}`)
+	now := time.Unix(1760000000, 0)
+
+	req, err := webhook.NewRequest(context.Background(), endpoint, body, now)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	signature := req.Header.Get(webhook.SignatureHeader)
+	timestamp := req.Header.Get(webhook.TimestampHeader)
+
+	sent, _ := io.ReadAll(req.Body)
+	if string(sent) != string(body) {
+		t.Errorf("Expected body %s, but got %s", body, sent)
+	}
+
+	earlier := strconv.FormatInt(now.Add(-time.Second).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		now       time.Time
+		expected  error
+	}{
+		{
+			name:      "Valid delivery",
+			secret:    endpoint.Secret,
+			signature: signature,
+			timestamp: timestamp,
+			body:      body,
+			now:       now.Add(time.Minute),
+			expected:  nil,
+		},
+		{
+			name:      "Tampered body",
+			secret:    endpoint.Secret,
+			signature: signature,
+			timestamp: timestamp,
+			body:      []byte(`{"event":"user.created","user_id":2}`),
+			now:       now,
+			expected:  webhook.ErrInvalidSignature,
+		},
+		{
+			name:      "Tampered timestamp",
+			secret:    endpoint.Secret,
+			signature: signature,
+			timestamp: earlier,
+			body:      body,
+			now:       now,
+			expected:  webhook.ErrInvalidSignature,
+		},
+		{
+			name:      "Another endpoint's secret",
+			secret:    "other-secret",
+			signature: signature,
+			timestamp: timestamp,
+			body:      body,
+			now:       now,
+			expected:  webhook.ErrInvalidSignature,
+		},
+		{
+			name:      "Replayed after tolerance",
+			secret:    endpoint.Secret,
+			signature: signature,
+			timestamp: timestamp,
+			body:      body,
+			now:       now.Add(webhook.DefaultTolerance + time.Second),
+			expected:  webhook.ErrStaleTimestamp,
+		},
+		{
+			name:      "Rotated secret among several signatures",
+			secret:    endpoint.Secret,
+			signature: webhook.Sign("old-secret", now, body) + "," + signature,
+			timestamp: timestamp,
+			body:      body,
+			now:       now,
+			expected:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.Verify(tt.secret, tt.signature, tt.timestamp, tt.body, webhook.DefaultTolerance, tt.now)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, but got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestWebhookEndpointsFromEnv(t *testing.T) {
+	t.Setenv("WEBHOOK_SECRET", "shared-secret")
+	t.Setenv("WEBHOOK_ENDPOINTS", "https://a.example.com/hook|secret-a, https://b.example.com/hook")
+
+	endpoints := webhook.EndpointsFromEnv()
+	expected := []webhook.Endpoint{
+		{URL: "https://a.example.com/hook", Secret: "secret-a"},
+		{URL: "https://b.example.com/hook", Secret: "shared-secret"},
+	}
+	if len(endpoints) != len(expected) {
+		t.Fatalf("Expected %d endpoints, but got %d", len(expected), len(endpoints))
+	}
+	for i := range expected {
+		if endpoints[i] != expected[i] {
+			t.Errorf("Expected endpoint %+v, but got %+v", expected[i], endpoints[i])
+		}
+	}
+}
+
+// webhookReceiver is a local endpoint that verifies deliveries and answers
+// with the next status from statuses (200 once they run out)
+type webhookReceiver struct {
+	server   *httptest.Server
+	endpoint webhook.Endpoint
+	events   chan webhook.Event
+	mu       sync.Mutex
+	statuses []int
+	attempts int
+}
+
+// newWebhookReceiver starts a receiver that is closed when the test ends
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	t.Helper()
+
+	r := &webhookReceiver{events: make(chan webhook.Event, 10), statuses: statuses}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if err := webhook.Verify(r.endpoint.Secret, req.Header.Get(webhook.SignatureHeader),
+			req.Header.Get(webhook.TimestampHeader), body, webhook.DefaultTolerance, time.Now()); err != nil {
+			t.Errorf("Expected a valid signature, but got %v", err)
+		}
+
+		r.mu.Lock()
+		r.attempts++
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mu.Unlock()
+
+		if status == http.StatusOK {
+			var event webhook.Event
+			if err := json.Unmarshal(body, &event); err != nil {
+				t.Errorf("Failed to decode event: %v", err)
+			}
+			r.events <- event
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.server.Close)
+
+	r.endpoint = webhook.Endpoint{URL: r.server.URL, Secret: "receiver-secret"}
+	return r
+}
+
+// Attempts returns how many deliveries the receiver has seen
+func (r *webhookReceiver) Attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attempts
+}
+
+// waitForEvent returns the next event the receiver accepted
+func waitForEvent(t *testing.T, r *webhookReceiver) webhook.Event {
+	t.Helper()
+
+	select {
+	case event := <-r.events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for a webhook delivery after %d attempts", r.Attempts())
+		return webhook.Event{}
+	}
+}
+
+// startNotifier runs a notifier with short backoffs until the test ends
+func startNotifier(t *testing.T, config webhook.Config) *webhook.Notifier {
+	t.Helper()
+
+	config.InitialBackoff = time.Millisecond
+	config.MaxBackoff = 5 * time.Millisecond
+	notifier := webhook.NewNotifier(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		notifier.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return notifier
+}
+
+func TestNotifierRetriesUntilDelivered(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	notifier := startNotifier(t, webhook.Config{Endpoints: []webhook.Endpoint{receiver.endpoint}})
+
+	notifier.Notify(context.Background(), webhook.EventUserUpdated, 42)
+
+	event := waitForEvent(t, receiver)
+	if event.Event != webhook.EventUserUpdated || event.UserID != 42 {
+		t.Errorf("Expected %s for user 42, but got %+v", webhook.EventUserUpdated, event)
+	}
+	if event.ID == "" || event.Timestamp.IsZero() {
+		t.Errorf("Expected an event ID and timestamp, but got %+v", event)
+	}
+	if attempts := receiver.Attempts(); attempts != 3 {
+		t.Errorf("Expected 3 attempts, but got %d", attempts)
+	}
+}
+
+func TestNotifierDeadLetters(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		expectedAttempts int
+	}{
+		{
+			name:             "retryable failures until max attempts",
+			statuses:         []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "client errors are not retried",
+			statuses:         []int{http.StatusBadRequest},
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newWebhookReceiver(t, tt.statuses...)
+			deadLetters := make(chan int, 1)
+			notifier := startNotifier(t, webhook.Config{
+				Endpoints:   []webhook.Endpoint{receiver.endpoint},
+				MaxAttempts: 3,
+				DeadLetter: func(_ webhook.Endpoint, _ []byte, attempts int, _ error) {
+					deadLetters <- attempts
+				},
+			})
+
+			notifier.Notify(context.Background(), webhook.EventUserDeleted, 7)
+
+			select {
+			case attempts := <-deadLetters:
+				if attempts != tt.expectedAttempts {
+					t.Errorf("Expected dead letter after %d attempts, but got %d", tt.expectedAttempts, attempts)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for a dead letter")
+			}
+			if attempts := receiver.Attempts(); attempts != tt.expectedAttempts {
+				t.Errorf("Expected the receiver to see %d attempts, but got %d", tt.expectedAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRegisterNotifiesWebhooks(t *testing.T) {
+	setupTestDB(t)
+	receiver := newWebhookReceiver(t)
+	handlers.Webhooks = startNotifier(t, webhook.Config{Endpoints: []webhook.Endpoint{receiver.endpoint}})
+	t.Cleanup(func() { handlers.Webhooks = nil })
+
+	w := postJSON(newAuthRouter(), "/register", `{"username":"hookuser","email":"hook@example.com","password":"StrongPass123"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp handlers.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	event := waitForEvent(t, receiver)
+	if event.Event != webhook.EventUserRegistered || event.UserID != resp.User.ID {
+		t.Errorf("Expected %s for user %d, but got %+v", webhook.EventUserRegistered, resp.User.ID, event)
+	}
+}